@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// usersBucket is the bbolt bucket the users are kept in.
+var usersBucket = []byte("users")
+
+// BoltStore is a UserStore kept in a bbolt file: an embedded key-value
+// database, so the users survive restarts without an external database.
+// Each user is stored as JSON under its ID, and IDs come from the bucket's
+// sequence, which bbolt persists along with the data.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens (creating it if needed) the bbolt database at path.
+// bbolt locks the file, so only one server can have it open at a time.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	// Give up rather than hang if another process has the file open.
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(usersBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating bucket in %s: %w", path, err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// boltKey encodes an ID as a big-endian key, so the bucket is sorted by ID.
+func boltKey(id int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
+
+// getUser reads the user with the ID from the bucket.
+func getUser(b *bolt.Bucket, id int) (User, error) {
+	v := b.Get(boltKey(id))
+	if v == nil {
+		return User{}, ErrNotFound
+	}
+	var u User
+	if err := json.Unmarshal(v, &u); err != nil {
+		return User{}, fmt.Errorf("decoding user %d: %w", id, err)
+	}
+	return u, nil
+}
+
+// putUser writes the user to the bucket under its ID.
+func putUser(b *bolt.Bucket, u User) error {
+	v, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return b.Put(boltKey(u.ID), v)
+}
+
+func (s *BoltStore) Get(id int) (User, error) {
+	var u User
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		u, err = getUser(tx.Bucket(usersBucket), id)
+		return err
+	})
+	return u, err
+}
+
+func (s *BoltStore) List() ([]User, error) {
+	users := []User{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// Keys are big-endian IDs, so ForEach goes through them in ID order.
+		return tx.Bucket(usersBucket).ForEach(func(k, v []byte) error {
+			var u User
+			if err := json.Unmarshal(v, &u); err != nil {
+				return fmt.Errorf("decoding user %d: %w", binary.BigEndian.Uint64(k), err)
+			}
+			users = append(users, u)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *BoltStore) Create(user User) (User, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		// The sequence starts at 1 and only ever goes up, so IDs aren't reused.
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		user.ID = int(id)
+		return putUser(b, user)
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (s *BoltStore) Update(id int, fn func(User) (User, error)) (User, error) {
+	var user User
+	// bbolt runs one read-write transaction at a time, so nothing can change
+	// the user between reading it and writing it back.
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		old, err := getUser(b, id)
+		if err != nil {
+			return err
+		}
+		user, err = fn(old)
+		if err != nil {
+			return err
+		}
+		user.ID = id
+		return putUser(b, user)
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (s *BoltStore) Delete(id int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		// Deleting a key that doesn't exist does nothing.
+		return tx.Bucket(usersBucket).Delete(boltKey(id))
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// fileSnapshot is the JSON file a file-backed MemoryStore is saved to.
+type fileSnapshot struct {
+	// NextID is saved too, so IDs of deleted users aren't handed out again
+	// after a restart.
+	NextID int    `json:"nextId"`
+	Users  []User `json:"users"`
+}
+
+// OpenFileStore returns a MemoryStore that is loaded from the JSON file at path
+// (if it exists) and saves a snapshot of every user back to it after each
+// change, so the users survive restarts without a database.
+// Snapshots are written to a temporary file that then replaces the old one,
+// so a crash mid-write can't leave a half-written file behind.
+func OpenFileStore(path string) (*MemoryStore, error) {
+	s := NewMemoryStore()
+
+	// Load the last snapshot; a missing file just means there are no users yet.
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	default:
+		var snap fileSnapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		for _, u := range snap.Users {
+			s.users[u.ID] = u
+			// Never reuse an ID, even if the counter in the file is behind.
+			s.nextID = max(s.nextID, u.ID+1)
+		}
+		s.nextID = max(s.nextID, snap.NextID)
+	}
+
+	s.save = func(users map[int]User, nextID int) error {
+		snap := fileSnapshot{NextID: nextID, Users: make([]User, 0, len(users))}
+		for _, u := range users {
+			snap.Users = append(snap.Users, u)
+		}
+		slices.SortFunc(snap.Users, func(a, b User) int { return a.ID - b.ID })
+		data, err := json.MarshalIndent(snap, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(path, data)
+	}
+	return s, nil
+}
+
+// writeFileAtomic replaces the file at path with data: it is written to a
+// temporary file in the same directory, flushed to disk, then renamed over
+// path, so readers (and the next start) see either the old or the new file,
+// never a mix.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("saving %s: %w", path, err)
+	}
+	// Once renamed, the temporary file is gone and this does nothing.
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("saving %s: %w", path, err)
+	}
+	return nil
+}
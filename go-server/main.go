@@ -1,51 +1,85 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"mime"
 	"net/http"
 	"strconv"
-	"sync"
+	"time"
 	"log" // Added for better error logging
 )
 
-// --- Data Structures and Global State ---
+// --- Data Structures and Server State ---
 
-// User defines the structure for a user object.
-// The `json:"name"` tag is crucial, telling the `encoding/json` package
-// how to map the struct field to the JSON key when encoding/decoding.
-type User struct {
-	Name string `json:"name"`
-}
-
-// userCache acts as our in-memory "database" to store User objects.
-// Keys are integers (acting as user IDs), and values are User structs.
-var userCache = make(map[int]User)
-
-// cacheMutex is a Read-Write Mutex (RWMutex) used to protect userCache
-// from race conditions when multiple goroutines (requests) try to read or write concurrently.
-var cacheMutex sync.RWMutex
+// User and its validation live in user.go, and the UserStore interface and
+// its implementations in store.go.
 
-// nextID tracks the next ID to assign to a new user.
-var nextID = 1
+// server holds what the handlers share. The handlers are its methods, so they
+// reach the users through the store rather than through global variables.
+type server struct {
+	// store is where the users are kept.
+	store UserStore
+}
 
 // --- Main Function and Server Setup ---
 
 func main() {
+	// Command-line flags choose where the users are kept, e.g.
+	//   go run . -storage=sqlite -db=users.db
+	//   go run . -storage=file
+	//   go run . -storage=bolt -db=users.bolt
+	storage := flag.String("storage", "memory", "where users are kept: memory, file, sqlite or bolt")
+	dbPath := flag.String("db", "", "file the users are kept in (default users.json for file, users.db for sqlite, users.bolt for bolt)")
+	// Any of them can be put behind a Redis cache, e.g.
+	//   go run . -storage=sqlite -redis=localhost:6379 -cache-ttl=10m
+	redisAddr := flag.String("redis", "", "cache users in the Redis server at this host:port")
+	cacheTTL := flag.Duration("cache-ttl", 5*time.Minute, "how long users stay in the Redis cache")
+	flag.Parse()
+
+	store, err := openStore(*storage, *dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *redisAddr != "" {
+		cache := NewRedisCache(store, *redisAddr, *cacheTTL)
+		// The server works without the cache, so an unreachable Redis is only a warning.
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		if err := cache.Health(ctx); err != nil {
+			log.Printf("warning: %v; users will be read from the store until it's reachable", err)
+		}
+		cancel()
+		store = cache
+	}
+	srv := &server{store: store}
+
 	// Initialize a new HTTP request multiplexer (router).
 	// This is responsible for matching incoming requests to their appropriate handlers.
 	mux := http.NewServeMux()
 
 	// 1. Root Handler: A simple health check or welcome message.
 	mux.HandleFunc("/", handleRoot)
+	// GET /healthz: Report whether the store's connections (e.g. to Redis) are up.
+	mux.HandleFunc("GET /healthz", srv.handleHealth)
 
 	// 2. RESTful API Handlers: Using the new Go 1.22 routing features (HTTP method + path pattern).
 	// POST /users: Create a new user.
-	mux.HandleFunc("POST /users", handleCreateUser)
+	mux.HandleFunc("POST /users", srv.handleCreateUser)
+	// GET /users: List every user, by ID.
+	mux.HandleFunc("GET /users", srv.handleListUsers)
 	// GET /users/{id}: Fetch a user by their ID (the {id} is a path variable).
-	mux.HandleFunc("GET /users/{id}", handleGetUser)
+	mux.HandleFunc("GET /users/{id}", srv.handleGetUser)
+	// PUT /users/{id}: Replace an existing user with the one in the request body.
+	mux.HandleFunc("PUT /users/{id}", srv.handleUpdateUser)
+	// PATCH /users/{id}: Change only the fields sent, as a JSON Merge Patch (RFC 7386).
+	mux.HandleFunc("PATCH /users/{id}", srv.handlePatchUser)
 	// DELETE /users/{id}: Delete a user by their ID.
-	mux.HandleFunc("DELETE /users/{id}", handleDeleteUser)
+	mux.HandleFunc("DELETE /users/{id}", srv.handleDeleteUser)
 
 	// Start the HTTP server. http.ListenAndServe blocks execution until the server stops.
 	fmt.Println("Server is listening on port 8080...")
@@ -53,6 +87,24 @@ func main() {
 	log.Fatal(http.ListenAndServe(":8080", mux))
 }
 
+// openStore returns the UserStore for the -storage flag; path is the file
+// for the backends that keep one ("" for their default).
+func openStore(storage, path string) (UserStore, error) {
+	switch storage {
+	case "memory":
+		// The users are lost when the server stops.
+		return NewMemoryStore(), nil
+	case "file":
+		// The users are kept in memory and saved to a JSON file on every change.
+		return OpenFileStore(cmp.Or(path, "users.json"))
+	case "sqlite":
+		return OpenSQLiteStore(cmp.Or(path, "users.db"))
+	case "bolt":
+		return OpenBoltStore(cmp.Or(path, "users.bolt"))
+	}
+	return nil, fmt.Errorf("unknown -storage %q (want memory, file, sqlite or bolt)", storage)
+}
+
 // --- Handlers Implementation ---
 
 // handleRoot simply responds with a static "Hello, World" message.
@@ -64,41 +116,58 @@ func handleRoot(
 	fmt.Fprintf(w, "Hello, Go API World!")
 }
 
+// handleHealth handles GET requests to /healthz, for load balancers and monitoring.
+// It responds 200 OK with {"status": "ok"} when the store is healthy, and
+// 503 Service Unavailable when one of its connections is down.
+func (s *server) handleHealth(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	// Only stores with connections to check implement HealthChecker.
+	if hc, ok := s.store.(HealthChecker); ok {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := hc.Health(ctx); err != nil {
+			writeProblem(w, r, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, `{"status":"ok"}`)
+}
+
 // handleCreateUser handles POST requests to /users to add a new user.
-func handleCreateUser(
+func (s *server) handleCreateUser(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	var user User
-	
 	// 1. Decode the JSON request body into the User struct.
-	err := json.NewDecoder(r.Body).Decode(&user)
+	user, err := decodeUser(r.Body)
 	if err != nil {
-		// If JSON decoding fails (e.g., malformed JSON), return 400 Bad Request.
-		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		// If JSON decoding fails (e.g., malformed JSON or an unknown field), return 400 Bad Request.
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
 
 	// 2. Input Validation
-	if user.Name == "" {
-		// If the required 'name' field is missing, return 400 Bad Request.
-		http.Error(w, "Name field is required", http.StatusBadRequest)
+	if errs := user.Validate(); errs != nil {
+		// Report every invalid field at once, not just the first.
+		writeValidationErrors(w, r, errs)
 		return
 	}
 
-	// 3. Acquire Write Lock
-	// We use Lock() because we are modifying the shared resource (userCache and nextID).
-	cacheMutex.Lock()
-	
-	// Assign the current nextID as the new user's ID.
-	userID := nextID
-	// Store the new user in the cache.
-	userCache[userID] = user
-	// Increment the ID counter for the next user.
-	nextID++
-	
-	// Release the lock immediately after modifying the shared resources.
-	cacheMutex.Unlock()
+	// The timestamps belong to the server, whatever the client sent.
+	now := time.Now().UTC()
+	user.CreatedAt, user.UpdatedAt = now, now
+
+	// 3. Store the user
+	// The store assigns the new user's ID.
+	user, err = s.store.Create(user)
+	if err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
 	
 	// 4. Send Response
 	// Set the status code to 201 Created to indicate successful resource creation.
@@ -107,14 +176,40 @@ func handleCreateUser(
 	// Write a response body indicating the success and the assigned ID.
 	// NOTE: The previous code had a bug where fmt.Fprintf was called before WriteHeader,
 	// which would incorrectly set the status to 200 OK. This is now corrected.
-	fmt.Fprintf(w, "User successfully created with ID: %d", userID)
+	fmt.Fprintf(w, "User successfully created with ID: %d", user.ID)
 	
 	// Optional: In a real-world scenario, you might return the full created resource object 
-	// or the location header (w.Header().Set("Location", "/users/"+strconv.Itoa(userID))).
+	// or the location header (w.Header().Set("Location", "/users/"+strconv.Itoa(user.ID))).
+}
+
+// handleListUsers handles GET requests to /users to list every user.
+func (s *server) handleListUsers(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	users, err := s.store.List()
+	if err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(users)
+	if err != nil {
+		writeProblem(
+			w,
+			r,
+			http.StatusInternalServerError,
+			"Error encoding JSON response",
+		)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
 }
 
 // handleGetUser handles GET requests to /users/{id} to retrieve a user by ID.
-func handleGetUser(
+func (s *server) handleGetUser(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
@@ -124,29 +219,19 @@ func handleGetUser(
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		// If the 'id' is not a valid integer, return 400 Bad Request.
-		http.Error(w, "Invalid user ID format: "+err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid user ID format: "+err.Error())
 		return
 	}
 
-	// 2. Acquire Read Lock
-	// We use RLock() because we are only reading the shared resource (userCache).
-	// This allows multiple readers to access the map simultaneously.
-	cacheMutex.RLock()
-	user, ok := userCache[id]
-	cacheMutex.RUnlock() // Release the lock immediately after reading.
-
-	// 3. Check for User Existence
-	if !ok {
-		// If the user ID is not found in the map, return 404 Not Found.
-		http.Error(
-			w,
-			fmt.Sprintf("User with ID %d not found", id),
-			http.StatusNotFound,
-		)
+	// 2. Fetch the User
+	user, err := s.store.Get(id)
+	if err != nil {
+		// If the user ID is not found in the store, return 404 Not Found.
+		writeStoreError(w, r, err)
 		return
 	}
 
-	// 4. Encode and Send Response
+	// 3. Encode and Send Response
 	// Set the Content-Type header to inform the client that the response body is JSON.
 	w.Header().Set("Content-Type", "application/json")
 
@@ -154,10 +239,11 @@ func handleGetUser(
 	j, err := json.Marshal(user)
 	if err != nil {
 		// If JSON encoding fails (shouldn't happen with simple structs), return 500 Internal Server Error.
-		http.Error(
+		writeProblem(
 			w,
-			"Error encoding JSON response",
+			r,
 			http.StatusInternalServerError,
+			"Error encoding JSON response",
 		)
 		return
 	}
@@ -168,8 +254,210 @@ func handleGetUser(
 	w.Write(j)
 }
 
+// handleUpdateUser handles PUT requests to /users/{id} to replace an existing user.
+// PUT replaces the whole resource, so the body must be a complete, valid user.
+func (s *server) handleUpdateUser(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	// 1. Extract and Convert Path Variable
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		// If the 'id' is not a valid integer, return 400 Bad Request.
+		writeProblem(w, r, http.StatusBadRequest, "Invalid user ID format: "+err.Error())
+		return
+	}
+
+	// 2. Decode the JSON request body into the User struct.
+	user, err := decodeUser(r.Body)
+	if err != nil {
+		// If JSON decoding fails (e.g., malformed JSON or an unknown field), return 400 Bad Request.
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	// 3. Input Validation (the same rules as creating a user)
+	if errs := user.Validate(); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	// 4. Replace the User
+	// The store checks the user exists and replaces it in one step, so a
+	// concurrent DELETE can't slip in between them.
+	user, err = s.store.Update(id, func(old User) (User, error) {
+		// Replacing a user doesn't change when it was created.
+		user.CreatedAt, user.UpdatedAt = old.CreatedAt, time.Now().UTC()
+		return user, nil
+	})
+	if err != nil {
+		// PUT only replaces existing users; IDs are assigned by POST /users.
+		writeStoreError(w, r, err)
+		return
+	}
+
+	// 5. Encode and Send Response
+	// Return the updated resource, just like GET /users/{id} would now.
+	j, err := json.Marshal(user)
+	if err != nil {
+		writeProblem(
+			w,
+			r,
+			http.StatusInternalServerError,
+			"Error encoding JSON response",
+		)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// handlePatchUser handles PATCH requests to /users/{id} to partially update a user.
+// The body is a JSON Merge Patch (RFC 7386): fields present in it are changed,
+// fields set to null are removed (reset to their zero value), and fields left out
+// stay as they are. For example, {"name": "Ada"} only renames the user.
+func (s *server) handlePatchUser(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	// 1. Extract and Convert Path Variable
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		// If the 'id' is not a valid integer, return 400 Bad Request.
+		writeProblem(w, r, http.StatusBadRequest, "Invalid user ID format: "+err.Error())
+		return
+	}
+
+	// 2. Check the Content-Type
+	// Merge patches have their own media type; plain JSON is accepted too,
+	// since it's what most clients send by default.
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			writeProblem(w, r, http.StatusUnsupportedMediaType, "Content-Type must be application/merge-patch+json")
+			return
+		}
+	}
+
+	// 3. Decode the patch
+	// Decoding into a map (rather than a User) is what lets us tell a field that
+	// was left out (no key) from one set to its zero value (e.g. "name": "").
+	var patch map[string]any
+	dec := json.NewDecoder(r.Body)
+	err = dec.Decode(&patch)
+	if err != nil || patch == nil {
+		// A patch that isn't a JSON object would replace the whole user
+		// with something that isn't a user, so it's rejected too.
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body: the patch must be a JSON object")
+		return
+	}
+	if dec.More() {
+		// Like decodeUser, anything after the object means a malformed body.
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body: unexpected data after the JSON object")
+		return
+	}
+
+	// 4. Patch the User
+	// The store reads the user, lets us patch it and stores it back in one step,
+	// so two concurrent PATCH requests can't overwrite each other's changes.
+	user, err := s.store.Update(id, func(user User) (User, error) {
+		patched, err := applyMergePatch(user, patch)
+		if err != nil {
+			return User{}, errBadPatch{err}
+		}
+
+		// 5. Input Validation
+		// The patched user must be as valid as a newly created one.
+		if errs := patched.Validate(); errs != nil {
+			return User{}, ValidationError(errs)
+		}
+		// The timestamps belong to the server, even if the patch touched them.
+		patched.CreatedAt, patched.UpdatedAt = user.CreatedAt, time.Now().UTC()
+		return patched, nil
+	})
+	var badPatch errBadPatch
+	var invalid ValidationError
+	switch {
+	case errors.As(err, &badPatch):
+		writeProblem(w, r, http.StatusBadRequest, "Invalid patch: "+badPatch.err.Error())
+		return
+	case errors.As(err, &invalid):
+		writeValidationErrors(w, r, invalid)
+		return
+	case err != nil:
+		writeStoreError(w, r, err)
+		return
+	}
+
+	// 6. Encode and Send Response
+	j, err := json.Marshal(user)
+	if err != nil {
+		writeProblem(
+			w,
+			r,
+			http.StatusInternalServerError,
+			"Error encoding JSON response",
+		)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// errBadPatch wraps the error from a merge patch that can't be applied to a user.
+type errBadPatch struct{ err error }
+
+func (e errBadPatch) Error() string { return "invalid patch: " + e.err.Error() }
+
+// applyMergePatch returns user with an RFC 7386 merge patch applied.
+// The user is turned into its JSON form, the patch merged into that, and the
+// result decoded back, so every field is patched by its JSON name.
+func applyMergePatch(user User, patch map[string]any) (User, error) {
+	j, err := json.Marshal(user)
+	if err != nil {
+		return User{}, err
+	}
+	var doc any
+	if err := json.Unmarshal(j, &doc); err != nil {
+		return User{}, err
+	}
+
+	j, err = json.Marshal(mergePatch(doc, patch))
+	if err != nil {
+		return User{}, err
+	}
+	// Decoding like a request body rejects patches adding unknown fields.
+	return decodeUser(bytes.NewReader(j))
+}
+
+// mergePatch applies the merge patch to target (both decoded JSON values), as
+// described in RFC 7386: objects are merged key by key, recursively, a null
+// removes the key, and any other value replaces the target's outright.
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
 // handleDeleteUser handles DELETE requests to /users/{id} to remove a user.
-func handleDeleteUser(
+func (s *server) handleDeleteUser(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
@@ -179,19 +467,16 @@ func handleDeleteUser(
 
 	if err != nil {
 		// If the 'id' is not a valid integer, return 400 Bad Request.
-		http.Error(w, "Invalid user ID format: "+err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid user ID format: "+err.Error())
 		return
 	}
 
-	// 2. Acquire Write Lock
-	// We use Lock() because we are modifying the shared resource (userCache).
-	cacheMutex.Lock()
-	
-	// delete() is safe to call even if the key doesn't exist; it simply does nothing.
-	delete(userCache, id) 
-	
-	// Release the lock.
-	cacheMutex.Unlock()
+	// 2. Delete the User
+	// Deleting a user that doesn't exist isn't an error: the user is gone either way.
+	if err := s.store.Delete(id); err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
 
 	// 3. Send Response
 	// HTTP 204 No Content is the standard successful response for DELETE operations.
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// --- Error Responses ---
+
+// Problem is an error response in the RFC 7807 "problem details" format, served
+// as application/problem+json so clients can handle errors programmatically
+// instead of parsing plain-text messages. For example:
+//
+//	{"type": "about:blank", "title": "Not Found", "status": 404,
+//	 "detail": "User with ID 7 not found", "instance": "/users/7"}
+type Problem struct {
+	// Type is a URI identifying the kind of problem; "about:blank" means
+	// the HTTP status code says all there is to say.
+	Type string `json:"type"`
+	// Title is a short summary of the kind of problem (here the status text).
+	Title string `json:"title"`
+	// Status repeats the HTTP status code, for clients that only see the body.
+	Status int `json:"status"`
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance is the request path the problem occurred on.
+	Instance string `json:"instance,omitempty"`
+
+	// Errors is an extension member listing the invalid fields of a
+	// validation failure.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// writeProblem responds to r with a problem+json error for the status code,
+// with detail explaining what went wrong.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	sendProblem(w, Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+}
+
+// writeValidationErrors responds to r with 400 Bad Request, listing every
+// invalid field in the problem's "errors" member.
+func writeValidationErrors(w http.ResponseWriter, r *http.Request, errs []FieldError) {
+	sendProblem(w, Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(http.StatusBadRequest),
+		Status:   http.StatusBadRequest,
+		Detail:   "The user has invalid fields.",
+		Instance: r.URL.Path,
+		Errors:   errs,
+	})
+}
+
+// sendProblem writes p as the response.
+func sendProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	// Like http.Error, stop clients from sniffing the body as something else.
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// writeStoreError responds to r with the problem for an error from the
+// UserStore: 404 Not Found for ErrNotFound, or else 500 Internal Server Error.
+// The details of an internal error are logged rather than sent to the client.
+func writeStoreError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, http.StatusNotFound, fmt.Sprintf("User with ID %s not found", r.PathValue("id")))
+		return
+	}
+	log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	writeProblem(w, r, http.StatusInternalServerError, "Error accessing the user store")
+}
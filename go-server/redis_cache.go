@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout bounds every Redis call, so a slow or unreachable Redis only
+// costs a request that long before it falls back to the store.
+const redisTimeout = 500 * time.Millisecond
+
+// RedisCache is a UserStore that caches the users of another store in Redis.
+// Reads try Redis first and fill it from the store on a miss (read-through);
+// updates and deletes go to the store and then drop the user from the cache,
+// so the next read sees the change. Cached users expire after the TTL anyway,
+// in case something else changes the store.
+// Each user also has a version key that every invalidation bumps. A read only
+// fills the cache if the version didn't change while it read the store, so a
+// user that was updated in the meantime can't be cached stale.
+// The cache is only ever an optimization: when Redis fails, the error is logged
+// and the store answers, so the API keeps working without it.
+type RedisCache struct {
+	store UserStore
+	rdb   *redis.Client
+	ttl   time.Duration
+}
+
+// NewRedisCache returns a cache for store on the Redis server at addr
+// (host:port), keeping users for ttl.
+func NewRedisCache(store UserStore, addr string, ttl time.Duration) *RedisCache {
+	return &RedisCache{
+		store: store,
+		rdb:   redis.NewClient(&redis.Options{Addr: addr}),
+		ttl:   ttl,
+	}
+}
+
+// Close closes the connection to Redis, and the store if it can be closed.
+func (c *RedisCache) Close() error {
+	err := c.rdb.Close()
+	if closer, ok := c.store.(interface{ Close() error }); ok {
+		err = errors.Join(err, closer.Close())
+	}
+	return err
+}
+
+// Health pings Redis, reporting whether the cache is reachable.
+func (c *RedisCache) Health(ctx context.Context) error {
+	if err := c.rdb.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	return nil
+}
+
+// cacheKey is the Redis key a user is cached under.
+func cacheKey(id int) string {
+	return "user:" + strconv.Itoa(id)
+}
+
+// versionKey is the Redis key counting the invalidations of a user.
+func versionKey(id int) string {
+	return cacheKey(id) + ":version"
+}
+
+func (c *RedisCache) Get(id int) (User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	// 1. Try the cache.
+	data, err := c.rdb.Get(ctx, cacheKey(id)).Bytes()
+	if err == nil {
+		var u User
+		if err := json.Unmarshal(data, &u); err == nil {
+			return u, nil
+		}
+		// A value we can't read is treated as a miss, and overwritten below.
+	} else if !errors.Is(err, redis.Nil) {
+		log.Printf("redis cache: get user %d: %v", id, err)
+	}
+
+	// 2. On a miss, read the store and fill the cache.
+	// The version key is watched while the store is read: if an update or delete
+	// invalidates the user in between, the transaction setting it fails instead
+	// of caching what may be the old user.
+	var u User
+	var storeErr error
+	read := false
+	err = c.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		u, storeErr = c.store.Get(id)
+		read = true
+		if storeErr != nil {
+			return nil
+		}
+		data, err := json.Marshal(u)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, cacheKey(id), data, c.ttl)
+			return nil
+		})
+		return err
+	}, versionKey(id))
+	switch {
+	case errors.Is(err, redis.TxFailedErr):
+		// Invalidated while we read the store: the next read caches it.
+	case err != nil:
+		log.Printf("redis cache: set user %d: %v", id, err)
+	}
+	if !read {
+		// Redis failed before the store was read, so read it without the cache.
+		u, storeErr = c.store.Get(id)
+	}
+	if storeErr != nil {
+		return User{}, storeErr
+	}
+	return u, nil
+}
+
+// List isn't cached: it would have to be invalidated on every change.
+func (c *RedisCache) List() ([]User, error) {
+	return c.store.List()
+}
+
+// Create doesn't touch the cache: a new user is cached when it's first read.
+func (c *RedisCache) Create(user User) (User, error) {
+	return c.store.Create(user)
+}
+
+func (c *RedisCache) Update(id int, fn func(User) (User, error)) (User, error) {
+	// fn is given the store's copy, never a cached one, so an update can't be
+	// based on a stale user.
+	user, err := c.store.Update(id, fn)
+	if err != nil {
+		return User{}, err
+	}
+	c.invalidate(id)
+	return user, nil
+}
+
+func (c *RedisCache) Delete(id int) error {
+	if err := c.store.Delete(id); err != nil {
+		return err
+	}
+	c.invalidate(id)
+	return nil
+}
+
+// invalidate drops the user from the cache after it has changed in the store,
+// and bumps its version so reads already under way don't cache it again.
+// If Redis can't be reached the cached copy may be served until it expires,
+// so that is logged.
+func (c *RedisCache) invalidate(id int) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, versionKey(id))
+		// The version only matters to reads in flight, so it doesn't outlive
+		// the cached users.
+		pipe.Expire(ctx, versionKey(id), c.ttl)
+		pipe.Del(ctx, cacheKey(id))
+		return nil
+	})
+	if err != nil {
+		log.Printf("redis cache: invalidate user %d (stale for up to the TTL): %v", id, err)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+
+	_ "github.com/mattn/go-sqlite3" // registers the "sqlite3" database/sql driver
+)
+
+// SQLiteStore is a UserStore kept in an SQLite database file, so the users
+// survive restarts.
+type SQLiteStore struct {
+	db *sql.DB
+
+	// The statements are prepared once, when the store is opened, rather than
+	// parsed again for every request.
+	get, list, insert, update, remove *sql.Stmt
+}
+
+// sqliteSchema creates the users table if the database doesn't have it yet.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS users (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	name       TEXT     NOT NULL,
+	email      TEXT     NOT NULL DEFAULT '',
+	age        INTEGER  NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+)`
+
+// userColumns are the columns scanUser reads, in order.
+const userColumns = `id, name, email, age, created_at, updated_at`
+
+// OpenSQLiteStore opens (creating it if needed) the SQLite database at path,
+// creates the schema, and prepares the statements.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	// _txlock=immediate makes transactions take the write lock when they begin,
+	// so two concurrent updates wait for each other instead of failing when
+	// they both try to write; _busy_timeout is how long they wait, in ms.
+	dsn := "file:" + url.PathEscape(path) + "?_txlock=immediate&_busy_timeout=5000&_journal_mode=WAL"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema in %s: %w", path, err)
+	}
+
+	s := &SQLiteStore{db: db}
+	for _, p := range []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&s.get, `SELECT ` + userColumns + ` FROM users WHERE id = ?`},
+		{&s.list, `SELECT ` + userColumns + ` FROM users ORDER BY id`},
+		{&s.insert, `INSERT INTO users (name, email, age, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`},
+		{&s.update, `UPDATE users SET name = ?, email = ?, age = ?, created_at = ?, updated_at = ? WHERE id = ?`},
+		{&s.remove, `DELETE FROM users WHERE id = ?`},
+	} {
+		if *p.stmt, err = db.Prepare(p.query); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("preparing %q: %w", p.query, err)
+		}
+	}
+	return s, nil
+}
+
+// Close closes the statements and the database.
+func (s *SQLiteStore) Close() error {
+	for _, stmt := range []*sql.Stmt{s.get, s.list, s.insert, s.update, s.remove} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	return s.db.Close()
+}
+
+// scanUser reads a row of userColumns.
+func scanUser(row interface{ Scan(...any) error }) (User, error) {
+	var u User
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Age, &u.CreatedAt, &u.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
+	return u, err
+}
+
+func (s *SQLiteStore) Get(id int) (User, error) {
+	return scanUser(s.get.QueryRow(id))
+}
+
+func (s *SQLiteStore) List() ([]User, error) {
+	rows, err := s.list.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *SQLiteStore) Create(user User) (User, error) {
+	res, err := s.insert.Exec(user.Name, user.Email, user.Age, user.CreatedAt, user.UpdatedAt)
+	if err != nil {
+		return User{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return User{}, err
+	}
+	user.ID = int(id)
+	return user, nil
+}
+
+func (s *SQLiteStore) Update(id int, fn func(User) (User, error)) (User, error) {
+	// Reading, changing and writing back happen in one transaction, so no
+	// other update can come in between.
+	tx, err := s.db.Begin()
+	if err != nil {
+		return User{}, err
+	}
+	defer tx.Rollback() // does nothing once the transaction is committed
+
+	user, err := scanUser(tx.Stmt(s.get).QueryRow(id))
+	if err != nil {
+		return User{}, err
+	}
+	user, err = fn(user)
+	if err != nil {
+		return User{}, err
+	}
+	user.ID = id
+	_, err = tx.Stmt(s.update).Exec(user.Name, user.Email, user.Age, user.CreatedAt, user.UpdatedAt, id)
+	if err != nil {
+		return User{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (s *SQLiteStore) Delete(id int) error {
+	_, err := s.remove.Exec(id)
+	return err
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// --- Storage ---
+
+// ErrNotFound is returned by a UserStore for an ID it has no user for.
+var ErrNotFound = errors.New("user not found")
+
+// UserStore is where users are kept. The handlers only talk to this interface,
+// so a new backend (a database, a file, ...) is a new implementation of it,
+// chosen in main, without touching the handlers.
+// Implementations must be safe for concurrent use, since every request runs
+// in its own goroutine.
+type UserStore interface {
+	// Get returns the user with the ID, or ErrNotFound.
+	Get(id int) (User, error)
+
+	// List returns every user, sorted by ID.
+	List() ([]User, error)
+
+	// Create stores a new user under the next free ID and returns it with
+	// its ID set.
+	Create(user User) (User, error)
+
+	// Update replaces the user with the ID by what fn returns for the current
+	// one, and returns the stored result, or ErrNotFound. Reading, changing and
+	// writing back happen atomically, so concurrent updates can't overwrite each
+	// other's changes. An error from fn leaves the user unchanged and is returned.
+	Update(id int, fn func(User) (User, error)) (User, error)
+
+	// Delete removes the user with the ID. Deleting a user that doesn't exist
+	// is not an error, so DELETE stays idempotent.
+	Delete(id int) error
+}
+
+// HealthChecker is implemented by stores that depend on a connection (such as
+// RedisCache), so GET /healthz can check it.
+type HealthChecker interface {
+	// Health returns an error if the store can't currently be used as intended.
+	Health(ctx context.Context) error
+}
+
+// MemoryStore is a UserStore that keeps users in a map. They are lost when the
+// server stops, unless the store was opened with OpenFileStore.
+type MemoryStore struct {
+	// mu is a Read-Write Mutex (RWMutex) protecting users and nextID from race
+	// conditions when multiple goroutines (requests) read or write concurrently.
+	mu sync.RWMutex
+
+	// users maps user IDs to users.
+	users map[int]User
+
+	// nextID is the ID the next new user gets.
+	nextID int
+
+	// save, when set, is called with the lock held after every change, to
+	// persist the users; if it fails, the change is undone and the error returned.
+	save func(users map[int]User, nextID int) error
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{users: make(map[int]User), nextID: 1}
+}
+
+func (s *MemoryStore) Get(id int) (User, error) {
+	// RLock allows multiple readers to access the map simultaneously.
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *MemoryStore) List() ([]User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	users := make([]User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	slices.SortFunc(users, func(a, b User) int { return a.ID - b.ID })
+	return users, nil
+}
+
+func (s *MemoryStore) Create(user User) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user.ID = s.nextID
+	s.users[user.ID] = user
+	s.nextID++
+	if err := s.persist(); err != nil {
+		delete(s.users, user.ID)
+		s.nextID--
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (s *MemoryStore) Update(id int, fn func(User) (User, error)) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	user, err := fn(old)
+	if err != nil {
+		return User{}, err
+	}
+	user.ID = id
+	s.users[id] = user
+	if err := s.persist(); err != nil {
+		s.users[id] = old
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (s *MemoryStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.users[id]
+	if !ok {
+		// Nothing to delete, and nothing to save.
+		return nil
+	}
+	delete(s.users, id)
+	if err := s.persist(); err != nil {
+		s.users[id] = old
+		return err
+	}
+	return nil
+}
+
+// persist saves the users with s.save, if it is set. The lock must be held.
+func (s *MemoryStore) persist() error {
+	if s.save == nil {
+		return nil
+	}
+	return s.save(s.users, s.nextID)
+}
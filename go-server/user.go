@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// --- User Model and Validation ---
+
+// User defines the structure for a user object.
+// The `json:"name"` tag is crucial, telling the `encoding/json` package
+// how to map the struct field to the JSON key when encoding/decoding.
+// `omitempty` leaves optional fields out of the JSON when they aren't set.
+type User struct {
+	// ID is assigned by the store when the user is created.
+	ID int `json:"id"`
+
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Age   int    `json:"age,omitempty"`
+
+	// CreatedAt and UpdatedAt are set by the server; values sent by clients are ignored.
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Validation limits for the User fields.
+const (
+	maxNameLength = 100
+	maxAge        = 150
+)
+
+// FieldError describes one invalid field, so clients can show the message next to it.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validate checks every client-supplied field and returns one FieldError per
+// problem found (nil if the user is valid). Field names are the JSON names.
+func (u User) Validate() []FieldError {
+	var errs []FieldError
+
+	// Name is required, and must be something more than whitespace.
+	switch {
+	case strings.TrimSpace(u.Name) == "":
+		errs = append(errs, FieldError{"name", "is required"})
+	case utf8.RuneCountInString(u.Name) > maxNameLength:
+		errs = append(errs, FieldError{"name", fmt.Sprintf("must be at most %d characters", maxNameLength)})
+	}
+
+	// Email is optional, but must be a bare address (no display name) when given.
+	if u.Email != "" {
+		addr, err := mail.ParseAddress(u.Email)
+		if err != nil || addr.Address != u.Email {
+			errs = append(errs, FieldError{"email", "must be a valid email address, e.g. ada@example.com"})
+		}
+	}
+
+	// Age is optional (0 means not given), but must be realistic.
+	if u.Age < 0 || u.Age > maxAge {
+		errs = append(errs, FieldError{"age", fmt.Sprintf("must be between 0 and %d", maxAge)})
+	}
+	return errs
+}
+
+// decodeUser decodes a JSON user from r, rejecting fields the User type doesn't
+// have (a typo like "emial" would otherwise be silently ignored) and anything
+// after the JSON object.
+func decodeUser(r io.Reader) (User, error) {
+	var user User
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&user); err != nil {
+		return User{}, err
+	}
+	if dec.More() {
+		return User{}, errors.New("unexpected data after the JSON object")
+	}
+	return user, nil
+}
+
+// ValidationError is the error for a user with invalid fields, for code that
+// can only return an error (such as the function passed to UserStore.Update).
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + " " + fe.Message
+	}
+	return "invalid user: " + strings.Join(msgs, "; ")
+}
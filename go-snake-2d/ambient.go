@@ -0,0 +1,172 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Ambient effects are purely cosmetic layers composed with the picture
+// through offscreen images, each of which can be turned off in the
+// options for slower machines:
+//
+//   - a starfield drifting slowly under the board's items
+//   - a vignette darkening the corners of the screen
+//   - scanlines, like an old monitor
+//
+// The starfield's tiles are drawn once, and the vignette and scanlines once
+// per screen size, so every frame only copies images.
+
+const (
+	// starTileSize is the side of a starfield tile, in logical pixels;
+	// the tiles repeat across the board
+	starTileSize = 160
+
+	// starLayers is how many tiles are stacked, the nearer ones brighter
+	// and drifting faster for a sense of depth
+	starLayers = 2
+
+	// starsPerTile is how many stars the farthest layer has in a tile;
+	// each nearer layer has half as many
+	starsPerTile = 40
+
+	// starDrift is how far, in logical pixels, the farthest layer drifts
+	// each update (tuned for baseTPS, see timing.go)
+	starDrift = 0.05
+
+	// starTwinkle is how far, in radians, the stars' shimmer moves on each
+	// update
+	starTwinkle = 0.02
+
+	// vignetteStrength is how dark the corners get, and vignetteStart how
+	// far out from the center, as a fraction of the way to the corners,
+	// the darkening begins
+	vignetteStrength = 0.45
+	vignetteStart    = 0.55
+
+	// scanlineAlpha is how dark every other line is
+	scanlineAlpha = 0.12
+)
+
+// Ambient holds the ambient effects' offscreen images and the
+// starfield's drift
+type Ambient struct {
+	// stars are the starfield's tiles, one per layer, drawn at scale
+	stars [starLayers]*ebiten.Image
+	scale float64
+
+	// drift is how far the farthest layer has drifted, and twinkle the
+	// phase of the stars' shimmer
+	drift, twinkle float64
+
+	// overlay is the vignette and scanlines together, for a screen of
+	// overlaySize with the effects in overlayFor
+	overlay     *ebiten.Image
+	overlaySize image.Point
+	overlayFor  [2]bool
+}
+
+// update moves the starfield on
+func (a *Ambient) update() {
+	a.drift = math.Mod(a.drift+starDrift*updateStep(), starTileSize*starLayers)
+	a.twinkle = math.Mod(a.twinkle+starTwinkle*updateStep(), 2*math.Pi)
+}
+
+// drawStars tiles the starfield over the board in screen, which is the
+// board's viewport
+// The stars keep to the screen rather than the board, so they seem far
+// away while a big board scrolls.
+func (a *Ambient) drawStars(screen *ebiten.Image) {
+	if a.stars[0] == nil || a.scale != displayScale {
+		a.renderStars()
+	}
+	r := screen.Bounds()
+	tile := int(starTileSize * displayScale)
+	for i, img := range a.stars {
+		// Nearer layers drift faster and shimmer out of step; all of them
+		// drift down and, at half the speed, to the right
+		d := a.drift * float64(i+1)
+		offX := int(math.Mod(d/2, starTileSize) * displayScale)
+		offY := int(math.Mod(d, starTileSize) * displayScale)
+		op := &ebiten.DrawImageOptions{}
+		op.ColorScale.ScaleAlpha(float32(0.75 + 0.25*math.Sin(a.twinkle+float64(i)*math.Pi)))
+		for y := r.Min.Y - r.Min.Y%tile - tile + offY; y < r.Max.Y; y += tile {
+			for x := r.Min.X - r.Min.X%tile - tile + offX; x < r.Max.X; x += tile {
+				op.GeoM.Reset()
+				op.GeoM.Translate(float64(x), float64(y))
+				screen.DrawImage(img, op)
+			}
+		}
+	}
+}
+
+// renderStars draws the starfield's tiles at the current display scale
+// The stars are placed by a fixed seed, so they are the same every time.
+func (a *Ambient) renderStars() {
+	rng := rand.New(rand.NewPCG(7, 7))
+	a.scale = displayScale
+	for i := range a.stars {
+		if a.stars[i] != nil {
+			a.stars[i].Deallocate()
+		}
+		size := int(starTileSize * displayScale)
+		img := ebiten.NewImage(size, size)
+		n := starsPerTile >> i
+		for range n {
+			x, y := rng.Float32()*starTileSize, rng.Float32()*starTileSize
+			v := uint8(90 + 60*i + rng.IntN(40))
+			fillRect(img, x, y, 1, 1, color.RGBA{v, v, v, 255}, false)
+		}
+		a.stars[i] = img
+	}
+}
+
+// drawOverlay draws the vignette and scanlines, those that are on, over
+// the whole of screen
+func (a *Ambient) drawOverlay(screen *ebiten.Image, vignette, scanlines bool) {
+	if !vignette && !scanlines {
+		return
+	}
+	size := screen.Bounds().Size()
+	if a.overlay == nil || a.overlaySize != size || a.overlayFor != [2]bool{vignette, scanlines} {
+		if a.overlay != nil {
+			a.overlay.Deallocate()
+		}
+		a.overlay = renderOverlay(size, vignette, scanlines)
+		a.overlaySize, a.overlayFor = size, [2]bool{vignette, scanlines}
+	}
+	screen.DrawImage(a.overlay, nil)
+}
+
+// renderOverlay draws the vignette and scanlines into a new image of size
+// pixels, as black of varying opacity
+func renderOverlay(size image.Point, vignette, scanlines bool) *ebiten.Image {
+	// Each line is a logical pixel high, so they keep their look at any
+	// window size
+	line := max(int(displayScale), 1)
+	pix := make([]byte, 4*size.X*size.Y)
+	cx, cy := float64(size.X)/2, float64(size.Y)/2
+	for y := range size.Y {
+		for x := range size.X {
+			var alpha float64
+			if vignette {
+				// Elliptical, so it follows the shape of the screen
+				dx, dy := (float64(x)-cx)/cx, (float64(y)-cy)/cy
+				d := math.Sqrt(dx*dx+dy*dy) / math.Sqrt2
+				t := min(max((d-vignetteStart)/(1-vignetteStart), 0), 1)
+				alpha = vignetteStrength * t * t * (3 - 2*t)
+			}
+			if scanlines && y/line%2 == 1 {
+				alpha = 1 - (1-alpha)*(1-scanlineAlpha)
+			}
+			// Premultiplied black: only the alpha is set
+			pix[4*(y*size.X+x)+3] = uint8(alpha * 255)
+		}
+	}
+	img := ebiten.NewImage(size.X, size.Y)
+	img.WritePixels(pix)
+	return img
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/png" // registers the PNG decoder for Assets.Image
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"github.com/hajimehoshi/ebiten/v2/examples/resources/fonts"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+// ASSETS
+//
+// The sprites, sounds and fonts are built into the game, so a single
+// binary (or the browser build) has everything it needs. Each can still be
+// replaced by dropping a file of the same name into ./assets, e.g.
+// assets/sprites/atlas.png for a new skin or assets/sounds/eat.wav for a
+// new sound. A replacement that can't be loaded is reported, and the
+// built-in asset used instead.
+
+// assetsDirName is the folder (relative to the working directory) that
+// replacements for the built-in assets are loaded from
+const assetsDirName = "assets"
+
+// assetFiles are the built-in assets, by their path under assets/
+//
+//go:embed assets/*
+var assetFiles embed.FS
+
+// packagedAssets are built-in assets that come with a Go package instead
+// of living under assets/, so they aren't stored twice
+var packagedAssets = map[string][]byte{
+	mplusFontName: fonts.MPlus1pRegular_ttf,
+}
+
+// Assets loads the game's assets by name, e.g. "sounds/eat.wav", and
+// keeps each once it is decoded, so asking for it again is free
+type Assets struct {
+	// dir is where replacements are looked for ("" for nowhere, in the
+	// browser)
+	dir string
+
+	// cache holds the decoded assets, by kind and name
+	cache map[assetKey]any
+}
+
+// assetKey identifies a decoded asset: the same file can be decoded as
+// different kinds
+type assetKey struct {
+	kind, name string
+}
+
+// newAssets returns a registry that looks for replacements in dir
+func newAssets(dir string) *Assets {
+	return &Assets{dir: dir, cache: map[assetKey]any{}}
+}
+
+// override reads name's replacement, returning nil data and no error
+// when there isn't one
+func (a *Assets) override(name string) ([]byte, error) {
+	if a.dir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(a.dir, filepath.FromSlash(name)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading asset: %w", err)
+	}
+	return data, nil
+}
+
+// builtinAsset returns the contents of a built-in asset
+func builtinAsset(name string) ([]byte, error) {
+	if data, ok := packagedAssets[name]; ok {
+		return data, nil
+	}
+	data, err := assetFiles.ReadFile("assets/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("no asset %q", name)
+	}
+	return data, nil
+}
+
+// Image returns an image asset (PNG)
+func (a *Assets) Image(name string) (*ebiten.Image, error) {
+	return loadAsset(a, "image", name, func(data []byte) (*ebiten.Image, error) {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return ebiten.NewImageFromImage(img), nil
+	})
+}
+
+// Sound returns a sound asset (WAV) decoded to raw PCM at the audio
+// sample rate, ready to play
+func (a *Assets) Sound(name string) ([]byte, error) {
+	return loadAsset(a, "sound", name, func(data []byte) ([]byte, error) {
+		stream, err := wav.DecodeWithSampleRate(audioSampleRate, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(stream)
+	})
+}
+
+// Font returns a font asset (TTF or OTF)
+func (a *Assets) Font(name string) (*text.GoTextFaceSource, error) {
+	return loadAsset(a, "font", name, func(data []byte) (*text.GoTextFaceSource, error) {
+		return text.NewGoTextFaceSource(bytes.NewReader(data))
+	})
+}
+
+// loadAsset returns the asset name decoded by decode, from the cache if
+// it has been already
+// A replacement that fails to decode is logged and the built-in asset
+// decoded instead, so a bad skin can't keep the game from starting.
+func loadAsset[T any](a *Assets, kind, name string, decode func([]byte) (T, error)) (T, error) {
+	key := assetKey{kind, name}
+	if v, ok := a.cache[key]; ok {
+		return v.(T), nil
+	}
+
+	var zero T
+	data, err := a.override(name)
+	if err != nil {
+		log.Printf("using the built-in %s: %v", name, err)
+	}
+	if data != nil {
+		v, err := decode(data)
+		if err == nil {
+			a.cache[key] = v
+			return v, nil
+		}
+		log.Printf("using the built-in %s: decoding %s: %v", name, filepath.Join(a.dir, name), err)
+	}
+
+	data, err = builtinAsset(name)
+	if err != nil {
+		return zero, err
+	}
+	v, err := decode(data)
+	if err != nil {
+		return zero, fmt.Errorf("decoding %s: %w", name, err)
+	}
+	a.cache[key] = v
+	return v, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// audioSampleRate is the sample rate of the audio context; sounds are
+// resampled to it when they are decoded
+const audioSampleRate = 44100
+
+// Sound identifies one of the game's sound effects
+type Sound int
+
+const (
+	SoundEat Sound = iota
+	SoundPoison
+	SoundPowerUp
+	SoundDie
+	SoundMenuMove
+	SoundMenuSelect
+	soundCount // keep last: number of sounds
+)
+
+// soundFileNames are the WAV assets for each sound
+var soundFileNames = [soundCount]string{
+	SoundEat:        "sounds/eat.wav",
+	SoundPoison:     "sounds/poison.wav",
+	SoundPowerUp:    "sounds/powerup.wav",
+	SoundDie:        "sounds/die.wav",
+	SoundMenuMove:   "sounds/menu_move.wav",
+	SoundMenuSelect: "sounds/menu_select.wav",
+}
+
+// musicFileName is the background music track
+// It is made to loop seamlessly.
+const musicFileName = "sounds/music.wav"
+
+// Audio plays the sound effects and the background music
+// Every sound is decoded once at startup and kept as raw PCM, so playing
+// one is just creating a cheap player over the bytes. Several sounds (or
+// the same sound twice) can overlap. The music is played through an
+// infinite loop so it never ends.
+//
+// A nil *Audio is valid and silent, so the game still runs when the
+// audio device can't be opened.
+// In the browser, audio only starts after the first key press or touch;
+// Ebiten resumes the context by itself when that happens.
+type Audio struct {
+	ctx    *audio.Context
+	sounds [soundCount][]byte
+
+	// music is the player for the looping background track
+	music *audio.Player
+
+	// sfxVolume and musicVolume are the playback volumes from 0 to 1
+	sfxVolume   float64
+	musicVolume float64
+
+	// muted silences every sound without touching the volume setting
+	muted bool
+}
+
+// newAudio opens the audio context and loads every sound from assets
+func newAudio(assets *Assets) (*Audio, error) {
+	a := &Audio{ctx: audio.NewContext(audioSampleRate), sfxVolume: 1, musicVolume: 1}
+	for s, name := range soundFileNames {
+		pcm, err := assets.Sound(name)
+		if err != nil {
+			return nil, err
+		}
+		a.sounds[s] = pcm
+	}
+
+	pcm, err := assets.Sound(musicFileName)
+	if err != nil {
+		return nil, err
+	}
+	music, err := a.ctx.NewPlayer(audio.NewInfiniteLoop(bytes.NewReader(pcm), int64(len(pcm))))
+	if err != nil {
+		return nil, fmt.Errorf("creating music player: %w", err)
+	}
+	a.music = music
+	return a, nil
+}
+
+// play starts a sound effect
+func (a *Audio) play(s Sound) {
+	if a == nil || a.muted || a.sfxVolume <= 0 {
+		return
+	}
+	p := a.ctx.NewPlayerFromBytes(a.sounds[s])
+	p.SetVolume(a.sfxVolume)
+	p.Play()
+}
+
+// setVolumes sets the sound effect and music volumes from percentages
+// (0-100). The music volume changes straight away, even mid-track.
+func (a *Audio) setVolumes(sfxPercent, musicPercent int) {
+	if a == nil {
+		return
+	}
+	a.sfxVolume = float64(sfxPercent) / 100
+	a.musicVolume = float64(musicPercent) / 100
+	a.music.SetVolume(a.musicVolume)
+}
+
+// playMusic starts the background music, unless muted
+// The track loops until the game quits.
+func (a *Audio) playMusic() {
+	if a == nil || a.muted {
+		return
+	}
+	a.music.Play()
+}
+
+// setMuted mutes or unmutes every sound, pausing the music while muted
+func (a *Audio) setMuted(muted bool) {
+	if a == nil || a.muted == muted {
+		return
+	}
+	a.muted = muted
+	if a.muted {
+		a.music.Pause()
+	} else {
+		a.music.Play()
+	}
+}
+
+// isMuted reports whether sound is muted
+func (a *Audio) isMuted() bool {
+	return a != nil && a.muted
+}
+
+// subscribeSounds plays the sound effects for the world's events
+// Dying isn't among them: endGame plays that once, even if both snakes
+// died.
+func (g *Game) subscribeSounds() {
+	sound := func(s Sound) func(snake.Event) {
+		return func(snake.Event) { g.audio.play(s) }
+	}
+	g.events.subscribe(sound(SoundEat), snake.EventAte, snake.EventBossBitten)
+	g.events.subscribe(sound(SoundPoison), snake.EventPoisoned, snake.EventShieldBroken, snake.EventShot)
+	g.events.subscribe(sound(SoundPowerUp), snake.EventPowerUp, snake.EventCheckpoint, EventRunFinished)
+}
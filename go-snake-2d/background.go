@@ -0,0 +1,129 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// BackgroundStyle selects what is drawn under the board
+type BackgroundStyle int
+
+const (
+	BackgroundPlain BackgroundStyle = iota
+	BackgroundChecker
+	BackgroundGrid
+	backgroundStyleCount
+)
+
+var backgroundStyleNames = [backgroundStyleCount]string{"Plain", "Checkerboard", "Grid"}
+
+// backgroundTint is how far the checkerboard's second shade and the grid
+// lines are blended from the background color toward white
+const backgroundTint = 0.06
+
+// boardBackground caches the board's background
+// The checkerboard or grid only changes with the board size, the style
+// or the theme, so it is drawn once into an offscreen image that is then
+// copied to the screen every frame, instead of filling hundreds of cells
+// each frame.
+type boardBackground struct {
+	img *ebiten.Image
+
+	// key describes what img was drawn for
+	key backgroundKey
+}
+
+// backgroundKey is everything the cached background depends on
+type backgroundKey struct {
+	style    BackgroundStyle
+	w, h     int
+	cellSize float32
+	clr      color.RGBA
+
+	// scale is displayScale, since the image is drawn in screen pixels
+	scale float64
+}
+
+// drawBackground draws the board background in the current style
+func (g *Game) drawBackground(screen *ebiten.Image) {
+	key := backgroundKey{
+		style:    g.settings.Background,
+		w:        g.world.Width,
+		h:        g.world.Height,
+		cellSize: g.view.cell,
+		clr:      color.RGBA(g.theme.Background),
+		scale:    displayScale,
+	}
+	if key.style == BackgroundPlain {
+		return // the screen is already cleared to the background color
+	}
+
+	bg := &g.background
+	if bg.img == nil || bg.key != key {
+		if bg.img != nil {
+			bg.img.Deallocate()
+		}
+		bg.img = renderBackground(key)
+		bg.key = key
+	}
+	op := &ebiten.DrawImageOptions{}
+	// Whole screen pixels keep the grid sharp while the view scrolls
+	op.GeoM.Translate(math.Round(float64(g.view.x)*displayScale), math.Round(float64(g.view.y)*displayScale))
+	screen.DrawImage(bg.img, op)
+}
+
+// renderBackground draws a background into a new image the size of the
+// board on screen
+func renderBackground(k backgroundKey) *ebiten.Image {
+	cell := k.cellSize
+	bw, bh := float32(k.w)*cell, float32(k.h)*cell
+	img := ebiten.NewImage(int(float64(bw)*k.scale), int(float64(bh)*k.scale))
+	img.Fill(k.clr)
+	shade := tint(k.clr, backgroundTint)
+
+	switch k.style {
+	case BackgroundChecker:
+		for y := range k.h {
+			for x := range k.w {
+				if (x+y)%2 == 1 {
+					fillRect(img, float32(x)*cell, float32(y)*cell, cell, cell, shade, false)
+				}
+			}
+		}
+	case BackgroundGrid:
+		// Lines sit on the left and top edge of each cell, plus one to
+		// close off the right and bottom of the board
+		for x := range k.w + 1 {
+			fillRect(img, min(float32(x)*cell, bw-1), 0, 1, bh, shade, false)
+		}
+		for y := range k.h + 1 {
+			fillRect(img, 0, min(float32(y)*cell, bh-1), bw, 1, shade, false)
+		}
+	}
+	return img
+}
+
+// tint blends c toward white by the fraction t
+func tint(c color.RGBA, t float64) color.RGBA {
+	blend := func(v uint8) uint8 {
+		return v + uint8(float64(255-v)*t)
+	}
+	return color.RGBA{blend(c.R), blend(c.G), blend(c.B), c.A}
+}
+
+// MarshalText encodes the background style by name
+func (s BackgroundStyle) MarshalText() ([]byte, error) {
+	return marshalName(backgroundStyleNames[:], int(s))
+}
+
+// UnmarshalText decodes the background style from its name
+func (s *BackgroundStyle) UnmarshalText(b []byte) error {
+	return unmarshalName(backgroundStyleNames[:], b, (*int)(s))
+}
+
+// String returns the display name of the background style
+func (s BackgroundStyle) String() string {
+	return backgroundStyleNames[s]
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Action is a logical game command, independent of the physical key
+// that triggers it. Update asks "is MoveUp pressed?" instead of
+// checking ebiten.KeyW directly, so keys can be remapped freely.
+type Action int
+
+const (
+	ActionMoveUp Action = iota
+	ActionMoveDown
+	ActionMoveLeft
+	ActionMoveRight
+	ActionDash
+	ActionPause
+	ActionRestart
+	ActionNextLevel
+	ActionSelect
+	ActionBack
+	ActionMute
+	ActionSave
+	ActionFullscreen
+	ActionDebug
+	ActionClip
+	ActionConsole
+	ActionFrameAdvance
+	ActionTASRun
+	ActionSaveState
+	ActionLoadState
+	ActionExportReplay
+	ActionP2MoveUp
+	ActionP2MoveDown
+	ActionP2MoveLeft
+	ActionP2MoveRight
+	ActionP2Dash
+	actionCount // keep last: number of actions
+)
+
+// actionNames are the names used for actions in the settings file
+var actionNames = [actionCount]string{
+	ActionMoveUp:    "moveUp",
+	ActionMoveDown:  "moveDown",
+	ActionMoveLeft:  "moveLeft",
+	ActionMoveRight: "moveRight",
+	ActionDash:      "dash",
+	ActionPause:     "pause",
+	ActionRestart:   "restart",
+	ActionNextLevel: "nextLevel",
+	ActionSelect:    "select",
+	ActionBack:      "back",
+	ActionMute:      "mute",
+	ActionSave:      "save",
+
+	ActionFullscreen: "fullscreen",
+	ActionDebug:      "debug",
+	ActionClip:       "clip",
+	ActionConsole:    "console",
+
+	ActionFrameAdvance: "frameAdvance",
+	ActionTASRun:       "tasRun",
+	ActionSaveState:    "saveState",
+	ActionLoadState:    "loadState",
+	ActionExportReplay: "exportReplay",
+
+	ActionP2MoveUp:    "p2MoveUp",
+	ActionP2MoveDown:  "p2MoveDown",
+	ActionP2MoveLeft:  "p2MoveLeft",
+	ActionP2MoveRight: "p2MoveRight",
+	ActionP2Dash:      "p2Dash",
+}
+
+// actionLabels are the names of the actions on the controls screen
+var actionLabels = [actionCount]string{
+	ActionMoveUp:    "Move up",
+	ActionMoveDown:  "Move down",
+	ActionMoveLeft:  "Move left",
+	ActionMoveRight: "Move right",
+	ActionDash:      "Dash",
+	ActionPause:     "Pause",
+	ActionRestart:   "Restart",
+	ActionNextLevel: "Next level",
+	ActionSelect:    "Select",
+	ActionBack:      "Back",
+	ActionMute:      "Mute",
+	ActionSave:      "Save game",
+
+	ActionFullscreen: "Fullscreen",
+	ActionDebug:      "Debug overlay",
+	ActionClip:       "Save clip",
+	ActionConsole:    "Developer console",
+
+	ActionFrameAdvance: "Frame advance (TAS)",
+	ActionTASRun:       "Run/stop (TAS)",
+	ActionSaveState:    "Save state (TAS)",
+	ActionLoadState:    "Load state (TAS)",
+	ActionExportReplay: "Export replay (TAS)",
+
+	ActionP2MoveUp:    "P2 move up",
+	ActionP2MoveDown:  "P2 move down",
+	ActionP2MoveLeft:  "P2 move left",
+	ActionP2MoveRight: "P2 move right",
+	ActionP2Dash:      "P2 dash",
+}
+
+// String returns the action's settings-file name
+func (a Action) String() string {
+	if a < 0 || a >= actionCount {
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+	return actionNames[a]
+}
+
+// MarshalText lets actions be used as JSON object keys
+func (a Action) MarshalText() ([]byte, error) {
+	return marshalName(actionNames[:], int(a))
+}
+
+// UnmarshalText parses an action from its settings-file name
+func (a *Action) UnmarshalText(b []byte) error {
+	return unmarshalName(actionNames[:], b, (*int)(a))
+}
+
+// KeyBindings maps each action to the keys that trigger it
+// An action may have several keys (e.g. W and the up arrow)
+type KeyBindings map[Action][]ebiten.Key
+
+// defaultKeyBindings returns the out-of-the-box controls:
+// WASD and the arrow keys both steer the snake, and either Shift dashes.
+// In versus mode player two takes over the arrow keys and right Shift
+// (see keyBindings).
+func defaultKeyBindings() KeyBindings {
+	return KeyBindings{
+		ActionMoveUp:    {ebiten.KeyW, ebiten.KeyArrowUp},
+		ActionMoveDown:  {ebiten.KeyS, ebiten.KeyArrowDown},
+		ActionMoveLeft:  {ebiten.KeyA, ebiten.KeyArrowLeft},
+		ActionMoveRight: {ebiten.KeyD, ebiten.KeyArrowRight},
+		ActionDash:      {ebiten.KeyShiftLeft, ebiten.KeyShiftRight},
+		ActionPause:     {ebiten.KeyP, ebiten.KeyEscape},
+		ActionRestart:   {ebiten.KeyEnter, ebiten.KeySpace},
+		ActionNextLevel: {ebiten.KeyL},
+		ActionSelect:    {ebiten.KeyEnter, ebiten.KeySpace},
+		ActionBack:      {ebiten.KeyEscape, ebiten.KeyBackspace},
+		ActionMute:      {ebiten.KeyM},
+		ActionSave:      {ebiten.KeyF5},
+
+		ActionFullscreen: {ebiten.KeyF11},
+		ActionDebug:      {ebiten.KeyF3},
+		ActionClip:       {ebiten.KeyF8},
+		ActionConsole:    {ebiten.KeyGraveAccent},
+
+		ActionFrameAdvance: {ebiten.KeyF},
+		ActionTASRun:       {ebiten.KeyR},
+		ActionSaveState:    {ebiten.KeyF6},
+		ActionLoadState:    {ebiten.KeyF7},
+		ActionExportReplay: {ebiten.KeyF9},
+
+		ActionP2MoveUp:    {ebiten.KeyArrowUp},
+		ActionP2MoveDown:  {ebiten.KeyArrowDown},
+		ActionP2MoveLeft:  {ebiten.KeyArrowLeft},
+		ActionP2MoveRight: {ebiten.KeyArrowRight},
+		ActionP2Dash:      {ebiten.KeyShiftRight},
+	}
+}
+
+// Bind replaces the keys assigned to an action
+// Passing no keys leaves the action unbound
+func (b KeyBindings) Bind(action Action, keys ...ebiten.Key) {
+	b[action] = keys
+}
+
+// keyConflict is a key bound to two actions that are in use at the same
+// time, so pressing it would do both
+type keyConflict struct {
+	key  ebiten.Key
+	a, b Action
+}
+
+// globalActions work on every screen
+var globalActions = []Action{ActionMute, ActionFullscreen, ActionDebug, ActionClip, ActionConsole}
+
+// actionGroups are the actions in use at the same time, with the mode
+// whose bindings apply. Actions that are never used together may share a
+// key, like Enter for Select in the menus and Restart after a run.
+var actionGroups = []struct {
+	mode    GameMode
+	actions []Action
+}{
+	// Solo play
+	{ModeSolo, []Action{ActionMoveUp, ActionMoveDown, ActionMoveLeft, ActionMoveRight, ActionDash, ActionPause, ActionSave}},
+	// Versus play and split screen, where player two steers too
+	{ModeVersus, []Action{ActionMoveUp, ActionMoveDown, ActionMoveLeft, ActionMoveRight, ActionDash, ActionP2MoveUp, ActionP2MoveDown, ActionP2MoveLeft, ActionP2MoveRight, ActionP2Dash, ActionPause, ActionSave}},
+	// TAS runs (see TASScene)
+	{ModeSolo, []Action{ActionMoveUp, ActionMoveDown, ActionMoveLeft, ActionMoveRight, ActionDash, ActionPause, ActionFrameAdvance, ActionTASRun, ActionSaveState, ActionLoadState, ActionExportReplay}},
+	// Menus
+	{ModeSolo, []Action{ActionMoveUp, ActionMoveDown, ActionMoveLeft, ActionMoveRight, ActionSelect, ActionBack}},
+	// The game over screen
+	{ModeSolo, []Action{ActionRestart, ActionNextLevel, ActionBack}},
+}
+
+// keyConflicts returns every key the settings bind to two actions in use
+// at the same time, each pair once
+func (s *Settings) keyConflicts() []keyConflict {
+	var conflicts []keyConflict
+	for _, group := range actionGroups {
+		b := s.keyBindings(group.mode)
+		actions := append(slices.Clone(group.actions), globalActions...)
+		for i, a := range actions {
+			for _, other := range actions[i+1:] {
+				for _, k := range b[a] {
+					c := keyConflict{k, min(a, other), max(a, other)}
+					if slices.Contains(b[other], k) && !slices.Contains(conflicts, c) {
+						conflicts = append(conflicts, c)
+					}
+				}
+			}
+		}
+	}
+	return conflicts
+}
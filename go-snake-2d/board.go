@@ -0,0 +1,101 @@
+package main
+
+import (
+	"image/color"
+	"time"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Board is one board in play: the world its snakes move in, where it is
+// shown on screen, and the timers and drawing state that go with them
+// Most modes have one board for every snake; split screen gives each
+// player a board of their own, with a world of its own (see split.go).
+// Game embeds the board it is working on, so the code that updates or
+// draws a board just uses g.world, g.view and the rest, and eachBoard
+// makes each board the current one in turn.
+type Board struct {
+	// world is the run on this board: the walls, the snakes and the food
+	// (see package snake). It has a single player in solo mode and two in
+	// versus mode; player one is always Players[0].
+	world *snake.World
+
+	// view places the board on screen, within its viewport (see
+	// boardview.go)
+	view boardView
+
+	// first is the index of the board's first player among every board's
+	// players, so each player keeps their own color (see playerColor)
+	first int
+
+	// prevSnakes are the players' snakes as they were before the last
+	// tick (nil before the first one), for smooth movement
+	prevSnakes [][]snake.Point
+
+	// lastUpdate tracks when we last moved the snake
+	// This allows us to control game speed independent of frame rate
+	lastUpdate time.Time
+
+	// tickInterval is the current time between moves, recalculated from
+	// difficulty every Update
+	tickInterval time.Duration
+
+	// vanished counts, for each player, how many of its segments have
+	// crumbled away during the death animation (from the head back), and
+	// flash is whether the dead snakes are drawn lit up this frame. Both
+	// are set by DyingScene; vanished is nil during play.
+	vanished []int
+	flash    bool
+
+	// particles are the bursts shown when food is eaten or a snake dies
+	particles *Particles
+
+	// trail holds the snakes' fading afterimages (see Settings.Trails)
+	trail Trail
+
+	// minimap is the overview of boards too big for their viewport
+	minimap Minimap
+
+	// ticks counts the moves made on the board this run, for mods (see
+	// mods.go)
+	ticks int
+
+	// hints are the routes to food shown to the players, by player, with
+	// Settings.Hints (see findHints); empty for players without one
+	hints []snake.Path
+}
+
+// newBoard returns an empty board, to be set up by resetGame
+func newBoard() *Board {
+	return &Board{particles: newParticles()}
+}
+
+// setBoards keeps n boards, adding empty ones as needed, and makes the
+// first the current one
+func (g *Game) setBoards(n int) {
+	for len(g.boards) < n {
+		g.boards = append(g.boards, newBoard())
+	}
+	g.boards = g.boards[:n]
+	g.Board = g.boards[0]
+}
+
+// eachBoard calls fn with each board in turn as the current one, then
+// makes the board that was current before current again
+// It stops early if fn switches scenes, e.g. when a board ends the run.
+func (g *Game) eachBoard(fn func()) {
+	current := g.Board
+	defer func() { g.Board = current }()
+	for _, b := range g.boards {
+		g.Board = b
+		fn()
+		if g.scenes.switching() {
+			return
+		}
+	}
+}
+
+// playerColor returns the color of player i of the current board
+func (g *Game) playerColor(i int) color.Color {
+	return g.theme.snakeColor(g.first + i)
+}
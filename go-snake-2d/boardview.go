@@ -0,0 +1,168 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+const (
+	// boardEdge is the thickness in pixels of the line around a board
+	// that doesn't fill the screen, so its deadly edge can be seen
+	boardEdge = 2
+
+	// scrollCellSize is the cell size of boards too big to fit on screen
+	// with cells of at least minCellSize; the view scrolls over them
+	scrollCellSize = 16
+
+	// cameraFollow is the fraction of the way to the snakes a scrolling
+	// view moves each update at baseTPS (see timing.go), which smooths out
+	// the jump of every tick
+	cameraFollow = 0.12
+)
+
+// viewport is the part of the screen a board is shown in, in logical
+// pixels
+type viewport struct {
+	x, y, w, h float32
+}
+
+// fullScreen is the viewport of a board that has the screen to itself;
+// setResolution keeps it to the screen's width
+var fullScreen = viewport{0, 0, baseScreenWidth, screenHeight}
+
+// bounds returns the viewport in screen pixels, to clip drawing to
+func (vp viewport) bounds() image.Rectangle {
+	px := func(v float32) int { return int(float64(v) * displayScale) }
+	return image.Rect(px(vp.x), px(vp.y), px(vp.x+vp.w), px(vp.y+vp.h))
+}
+
+// boardView maps board cells to logical screen pixels
+// The board can have any width and height in cells: cells are sized so
+// the whole board fits its viewport, and the board is centered in
+// whatever space is left over. Cell sizes are whole pixels so the grid
+// stays sharp. Boards too big for that get scrollCellSize cells and
+// scroll to follow the snakes, each axis stopping at the board's edges.
+type boardView struct {
+	// cell is the size of one cell in pixels
+	cell float32
+
+	// x and y are where the board's top-left corner is drawn; negative
+	// when the board is scrolled
+	x, y float32
+
+	// w and h are the board size in cells
+	w, h int
+
+	// area is the viewport the board is shown in
+	area viewport
+}
+
+// newBoardView lays out a w×h board, centered in area
+func newBoardView(w, h int, area viewport) boardView {
+	cell := min(int(area.w)/w, int(area.h)/h)
+	if cell < minCellSize {
+		cell = scrollCellSize
+	}
+	v := boardView{cell: float32(cell), w: w, h: h, area: area}
+	bw, bh := v.size()
+	v.x, v.y = v.target(bw/2, bh/2)
+	return v
+}
+
+// scrolls reports whether the board is bigger than its viewport
+func (v boardView) scrolls() bool {
+	bw, bh := v.size()
+	return bw > v.area.w || bh > v.area.h
+}
+
+// target returns the board origin that puts the point (fx, fy), in
+// pixels from the board's top-left corner, in the middle of the viewport
+// An axis that fits is centered instead, and one that doesn't never
+// shows past the board's edges.
+func (v boardView) target(fx, fy float32) (float32, float32) {
+	bw, bh := v.size()
+	axis := func(focus, board, start, size float32) float32 {
+		if board <= size {
+			return start + float32(int(size-board)/2)
+		}
+		return min(max(start+size/2-focus, start+size-board), start)
+	}
+	return axis(fx, bw, v.area.x, v.area.w), axis(fy, bh, v.area.y, v.area.h)
+}
+
+// follow moves the view part of the way toward centering (fx, fy), or
+// all the way with snap
+func (v *boardView) follow(fx, fy float32, snap bool) {
+	tx, ty := v.target(fx, fy)
+	if snap {
+		v.x, v.y = tx, ty
+		return
+	}
+	f := float32(1 - perUpdate(1-cameraFollow))
+	v.x += (tx - v.x) * f
+	v.y += (ty - v.y) * f
+}
+
+// followSnakes keeps the live snakes' heads in view, snapping straight to
+// them with snap (e.g. at the start of a run)
+// With several snakes the view follows the point between them, except in
+// battle royale, where it follows the player's.
+func (g *Game) followSnakes(snap bool) {
+	if g.world == nil || !g.view.scrolls() {
+		return
+	}
+	var fx, fy float32
+	n := 0
+	for _, p := range g.trackedPlayers() {
+		if p.Dead {
+			continue
+		}
+		x, y := g.view.local(p.Head())
+		fx, fy, n = fx+x, fy+y, n+1
+	}
+	if n == 0 {
+		return
+	}
+	g.view.follow(fx/float32(n), fy/float32(n), snap)
+}
+
+// pos returns the top-left corner of the cell at (cx, cy); fractional
+// cells are allowed, for things between two cells
+func (v boardView) pos(cx, cy float32) (float32, float32) {
+	return v.x + cx*v.cell, v.y + cy*v.cell
+}
+
+// cellPos returns the top-left corner of cell p
+func (v boardView) cellPos(p snake.Point) (float32, float32) {
+	return v.pos(float32(p.X), float32(p.Y))
+}
+
+// center returns the center of cell p
+func (v boardView) center(p snake.Point) (float32, float32) {
+	x, y := v.cellPos(p)
+	return x + v.cell/2, y + v.cell/2
+}
+
+// local returns the center of cell p in pixels from the board's top-left
+// corner, which stays put when the view scrolls
+func (v boardView) local(p snake.Point) (float32, float32) {
+	return (float32(p.X) + 0.5) * v.cell, (float32(p.Y) + 0.5) * v.cell
+}
+
+// size returns the board's size on screen in pixels
+func (v boardView) size() (float32, float32) {
+	return float32(v.w) * v.cell, float32(v.h) * v.cell
+}
+
+// drawEdge outlines a board that doesn't fill its viewport
+func (v boardView) drawEdge(screen *ebiten.Image, clr color.Color) {
+	if v.x-v.area.x < boardEdge && v.y-v.area.y < boardEdge {
+		return
+	}
+	bw, bh := v.size()
+	drawFrame(screen, v.x-boardEdge, v.y-boardEdge, bw+2*boardEdge, bh+2*boardEdge, boardEdge, clr)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"image/color"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Boss levels put a boss on the board (see snake.Boss): a big target that
+// wanders about firing at the snake, which has to bite it health times to
+// win, losing segments for every bite and every shot that hits it. Beating
+// the boss finishes the run; dying ends it as usual. Bosses only appear in
+// solo runs; other modes play the level without one.
+
+const (
+	// defaultBossSize and defaultBossHealth are a boss's size and health
+	// when the level doesn't set them, and the bounds a level file may set
+	defaultBossSize   = 3
+	minBossSize       = 2
+	maxBossSize       = 5
+	defaultBossHealth = 5
+	maxBossHealth     = 20
+
+	// bossIntroDuration is how long the boss is announced before the
+	// countdown, unless the player skips it
+	bossIntroDuration = 2 * time.Second
+)
+
+var (
+	// bossColor is the boss's body, bossHurtColor the flash when it has
+	// just been bitten, bossRageColor its eyes once enraged and shotColor
+	// its shots
+	bossColor     = color.RGBA{200, 40, 90, 255}
+	bossHurtColor = color.RGBA{255, 220, 220, 255}
+	bossRageColor = color.RGBA{255, 40, 40, 255}
+	shotColor     = color.RGBA{255, 170, 40, 255}
+)
+
+// boss returns the boss the world of a new run of lvl on a w×h board
+// starts with: the level's, for solo runs
+func (g *Game) boss(lvl Level, w, h int) *snake.Boss {
+	if g.mode != ModeSolo {
+		return nil
+	}
+	return lvl.boss(w, h)
+}
+
+// newRunScene returns the scene a new run starts on: the countdown, after
+// the boss has been announced on boss levels
+func (g *Game) newRunScene() Scene {
+	if g.world.Boss() != nil {
+		return &BossIntroScene{g: g, startedAt: g.clock.Now()}
+	}
+	return newCountdownScene(g)
+}
+
+// BossIntroScene announces the boss over the waiting board, then hands
+// over to the countdown
+type BossIntroScene struct {
+	g         *Game
+	startedAt time.Time
+}
+
+// Update waits out the announcement, or skips it on select
+// Like the countdown, it doesn't eat into the run's timers.
+func (s *BossIntroScene) Update() error {
+	g := s.g
+	now := g.clock.Now()
+	if g.isJustPressed(ActionSelect) || now.Sub(s.startedAt) >= bossIntroDuration {
+		g.shiftTimers(now.Sub(s.startedAt))
+		g.scenes.Switch(newCountdownScene(g))
+	}
+	return nil
+}
+
+// Draw renders the waiting board with the boss's name and health
+func (s *BossIntroScene) Draw(screen *ebiten.Image) {
+	g := s.g
+	g.drawBoard(screen, g.lastUpdate, false)
+	g.drawHUD(screen, g.lastUpdate)
+	fillRect(screen, 0, screenHeight/2-90, float32(screenWidth), 150, color.RGBA{0, 0, 0, 160}, false)
+	drawCenteredText(screen, tr("Boss fight!"), 48, screenHeight/2-80, bossColor)
+	drawCenteredText(screen, trf("Bite it %d times and dodge its shots", g.world.Boss().MaxHealth), 20, screenHeight/2-10, color.White)
+	drawCenteredText(screen, tr("Every bite costs you segments"), 16, screenHeight/2+20, menuTextColor)
+}
+
+// bossSystem draws the boss with its health bar, and its shots
+// The boss flashes while it can't be bitten, and its eyes turn red once
+// it is enraged.
+type bossSystem struct{ still }
+
+func (bossSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	b := g.world.Boss()
+	if b == nil {
+		return
+	}
+	v, cell := g.view, g.view.cell
+	for s := range g.world.Projectiles() {
+		cx, cy := v.center(s.Pos)
+		fillCircle(screen, cx, cy, cell/4, shotColor, true)
+	}
+	if b.Defeated() {
+		return
+	}
+
+	// BODY
+	x, y := v.cellPos(b.Pos)
+	size := cell * float32(b.Size)
+	inset := cell / 8
+	clr := bossColor
+	if b.Hurt%2 == 1 {
+		clr = bossHurtColor
+	}
+	fillRect(screen, x+inset, y+inset, size-2*inset, size-2*inset, clr, false)
+	var eyes color.Color = g.theme.Background
+	if b.Enraged() {
+		eyes = bossRageColor
+	}
+	fillCircle(screen, x+size/3, y+size*2/5, size/10, eyes, true)
+	fillCircle(screen, x+size*2/3, y+size*2/5, size/10, eyes, true)
+
+	// HEALTH BAR
+	// Along the top of the boss, in the part it has left
+	bar := max(cell/4, 2)
+	fillRect(screen, x+inset, y+inset, size-2*inset, bar, g.theme.Background, false)
+	fillRect(screen, x+inset, y+inset, (size-2*inset)*float32(b.Health)/float32(b.MaxHealth), bar, shotColor, false)
+}
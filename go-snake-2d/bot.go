@@ -0,0 +1,45 @@
+package main
+
+import "github.com/obliviousorion/go-basics/pkg/snake"
+
+// BotLevel selects how well the computer-controlled snake plays
+type BotLevel int
+
+const (
+	BotEasy BotLevel = iota
+	BotNormal
+	BotHard
+	botLevelCount
+)
+
+var botLevelNames = [botLevelCount]string{"Easy", "Normal", "Hard"}
+
+// botLevels configure the bot (see snake.Bot) for each level: how far
+// ahead, in moves, it looks for food, and whether it checks it won't
+// trap itself or crash head-on
+var botLevels = [botLevelCount]snake.Bot{
+	BotEasy:   {SearchDepth: 6},
+	BotNormal: {SearchDepth: 16, CheckSpace: true},
+	BotHard:   {SearchDepth: 0, CheckSpace: true, AvoidHeads: true},
+}
+
+// newBot returns a computer player of the given level
+func newBot(level BotLevel) *snake.Bot {
+	b := botLevels[level]
+	return &b
+}
+
+// MarshalText encodes the bot level by name
+func (l BotLevel) MarshalText() ([]byte, error) {
+	return marshalName(botLevelNames[:], int(l))
+}
+
+// UnmarshalText decodes the bot level from its name
+func (l *BotLevel) UnmarshalText(b []byte) error {
+	return unmarshalName(botLevelNames[:], b, (*int)(l))
+}
+
+// String returns the display name of the bot level
+func (l BotLevel) String() string {
+	return botLevelNames[l]
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Screen shake tuning
+// Amplitudes are in pixels; the shake decays a little every update (tuned
+// for baseTPS, see timing.go) until it is too small to see.
+const (
+	deathShake = 8.0
+	arenaShake = 3.0
+
+	// shakeDecay is the fraction of its amplitude the shake keeps each
+	// update
+	shakeDecay = 0.88
+
+	// minShake is the amplitude below which the shake stops
+	minShake = 0.5
+)
+
+// Camera offsets the whole scene when it is drawn, for effects like
+// screen shake
+// Like particles, the shake is cosmetic and uses the global random
+// source, so it never changes how a seeded run plays out.
+type Camera struct {
+	// amplitude is how far, in pixels, the scene may currently be pushed
+	// in each direction
+	amplitude float64
+
+	// x and y are this frame's offset, in logical pixels
+	x, y float64
+
+	// frame is the offscreen image scenes are drawn into while the camera
+	// is offset (created on first use)
+	frame *ebiten.Image
+}
+
+// shake jolts the camera by amplitude pixels
+// A stronger shake replaces a weaker one that is still going, rather than
+// adding to it.
+func (c *Camera) shake(amplitude float64) {
+	c.amplitude = max(c.amplitude, amplitude)
+}
+
+// update picks this frame's offset and lets the shake die down
+func (c *Camera) update() {
+	if c.amplitude < minShake {
+		c.amplitude, c.x, c.y = 0, 0, 0
+		return
+	}
+	c.x = (rand.Float64()*2 - 1) * c.amplitude
+	c.y = (rand.Float64()*2 - 1) * c.amplitude
+	c.amplitude *= perUpdate(shakeDecay)
+}
+
+// still reports whether the camera has no offset this frame
+func (c *Camera) still() bool {
+	return c.x == 0 && c.y == 0
+}
+
+// target returns the image to draw the scene into this frame: screen
+// itself when the camera is still, or the offscreen frame that present
+// then copies to screen
+func (c *Camera) target(screen *ebiten.Image) *ebiten.Image {
+	if c.still() {
+		return screen
+	}
+	// The screen changes size with the window (see Layout)
+	if c.frame == nil || c.frame.Bounds() != screen.Bounds() {
+		if c.frame != nil {
+			c.frame.Deallocate()
+		}
+		c.frame = ebiten.NewImage(screen.Bounds().Dx(), screen.Bounds().Dy())
+	}
+	return c.frame
+}
+
+// present draws the offscreen frame onto screen, shifted by the offset
+// Does nothing when the camera is still, since the scene was drawn
+// straight to screen.
+func (c *Camera) present(screen *ebiten.Image) {
+	if c.still() {
+		return
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(c.x*displayScale, c.y*displayScale)
+	screen.DrawImage(c.frame, op)
+}
+
+// subscribeShakes shakes the screen for the world's events: a death, the
+// arena closing in or a shield taking a hit
+func (g *Game) subscribeShakes() {
+	shake := func(amplitude float64) func(snake.Event) {
+		return func(snake.Event) { g.camera.shake(amplitude) }
+	}
+	g.events.subscribe(shake(deathShake), snake.EventDied)
+	g.events.subscribe(shake(arenaShake), snake.EventArenaShrank, snake.EventShieldBroken)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Checkpoints are tiles in level files (see levelfile.go) that save a
+// solo run's progress. When the snake reaches one, the run is kept as it
+// stands, in the save game's form, and when the snake dies afterwards the
+// run goes back to it, the snake as long as it was there, rather than
+// ending. The time played keeps counting across deaths, so going back
+// doesn't win time in timed runs. The run saved to disk holds its last
+// checkpoint, so continuing it keeps it.
+
+// checkpoints returns the checkpoints the world of a new run of lvl has:
+// the level's, for solo runs, none reached yet
+func (g *Game) checkpoints(lvl Level) []snake.Checkpoint {
+	if g.mode != ModeSolo {
+		return nil
+	}
+	var cps []snake.Checkpoint
+	for _, p := range lvl.Checkpoints {
+		cps = append(cps, snake.Checkpoint{Pos: p})
+	}
+	return cps
+}
+
+// saveCheckpoint keeps the run as it stands at now, for a death to go
+// back to
+func (g *Game) saveCheckpoint(now time.Time) {
+	sg, err := g.snapshot(now)
+	if err != nil {
+		log.Printf("saving checkpoint: %v", err)
+		return
+	}
+	// The one before is of no use any more
+	sg.Checkpoint = nil
+	g.checkpoint = sg
+	g.notify(tr("Checkpoint reached"))
+}
+
+// resumeCheckpoint puts the run back to its last checkpoint once the
+// snake has died at now, counting down again before it carries on
+func (g *Game) resumeCheckpoint(now time.Time) {
+	cp, elapsed := g.checkpoint, g.elapsed(now)
+	if err := g.restoreGame(cp); err != nil {
+		// Checkpoints are taken from the run itself, so this can't happen
+		// short of a bug; end the run as if there were none
+		log.Printf("restoring checkpoint: %v", err)
+		g.checkpoint = nil
+		g.endGame(now)
+		return
+	}
+	g.checkpoint = cp
+	g.runStart = g.lastUpdate.Add(-elapsed)
+	g.audio.play(SoundDie)
+	g.notify(tr("Back to the checkpoint"))
+
+	// A TAS run stops there instead, and keeps its own time
+	if g.tas != nil {
+		g.tas.ready()
+		return
+	}
+	g.scenes.Switch(newCountdownScene(g))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Clip recording: the last clipLength of play is kept at clipFPS frames a
+// second, each one downscaled to clipScale of the logical screen
+const (
+	clipLength = 10 * time.Second
+	clipFPS    = 10
+	clipScale  = 0.4
+
+	clipHeight = int(screenHeight * clipScale)
+	clipFrames = int(clipLength / time.Second * clipFPS)
+
+	// clipsDirName is the folder, next to the saved files, that clips are
+	// saved to on desktop
+	clipsDirName = "clips"
+)
+
+// ClipRecorder keeps the last few seconds on screen, so a great run or a
+// silly death can be saved as an animated GIF after the fact
+// Frames are captured into a ring buffer of raw pixels, which is cheap;
+// turning them into a GIF is not, so that happens on a goroutine and the
+// result comes back through done.
+type ClipRecorder struct {
+	// img is the downscaled copy of the screen that frames are read from
+	img *ebiten.Image
+
+	// frames is the ring buffer of captured frames, RGBA pixels each;
+	// next is where the next one goes
+	frames [][]byte
+	next   int
+
+	// nextAt is when the next frame is due
+	nextAt time.Time
+
+	// saving is set while a clip is being encoded, and done receives the
+	// file it was saved to (or the error) when it's finished
+	saving bool
+	done   chan clipResult
+}
+
+type clipResult struct {
+	path string
+	err  error
+}
+
+// capture grabs the screen into the ring buffer if a frame is due
+func (c *ClipRecorder) capture(screen *ebiten.Image, now time.Time) {
+	if now.Before(c.nextAt) {
+		return
+	}
+	c.nextAt = now.Add(time.Second / clipFPS)
+
+	// A clip is all one size, so one taken across a change of resolution
+	// starts again from there
+	w := clipWidth()
+	if c.img != nil && c.img.Bounds().Dx() != w {
+		c.img.Deallocate()
+		c.img, c.frames, c.next = nil, nil, 0
+	}
+	if c.img == nil {
+		c.img = ebiten.NewImage(w, clipHeight)
+	}
+	b := screen.Bounds()
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(float64(w)/float64(b.Dx()), float64(clipHeight)/float64(b.Dy()))
+	c.img.Clear()
+	c.img.DrawImage(screen, op)
+
+	// The buffer's slots are reused once it has gone round
+	if len(c.frames) < clipFrames {
+		c.frames = append(c.frames, make([]byte, 4*w*clipHeight))
+	}
+	c.img.ReadPixels(c.frames[c.next])
+	c.next = (c.next + 1) % clipFrames
+}
+
+// clipWidth returns the width of a clip's frames at the current
+// resolution
+func clipWidth() int {
+	return int(float64(screenWidth) * clipScale)
+}
+
+// save hands the recorded frames over to be encoded in the background
+// The recorder starts a fresh buffer, since the encoder owns the old one
+// until it's done. Returns false if there is nothing to save yet or the
+// previous clip is still being encoded.
+func (c *ClipRecorder) save() bool {
+	if c.saving || len(c.frames) == 0 {
+		return false
+	}
+	// Oldest first: once the buffer is full, that's the next slot
+	frames := c.frames
+	if len(frames) == clipFrames {
+		frames = slices.Concat(frames[c.next:], frames[:c.next])
+	}
+	w := c.img.Bounds().Dx()
+	c.frames, c.next = nil, 0
+	c.saving = true
+	if c.done == nil {
+		c.done = make(chan clipResult, 1)
+	}
+	name := "snake-" + time.Now().Format("20060102-150405") + ".gif"
+	go func() {
+		path, err := writeClip(name, w, frames)
+		c.done <- clipResult{path, err}
+	}()
+	return true
+}
+
+// poll returns the outcome of the clip being saved, once it's finished
+func (c *ClipRecorder) poll() (res clipResult, ok bool) {
+	if !c.saving {
+		return clipResult{}, false
+	}
+	select {
+	case res = <-c.done:
+		c.saving = false
+		return res, true
+	default:
+		return clipResult{}, false
+	}
+}
+
+// writeClip encodes the frames, w pixels wide, as an animated GIF and
+// exports it
+// It runs on its own goroutine and touches nothing but its arguments.
+func writeClip(name string, w int, frames [][]byte) (string, error) {
+	anim := &gif.GIF{}
+	bounds := image.Rect(0, 0, w, clipHeight)
+	for _, pix := range frames {
+		src := &image.RGBA{Pix: pix, Stride: 4 * w, Rect: bounds}
+		dst := image.NewPaletted(bounds, palette.Plan9)
+		draw.Draw(dst, bounds, src, image.Point{}, draw.Src)
+		anim.Image = append(anim.Image, dst)
+		anim.Delay = append(anim.Delay, 100/clipFPS)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return "", fmt.Errorf("encoding clip: %w", err)
+	}
+	path, err := exportFile(name, buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("writing clip: %w", err)
+	}
+	return path, nil
+}
+
+// saveClip starts saving the last few seconds as a GIF
+func (g *Game) saveClip() {
+	if !g.clip.save() {
+		return
+	}
+	g.notify(tr("Saving clip..."))
+}
+
+// checkClip tells the player once a clip has been saved
+func (g *Game) checkClip() {
+	res, ok := g.clip.poll()
+	if !ok {
+		return
+	}
+	if res.err != nil {
+		log.Printf("saving clip: %v", res.err)
+		g.notify(tr("Could not save the clip"))
+		return
+	}
+	log.Printf("saved clip to %s", res.path)
+	g.notify(tr("Clip saved"))
+}
@@ -0,0 +1,98 @@
+// Command snake-gym serves the Snake environment (package gym) over
+// standard input and output, so agents written in any language, e.g. a
+// Python training loop driving it as a subprocess, can play the real
+// game's rules
+//
+// It speaks JSON lines: one request per line in, one reply per line out.
+// A request is either
+//
+//	{"reset": true}
+//	{"action": 3}
+//
+// where the action is 0-3 for up, down, left and right, and the reply is
+//
+//	{"observation": {...}, "reward": 1, "done": false}
+//
+// A reset replies with the first observation of a new episode, a zero
+// reward and done false. Requests that can't be read, and actions sent
+// while no episode is running, get a reply with "error" set instead.
+//
+// Usage:
+//
+//	snake-gym -width 20 -height 20 -seed 1 -max-steps 2000
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/obliviousorion/go-basics/pkg/gym"
+)
+
+// request is one line of input
+type request struct {
+	Reset  bool        `json:"reset,omitempty"`
+	Action *gym.Action `json:"action,omitempty"`
+}
+
+// reply is one line of output
+type reply struct {
+	Observation *gym.Observation `json:"observation,omitempty"`
+	Reward      float64          `json:"reward"`
+	Done        bool             `json:"done"`
+	Error       string           `json:"error,omitempty"`
+}
+
+func main() {
+	var cfg gym.Config
+	flag.IntVar(&cfg.Width, "width", 20, "board width in cells")
+	flag.IntVar(&cfg.Height, "height", 20, "board height in cells")
+	flag.Uint64Var(&cfg.Seed, "seed", 1, "seed for the episodes")
+	flag.IntVar(&cfg.Enemies, "enemies", 0, "enemies on the board")
+	flag.IntVar(&cfg.MaxSteps, "max-steps", 0, "end episodes after this many steps (0 = no limit)")
+	flag.Parse()
+	env, err := gym.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	in := bufio.NewScanner(os.Stdin)
+	out := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(out)
+	for in.Scan() {
+		if err := enc.Encode(handle(env, in.Bytes())); err != nil {
+			log.Fatalf("writing reply: %v", err)
+		}
+		// Flush after every reply: the agent waits for it before sending
+		// the next request
+		if err := out.Flush(); err != nil {
+			log.Fatalf("writing reply: %v", err)
+		}
+	}
+	if err := in.Err(); err != nil {
+		log.Fatalf("reading requests: %v", err)
+	}
+}
+
+// handle answers one request line
+func handle(env *gym.Env, line []byte) reply {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return reply{Error: fmt.Sprintf("bad request: %v", err)}
+	}
+	switch {
+	case req.Reset:
+		obs := env.Reset()
+		return reply{Observation: &obs}
+	case req.Action != nil:
+		obs, reward, done, err := env.Step(*req.Action)
+		if err != nil {
+			return reply{Error: err.Error(), Done: true}
+		}
+		return reply{Observation: &obs, Reward: reward, Done: done}
+	}
+	return reply{Error: `want "reset" or "action"`}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// The developer console is a command line for testing the game, opened
+// and closed with the key left of 1 (ActionConsole). It drops down over
+// whatever is on screen and takes the keys while it is open; a run under
+// it waits, its clock stopped as if paused. Commands are looked up in
+// consoleCommands, past ones can be brought back with Up and Down, and
+// each command's output is added to the log above the prompt.
+//
+// Commands that change how a run plays (the cheats) keep the run they are
+// used in, and every run started while they are on, off the high scores.
+
+const (
+	// consoleLogLines is how many lines of output the console keeps, and
+	// consoleHistory how many commands
+	consoleLogLines = 200
+	consoleHistory  = 50
+
+	// consoleRows is how many lines of the log fit on the console, and
+	// consoleTextSize and consoleLineHeight their size
+	consoleRows       = 12
+	consoleTextSize   = 14
+	consoleLineHeight = 18
+
+	// consoleMaxInput is the longest command line that can be typed
+	consoleMaxInput = 120
+
+	// maxSpawnFood is the most food spawnfood puts down at once, and
+	// maxConsoleSpeed the fastest speed can set, in moves per second
+	maxSpawnFood    = 100
+	maxConsoleSpeed = 60
+)
+
+var (
+	consoleBackgroundColor = color.RGBA{0, 0, 0, 210}
+	consoleErrorColor      = color.RGBA{255, 100, 100, 255}
+)
+
+// Console is the developer console's state: whether it is open, what has
+// been typed and said, and the cheats it has switched on
+type Console struct {
+	open bool
+
+	// openedAt is when the console was last opened, which a run under it
+	// is paused at
+	openedAt time.Time
+
+	// input is the command line being typed, and chars is reused between
+	// frames to collect typed characters
+	input []rune
+	chars []rune
+
+	// log is the output so far, oldest first
+	log []consoleLine
+
+	// history holds the commands entered, oldest first, and browsing the
+	// one being shown at the prompt (len(history) when none is)
+	history  []string
+	browsing int
+
+	// god keeps player one's snake alive (see snake.Player.Invincible),
+	// and speed, when above 0, fixes the snakes' speed in moves per
+	// second; both last until they are switched off
+	god   bool
+	speed float64
+}
+
+// consoleLine is one line of the console's output
+type consoleLine struct {
+	text string
+	err  bool
+}
+
+// consoleCommand is a command the console understands
+type consoleCommand struct {
+	name string
+
+	// usage describes the arguments and help what the command does, for
+	// the help command
+	usage, help string
+
+	// run carries the command out with its arguments, returning what to
+	// print
+	run func(g *Game, args []string) (string, error)
+}
+
+// errNoRun is returned by the commands that need a run in progress
+var errNoRun = errors.New("no run in progress")
+
+// consoleCommands are the commands the console understands, besides its
+// own help and clear
+var consoleCommands = []consoleCommand{
+	{
+		name:  "spawnfood",
+		usage: "[n]",
+		help:  "puts n more food on the board (default 1)",
+		run: func(g *Game, args []string) (string, error) {
+			n, err := intArg(args, 1, 1, maxSpawnFood)
+			if err != nil {
+				return "", err
+			}
+			at, ok := g.runClock()
+			if !ok {
+				return "", errNoRun
+			}
+			g.cheated = true
+			return fmt.Sprintf("spawned %d food", g.world.AddFood(n, at)), nil
+		},
+	},
+	{
+		name:  "speed",
+		usage: "[moves/s | off]",
+		help:  "fixes the snakes' speed, or shows it",
+		run: func(g *Game, args []string) (string, error) {
+			c := &g.console
+			switch {
+			case len(args) == 0:
+				if c.speed > 0 {
+					return fmt.Sprintf("speed fixed at %g moves/s", c.speed), nil
+				}
+				return "speed follows the difficulty", nil
+			case args[0] == "off":
+				c.speed = 0
+				g.applySettings()
+				return "speed follows the difficulty", nil
+			}
+			v, err := strconv.ParseFloat(args[0], 64)
+			if err != nil || v <= 0 || v > maxConsoleSpeed {
+				return "", fmt.Errorf("speed must be above 0 and at most %d moves/s", maxConsoleSpeed)
+			}
+			c.speed = v
+			g.applySettings()
+			g.cheated = true
+			return fmt.Sprintf("speed fixed at %g moves/s", v), nil
+		},
+	},
+	{
+		name:  "god",
+		usage: "[on | off]",
+		help:  "makes player one's snake invincible",
+		run: func(g *Game, args []string) (string, error) {
+			c := &g.console
+			if len(args) > 0 {
+				on, err := onOffArg(args[0])
+				if err != nil {
+					return "", err
+				}
+				c.god = on
+			} else {
+				c.god = !c.god
+			}
+			if w := g.boards[0].world; w != nil {
+				w.Players[0].Invincible = c.god
+			}
+			if c.god {
+				g.cheated = true
+				return "god mode on", nil
+			}
+			return "god mode off", nil
+		},
+	},
+	{
+		name:  "seed",
+		usage: "[n | off]",
+		help:  "fixes the seed of the next runs, like -seed, or shows this run's",
+		run: func(g *Game, args []string) (string, error) {
+			switch {
+			case len(args) == 0:
+				return fmt.Sprintf("seed %d", g.seed), nil
+			case args[0] == "off":
+				g.launch.seedSet = false
+				return "runs get fresh seeds", nil
+			}
+			seed, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("bad seed %q", args[0])
+			}
+			g.launch.seed, g.launch.seedSet = seed, true
+			return fmt.Sprintf("the next runs use seed %d", seed), nil
+		},
+	},
+	{
+		name: "mods",
+		help: "lists the mods loaded from ./mods",
+		run: func(g *Game, args []string) (string, error) {
+			if len(g.mods) == 0 {
+				return "no mods loaded", nil
+			}
+			names := make([]string, len(g.mods))
+			for i, m := range g.mods {
+				names[i] = m.Name
+			}
+			return fmt.Sprintf("%d mods: %s", len(names), strings.Join(names, ", ")), nil
+		},
+	},
+}
+
+// updateConsole handles the keys while the console is open, or opens it
+// Closing it carries on a run from where it was paused.
+func (g *Game) updateConsole() {
+	c := &g.console
+	if !c.open {
+		c.open, c.openedAt = true, g.clock.Now()
+		c.browsing = len(c.history)
+		return
+	}
+
+	switch {
+	case g.isJustPressed(ActionConsole) || inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		c.open = false
+		if _, ok := g.scenes.Current().(*PlayingScene); ok {
+			g.shiftTimers(g.clock.Now().Sub(c.openedAt))
+		}
+		g.input.ignoreHeld()
+		return
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter):
+		line := strings.TrimSpace(string(c.input))
+		c.input = c.input[:0]
+		if line != "" {
+			g.runCommand(line)
+		}
+		return
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace) || repeatingKey(ebiten.KeyBackspace):
+		if len(c.input) > 0 {
+			c.input = c.input[:len(c.input)-1]
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowUp):
+		c.browse(-1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowDown):
+		c.browse(1)
+	}
+
+	c.chars = ebiten.AppendInputChars(c.chars[:0])
+	for _, r := range c.chars {
+		if len(c.input) < consoleMaxInput && unicode.IsPrint(r) {
+			c.input = append(c.input, r)
+		}
+	}
+}
+
+// browse moves through the history by delta, putting the command there
+// at the prompt; going past the newest clears it
+func (c *Console) browse(delta int) {
+	c.browsing = min(max(c.browsing+delta, 0), len(c.history))
+	c.input = c.input[:0]
+	if c.browsing < len(c.history) {
+		c.input = append(c.input, []rune(c.history[c.browsing])...)
+	}
+}
+
+// runCommand carries out a command line, logging it and its output
+func (g *Game) runCommand(line string) {
+	c := &g.console
+	if len(c.history) == 0 || c.history[len(c.history)-1] != line {
+		c.history = append(c.history, line)
+		if len(c.history) > consoleHistory {
+			c.history = c.history[1:]
+		}
+	}
+	c.browsing = len(c.history)
+	c.print("> "+line, false)
+
+	fields := strings.Fields(line)
+	name, args := strings.ToLower(fields[0]), fields[1:]
+	switch name {
+	case "help":
+		for _, cmd := range consoleCommands {
+			c.print(strings.TrimSpace(cmd.name+" "+cmd.usage)+" - "+cmd.help, false)
+		}
+		c.print("clear - empties the console", false)
+		return
+	case "clear":
+		c.log = c.log[:0]
+		return
+	}
+
+	i := slices.IndexFunc(consoleCommands, func(cmd consoleCommand) bool { return cmd.name == name })
+	if i < 0 {
+		c.print(fmt.Sprintf("unknown command %q, try help", name), true)
+		return
+	}
+	out, err := consoleCommands[i].run(g, args)
+	if err != nil {
+		c.print(err.Error(), true)
+		return
+	}
+	if out != "" {
+		c.print(out, false)
+	}
+}
+
+// print adds a line to the log, dropping the oldest once it is full
+func (c *Console) print(text string, err bool) {
+	c.log = append(c.log, consoleLine{text, err})
+	if len(c.log) > consoleLogLines {
+		c.log = slices.Delete(c.log, 0, len(c.log)-consoleLogLines)
+	}
+}
+
+// applyCheats gives a new run what the console has switched on, and keeps
+// it off the high scores if anything is
+func (g *Game) applyCheats() {
+	c := &g.console
+	g.cheated = c.god || c.speed > 0
+	if c.god {
+		g.boards[0].world.Players[0].Invincible = true
+	}
+}
+
+// drawConsole draws the console, if it is open, over the top of the
+// screen
+// Like the debug overlay, it is drawn outside the camera, so it doesn't
+// shake.
+func (g *Game) drawConsole(screen *ebiten.Image) {
+	c := &g.console
+	if !c.open {
+		return
+	}
+	const margin = 8
+	height := float32((consoleRows+1)*consoleLineHeight + 2*margin)
+	fillRect(screen, 0, 0, float32(screenWidth), height, consoleBackgroundColor, false)
+
+	// The newest lines of the log, above the prompt
+	lines := c.log[max(len(c.log)-consoleRows, 0):]
+	y := float64(margin + (consoleRows-len(lines))*consoleLineHeight)
+	for _, l := range lines {
+		clr := debugTextColor
+		if l.err {
+			clr = consoleErrorColor
+		}
+		drawText(screen, l.text, consoleTextSize, margin, y, clr)
+		y += consoleLineHeight
+	}
+	drawText(screen, "> "+string(c.input)+"_", consoleTextSize, margin, y, color.White)
+}
+
+// intArg returns the first argument as a number from lo to hi, or def if
+// there is none
+func intArg(args []string, def, lo, hi int) (int, error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("%q must be a number from %d to %d", args[0], lo, hi)
+	}
+	return n, nil
+}
+
+// onOffArg parses "on" or "off"
+func onOffArg(arg string) (bool, error) {
+	switch strings.ToLower(arg) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q must be on or off", arg)
+}
@@ -0,0 +1,90 @@
+package main
+
+import "github.com/obliviousorion/go-basics/pkg/snake"
+
+// Snakes are steered by snake.Controller implementations; the bots and
+// scripted players live in package snake with the rest of the rules.
+// Keyboard (and touch) players are here because they read Ebiten input.
+
+// poller is implemented by controllers that read input every frame
+// rather than only when asked for a direction, so a key tapped between
+// two ticks isn't missed
+type poller interface {
+	poll()
+}
+
+// pollControllers lets the current board's controllers read their input
+func (g *Game) pollControllers() {
+	for _, p := range g.world.Players {
+		if c, ok := p.Controller.(poller); ok {
+			c.poll()
+		}
+	}
+}
+
+// pressedInput reports whether an action is held, from any input source
+type pressedInput interface {
+	isPressed(action Action) bool
+}
+
+// KeyboardController steers a snake with a player's bound keys (and, for
+// player one, touch gestures)
+type KeyboardController struct {
+	input    pressedInput
+	steering Steering
+
+	// want is the last direction asked for since the previous tick
+	// (zero if none)
+	want snake.Point
+
+	// dash is set when the dash key was pressed since the previous tick,
+	// and dashHeld while it is held, so holding it dashes only once
+	dash, dashHeld bool
+}
+
+// newKeyboardController steers with the given actions
+func newKeyboardController(input pressedInput, steering Steering) *KeyboardController {
+	return &KeyboardController{input: input, steering: steering}
+}
+
+// poll reads the keys
+// We capture input every frame, not just on ticks, so direction changes
+// feel responsive; the snake turns on the next tick.
+func (c *KeyboardController) poll() {
+	s := c.steering
+	if c.input.isPressed(s.Up) {
+		c.want = snake.Up
+	} else if c.input.isPressed(s.Down) {
+		c.want = snake.Down
+	} else if c.input.isPressed(s.Left) {
+		c.want = snake.Left
+	} else if c.input.isPressed(s.Right) {
+		c.want = snake.Right
+	}
+
+	held := c.input.isPressed(s.Dash)
+	if held && !c.dashHeld {
+		c.dash = true
+	}
+	c.dashHeld = held
+}
+
+// NextDirection turns toward the last direction pressed
+// A press pointing back into the snake is ignored, which prevents the
+// snake from reversing into itself.
+func (c *KeyboardController) NextDirection(state snake.GameState) snake.Point {
+	want := c.want
+	c.want = snake.Point{}
+	if want == (snake.Point{}) || snake.IsReverse(want, state.Direction()) {
+		return state.Direction()
+	}
+	return want
+}
+
+// WantsDash reports whether the dash key was pressed since the previous
+// tick
+func (c *KeyboardController) WantsDash() bool {
+	dash := c.dash
+	c.dash = false
+	return dash
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"image/color"
+	"log"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// ControlsScene lists every action with its keys, and rebinds one by
+// waiting for the next key pressed after it is selected
+// The new key replaces the action's keys and is saved in the settings'
+// Keys; a key that clashes with another action in use at the same time
+// is allowed but warned about, and marked on both rows until fixed.
+type ControlsScene struct {
+	g    *Game
+	menu Menu
+
+	// back is the scene to return to (the options)
+	back Scene
+
+	// waiting is set while waiting for the key for the selected action
+	waiting bool
+
+	// warning is the last conflict found, shown under the list
+	warning string
+
+	// keys is reused between frames to collect pressed keys
+	keys []ebiten.Key
+}
+
+// The rows after the actions
+const (
+	controlsReset = int(actionCount) + iota
+	controlsBack
+	controlsCount
+)
+
+// newControlsScene creates the controls screen, returning to back when
+// closed
+func newControlsScene(g *Game, back Scene) *ControlsScene {
+	return &ControlsScene{
+		g:    g,
+		menu: Menu{Items: make([]string, controlsCount), TextSize: 18, ItemHeight: 22, Rows: 13},
+		back: back,
+	}
+}
+
+// Update rebinds the selected action, or navigates the list
+// Keys are read directly while waiting, so any key can be bound; Escape
+// cancels instead.
+func (s *ControlsScene) Update() error {
+	g := s.g
+	if s.waiting {
+		s.keys = inpututil.AppendJustPressedKeys(s.keys[:0])
+		if len(s.keys) == 0 {
+			return nil
+		}
+		s.waiting = false
+		if s.keys[0] != ebiten.KeyEscape {
+			s.bind(Action(s.menu.Selected), s.keys[0])
+		}
+		return nil
+	}
+
+	if g.isJustPressed(ActionBack) {
+		s.close()
+		return nil
+	}
+	chosen, ok := g.updateMenu(&s.menu)
+	if !ok {
+		return nil
+	}
+	switch chosen {
+	case controlsReset:
+		g.settings.Keys = nil
+		g.applySettings()
+		s.warning = ""
+	case controlsBack:
+		s.close()
+	default:
+		s.waiting = true
+	}
+	return nil
+}
+
+// bind sets key as the only key for action and warns if it clashes
+func (s *ControlsScene) bind(action Action, key ebiten.Key) {
+	g := s.g
+	if g.settings.Keys == nil {
+		g.settings.Keys = KeyBindings{}
+	}
+	g.settings.Keys.Bind(action, key)
+	g.applySettings()
+
+	s.warning = ""
+	for _, c := range g.settings.keyConflicts() {
+		if c.key != key || c.a != action && c.b != action {
+			continue
+		}
+		other := c.a
+		if other == action {
+			other = c.b
+		}
+		s.warning = trf("%s is also used for %s", key, tr(actionLabels[other]))
+		g.audio.play(SoundPoison)
+		return
+	}
+}
+
+// close saves the settings and returns to the options
+func (s *ControlsScene) close() {
+	if err := s.g.settings.Save(); err != nil {
+		log.Printf("saving settings: %v", err)
+	}
+	s.g.scenes.Switch(s.back)
+}
+
+// Draw renders the list of actions and their keys
+// The keys shown are the ones in use in solo play and the menus; in
+// versus, player one's arrow keys go to player two (see keyBindings).
+func (s *ControlsScene) Draw(screen *ebiten.Image) {
+	g := s.g
+	drawCenteredText(screen, tr("Key Bindings"), 48, 40, color.White)
+
+	bindings := g.settings.keyBindings(ModeSolo)
+	clashing := make(map[Action]bool)
+	for _, c := range g.settings.keyConflicts() {
+		clashing[c.a], clashing[c.b] = true, true
+	}
+	for a := range actionCount {
+		item := tr(actionLabels[a]) + ": " + keyNames(bindings[a])
+		if clashing[a] {
+			item += " (!)"
+		}
+		s.menu.Items[a] = item
+	}
+	s.menu.Items[controlsReset] = tr("Reset to defaults")
+	s.menu.Items[controlsBack] = tr("Back")
+	s.menu.draw(screen, 100)
+
+	switch {
+	case s.waiting:
+		drawCenteredText(screen, trf("Press a key for %s (ESC to cancel)", tr(actionLabels[s.menu.Selected])), 18, screenHeight-72, menuSelectedColor)
+	case s.warning != "":
+		drawCenteredText(screen, s.warning, 18, screenHeight-72, color.RGBA{255, 80, 80, 255})
+	case len(clashing) > 0:
+		drawCenteredText(screen, tr("(!) Keys used for two things at once"), 18, screenHeight-72, menuTextColor)
+	}
+	drawCenteredText(screen, tr("ENTER to change a key, ESC to go back"), 16, screenHeight-40, menuTextColor)
+}
+
+// keyNames lists keys for display, e.g. "W, ArrowUp"
+func keyNames(keys []ebiten.Key) string {
+	if len(keys) == 0 {
+		return tr("(none)")
+	}
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.String()
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// The daily challenge is a Classic run whose seed comes from the date, so
+// everyone playing on the same day gets the same maze and the same food
+// and power-ups (as long as they make the same moves). Days are counted
+// in UTC so players in different time zones share them. The board size
+// and level are fixed too, since both change the layout, and there are no
+// enemies. Each date keeps its own best score (see HighScoreTable.Add).
+
+const (
+	// dailyLevelName is the level every daily challenge is played on
+	dailyLevelName = "Maze"
+
+	// dailyBoard is the board size of daily challenges
+	dailyBoard = BoardNormal
+
+	// maxDailyRecords is how many days of daily bests are kept
+	maxDailyRecords = 30
+
+	// dateFormat is how challenge dates are written, e.g. "2024-05-01"
+	dateFormat = "2006-01-02"
+)
+
+// dailyDate returns the date of the daily challenge being played at now
+func dailyDate(now time.Time) string {
+	return now.UTC().Format(dateFormat)
+}
+
+// dailySeed derives the seed of the given date's challenge
+func dailySeed(date string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte("go-snake-2d daily " + date))
+	return h.Sum64()
+}
+
+// startDaily sets up today's challenge: its date, seed, level and
+// difficulty (always Normal, so everyone plays the same game)
+// Restarting replays the same day's challenge, even after midnight.
+func (g *Game) startDaily() {
+	g.daily = dailyDate(g.clock.Now())
+	g.mazeSeed = dailySeed(g.daily)
+	g.runDifficulty = DifficultyNormal
+	g.level = builtInLevel(dailyLevelName)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"time"
+)
+
+// DayNightCycle selects what, if anything, moves the colors between the
+// day theme (Settings.Colors) and the night theme (Settings.NightColors)
+// The colors blend gradually, so the board darkens into night and
+// brightens back into day; the palette's colors, which have to stay
+// apart, are the same at any hour.
+type DayNightCycle int
+
+const (
+	// DayNightOff keeps the day theme
+	DayNightOff DayNightCycle = iota
+
+	// DayNightPlayTime follows the time played in the run: every run
+	// starts at midday, and night falls halfway through each
+	// dayNightPeriod
+	DayNightPlayTime
+
+	// DayNightClock follows the time of day on the computer's clock, from
+	// midday to midnight
+	DayNightClock
+
+	dayNightCycleCount
+)
+
+var dayNightCycleNames = [dayNightCycleCount]string{"Off", "Play time", "Clock"}
+
+// dayNightPeriod is how much play time a whole day lasts
+const dayNightPeriod = 6 * time.Minute
+
+// dayNightSteps is how many shades the colors pass through from day to
+// night; the theme only changes, and the cached background is only drawn
+// again, when the cycle reaches the next one
+const dayNightSteps = 48
+
+// defaultNightTheme is the default theme's night: a deep blue board with
+// the rest dimmed and cooled to match
+func defaultNightTheme() Theme {
+	return Theme{
+		Background: HexColor{6, 10, 34, 255},
+		Snake:      HexColor{190, 215, 255, 255},
+		Snake2:     HexColor{0, 150, 230, 255},
+		Food:       HexColor{230, 40, 70, 255},
+		Poison:     HexColor{90, 170, 60, 255},
+		Fleeing:    HexColor{230, 130, 40, 255},
+		Golden:     HexColor{240, 210, 90, 255},
+		Enemy:      HexColor{170, 40, 210, 255},
+		Obstacle:   HexColor{60, 70, 110, 255},
+		HUD:        HexColor{160, 175, 215, 255},
+		Text:       HexColor{160, 175, 215, 255},
+		Highlight:  HexColor{240, 210, 90, 255},
+	}
+}
+
+// nightness returns how far into the night the game is now, from 0 for
+// the full day theme to 1 for the full night theme
+// The play time cycle is only under way during a run; elsewhere it is
+// day.
+func (g *Game) nightness() float64 {
+	var day float64
+	switch g.settings.DayNight {
+	case DayNightPlayTime:
+		at, ok := g.runClock()
+		if g.tas != nil {
+			at, ok = g.lastUpdate, true
+		}
+		if !ok {
+			return 0
+		}
+		day = float64(g.elapsed(at)) / float64(dayNightPeriod)
+	case DayNightClock:
+		now := g.clock.Now()
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		day = float64(now.Sub(midnight))/float64(24*time.Hour) - 0.5
+	default:
+		return 0
+	}
+	// Midday is 0, midnight halfway round
+	return (1 - math.Cos(2*math.Pi*day)) / 2
+}
+
+// updateDayNight blends the theme for the time of day, once the cycle has
+// reached the next shade (see dayNightSteps)
+func (g *Game) updateDayNight() {
+	shade := math.Round(g.nightness()*dayNightSteps) / dayNightSteps
+	if shade == g.night {
+		return
+	}
+	g.night = shade
+	g.theme = g.dayTheme.lerp(g.nightTheme, shade)
+	menuTextColor, menuSelectedColor = color.RGBA(g.theme.Text), color.RGBA(g.theme.Highlight)
+}
+
+// MarshalText encodes the cycle by name
+func (c DayNightCycle) MarshalText() ([]byte, error) {
+	return marshalName(dayNightCycleNames[:], int(c))
+}
+
+// UnmarshalText decodes the cycle from its name
+func (c *DayNightCycle) UnmarshalText(b []byte) error {
+	return unmarshalName(dayNightCycleNames[:], b, (*int)(c))
+}
+
+// String returns the display name of the cycle
+func (c DayNightCycle) String() string {
+	return dayNightCycleNames[c]
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// debugOverlay shows diagnostics for tuning the movement interpolation
+// and the difficulty curve: frame rates, tick timing and the state of
+// player one's snake
+type debugOverlay struct {
+	visible bool
+
+	// lastFrame is when the previous frame was drawn, and frameTime the
+	// time between it and the one before
+	lastFrame time.Time
+	frameTime time.Duration
+}
+
+var (
+	debugTextColor       = color.RGBA{0, 255, 128, 255}
+	debugBackgroundColor = color.RGBA{0, 0, 0, 180}
+)
+
+// drawDebug draws the debug overlay in the top-right corner, if it is on
+// It is drawn on top of everything, outside the camera, so it doesn't
+// shake.
+func (g *Game) drawDebug(screen *ebiten.Image) {
+	d := &g.debug
+	now := g.clock.Now()
+	if !d.lastFrame.IsZero() {
+		d.frameTime = now.Sub(d.lastFrame)
+	}
+	d.lastFrame = now
+	if !d.visible {
+		return
+	}
+
+	lines := []string{
+		fmt.Sprintf("FPS %.1f  TPS %.1f", ebiten.ActualFPS(), ebiten.ActualTPS()),
+		fmt.Sprintf("frame %.2fms", float64(d.frameTime.Microseconds())/1000),
+		fmt.Sprintf("tick %v  progress %.2f", g.tickInterval.Round(time.Millisecond), g.tickProgress(now)),
+	}
+	if w := g.world; w != nil {
+		p := w.Players[0]
+		lines = append(lines, fmt.Sprintf("length %d  head %v  dir %v", p.Snake.Len(), p.Head(), p.Direction))
+		var food []string
+		for _, f := range w.Foods {
+			food = append(food, fmt.Sprint(f.Pos))
+		}
+		lines = append(lines, "food "+strings.Join(food, " "))
+	}
+
+	// A dark box behind the text keeps it readable over the board
+	const size, lineHeight, width = 12, 16, 230
+	x := float64(screenWidth - width - 4)
+	fillRect(screen, float32(x), 4, width, float32(len(lines)*lineHeight+8), debugBackgroundColor, false)
+	for i, l := range lines {
+		drawText(screen, l, size, x+6, float64(8+i*lineHeight), debugTextColor)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// The demo lets a perfect bot (see snake.HamiltonBot) play alone until it
+// fills the whole board. It is always played on an open board, which the
+// bot needs, with food that never flees. Started with -demo on a big board
+// and a high -tps, it doubles as a stress test: the snake ends up as long
+// as the board is big, and every segment is drawn every frame.
+
+// demoLevelName is the level the demo is played on: the one without walls
+const demoLevelName = "Open Field"
+
+// startDemo sets up the demo's level and difficulty, the same every time
+// like the daily challenge's
+func (g *Game) startDemo() {
+	g.runDifficulty = DifficultyNormal
+	g.level = builtInLevel(demoLevelName)
+}
+
+// demoStart returns where the demo's snake starts on a w×h board: on the
+// bot's cycle, heading along it
+func demoStart(w, h int) SnakeStart {
+	cycle := snake.HamiltonCycle(w, h)
+	return SnakeStart{
+		Head:      cycle[1],
+		Direction: snake.Point{X: cycle[1].X - cycle[0].X, Y: cycle[1].Y - cycle[0].Y},
+	}
+}
+
+// drawDemoResult shows how far the bot got
+func (s *GameOverScene) drawDemoResult(screen *ebiten.Image) {
+	g := s.g
+	headline := "Game Over!"
+	if g.world.Full {
+		headline = "Board filled!"
+	}
+	drawCenteredText(screen, tr(headline), 48, 40, color.White)
+
+	p := g.world.Players[0]
+	cells := g.world.Width * g.world.Height
+	result := trf("Length %d of %d cells in %s", p.Snake.Len(), cells, formatRunTime(g.runTime))
+	drawCenteredText(screen, result, 24, 100, g.playerColor(0))
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// DifficultyCurve describes how the snake's speed ramps up as it grows
+// Rates are in moves per second ("ticks"); the tick interval is derived
+// from them every frame so the speed changes as soon as food is eaten.
+type DifficultyCurve struct {
+	// StartRate is the speed of a freshly spawned snake
+	StartRate float64 `json:"startRate"`
+
+	// MaxRate caps the speed so the game stays playable
+	MaxRate float64 `json:"maxRate"`
+
+	// RatePerSegment is how much faster the snake gets for each segment
+	// it has grown beyond its starting length
+	RatePerSegment float64 `json:"ratePerSegment"`
+
+	// Exponent shapes the ramp: 1 is linear, below 1 front-loads the
+	// speed-up, above 1 keeps the early game slow for longer
+	Exponent float64 `json:"exponent"`
+
+	// FoodLifetime, when above 0, is how many seconds food stays put
+	// before it moves somewhere else, so the snake can't wait for a safe
+	// moment to grab it
+	FoodLifetime float64 `json:"foodLifetime,omitempty"`
+
+	// FoodSpread, when above 1, makes food tend to appear away from the
+	// snake: that many free cells are drawn and the furthest one wins
+	FoodSpread int `json:"foodSpread,omitempty"`
+}
+
+// defaultDifficulty starts at 6 moves/sec and reaches the 20 moves/sec
+// cap after 28 pieces of food
+var defaultDifficulty = DifficultyCurve{
+	StartRate:      6,
+	MaxRate:        20,
+	RatePerSegment: 0.5,
+	Exponent:       1,
+}
+
+// Rate returns the moves per second for a snake that has grown by
+// the given number of segments
+func (c DifficultyCurve) Rate(grown int) float64 {
+	if grown < 0 {
+		grown = 0
+	}
+	exp := c.Exponent
+	if exp <= 0 {
+		exp = 1
+	}
+	rate := c.StartRate + c.RatePerSegment*math.Pow(float64(grown), exp)
+	return math.Min(rate, c.MaxRate)
+}
+
+// FoodDuration returns FoodLifetime as a duration, 0 meaning food stays
+// until it is eaten
+func (c DifficultyCurve) FoodDuration() time.Duration {
+	if c.FoodLifetime <= 0 {
+		return 0
+	}
+	return time.Duration(c.FoodLifetime * float64(time.Second))
+}
+
+// TickInterval converts Rate into the time between snake moves
+func (c DifficultyCurve) TickInterval(grown int) time.Duration {
+	rate := c.Rate(grown)
+	if rate <= 0 {
+		// A broken curve shouldn't freeze the game; fall back to the start speed
+		rate = defaultDifficulty.StartRate
+	}
+	return time.Duration(float64(time.Second) / rate)
+}
+
+// Difficulty is a named preset bundling everything that makes a run
+// harder: the speed ramp, how long food lasts and where it appears, rocks
+// scattered over the board and how fast the survival arena closes in
+// Each difficulty keeps its own high-score tables.
+type Difficulty int
+
+const (
+	DifficultyEasy Difficulty = iota
+	DifficultyNormal
+	DifficultyHard
+	DifficultyInsane
+	difficultyCount
+)
+
+var difficultyNames = [difficultyCount]string{"Easy", "Normal", "Hard", "Insane"}
+
+// difficultyPreset is what a Difficulty sets
+type difficultyPreset struct {
+	// curve is the speed ramp, food lifetime and food spread
+	curve DifficultyCurve
+
+	// rocks is the fraction of the board's free cells covered with
+	// single-cell rocks on top of the level's walls
+	rocks float64
+
+	// The survival arena closes a ring every shrinkInterval, down to
+	// arenaMinWidth×arenaMinHeight cells
+	shrinkInterval                time.Duration
+	arenaMinWidth, arenaMinHeight int
+}
+
+// difficultyPresets maps each difficulty to its settings; Normal plays
+// like the game always has
+var difficultyPresets = [difficultyCount]difficultyPreset{
+	DifficultyEasy: {
+		curve:          DifficultyCurve{StartRate: 4, MaxRate: 14, RatePerSegment: 0.35, Exponent: 1},
+		shrinkInterval: 20 * time.Second,
+		arenaMinWidth:  12,
+		arenaMinHeight: 10,
+	},
+	DifficultyNormal: {
+		curve:          defaultDifficulty,
+		shrinkInterval: 15 * time.Second,
+		arenaMinWidth:  10,
+		arenaMinHeight: 8,
+	},
+	DifficultyHard: {
+		curve:          DifficultyCurve{StartRate: 9, MaxRate: 26, RatePerSegment: 0.6, Exponent: 1, FoodLifetime: 10, FoodSpread: 3},
+		rocks:          0.02,
+		shrinkInterval: 12 * time.Second,
+		arenaMinWidth:  8,
+		arenaMinHeight: 6,
+	},
+	DifficultyInsane: {
+		curve:          DifficultyCurve{StartRate: 12, MaxRate: 32, RatePerSegment: 0.8, Exponent: 0.9, FoodLifetime: 6, FoodSpread: 5},
+		rocks:          0.04,
+		shrinkInterval: 8 * time.Second,
+		arenaMinWidth:  6,
+		arenaMinHeight: 5,
+	},
+}
+
+// MarshalText encodes the difficulty by name
+func (d Difficulty) MarshalText() ([]byte, error) {
+	return marshalName(difficultyNames[:], int(d))
+}
+
+// UnmarshalText decodes the difficulty from its name
+func (d *Difficulty) UnmarshalText(b []byte) error {
+	return unmarshalName(difficultyNames[:], b, (*int)(d))
+}
+
+// String returns the display name of the difficulty
+func (d Difficulty) String() string {
+	return difficultyNames[d]
+}
+
+// rockClearance is how many cells around each snake's starting position
+// are kept free of rocks
+const rockClearance = 3
+
+// obstacleSet builds the walls of a w×h board on lvl for the current run:
+// the level's own, plus the rocks of the run's difficulty, kept away from
+// where the snakes start
+func (g *Game) obstacleSet(lvl Level, w, h int) map[snake.Point]bool {
+	set := lvl.obstacleSet(w, h, g.mazeSeed)
+	start1, start2 := lvl.versusStarts(w, h)
+	starts := []SnakeStart{lvl.snakeStart(w, h), start1, start2}
+	if g.mode == ModeRoyale {
+		// The level's walls can be anywhere around battle royale's starts
+		starts = royaleStarts(g.rivals+1, w, h)
+		clearStarts(set, starts)
+	}
+	if density := difficultyPresets[g.runDifficulty].rocks; density > 0 {
+		scatterRocks(set, w, h, density, g.mazeSeed, starts, lvl)
+	}
+	return set
+}
+
+// scatterRocks adds single-cell rocks to the walls in set, covering about
+// density of the board, placed from seed so a board can be rebuilt
+// Rocks stay off the outer ring, out of the snakes' way at the starts and
+// off the level's portals, food spawns, checkpoints and boss. Each rock is also kept clear of
+// every other wall, diagonals included: lone rocks can't close off any
+// part of the board, so all of it stays reachable.
+func scatterRocks(set map[snake.Point]bool, w, h int, density float64, seed uint64, starts []SnakeStart, lvl Level) {
+	rng := rand.New(rand.NewPCG(seed, seed^0x9E3779B97F4A7C15))
+	keep := map[snake.Point]bool{}
+	for _, s := range starts {
+		for dy := -rockClearance; dy <= rockClearance; dy++ {
+			for dx := -rockClearance; dx <= rockClearance; dx++ {
+				keep[snake.Point{X: s.Head.X + dx, Y: s.Head.Y + dy}] = true
+			}
+		}
+		for _, p := range s.body() {
+			keep[p] = true
+		}
+	}
+	for _, pt := range lvl.portals(w, h) {
+		keep[pt.A], keep[pt.B] = true, true
+	}
+	for _, p := range concatPoints(lvl.FoodSpawns, lvl.Checkpoints) {
+		keep[p] = true
+	}
+	if b := lvl.boss(w, h); b != nil {
+		for _, p := range b.Cells() {
+			keep[p] = true
+		}
+	}
+
+	want := int(float64(w*h) * density)
+	for try := 0; want > 0 && try < w*h; try++ {
+		p := snake.Point{X: 1 + rng.IntN(max(w-2, 1)), Y: 1 + rng.IntN(max(h-2, 1))}
+		if keep[p] || nearWall(set, p) {
+			continue
+		}
+		set[p] = true
+		want--
+	}
+}
+
+// nearWall reports whether p or any of its eight neighbors is a wall
+func nearWall(set map[snake.Point]bool, p snake.Point) bool {
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if set[snake.Point{X: p.X + dx, Y: p.Y + dy}] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// The game is laid out on a screenWidth×screenHeight logical screen, its
+// width set by the resolution (see Resolution), but the screen image Ebiten hands to Draw has one pixel per
+// physical pixel it covers: on a retina or 4K monitor, or in a big
+// window, that is several per logical pixel. Drawing at that resolution
+// keeps text and shapes sharp instead of upscaling a small image.
+//
+// All drawing code works in logical coordinates and goes through the
+// helpers below, which scale by displayScale. Touch positions come back
+// in screen pixels and are turned into logical ones with logicalPos.
+
+// displayScale is the number of screen pixels per logical pixel, set by
+// Layout every frame
+var displayScale = 1.0
+
+// Layout defines the screen size
+// Called by Ebiten to determine the game's screen dimensions
+// The logical screen only changes with the resolution, whatever the
+// outside size is: it is scaled
+// to fit the window (which the player can resize freely, or the monitor
+// in fullscreen, or in the browser the canvas filling the page) keeping
+// the aspect ratio, centered with black bars on the sides that don't fit.
+// The screen image is sized in physical pixels, using the monitor's
+// device scale factor, so nothing is drawn at a lower resolution than the
+// display has. The board, text and menus are all placed in logical
+// coordinates, so they scale together and the layout never breaks,
+// whatever the real size.
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	fit := min(float64(outsideWidth)/float64(screenWidth), float64(outsideHeight)/screenHeight)
+	scale := fit * ebiten.Monitor().DeviceScaleFactor()
+	if scale <= 0 {
+		// Minimized windows can report a zero size
+		scale = 1
+	}
+	displayScale = scale
+	return int(float64(screenWidth) * scale), int(screenHeight * scale)
+}
+
+// logicalPos converts a position in screen pixels (e.g. a touch) to
+// logical coordinates
+func logicalPos(x, y int) (int, int) {
+	return int(float64(x) / displayScale), int(float64(y) / displayScale)
+}
+
+// fillRect is vector.FillRect in logical coordinates
+func fillRect(dst *ebiten.Image, x, y, w, h float32, clr color.Color, antialias bool) {
+	s := float32(displayScale)
+	vector.FillRect(dst, x*s, y*s, w*s, h*s, clr, antialias)
+}
+
+// fillCircle is vector.FillCircle in logical coordinates
+func fillCircle(dst *ebiten.Image, cx, cy, r float32, clr color.Color, antialias bool) {
+	s := float32(displayScale)
+	vector.FillCircle(dst, cx*s, cy*s, r*s, clr, antialias)
+}
+
+// strokeLine is vector.StrokeLine in logical coordinates
+func strokeLine(dst *ebiten.Image, x0, y0, x1, y1, width float32, clr color.Color, antialias bool) {
+	s := float32(displayScale)
+	vector.StrokeLine(dst, x0*s, y0*s, x1*s, y1*s, width*s, clr, antialias)
+}
+
+// strokeArc strokes the arc of the circle around (cx, cy) from angle
+// start clockwise to angle end, in logical coordinates
+// Angles are in radians, with 0 pointing right.
+func strokeArc(dst *ebiten.Image, cx, cy, r, start, end, width float32, clr color.Color) {
+	s := float32(displayScale)
+	var path vector.Path
+	path.Arc(cx*s, cy*s, r*s, start, end, vector.Clockwise)
+	op := &vector.DrawPathOptions{AntiAlias: true}
+	op.ColorScale.ScaleWithColor(clr)
+	vector.StrokePath(dst, &path, &vector.StrokeOptions{Width: width * s}, op)
+}
+
+// drawText draws txt with its top-left corner at (x, y), in logical
+// coordinates and a logical font size
+// The font is rasterized at the screen's resolution, so text stays crisp
+// at any scale.
+func drawText(dst *ebiten.Image, txt string, size, x, y float64, clr color.Color) {
+	face := textFace(size * displayScale)
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(x*displayScale, y*displayScale)
+	op.ColorScale.ScaleWithColor(clr)
+	text.Draw(dst, txt, face, op)
+}
+
+// measureText returns the logical width of txt at a logical font size
+func measureText(txt string, size float64) float64 {
+	w, _ := text.Measure(txt, textFace(size), size)
+	return w
+}
@@ -0,0 +1,51 @@
+package main
+
+import "github.com/obliviousorion/go-basics/pkg/snake"
+
+// The world reports what happens during a run as events (see
+// snake.Event), and handleEvents publishes them on the game's event bus
+// along with the front end's own. The features that react to them, like
+// the sounds, particle bursts and screen shake, subscribe to the kinds
+// they care about, so a new reaction is a new subscription rather than
+// another case wired into the game loop.
+
+// Front-end events, numbered after the world's
+const (
+	// EventRunFinished is published when a run reaches its variant's goal
+	// (Player is -1), which includes clearing the level: that is reported
+	// first, by the world, as snake.EventLevelCompleted
+	EventRunFinished = snake.EventKindCount + iota
+
+	eventKindCount // keep last: number of kinds, the world's included
+)
+
+// EventBus passes events to the handlers subscribed to their kind
+// Handlers run in the order they subscribed, as soon as an event is
+// published.
+type EventBus struct {
+	handlers [eventKindCount][]func(snake.Event)
+}
+
+// subscribe calls handle for every event of the given kinds
+func (b *EventBus) subscribe(handle func(snake.Event), kinds ...snake.EventKind) {
+	for _, k := range kinds {
+		b.handlers[k] = append(b.handlers[k], handle)
+	}
+}
+
+// publish hands e to the handlers subscribed to its kind
+func (b *EventBus) publish(e snake.Event) {
+	if e.Kind < 0 || e.Kind >= eventKindCount {
+		return
+	}
+	for _, handle := range b.handlers[e.Kind] {
+		handle(e)
+	}
+}
+
+// subscribeEffects hooks the game's reactions up to the event bus
+func (g *Game) subscribeEffects() {
+	g.subscribeSounds()
+	g.subscribeBursts()
+	g.subscribeShakes()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// maxCellSize is the largest cell size accepted by -grid; anything bigger
+// leaves too few cells to play on
+const maxCellSize = baseScreenWidth / minLevelCells
+
+// launchOptions are the command-line flags
+// They override the settings file for this session only and are never
+// saved back to it.
+type launchOptions struct {
+	// cellSize is the size of a grid cell in pixels (0 = use the
+	// board size from the settings)
+	cellSize int
+
+	// board is the board size in cells (zero = use the settings); it
+	// wins over cellSize
+	board GridSize
+
+	// tps is the snake's starting speed in moves per second
+	// (0 = use the speed from the settings)
+	tps float64
+
+	// seed fixes the seed of every run and of generated levels (only used
+	// when seedSet is true)
+	seed    uint64
+	seedSet bool
+
+	fullscreen bool
+
+	// demo starts the demo instead of showing the title screen
+	demo bool
+
+	// tas starts a TAS run, and replay a TAS run playing back the replay
+	// file at that path (see tas.go)
+	tas    bool
+	replay string
+
+	// pprof is the address to serve pprof on, turning the profiler on
+	// (see profiling.go)
+	pprof string
+}
+
+// parseFlags reads the command line, e.g.
+//
+//	go-snake-2d -grid 32 -tps 10 -seed 42 -fullscreen
+//	go-snake-2d -board 50x30
+//	go-snake-2d -demo -board 100x60 -tps 60
+//	go-snake-2d -replay snake-20240101-120000.replay.json
+//	go-snake-2d -pprof localhost:6060
+func parseFlags(args []string) (launchOptions, error) {
+	var opts launchOptions
+	fs := flag.NewFlagSet("go-snake-2d", flag.ContinueOnError)
+	fs.IntVar(&opts.cellSize, "grid", 0, fmt.Sprintf("cell size in pixels, %d-%d (default: board size from settings)", minCellSize, maxCellSize))
+	fs.Func("board", "board size in cells, e.g. 50x30 (default: board size from settings)", func(s string) error {
+		if _, err := fmt.Sscanf(s, "%dx%d", &opts.board.Width, &opts.board.Height); err != nil {
+			return fmt.Errorf("want WIDTHxHEIGHT: %w", err)
+		}
+		return opts.board.validate()
+	})
+	fs.Float64Var(&opts.tps, "tps", 0, "snake moves per second at the start of a run (default: set by the difficulty)")
+	fs.Uint64Var(&opts.seed, "seed", 0, "seed for food, power-ups and generated levels, so a game can be replayed (default: random)")
+	fs.BoolVar(&opts.fullscreen, "fullscreen", false, "start in fullscreen mode")
+	fs.BoolVar(&opts.demo, "demo", false, "start the demo, a bot filling the board, instead of the title screen")
+	fs.BoolVar(&opts.tas, "tas", false, "start a TAS run, with frame advance, savestates and replay export, instead of the title screen")
+	fs.StringVar(&opts.replay, "replay", "", "play back a replay file in a TAS run")
+	fs.StringVar(&opts.pprof, "pprof", "", "serve pprof on this address, e.g. localhost:6060, and log frame timings")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			opts.seedSet = true
+		}
+	})
+
+	if opts.cellSize != 0 && (opts.cellSize < minCellSize || opts.cellSize > maxCellSize) {
+		return opts, fmt.Errorf("-grid %d: must be between %d and %d", opts.cellSize, minCellSize, maxCellSize)
+	}
+	if opts.tps < 0 {
+		return opts, fmt.Errorf("-tps %g: must be positive", opts.tps)
+	}
+	return opts, nil
+}
+
+// applyLaunchOptions layers the command-line overrides on top of the
+// settings. Call it after applySettings. The -seed flag is read when runs
+// are seeded (see seed.go).
+func (g *Game) applyLaunchOptions(opts launchOptions) {
+	g.launch = opts
+	g.applySpeedOverride()
+}
+
+// applySpeedOverride replaces the starting speed with -tps, keeping the
+// rest of the curve. applySettings resets the curve, so this runs again
+// whenever the settings change.
+// The developer console's speed command wins over -tps, fixing the speed
+// for the whole run.
+func (g *Game) applySpeedOverride() {
+	if s := g.console.speed; s > 0 {
+		g.difficulty.StartRate, g.difficulty.MaxRate, g.difficulty.RatePerSegment = s, s, 0
+		return
+	}
+	if g.launch.tps <= 0 {
+		return
+	}
+	g.difficulty.StartRate = g.launch.tps
+	g.difficulty.MaxRate = max(g.difficulty.MaxRate, g.launch.tps)
+}
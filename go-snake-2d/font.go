@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+// FontSettings restyle the game's text
+// They are only set by editing the settings file, e.g.
+// "font": {"path": "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf", "scale": 1.1}
+type FontSettings struct {
+	// Path is a TTF or OTF file (or a collection, whose first font is
+	// used) to draw text with instead of the built-in M+ font, which
+	// still fills in any characters it doesn't have. A file that can't be
+	// loaded is reported and the built-in font is used.
+	Path string `json:"path,omitempty"`
+
+	// Scale multiplies every text size (0 = 1), for fonts that run
+	// bigger or smaller than M+, or just bigger text
+	Scale float64 `json:"scale,omitempty"`
+}
+
+// The range Scale is kept in, so text still fits the screens it is laid
+// out on
+const (
+	minFontScale = 0.5
+	maxFontScale = 1.5
+)
+
+// validate checks the scale is in range
+func (f FontSettings) validate() error {
+	if f.Scale != 0 && (f.Scale < minFontScale || f.Scale > maxFontScale) {
+		return fmt.Errorf("scale %g out of range %g-%g", f.Scale, minFontScale, maxFontScale)
+	}
+	return nil
+}
+
+// mplusFontName is the built-in font asset
+const mplusFontName = "fonts/mplus-1p-regular.ttf"
+
+var (
+	// mplusFaceSource is the built-in font
+	mplusFaceSource *text.GoTextFaceSource
+
+	// customFaceSource is the font from FontSettings.Path, loaded from
+	// customFontPath (nil when there is none or it failed to load)
+	customFaceSource *text.GoTextFaceSource
+	customFontPath   string
+
+	// fontScale is FontSettings.Scale, with 0 replaced by 1
+	fontScale = 1.0
+)
+
+// setFont applies the font settings, loading the font file when its path
+// has changed
+func setFont(f FontSettings) {
+	fontScale = cmp.Or(f.Scale, 1)
+	if f.Path == customFontPath {
+		return
+	}
+	customFontPath, customFaceSource = f.Path, nil
+	if f.Path == "" {
+		return
+	}
+	src, err := loadFontFile(f.Path)
+	if err != nil {
+		log.Printf("using the built-in font: %v", err)
+		return
+	}
+	customFaceSource = src
+}
+
+// loadFontFile loads a TTF, OTF or font collection file
+// A collection holds several faces; the first is the regular one.
+func loadFontFile(path string) (*text.GoTextFaceSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading font: %w", err)
+	}
+	faces, err := text.NewGoTextFaceSourcesFromCollection(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("loading font %s: %w", path, err)
+	}
+	if len(faces) == 0 {
+		return nil, fmt.Errorf("loading font %s: no faces in the file", path)
+	}
+	return faces[0], nil
+}
+
+// textFace returns the face to draw text with at size (in pixels, before
+// the font scale): the custom font if there is one, then the built-in
+// font and the language's fallback fonts for characters it doesn't have
+func textFace(size float64) text.Face {
+	size *= fontScale
+	var sources []*text.GoTextFaceSource
+	if customFaceSource != nil {
+		sources = append(sources, customFaceSource)
+	}
+	sources = append(sources, mplusFaceSource)
+	if lang != nil {
+		sources = append(sources, lang.fallbacks...)
+	}
+	if len(sources) == 1 {
+		return &text.GoTextFace{Source: sources[0], Size: size}
+	}
+
+	faces := make([]text.Face, len(sources))
+	for i, src := range sources {
+		faces[i] = &text.GoTextFace{Source: src, Size: size}
+	}
+	multi, err := text.NewMultiFace(faces...)
+	if err != nil {
+		return faces[0]
+	}
+	return multi
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// maxHighScores is how many entries the high-score table keeps
+	maxHighScores = 10
+
+	// appConfigDirName is the folder created under the user config dir
+	// (e.g. ~/.config on Linux) to hold the game's saved files
+	appConfigDirName = "go-snake-2d"
+
+	highScoresFileName = "highscores.json"
+)
+
+// HighScore is a single entry in the high-score table
+type HighScore struct {
+	// Name is the player's initials, entered arcade-style after the run
+	Name string `json:"name,omitempty"`
+
+	Score  int `json:"score"`
+	Length int `json:"length"`
+
+	// Challenge is the date of the daily challenge the run was for (see
+	// daily.go)
+	Challenge string `json:"challenge,omitempty"`
+
+	// Time is how long the run took, to the millisecond (only recorded
+	// for variants ranked by time)
+	Time time.Duration `json:"time,omitempty"`
+
+	Date time.Time `json:"date"`
+}
+
+// HighScoreTable holds one table of best runs per variant and difficulty,
+// best first, and knows where on disk it is persisted
+// The Normal tables keep the layout of files from before difficulties
+// existed, whose runs were all played at Normal.
+type HighScoreTable struct {
+	// Entries is the Classic table, kept under its original name so
+	// files from before variants existed still load
+	Entries []HighScore `json:"entries"`
+
+	// Variants holds the tables of the other variants
+	Variants map[Variant][]HighScore `json:"variants,omitempty"`
+
+	// Difficulties holds the tables of the other difficulties, by variant
+	Difficulties map[Difficulty]map[Variant][]HighScore `json:"difficulties,omitempty"`
+
+	// path is the JSON file the table is loaded from and saved to
+	path string
+}
+
+// highScoresPath returns the high-score file location of a profile
+func highScoresPath(profile string) (string, error) {
+	dir, err := profileDir(profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, highScoresFileName), nil
+}
+
+// loadHighScores reads the table from path
+// A missing file is not an error: on first run we simply start empty
+func loadHighScores(path string) (*HighScoreTable, error) {
+	table := &HighScoreTable{path: path}
+
+	data, err := readDataFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return table, nil
+	}
+	if err != nil {
+		return table, fmt.Errorf("reading high scores: %w", err)
+	}
+
+	if err := json.Unmarshal(data, table); err != nil {
+		// Keep going with an empty table rather than refusing to start
+		table.Entries, table.Variants, table.Difficulties = nil, nil, nil
+		return table, fmt.Errorf("parsing high scores %s: %w", path, err)
+	}
+	for d := range difficultyCount {
+		for v := range variantCount {
+			table.setTable(v, d, sortAndTrim(v, table.Table(v, d)))
+		}
+	}
+	return table, nil
+}
+
+// Save writes the table to disk, creating the config folder if needed
+func (t *HighScoreTable) Save() error {
+	if t.path == "" {
+		return errors.New("high score table has no file path")
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding high scores: %w", err)
+	}
+	if err := writeDataFile(t.path, data); err != nil {
+		return fmt.Errorf("writing high scores: %w", err)
+	}
+	return nil
+}
+
+// Table returns the table of the variant at difficulty d, best first
+// The daily challenge is always played at Normal, so it has just the one
+// table whatever d is.
+func (t *HighScoreTable) Table(v Variant, d Difficulty) []HighScore {
+	if v == VariantDaily {
+		d = DifficultyNormal
+	}
+	switch {
+	case d != DifficultyNormal:
+		return t.Difficulties[d][v]
+	case v == VariantClassic:
+		return t.Entries
+	}
+	return t.Variants[v]
+}
+
+// setTable replaces the table of the variant at difficulty d
+func (t *HighScoreTable) setTable(v Variant, d Difficulty, entries []HighScore) {
+	if v == VariantDaily {
+		d = DifficultyNormal
+	}
+	switch {
+	case d == DifficultyNormal && v == VariantClassic:
+		t.Entries = entries
+	case d == DifficultyNormal:
+		t.Variants = withTable(t.Variants, v, entries)
+	default:
+		tables := withTable(t.Difficulties[d], v, entries)
+		if len(tables) == 0 {
+			delete(t.Difficulties, d)
+			return
+		}
+		if t.Difficulties == nil {
+			t.Difficulties = make(map[Difficulty]map[Variant][]HighScore)
+		}
+		t.Difficulties[d] = tables
+	}
+}
+
+// withTable returns tables with the variant's table set to entries, or
+// removed if there are none
+func withTable(tables map[Variant][]HighScore, v Variant, entries []HighScore) map[Variant][]HighScore {
+	if len(entries) == 0 {
+		delete(tables, v)
+		return tables
+	}
+	if tables == nil {
+		tables = make(map[Variant][]HighScore)
+	}
+	tables[v] = entries
+	return tables
+}
+
+// Add inserts an entry into the table of the variant at difficulty d if
+// it makes the cut
+// Returns the 0-based rank of the new entry, or -1 if it didn't qualify
+// The Daily table holds one best per challenge date, newest first: a new
+// best for a date replaces the old one.
+func (t *HighScoreTable) Add(v Variant, d Difficulty, entry HighScore) int {
+	if !t.Qualifies(v, d, entry) {
+		return -1
+	}
+	entries := t.Table(v, d)
+	if v == VariantDaily {
+		if i := dailyIndex(entries, entry.Challenge); i >= 0 {
+			entries = append(entries[:i:i], entries[i+1:]...)
+		}
+	}
+	entries = sortAndTrim(v, append(entries, entry))
+	t.setTable(v, d, entries)
+
+	for i, e := range entries {
+		if e == entry {
+			return i
+		}
+	}
+	return -1
+}
+
+// Qualifies reports whether a run is good enough to enter the table of
+// the variant at difficulty d
+// Zero scores are never recorded, nor are runs without a time in tables
+// ranked by time
+func (t *HighScoreTable) Qualifies(v Variant, d Difficulty, entry HighScore) bool {
+	if v.ranksByTime() && entry.Time <= 0 || !v.ranksByTime() && entry.Score <= 0 {
+		return false
+	}
+	entries := t.Table(v, d)
+	if v == VariantDaily {
+		i := dailyIndex(entries, entry.Challenge)
+		return entry.Challenge != "" && (i < 0 || better(v, entry, entries[i]))
+	}
+	if len(entries) < maxHighScores {
+		return true
+	}
+	return better(v, entry, entries[len(entries)-1])
+}
+
+// dailyIndex returns the index of the date's best in the Daily table, or
+// -1 if there is none
+func dailyIndex(entries []HighScore, date string) int {
+	for i, e := range entries {
+		if e.Challenge == date {
+			return i
+		}
+	}
+	return -1
+}
+
+// better reports whether run a ranks above run b in the variant's table
+func better(v Variant, a, b HighScore) bool {
+	if v.ranksByTime() {
+		return a.Time < b.Time
+	}
+	return a.Score > b.Score
+}
+
+// sortAndTrim orders entries best-first and drops anything past the limit
+// Ties keep their original order so older runs stay ahead. The Daily
+// table is ordered by date instead, newest first.
+func sortAndTrim(v Variant, entries []HighScore) []HighScore {
+	limit := maxHighScores
+	if v == VariantDaily {
+		limit = maxDailyRecords
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Challenge > entries[j].Challenge
+		})
+	} else {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return better(v, entries[i], entries[j])
+		})
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Route hints show each keyboard player a safe way to the nearest food,
+// worked out once per tick by the hard bot's search (see snake.Bot.FoodPath):
+// a trail of dots along the route, and an arrow in front of the snake's
+// head showing which way to turn.
+
+// hintBot finds the routes: it looks over the whole board, avoids other
+// snakes' heads and refuses routes into pockets too small for the snake
+var hintBot = botLevels[BotHard]
+
+// findHints works out the current board's routes, with Settings.Hints
+// on; players it has no safe route for get none
+func (g *Game) findHints() {
+	g.hints = g.hints[:0]
+	if !g.settings.Hints {
+		return
+	}
+	for i, p := range g.world.Players {
+		var path snake.Path
+		if _, ok := p.Controller.(*KeyboardController); ok && !p.Dead {
+			path, _ = hintBot.FoodPath(g.world.State(i))
+		}
+		g.hints = append(g.hints, path)
+	}
+}
+
+// drawHint draws player i's route: a dot in every cell along it and an
+// arrow in the cell the snake moves into next
+func (g *Game) drawHint(screen *ebiten.Image, i int, path snake.Path) {
+	v, cell := g.view, g.view.cell
+	clr := faded(g.playerColor(i), 0.5)
+	for _, c := range path.Cells[1:] {
+		cx, cy := v.center(c)
+		fillCircle(screen, cx, cy, cell/8, clr, true)
+	}
+
+	// The arrow sits next to the head rather than on the route's first
+	// cell, which is elsewhere if the route starts through a portal
+	move := path.Moves[0]
+	cx, cy := v.center(g.world.Players[i].Head().Add(move))
+	dx, dy := float32(move.X), float32(move.Y)
+	r, w := cell*0.35, max(cell/8, 1)
+	tipX, tipY := cx+dx*r, cy+dy*r
+	// Back from the tip, and out to either side
+	for _, side := range [...]float32{-1, 1} {
+		bx := cx - dx*r*0.4 - dy*r*side
+		by := cy - dy*r*0.4 + dx*r*side
+		strokeLine(screen, tipX, tipY, bx, by, w, clr, true)
+	}
+}
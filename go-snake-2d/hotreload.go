@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// HOT RELOAD
+//
+// The settings file is watched while the game runs, so colors, speed
+// curves, key bindings and the rest can be tuned by editing it, the
+// changes showing up within a second. A file that doesn't parse or has
+// out-of-range values is rejected whole: the game keeps running on the
+// settings it had, and says what is wrong, until the file is fixed.
+// The browser keeps its settings where they can't be edited, so it has
+// nothing to watch.
+
+// reloadInterval is how often the settings file is checked for changes
+const reloadInterval = time.Second
+
+// settingsWatch tracks the settings file, to notice when it is edited
+type settingsWatch struct {
+	// modTime and size are the file's as last seen
+	modTime time.Time
+	size    int64
+
+	// nextCheck is when to look at the file again
+	nextCheck time.Time
+}
+
+// watchSettings starts watching the settings file from how it is now,
+// after the settings have been loaded from it
+func (g *Game) watchSettings() {
+	g.watch = settingsWatch{}
+	if info, err := os.Stat(g.settings.path); err == nil {
+		g.watch.modTime, g.watch.size = info.ModTime(), info.Size()
+	}
+}
+
+// checkSettingsFile reloads the settings if their file has changed since
+// it was last looked at
+func (g *Game) checkSettingsFile() {
+	now := g.clock.Now()
+	if isWeb || g.settings.path == "" || now.Before(g.watch.nextCheck) {
+		return
+	}
+	g.watch.nextCheck = now.Add(reloadInterval)
+
+	info, err := os.Stat(g.settings.path)
+	if err != nil || (info.ModTime().Equal(g.watch.modTime) && info.Size() == g.watch.size) {
+		return
+	}
+	g.watch.modTime, g.watch.size = info.ModTime(), info.Size()
+
+	s, err := g.reloadSettings()
+	switch {
+	case err != nil:
+		log.Printf("settings not reloaded: %v", err)
+		g.notify(tr("Settings file has errors; keeping the old settings"))
+	case s != nil:
+		g.settings = *s
+		g.applySettings()
+		g.notify(tr("Settings reloaded"))
+	}
+}
+
+// reloadSettings reads the settings file again, returning the settings in
+// it, or nil if they are the ones the game already has (the game saving
+// them also changes the file)
+// Unlike loadSettings, a bad file gives an error and no settings at all,
+// rather than the defaults.
+func (g *Game) reloadSettings() (*Settings, error) {
+	path := g.settings.path
+	data, err := readDataFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading settings: %w", err)
+	}
+	if saved, err := json.MarshalIndent(g.settings, "", "  "); err == nil && bytes.Equal(bytes.TrimSpace(data), saved) {
+		return nil, nil
+	}
+
+	s := defaultSettings()
+	s.path = path
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing settings %s: %w", path, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("settings %s: %w", path, err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+// The game's text is written in English in the code and translated when
+// it is shown: tr looks each string up in the message catalog of the
+// selected language, keyed by the English text itself. Anything missing
+// from a catalog (or a custom level's name, which no catalog knows)
+// simply stays in English, so a partial translation is fine.
+
+// langFiles are the embedded language files, one per language, named
+// after its code (e.g. lang/es.json)
+//
+//go:embed lang/*.json
+var langFiles embed.FS
+
+// defaultLanguage is the code of the language the game is written in
+const defaultLanguage = "en"
+
+// Language is a translation of the game's text, loaded from a language
+// file
+type Language struct {
+	// Code is the file name without its extension, e.g. "es"; the
+	// settings file stores the language by it
+	Code string `json:"-"`
+
+	// Name is the language's name in the language itself, as listed in
+	// the options
+	Name string `json:"name"`
+
+	// Fonts are font files (TTF, OTF or collections) to fall back on for
+	// characters the built-in font doesn't have, e.g. Hangul. Each is
+	// tried in turn, so a list can name the usual system font on every
+	// platform; files that are missing are skipped.
+	Fonts []string `json:"fonts,omitempty"`
+
+	// Messages maps the English text to its translation
+	Messages map[string]string `json:"messages"`
+
+	// fallbacks are the faces loaded from Fonts, once the language is
+	// first used
+	fallbacks []*text.GoTextFaceSource
+	loaded    bool
+}
+
+var (
+	// languages are the available languages, sorted by code
+	languages []*Language
+
+	// lang is the language the game is shown in
+	lang *Language
+)
+
+// loadLanguages reads the embedded language files
+// They are part of the build, so a broken one is a bug, not something to
+// recover from.
+func loadLanguages() ([]*Language, error) {
+	names, err := fs.Glob(langFiles, "lang/*.json")
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+
+	var langs []*Language
+	for _, name := range names {
+		data, err := langFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		l := &Language{Code: strings.TrimSuffix(path.Base(name), ".json")}
+		if err := json.Unmarshal(data, l); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		langs = append(langs, l)
+	}
+	return langs, nil
+}
+
+// findLanguage returns the index in languages of the language with the
+// given code, or of the default language if there is none
+func findLanguage(code string) int {
+	if i := slices.IndexFunc(languages, func(l *Language) bool { return l.Code == code }); i >= 0 {
+		return i
+	}
+	return max(slices.IndexFunc(languages, func(l *Language) bool { return l.Code == defaultLanguage }), 0)
+}
+
+// setLanguage switches the game's text to the language with the given
+// code, loading its fallback fonts the first time
+func setLanguage(code string) {
+	if len(languages) == 0 {
+		return
+	}
+	lang = languages[findLanguage(code)]
+	if !lang.loaded {
+		lang.loaded = true
+		lang.fallbacks = loadFallbackFonts(lang.Fonts)
+	}
+}
+
+// loadFallbackFonts loads the font files that exist out of paths
+// Missing files are skipped quietly, since most of the list is for other
+// platforms; the ones that are there but broken are reported.
+func loadFallbackFonts(paths []string) []*text.GoTextFaceSource {
+	if isWeb {
+		// The browser can't read files off the disk
+		return nil
+	}
+	var sources []*text.GoTextFaceSource
+	for _, p := range paths {
+		src, err := loadFontFile(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+// tr returns the translation of an English string into the selected
+// language, or the string itself if the language doesn't have it
+func tr(s string) string {
+	if lang == nil {
+		return s
+	}
+	if t, ok := lang.Messages[s]; ok && t != "" {
+		return t
+	}
+	return s
+}
+
+// trf translates an English format string and fills it in like
+// fmt.Sprintf
+func trf(format string, args ...any) string {
+	return fmt.Sprintf(tr(format), args...)
+}
+
+// label returns the translated display name of an option value, like a
+// difficulty or variant
+func label(v fmt.Stringer) string {
+	return tr(v.String())
+}
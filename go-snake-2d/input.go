@@ -0,0 +1,100 @@
+package main
+
+import "github.com/hajimehoshi/ebiten/v2/inpututil"
+
+// Key repeat timing for held keys, in updates at baseTPS (see timing.go):
+// the first repeat comes after repeatDelay, then one every repeatInterval
+const (
+	repeatDelay    = 24
+	repeatInterval = 4
+)
+
+// Input is the state of every action this frame, from the keyboard and
+// the touch screen combined. It is updated once per frame before the
+// scene runs, and answers three questions about an action:
+//   - pressed: is it held down right now? (steering)
+//   - just pressed: did it go down this frame? (toggles, confirming)
+//   - repeated: was it just pressed, or held long enough to repeat?
+//     (moving through menus and stepping settings)
+//
+// Actions still held when the scene changes are ignored until they are
+// released, so the key press that ended one screen never also acts on
+// the next one: holding Space on the game over screen restarts once,
+// and the new run doesn't see Space at all.
+type Input struct {
+	pressed, justPressed, repeated [actionCount]bool
+
+	// stale marks actions held through a scene change
+	stale [actionCount]bool
+}
+
+// update reads this frame's keys and touches
+func (in *Input) update(keys KeyBindings, touch *TouchInput) {
+	delay, interval := updates(repeatDelay), updates(repeatInterval)
+	for a := range actionCount {
+		ticks := keys.pressDuration(a)
+		down := ticks > 0 || touch.isPressed(a)
+		if in.stale[a] {
+			if down {
+				in.pressed[a], in.justPressed[a], in.repeated[a] = false, false, false
+				continue
+			}
+			in.stale[a] = false
+		}
+
+		in.pressed[a] = down
+		in.justPressed[a] = ticks == 1 || touch.isJustPressed(a)
+		in.repeated[a] = in.justPressed[a] ||
+			ticks >= delay && (ticks-delay)%interval == 0
+	}
+}
+
+// ignoreHeld makes every action that is held right now count as
+// released until it really is released
+func (in *Input) ignoreHeld() {
+	for a := range actionCount {
+		in.stale[a] = in.pressed[a] || in.justPressed[a]
+		in.pressed[a], in.justPressed[a], in.repeated[a] = false, false, false
+	}
+}
+
+// pressDuration returns how many ticks the action's keys have been held
+// (the longest-held one if several are down), or 0 if none is down
+func (b KeyBindings) pressDuration(action Action) int {
+	ticks := 0
+	for _, k := range b[action] {
+		ticks = max(ticks, inpututil.KeyPressDuration(k))
+	}
+	return ticks
+}
+
+// actionInput reports actions from every input source, so menus work
+// the same with keys and touch
+type actionInput interface {
+	isJustPressed(action Action) bool
+	isRepeated(action Action) bool
+	tap() (x, y int, ok bool)
+}
+
+// isPressed reports whether the action is held on the keyboard or
+// triggered by a touch gesture or on-screen button
+func (g *Game) isPressed(action Action) bool {
+	return g.input.pressed[action]
+}
+
+// isJustPressed reports whether the action was triggered this frame by a
+// key or by touch. Use this for toggles so holding a key doesn't repeat.
+func (g *Game) isJustPressed(action Action) bool {
+	return g.input.justPressed[action]
+}
+
+// isRepeated reports whether the action was just pressed, or has been
+// held long enough to repeat this frame, like a held key in a text box
+func (g *Game) isRepeated(action Action) bool {
+	return g.input.repeated[action]
+}
+
+// tap returns where the screen was tapped this frame, if it was
+func (g *Game) tap() (x, y int, ok bool) {
+	return g.touch.tap()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Layer is an offscreen image that part of the picture is drawn into and
+// then copied onto the screen through a transform, like a mirror
+// The code drawing into it keeps drawing at the same positions, in board
+// (view) coordinates, and never knows about the transform; the game's
+// logic, and its coordinates, are untouched by it too.
+type Layer struct {
+	// frame is the offscreen image (created on first use, and again when
+	// the screen grows), and part the piece of it in use
+	frame *ebiten.Image
+	part  *ebiten.Image
+}
+
+// begin returns the layer's image for the same part of the screen as
+// screen, which may be a sub-image for one viewport, cleared to bg, to
+// draw into
+func (l *Layer) begin(screen *ebiten.Image, bg color.Color) *ebiten.Image {
+	// The frame covers the screen up to screen's corner at least, so the
+	// part has the same coordinates as screen. The screen changes size
+	// with the window (see Layout).
+	size := screen.Bounds().Max
+	if l.frame == nil || size.X > l.frame.Bounds().Dx() || size.Y > l.frame.Bounds().Dy() {
+		if l.frame != nil {
+			l.frame.Deallocate()
+		}
+		l.frame = ebiten.NewImage(size.X, size.Y)
+	}
+	l.part = l.frame.SubImage(screen.Bounds()).(*ebiten.Image)
+	l.part.Fill(bg)
+	return l.part
+}
+
+// end draws what was drawn into the layer onto screen, through geo,
+// which places the part's top-left corner
+func (l *Layer) end(screen *ebiten.Image, geo ebiten.GeoM) {
+	op := &ebiten.DrawImageOptions{GeoM: geo}
+	screen.DrawImage(l.part, op)
+}
+
+// mirrorX returns the transform that draws an image the size of r over r,
+// flipped left to right
+func mirrorX(r image.Rectangle) ebiten.GeoM {
+	var geo ebiten.GeoM
+	geo.Scale(-1, 1)
+	geo.Translate(float64(r.Max.X), float64(r.Min.Y))
+	return geo
+}
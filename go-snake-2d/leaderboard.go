@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	// initialsLength is how many letters a high-score name has
+	initialsLength = 3
+
+	// leaderboardRows is how many entries the leaderboard shows at once;
+	// the rest are reached by scrolling
+	leaderboardRows = 7
+)
+
+// resultsScene returns the scene that follows a run: the name prompt if
+// a solo run made its table, otherwise the game over screen
+func (g *Game) resultsScene() Scene {
+	if g.mode == ModeSolo && g.lastRank >= 0 {
+		return newNameEntryScene(g)
+	}
+	return newGameOverScene(g)
+}
+
+// NameEntryScene asks for the initials of a new high score, arcade style:
+// up/down change the letter under the cursor and left/right move it.
+// Letters can also be typed straight in. Keys are read directly, like
+// TextEntryScene, so that W and S type letters rather than steer.
+type NameEntryScene struct {
+	g       *Game
+	letters [initialsLength]byte
+	cursor  int
+
+	// chars is reused between frames to collect typed characters
+	chars []rune
+}
+
+// newNameEntryScene creates the prompt, filled in with the initials
+// entered last time (or the profile name's)
+func newNameEntryScene(g *Game) *NameEntryScene {
+	s := &NameEntryScene{g: g}
+	prev := []byte(strings.ToUpper(g.settings.Initials))
+	if len(prev) == 0 {
+		prev = []byte(strings.ToUpper(g.profile))
+	}
+	for i := range s.letters {
+		s.letters[i] = 'A'
+		if i < len(prev) && prev[i] >= 'A' && prev[i] <= 'Z' {
+			s.letters[i] = prev[i]
+		}
+	}
+	return s
+}
+
+// Update edits the initials until the last letter is confirmed
+// Enter moves to the next letter, or confirms on the last one; Backspace
+// and Escape step back.
+func (s *NameEntryScene) Update() error {
+	g := s.g
+	g.updateSystems()
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter):
+		if s.cursor == initialsLength-1 {
+			s.confirm()
+			return nil
+		}
+		s.move(1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace) || inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		s.move(-1)
+	case keyRepeated(ebiten.KeyArrowLeft):
+		s.move(-1)
+	case keyRepeated(ebiten.KeyArrowRight):
+		s.move(1)
+	case keyRepeated(ebiten.KeyArrowUp):
+		s.step(1)
+	case keyRepeated(ebiten.KeyArrowDown):
+		s.step(-1)
+	}
+
+	s.chars = ebiten.AppendInputChars(s.chars[:0])
+	for _, r := range s.chars {
+		r = unicode.ToUpper(r)
+		if r < 'A' || r > 'Z' {
+			continue
+		}
+		s.letters[s.cursor] = byte(r)
+		g.audio.play(SoundMenuMove)
+		if s.cursor < initialsLength-1 {
+			s.cursor++
+		}
+	}
+	return nil
+}
+
+// keyRepeated reports whether a key was just pressed or, while held,
+// should repeat this frame
+func keyRepeated(k ebiten.Key) bool {
+	return inpututil.IsKeyJustPressed(k) || repeatingKey(k)
+}
+
+// move shifts the cursor by delta, stopping at either end
+func (s *NameEntryScene) move(delta int) {
+	s.cursor = min(max(s.cursor+delta, 0), initialsLength-1)
+	s.g.audio.play(SoundMenuMove)
+}
+
+// step changes the letter under the cursor by delta, wrapping Z to A
+func (s *NameEntryScene) step(delta int) {
+	s.letters[s.cursor] = 'A' + byte(cycle(int(s.letters[s.cursor]-'A'), delta, 26))
+	s.g.audio.play(SoundMenuMove)
+}
+
+// confirm names the new entry, saves the table and the initials, and
+// moves on to the game over screen
+func (s *NameEntryScene) confirm() {
+	g := s.g
+	name := string(s.letters[:])
+	entries := g.highScores.Table(g.variant, g.runDifficulty)
+	if g.lastRank < len(entries) {
+		entries[g.lastRank].Name = name
+		if err := g.highScores.Save(); err != nil {
+			log.Printf("saving high scores: %v", err)
+		}
+	}
+
+	g.settings.Initials = name
+	if err := g.settings.Save(); err != nil {
+		log.Printf("saving settings: %v", err)
+	}
+	g.audio.play(SoundMenuSelect)
+	g.scenes.Switch(newGameOverScene(g))
+}
+
+// Draw renders the letters over the final board, with the one under the
+// cursor highlighted
+func (s *NameEntryScene) Draw(screen *ebiten.Image) {
+	g := s.g
+	g.drawBoard(screen, g.lastUpdate, false)
+	fillRect(screen, 0, 0, float32(screenWidth), screenHeight, color.RGBA{0, 0, 0, 160}, false)
+
+	drawCenteredText(screen, tr("New High Score!"), 48, 60, menuSelectedColor)
+	placing := fmt.Sprintf("%s: #%d", label(g.variant), g.lastRank+1)
+	if g.variant == VariantDaily {
+		placing = trf("Best of the daily challenge %s", g.daily)
+	}
+	drawCenteredText(screen, placing, 22, 130, color.White)
+	drawCenteredText(screen, tr("Enter your initials"), 22, 180, menuTextColor)
+
+	// LETTERS
+	const size, spacing = 48.0, 56.0
+	x := float64(screenWidth)/2 - spacing*initialsLength/2
+	for i, l := range s.letters {
+		clr := color.Color(menuTextColor)
+		if i == s.cursor {
+			clr = menuSelectedColor
+			fillRect(screen, float32(x+8), 300, spacing-16, 4, menuSelectedColor, false)
+		}
+		txt := string(rune(l))
+		drawText(screen, txt, size, x+spacing/2-measureText(txt, size)/2, 236, clr)
+		x += spacing
+	}
+
+	drawCenteredText(screen, tr("Type or use the arrow keys, ENTER to confirm"), 16, screenHeight-40, menuTextColor)
+}
+
+// LeaderboardScene shows the persisted high-score tables, one variant and
+// difficulty at a time
+// Left/right switch the table and up/down scroll it.
+type LeaderboardScene struct {
+	g          *Game
+	variant    Variant
+	difficulty Difficulty
+
+	// scroll is the index of the first entry shown
+	scroll int
+}
+
+// newLeaderboardScene opens the leaderboard on the variant and difficulty
+// selected for play
+func newLeaderboardScene(g *Game) *LeaderboardScene {
+	s := &LeaderboardScene{g: g, variant: g.settings.Variant, difficulty: g.settings.Difficulty}
+	if s.variant == VariantDaily {
+		s.difficulty = DifficultyNormal
+	}
+	return s
+}
+
+// step moves delta tables on: through every difficulty of a variant,
+// then on to the next variant. The daily challenge only has its Normal
+// table.
+func (s *LeaderboardScene) step(delta int) {
+	n := int(difficultyCount)
+	for {
+		i := cycle(int(s.variant)*n+int(s.difficulty), delta, int(variantCount)*n)
+		s.variant, s.difficulty = Variant(i/n), Difficulty(i%n)
+		if s.variant != VariantDaily || s.difficulty == DifficultyNormal {
+			return
+		}
+	}
+}
+
+// Update switches tables, scrolls, and goes back to the title screen on
+// Escape or Enter
+func (s *LeaderboardScene) Update() error {
+	g := s.g
+	if g.isJustPressed(ActionBack) || g.isJustPressed(ActionSelect) {
+		g.scenes.Switch(newTitleScene(g))
+		return nil
+	}
+
+	delta := 0
+	if g.isRepeated(ActionMoveRight) || g.isRepeated(ActionP2MoveRight) {
+		delta = 1
+	}
+	if g.isRepeated(ActionMoveLeft) || g.isRepeated(ActionP2MoveLeft) {
+		delta = -1
+	}
+	if delta != 0 {
+		s.step(delta)
+		s.scroll = 0
+		g.audio.play(SoundMenuMove)
+	}
+
+	last := max(len(s.entries())-leaderboardRows, 0)
+	prev := s.scroll
+	if g.isRepeated(ActionMoveUp) || g.isRepeated(ActionP2MoveUp) {
+		s.scroll = max(s.scroll-1, 0)
+	}
+	if g.isRepeated(ActionMoveDown) || g.isRepeated(ActionP2MoveDown) {
+		s.scroll = min(s.scroll+1, last)
+	}
+	if s.scroll != prev {
+		g.audio.play(SoundMenuMove)
+	}
+	return nil
+}
+
+// entries returns the table being shown
+func (s *LeaderboardScene) entries() []HighScore {
+	if s.g.highScores == nil {
+		return nil
+	}
+	return s.g.highScores.Table(s.variant, s.difficulty)
+}
+
+// Draw renders the visible part of the table, with arrows when there is
+// more above or below
+func (s *LeaderboardScene) Draw(screen *ebiten.Image) {
+	drawCenteredText(screen, tr("Leaderboards"), 48, 40, color.White)
+	heading := fmt.Sprintf("< %s - %s >", label(s.variant), label(s.difficulty))
+	if s.variant == VariantDaily {
+		heading = fmt.Sprintf("< %s >", label(s.variant))
+	}
+	drawCenteredText(screen, heading, 26, 110, menuSelectedColor)
+
+	entries := s.entries()
+	y := 170.0
+	if len(entries) == 0 {
+		drawCenteredText(screen, tr("No scores yet"), 18, y, menuTextColor)
+	}
+	if s.scroll > 0 {
+		drawCenteredText(screen, "^", 18, y-24, menuTextColor)
+	}
+	end := min(s.scroll+leaderboardRows, len(entries))
+	for i := s.scroll; i < end; i++ {
+		drawCenteredText(screen, scoreLine(s.variant, i, entries[i]), 18, y, menuTextColor)
+		y += 28
+	}
+	if end < len(entries) {
+		drawCenteredText(screen, "v", 18, y, menuTextColor)
+	}
+
+	drawCenteredText(screen, tr("Left/Right to change table, Up/Down to scroll, ESC to go back"), 16, screenHeight-40, menuTextColor)
+}
+
+// scoreLine formats the i-th entry of the variant's table: rank, name,
+// then the time or score it is ranked by
+// Daily bests start with their challenge date instead of a rank. Entries
+// from before names were asked for show as "---".
+func scoreLine(v Variant, i int, e HighScore) string {
+	name := e.Name
+	if name == "" {
+		name = "---"
+	}
+	date := e.Date.Format(dateFormat)
+	switch {
+	case v == VariantDaily:
+		return trf("%s   %-3s  %5d   len %3d", e.Challenge, name, e.Score, e.Length)
+	case v.ranksByTime():
+		return fmt.Sprintf("%2d.  %-3s  %s   %5d   %s", i+1, name, formatRunTime(e.Time), e.Score, date)
+	default:
+		return trf("%2d.  %-3s  %5d   len %3d   %s", i+1, name, e.Score, e.Length, date)
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// levelsDirName is the folder (relative to the working directory) that
+// custom level files are loaded from
+const levelsDirName = "levels"
+
+// minLevelCells is the smallest width or height a level file may use
+const minLevelCells = 10
+
+// LEVEL FILE FORMAT
+//
+// Custom levels are JSON files with a .json extension, e.g.
+//
+//	{
+//	  "name": "Hourglass",
+//	  "width": 32,
+//	  "height": 24,
+//	  "walls": [
+//	    {"x": 4, "y": 3, "w": 24},
+//	    {"x": 15, "y": 4, "w": 2, "h": 6}
+//	  ],
+//	  "start": {"x": 6, "y": 12, "direction": "right"},
+//	  "start2": {"x": 25, "y": 11, "direction": "left"},
+//	  "foodSpawns": [{"x": 25, "y": 12}, {"x": 6, "y": 20}],
+//	  "portals": [{"a": {"x": 2, "y": 2}, "b": {"x": 29, "y": 21}}],
+//	  "checkpoints": [{"x": 20, "y": 12}],
+//	  "boss": {"x": 20, "y": 5, "size": 3, "health": 5}
+//	}
+//
+// - width/height set the board size in cells, up to maxGridWidth by
+//   maxGridHeight; boards too big for the screen scroll
+// - walls are rectangles; w and h default to 1 (a single cell)
+// - start is optional and defaults to the center of the board, moving right
+// - start2 is where the second snake starts in versus mode; it is optional
+//   and defaults to start mirrored through the center of the board
+// - foodSpawns is optional; when present, food only appears on those cells
+// - portals is optional; each links two free cells, and a snake entering
+//   one end comes out of the other
+// - checkpoints is optional; each is a free cell, reachable from the start,
+//   that saves a solo run when the snake gets there, so dying goes back to
+//   it instead of ending the run
+// - boss is optional and makes a boss level: x and y are the top left cell
+//   of a boss size cells square (defaultBossSize, up to maxBossSize), to be
+//   bitten health times (defaultBossHealth, up to maxBossHealth) to win; it
+//   must start on free cells, clear of the starts, spawns and checkpoints
+
+// levelFile mirrors the on-disk JSON layout of a custom level
+type levelFile struct {
+	Name        string            `json:"name"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	Walls       []levelFileRect   `json:"walls"`
+	Start       *levelFileStart   `json:"start"`
+	Start2      *levelFileStart   `json:"start2"`
+	FoodSpawns  []levelFileCell   `json:"foodSpawns"`
+	Portals     []levelFilePortal `json:"portals"`
+	Checkpoints []levelFileCell   `json:"checkpoints"`
+	Boss        *levelFileBoss    `json:"boss"`
+}
+
+type levelFileRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type levelFileStart struct {
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Direction string `json:"direction"`
+}
+
+type levelFileCell struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type levelFilePortal struct {
+	A levelFileCell `json:"a"`
+	B levelFileCell `json:"b"`
+}
+
+type levelFileBoss struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Size   int `json:"size"`
+	Health int `json:"health"`
+}
+
+// directionNames maps the direction strings used in level files to
+// direction vectors
+var directionNames = map[string]snake.Point{
+	"up":    snake.Up,
+	"down":  snake.Down,
+	"left":  snake.Left,
+	"right": snake.Right,
+}
+
+// loadLevelsDir loads every .json level in dir, sorted by file name
+// A missing directory simply means there are no custom levels. Invalid
+// files are skipped and reported together in the returned error so one
+// broken file doesn't hide the others.
+func loadLevelsDir(dir string) ([]Level, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading levels dir: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var levels []Level
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".json") {
+			continue
+		}
+		lvl, err := loadLevelFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		levels = append(levels, lvl)
+	}
+	return levels, errors.Join(errs...)
+}
+
+// loadLevelFile reads and validates a single level file
+func loadLevelFile(path string) (Level, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Level{}, fmt.Errorf("reading level: %w", err)
+	}
+
+	var lf levelFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&lf); err != nil {
+		return Level{}, fmt.Errorf("level %s: %w", path, err)
+	}
+
+	if lf.Name == "" {
+		lf.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	lvl, err := lf.toLevel()
+	if err != nil {
+		return Level{}, fmt.Errorf("level %s: %w", path, err)
+	}
+	return lvl, nil
+}
+
+// toLevel validates the decoded file and converts it into a Level
+func (lf levelFile) toLevel() (Level, error) {
+	gridW, gridH := lf.Width, lf.Height
+	maxW, maxH := maxGridWidth, maxGridHeight
+	if gridW < minLevelCells || gridH < minLevelCells || gridW > maxW || gridH > maxH {
+		return Level{}, fmt.Errorf("grid is %dx%d, must be between %dx%d and %dx%d",
+			gridW, gridH, minLevelCells, minLevelCells, maxW, maxH)
+	}
+	inBounds := func(p snake.Point) bool {
+		return p.X >= 0 && p.Y >= 0 && p.X < gridW && p.Y < gridH
+	}
+
+	// WALLS
+	walls := make(map[snake.Point]bool)
+	var obstacles []snake.Point
+	for i, r := range lf.Walls {
+		w, h := max(r.W, 1), max(r.H, 1)
+		if !inBounds(snake.Point{X: r.X, Y: r.Y}) || !inBounds(snake.Point{X: r.X + w - 1, Y: r.Y + h - 1}) {
+			return Level{}, fmt.Errorf("wall %d is outside the grid", i)
+		}
+		for _, p := range rectPoints(r.X, r.Y, w, h) {
+			if !walls[p] {
+				walls[p] = true
+				obstacles = append(obstacles, p)
+			}
+		}
+	}
+
+	// SNAKE STARTS
+	start, err := parseStart(lf.Start, defaultSnakeStart(gridW, gridH))
+	if err != nil {
+		return Level{}, err
+	}
+	start2, err := parseStart(lf.Start2, start.mirrored(gridW, gridH))
+	if err != nil {
+		return Level{}, err
+	}
+	// The whole starting body and the cell in front of the head must be
+	// free, or the snake would die on the first tick. The two snakes
+	// mustn't overlap either.
+	startCells := func(s SnakeStart) []snake.Point {
+		return append(s.body(), s.Head.Add(s.Direction))
+	}
+	taken := make(map[snake.Point]bool)
+	for _, p := range startCells(start) {
+		if !inBounds(p) || walls[p] {
+			return Level{}, fmt.Errorf("snake start at (%d,%d) is blocked or out of bounds", start.Head.X, start.Head.Y)
+		}
+		taken[p] = true
+	}
+	for _, p := range startCells(start2) {
+		if !inBounds(p) || walls[p] || taken[p] {
+			return Level{}, fmt.Errorf("second snake start at (%d,%d) is blocked or out of bounds (set start2)", start2.Head.X, start2.Head.Y)
+		}
+		taken[p] = true
+	}
+
+	// PORTALS
+	// Both ends must be free cells not used by another portal or a
+	// snake's start
+	var portals []snake.Portal
+	portalCells := make(map[snake.Point]bool)
+	for i, pf := range lf.Portals {
+		pt := snake.Portal{A: snake.Point{X: pf.A.X, Y: pf.A.Y}, B: snake.Point{X: pf.B.X, Y: pf.B.Y}}
+		for _, p := range []snake.Point{pt.A, pt.B} {
+			if !inBounds(p) || walls[p] || taken[p] || portalCells[p] {
+				return Level{}, fmt.Errorf("portal %d end at (%d,%d) is blocked, taken or out of bounds", i, p.X, p.Y)
+			}
+			portalCells[p] = true
+		}
+		portals = append(portals, pt)
+	}
+
+	// FOOD SPAWNS
+	// Every spawn cell must be reachable, otherwise the level can't be won
+	reachable := snake.FloodFill(start.Head, gridW, gridH, walls)
+	var spawns []snake.Point
+	for i, c := range lf.FoodSpawns {
+		p := snake.Point{X: c.X, Y: c.Y}
+		if !inBounds(p) || walls[p] || portalCells[p] {
+			return Level{}, fmt.Errorf("food spawn %d is on a wall, a portal or out of bounds", i)
+		}
+		if !reachable[p] {
+			return Level{}, fmt.Errorf("food spawn %d at (%d,%d) is unreachable from the start", i, p.X, p.Y)
+		}
+		spawns = append(spawns, p)
+	}
+
+	// CHECKPOINTS
+	// Like food spawns, but never on a snake's start either, and only one
+	// to a cell
+	var checkpoints []snake.Point
+	for i, c := range lf.Checkpoints {
+		p := snake.Point{X: c.X, Y: c.Y}
+		if !inBounds(p) || walls[p] || portalCells[p] || taken[p] || slices.Contains(checkpoints, p) {
+			return Level{}, fmt.Errorf("checkpoint %d is on a wall, a portal, a snake's start, another checkpoint or out of bounds", i)
+		}
+		if !reachable[p] {
+			return Level{}, fmt.Errorf("checkpoint %d at (%d,%d) is unreachable from the start", i, p.X, p.Y)
+		}
+		checkpoints = append(checkpoints, p)
+	}
+
+	// BOSS
+	// Its cells must be free, and clear of everything placed so far; like
+	// the spawns, it must be reachable to be beaten
+	var boss *BossSpec
+	if bf := lf.Boss; bf != nil {
+		boss = &BossSpec{Pos: snake.Point{X: bf.X, Y: bf.Y}, Size: bf.Size, Health: bf.Health}
+		if boss.Size == 0 {
+			boss.Size = defaultBossSize
+		}
+		if boss.Health == 0 {
+			boss.Health = defaultBossHealth
+		}
+		if boss.Size < minBossSize || boss.Size > maxBossSize || boss.Health < 1 || boss.Health > maxBossHealth {
+			return Level{}, fmt.Errorf("boss must be %d-%d cells square with 1-%d health", minBossSize, maxBossSize, maxBossHealth)
+		}
+		for _, p := range rectPoints(boss.Pos.X, boss.Pos.Y, boss.Size, boss.Size) {
+			if !inBounds(p) || walls[p] || portalCells[p] || taken[p] || slices.Contains(spawns, p) || slices.Contains(checkpoints, p) {
+				return Level{}, fmt.Errorf("boss at (%d,%d) is on a wall, a portal, a snake's start, a food spawn, a checkpoint or out of bounds", boss.Pos.X, boss.Pos.Y)
+			}
+		}
+		if !reachable[boss.Pos] {
+			return Level{}, fmt.Errorf("boss at (%d,%d) is unreachable from the start", boss.Pos.X, boss.Pos.Y)
+		}
+	}
+
+	return Level{
+		Name:        lf.Name,
+		Width:       gridW,
+		Height:      gridH,
+		Obstacles:   obstacles,
+		Start:       &start,
+		Start2:      &start2,
+		FoodSpawns:  spawns,
+		Portals:     portals,
+		Checkpoints: checkpoints,
+		Boss:        boss,
+	}, nil
+}
+
+// parseStart converts a start position from a level file, using fallback
+// when the file doesn't set one. The direction defaults to right.
+func parseStart(s *levelFileStart, fallback SnakeStart) (SnakeStart, error) {
+	if s == nil {
+		return fallback, nil
+	}
+	dir, ok := directionNames[strings.ToLower(s.Direction)]
+	if s.Direction == "" {
+		dir, ok = snake.Right, true
+	}
+	if !ok {
+		return SnakeStart{}, fmt.Errorf("unknown start direction %q", s.Direction)
+	}
+	return SnakeStart{Head: snake.Point{X: s.X, Y: s.Y}, Direction: dir}, nil
+}
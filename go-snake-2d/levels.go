@@ -0,0 +1,279 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Level is a named arena layout
+// Obstacles are static wall cells inside the play field; hitting one is
+// as fatal as hitting the screen edge.
+type Level struct {
+	Name string
+
+	// Width and Height fix the board size in cells
+	// Zero means the level adapts to the board size chosen in the options
+	Width, Height int
+
+	// Obstacles is a fixed list of wall cells (used by level files)
+	Obstacles []snake.Point
+
+	// Layout, when set, builds the obstacles for a board of the given
+	// size instead of using the fixed Obstacles list
+	Layout func(w, h int, seed uint64) []snake.Point
+
+	// Seeded marks levels whose Layout depends on the seed (e.g. mazes),
+	// so the seed is shown to the player and can be replayed
+	Seeded bool
+
+	// Start is where the snake appears; nil means the default start
+	Start *SnakeStart
+
+	// Start2, when set, is where the second snake starts in versus mode
+	// (see versusStarts)
+	Start2 *SnakeStart
+
+	// FoodSpawns, when not empty, restricts food to these cells
+	FoodSpawns []snake.Point
+
+	// Portals is a fixed list of linked portal pairs (used by level
+	// files), and PortalLayout, when set, places them for a board of the
+	// given size instead
+	Portals      []snake.Portal
+	PortalLayout func(w, h int) []snake.Portal
+
+	// Checkpoints are the cells that save a solo run's progress when the
+	// snake reaches them (used by level files; see checkpoint.go)
+	Checkpoints []snake.Point
+
+	// Boss, when set, puts a boss on the board for solo runs to beat (see
+	// boss.go), and BossLayout, when set, places it for a board of the
+	// given size instead
+	Boss       *BossSpec
+	BossLayout func(w, h int) BossSpec
+}
+
+// BossSpec describes a level's boss: its top left cell, how many cells
+// square it is and how many bites it takes to beat
+type BossSpec struct {
+	Pos          snake.Point
+	Size, Health int
+}
+
+// SnakeStart describes the snake's position at the beginning of a run
+type SnakeStart struct {
+	Head      snake.Point
+	Direction snake.Point
+}
+
+// defaultSnakeStart puts the snake in the center of a w×h board, moving right
+func defaultSnakeStart(w, h int) SnakeStart {
+	return SnakeStart{
+		Head:      snake.Point{X: w / 2, Y: h / 2},
+		Direction: snake.Right,
+	}
+}
+
+// body returns the starting snake segments, head first, trailing behind
+// the head opposite to the direction of travel
+func (s SnakeStart) body() []snake.Point {
+	body := make([]snake.Point, snake.InitialLength)
+	for i := range body {
+		body[i] = snake.Point{
+			X: s.Head.X - s.Direction.X*i,
+			Y: s.Head.Y - s.Direction.Y*i,
+		}
+	}
+	return body
+}
+
+// snakeStart returns the level's start, falling back to the default
+func (l Level) snakeStart(w, h int) SnakeStart {
+	if l.Start != nil {
+		return *l.Start
+	}
+	return defaultSnakeStart(w, h)
+}
+
+// boardSize returns the level's dimensions in cells, using the fallback
+// size for levels that adapt to the board-size setting
+func (l Level) boardSize(fallbackW, fallbackH int) (int, int) {
+	if l.Width > 0 && l.Height > 0 {
+		return l.Width, l.Height
+	}
+	return fallbackW, fallbackH
+}
+
+// builtInLevels are the layouts that ship with the game, in the order
+// they are cycled through (custom levels from levelsDirName follow them).
+// Layouts are proportional to the board so they work at every board size,
+// and all of them keep the snake's starting row clear around the center.
+var builtInLevels = []Level{
+	{
+		Name: "Open Field",
+	},
+	{
+		Name: "Pillars",
+		Layout: func(w, h int, _ uint64) []snake.Point {
+			px, py := w/5, h/5
+			return concatPoints(
+				rectPoints(px, py, 2, 2),
+				rectPoints(w-px-2, py, 2, 2),
+				rectPoints(px, h-py-2, 2, 2),
+				rectPoints(w-px-2, h-py-2, 2, 2),
+			)
+		},
+	},
+	{
+		Name: "Corridors",
+		Layout: func(w, h int, _ uint64) []snake.Point {
+			return concatPoints(
+				hLine(w/8, w-w/8-1, h/4),
+				hLine(w/8, w-w/8-1, h-h/4-1),
+			)
+		},
+	},
+	{
+		Name: "Cross",
+		Layout: func(w, h int, _ uint64) []snake.Point {
+			cx, cy := w/2, h/2
+			return concatPoints(
+				vLine(cx, 2, cy-4),
+				vLine(cx, cy+4, h-3),
+				hLine(3, cx-6, cy),
+				hLine(cx+7, w-4, cy),
+			)
+		},
+	},
+	{
+		Name: "Box",
+		Layout: func(w, h int, _ uint64) []snake.Point {
+			// Inner frame with a doorway in the middle of each side
+			cx, cy := w/2, h/2
+			left, right, top, bottom := 4, w-5, 3, h-4
+			return concatPoints(
+				hLine(left, cx-3, top), hLine(cx+3, right, top),
+				hLine(left, cx-3, bottom), hLine(cx+3, right, bottom),
+				vLine(left, top, cy-2), vLine(left, cy+2, bottom),
+				vLine(right, top, cy-2), vLine(right, cy+2, bottom),
+			)
+		},
+	},
+	{
+		// Two crossed pairs of portals between the corners, clear of the
+		// starting row
+		Name: "Portals",
+		PortalLayout: func(w, h int) []snake.Portal {
+			left, right, top, bottom := w/6, w-w/6-1, h/4, h-h/4-1
+			return []snake.Portal{
+				{A: snake.Point{X: left, Y: top}, B: snake.Point{X: right, Y: bottom}},
+				{A: snake.Point{X: right, Y: top}, B: snake.Point{X: left, Y: bottom}},
+			}
+		},
+	},
+	{
+		// Maze mode: a fresh procedural maze, reproducible from its seed
+		Name:   "Maze",
+		Seeded: true,
+		Layout: func(w, h int, seed uint64) []snake.Point {
+			return generateMaze(seed, w, h, defaultSnakeStart(w, h).Head)
+		},
+	},
+	{
+		// A boss level: the boss starts above the snake's starting row,
+		// with pillars in the corners to shelter behind
+		Name: "Lair",
+		Layout: func(w, h int, _ uint64) []snake.Point {
+			px, py := w/8, h/8
+			return concatPoints(
+				rectPoints(px, py, 2, 2),
+				rectPoints(w-px-2, py, 2, 2),
+				rectPoints(px, h-py-2, 2, 2),
+				rectPoints(w-px-2, h-py-2, 2, 2),
+			)
+		},
+		BossLayout: func(w, h int) BossSpec {
+			return BossSpec{Pos: snake.Point{X: w/2 - 1, Y: h / 5}, Size: defaultBossSize, Health: defaultBossHealth}
+		},
+	},
+}
+
+// builtInLevel returns the index of the built-in level with the name in
+// Game.levels. The built-in levels come first there, so a custom level
+// with the same name can't take the place of one the game relies on.
+func builtInLevel(name string) int {
+	return slices.IndexFunc(builtInLevels, func(l Level) bool { return l.Name == name })
+}
+
+// obstacleSet builds the level's walls for a w×h board as a set for fast
+// collision lookups. The seed is only used by seeded levels.
+func (l Level) obstacleSet(w, h int, seed uint64) map[snake.Point]bool {
+	pts := l.Obstacles
+	if l.Layout != nil {
+		pts = l.Layout(w, h, seed)
+	}
+	set := make(map[snake.Point]bool, len(pts))
+	for _, p := range pts {
+		set[p] = true
+	}
+	return set
+}
+
+// portals returns the level's portals for a w×h board
+func (l Level) portals(w, h int) []snake.Portal {
+	if l.PortalLayout != nil {
+		return l.PortalLayout(w, h)
+	}
+	return l.Portals
+}
+
+// boss returns a new boss for the level on a w×h board, or nil for a
+// level without one
+func (l Level) boss(w, h int) *snake.Boss {
+	spec := l.Boss
+	if l.BossLayout != nil {
+		s := l.BossLayout(w, h)
+		spec = &s
+	}
+	if spec == nil {
+		return nil
+	}
+	return snake.NewBoss(spec.Pos, spec.Size, spec.Health)
+}
+
+// hLine returns the cells from x1 to x2 (inclusive) on row y
+func hLine(x1, x2, y int) []snake.Point {
+	var pts []snake.Point
+	for x := x1; x <= x2; x++ {
+		pts = append(pts, snake.Point{X: x, Y: y})
+	}
+	return pts
+}
+
+// vLine returns the cells from y1 to y2 (inclusive) in column x
+func vLine(x, y1, y2 int) []snake.Point {
+	var pts []snake.Point
+	for y := y1; y <= y2; y++ {
+		pts = append(pts, snake.Point{X: x, Y: y})
+	}
+	return pts
+}
+
+// rectPoints returns every cell of a filled w×h rectangle at (x, y)
+func rectPoints(x, y, w, h int) []snake.Point {
+	var pts []snake.Point
+	for dy := range h {
+		pts = append(pts, hLine(x, x+w-1, y+dy)...)
+	}
+	return pts
+}
+
+// concatPoints joins several point lists into one
+func concatPoints(lists ...[]snake.Point) []snake.Point {
+	var pts []snake.Point
+	for _, l := range lists {
+		pts = append(pts, l...)
+	}
+	return pts
+}
@@ -1,16 +1,18 @@
 package main
 
 import (
-	"bytes"
+	"errors"
+	"flag"
+	"fmt"
 	"image/color"
 	"log"
 	"math/rand/v2"
+	"os"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/examples/resources/fonts"
-	"github.com/hajimehoshi/ebiten/v2/text/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/obliviousorion/go-basics/pkg/script"
+	"github.com/obliviousorion/go-basics/pkg/snake"
 )
 
 // ============================================================================
@@ -24,329 +26,1007 @@ import (
 // 2. Draw() - Called every frame to render the current game state
 // 3. Layout() - Defines the screen dimensions
 //
+// SCENES:
+// Update and Draw are forwarded to the active Scene (see scene.go):
+// Title → Countdown → Playing ⇄ Paused (⇄ Options, → Title),
+// Playing → Dying → (NameEntry →) GameOver → Countdown or Title,
+// Title ⇄ Leaderboard
+//
 // GAME MECHANICS:
-// - The snake is represented as a slice of Points (coordinates)
+// The rules live in package snake (pkg/snake) with no Ebiten code; this
+// package translates input into controllers, decides when to tick, and
+// draws the snake.World.
+// - The snake is a snake.Body: a ring buffer of Points (coordinates), head
+//   first, so moving reuses its cells instead of allocating
 // - The snake moves by adding a new head in the direction of movement
 // - If the snake eats food, it grows (old tail stays); otherwise tail is removed
+// - Poison food shrinks the snake, or kills it if it is too short
 // - Game over occurs when snake hits walls or itself
+// - In versus mode two snakes share the board (see player.go); hitting the
+//   other snake is fatal too, and the last one alive wins
+// - In split screen each player races on a board of their own (see
+//   board.go and split.go)
 // - Game speed is controlled independently from frame rate using time-based updates
+// - The snake speeds up as it grows, following a tunable DifficultyCurve
 //
 // DATA FLOW:
-// Input (WASD keys) → Update direction → Time check → Move snake → 
+// Controllers (keys, touch, bots) → Update direction → Time check → Move snake → 
 // Check collisions → Update snake/food → Draw everything
 //
 // ============================================================================
 
 const (
-	// gameSpeed controls how fast the snake moves (6 updates per second)
-	gameSpeed = time.Second / 6
-
-	// Screen dimensions in pixels
-	screenWidth  = 640
-	screenHeight = 480
+	// Screen dimensions in pixels: the height is fixed, the width
+	// depends on the resolution (see screenWidth and Resolution) and is
+	// baseScreenWidth for the original 4:3
+	baseScreenWidth = 640
+	screenHeight    = 480
 
-	// gridSize defines the size of each cell in pixels
-	// The game grid is screenWidth/gridSize by screenHeight/gridSize cells
-	gridSize = 20
+	// minCellSize is the smallest cell size in pixels a board may use
+	// The board's width and height in cells are chosen at runtime (see
+	// BoardSize, GridSize and Level); each cell is then scaled so the
+	// board fits the screen (see boardView).
+	minCellSize = 8
 )
 
-// Direction vectors - used to move the snake in 2D space
-var (
-	dirUp    = Point{x: 0, y: -1}  // Moving up decreases y
-	dirDown  = Point{x: 0, y: 1}   // Moving down increases y
-	dirRight = Point{x: 1, y: 0}   // Moving right increases x
-	dirLeft  = Point{x: -1, y: 0}  // Moving left decreases x
+// Game holds all the state for our snake game
+type Game struct {
+	// scenes switches between the title menu, gameplay, pause and
+	// game over screens; Update and Draw forward to the active scene
+	scenes *SceneManager
 
-	// Font source for rendering text (loaded from embedded fonts)
-	mplusFaceSource *text.GoTextFaceSource
-)
+	// mode is solo or two-player versus
+	mode GameMode
 
-// Point represents a position on the game grid
-// Note: These are grid coordinates, not pixel coordinates
-// To convert to pixels, multiply by gridSize
-type Point struct {
-	x, y int
-}
+	// variant is the goal of the current run (always Classic in versus)
+	variant Variant
 
-// Game holds all the state for our snake game
-type Game struct {
-	// snake is a slice where [0] is the head and [len-1] is the tail
-	snake []Point
+	// daily is the date of the daily challenge being played (see
+	// daily.go), "" for other runs
+	daily string
+
+	// Board is the board being updated or drawn: its world, view and
+	// timers (see board.go). It is the only one except in split screen,
+	// where boards lists both.
+	*Board
+	boards []*Board
+
+	// clock is where every timer reads the time: the wall clock, or a
+	// fake one in tests
+	clock snake.Clock
+
+	// runStart is when the run started, moved forward past pauses like
+	// the other timers, so the time played is always now minus runStart
+	runStart time.Time
+
+	// runTime is how long the last run lasted, and finished whether it
+	// ended by reaching its variant's goal rather than by dying
+	runTime  time.Duration
+	finished bool
+
+	// runDifficulty is the difficulty preset of the current run, fixed
+	// when it starts so it can't be changed mid-run for a better score
+	runDifficulty Difficulty
+
+	// rivals is how many bots the current battle royale has, fixed when
+	// it starts like runDifficulty
+	rivals int
+
+	// difficulty controls how the move rate ramps up with snake length
+	difficulty DifficultyCurve
+
+	// settings are the player's options-menu choices
+	settings Settings
+
+	// launch holds command-line overrides for this session
+	launch launchOptions
+
+	// levels lists the playable arenas: the built-in ones followed by any
+	// custom levels loaded from disk
+	levels []Level
+
+	// level is the index into levels of the arena being played
+	level int
+
+	// mazeSeed is the seed for generated levels; restarting keeps the same
+	// maze, and a new seed is rolled when the level is cycled
+	mazeSeed uint64
+
+	// seed is the seed of the current run, and rngSource the generator
+	// seeded with it that all of the world's random choices come from
+	// (see seed.go). It is kept so its state can be saved with the run.
+	seed      uint64
+	rngSource *rand.PCG
+
+	// savePath is where a run in progress is saved ("" if saving is
+	// unavailable)
+	savePath string
+
+	// watch notices when the settings file is edited, to reload it (see
+	// hotreload.go)
+	watch settingsWatch
+
+	// notice is a short message shown over the board until noticeUntil,
+	// e.g. to confirm the game was saved
+	notice      string
+	noticeUntil time.Time
+
+	// profile is the name of the player profile in use, and profiles the
+	// list of them; settings, highScores and savePath belong to it
+	profile  string
+	profiles *profileIndex
+
+	// highScores is the persisted table of best runs (nil if unavailable)
+	highScores *HighScoreTable
 
-	// direction is the current movement direction (one of the dir* vectors)
-	direction Point
+	// stats are the profile's lifetime stats and achievements (nil if
+	// unavailable), runStats what the current run adds to them, and
+	// unlocked the achievements the last run unlocked (see stats.go)
+	stats    *Stats
+	runStats Stats
+	unlocked []Achievement
 
-	// lastUpdate tracks when we last moved the snake
-	// This allows us to control game speed independent of frame rate
-	lastUpdate time.Time
+	// lastRank is where the most recent run placed in highScores,
+	// or -1 if it didn't make the table
+	lastRank int
 
-	// food is the current position of the food item
-	food Point
+	// bindings maps game actions to the keys that trigger them
+	bindings KeyBindings
 
-	// gameOver flag determines if the game has ended
-	gameOver bool
+	// touch turns swipes, taps and on-screen buttons into actions
+	touch TouchInput
+
+	// input combines the keys and touch into each action's state for
+	// the frame
+	input Input
+
+	// theme is the color theme from the settings with the palette
+	// applied; everything is drawn in its colors
+	theme Theme
+
+	// dayTheme and nightTheme are the themes theme blends between, and
+	// night how far it is into the night, or -1 when it has to be blended
+	// again (see daynight.go)
+	dayTheme, nightTheme Theme
+	night                float64
+
+	// background is the cached checkerboard or grid under the board
+	background boardBackground
+
+	// ambient draws the starfield, vignette and scanlines (see ambient.go)
+	ambient Ambient
+
+	// clip records the last few seconds on screen, for F8 to save as a GIF
+	clip ClipRecorder
+
+	// camera shakes the screen when a snake dies
+	camera Camera
+
+	// mirror is the layer the board is drawn into in mirror mode, to be
+	// flipped onto the screen (see Settings.Mirror)
+	mirror Layer
+
+	// debug is the F3 diagnostics overlay
+	debug debugOverlay
+
+	// prof times the game loop with -pprof, and is nil otherwise (see
+	// profiling.go)
+	prof *Profiler
+
+	// console is the developer console (see console.go), and cheated is
+	// set for runs it or the mods have changed, which don't make the high
+	// scores
+	console Console
+	cheated bool
+
+	// mods are the mods loaded from ./mods (see mods.go)
+	mods []*script.Script
+
+	// tas is the scene of the TAS run being played (see tas.go), nil for
+	// every other run
+	tas *TASScene
+
+	// checkpoint is the run as it stood at the last checkpoint reached,
+	// which a death goes back to (see checkpoint.go), or nil
+	checkpoint *savedGame
+
+	// sprites holds the tiles the snakes and food are drawn with
+	sprites *Atlas
+
+	// systems draw the objects on the board, one kind each (see
+	// systems.go)
+	systems []System
+
+	// events passes what happens during a run on to the features that
+	// react to it (see events.go)
+	events EventBus
+
+	// audio plays the sound effects (nil when no audio device is available)
+	audio *Audio
 }
 
 // Update is called every frame by Ebiten (~60 times per second)
 // This is where we handle input and update game state
+// Each scene (title, playing, paused, game over) has its own Update;
+// the scene manager forwards to the active one
 func (g *Game) Update() error {
-	// GAME OVER STATE HANDLING
-	// When game is over, we only check for restart input
-	if g.gameOver {
-		// Check if player wants to restart
-		if ebiten.IsKeyPressed(ebiten.KeyEnter) || ebiten.IsKeyPressed(ebiten.KeySpace) {
-			// Reset the game to initial state
-			g.resetGame()
-			return nil
-		}
-		// If Escape is pressed, we could exit, but for now just stay in game over
-		if ebiten.IsKeyPressed(ebiten.KeyEscape) {
-			// Game remains in game over state
-			return nil
-		}
-		return nil
-	}
+	defer g.prof.updated(g.prof.start())
 
-	// INPUT HANDLING
-	// We capture input BEFORE the time check so direction changes feel responsive
-	// The snake will move in the new direction on the next update tick
-	if ebiten.IsKeyPressed(ebiten.KeyW) {
-		// Only allow direction change if it's not the opposite direction
-		// (prevents snake from reversing into itself)
-		if g.direction != dirDown {
-			g.direction = dirUp
+	// Touches are read once per frame, before the scene looks at them
+	g.touch.update(g.clock.Now())
+	g.input.update(g.bindings, &g.touch)
+	g.camera.update()
+	g.ambient.update()
+	g.updateDayNight()
+	g.checkSettingsFile()
+	g.eachBoard(func() { g.followSnakes(false) })
+
+	// Mute works everywhere, so it is handled here rather than per scene
+	// (except while typing, when M is just a letter)
+	// So does F11, which switches between fullscreen and the window,
+	// F3, which shows the debug overlay, and F8, which saves a clip of
+	// the last few seconds. None of them work while the controls screen
+	// is waiting for a key to bind.
+	if !g.rebinding() {
+		if g.isJustPressed(ActionMute) && !g.typing() {
+			g.toggleMute()
+		}
+		if g.isJustPressed(ActionFullscreen) {
+			g.toggleFullscreen()
 		}
-	} else if ebiten.IsKeyPressed(ebiten.KeyS) {
-		if g.direction != dirUp {
-			g.direction = dirDown
+		if g.isJustPressed(ActionDebug) {
+			g.debug.visible = !g.debug.visible
 		}
-	} else if ebiten.IsKeyPressed(ebiten.KeyA) {
-		if g.direction != dirRight {
-			g.direction = dirLeft
+		if g.isJustPressed(ActionClip) {
+			g.saveClip()
 		}
-	} else if ebiten.IsKeyPressed(ebiten.KeyD) {
-		if g.direction != dirLeft {
-			g.direction = dirRight
+	}
+	g.checkClip()
+
+	// Closing the window mid-run saves the run so it can be continued
+	// from the title screen next time
+	if ebiten.IsWindowBeingClosed() {
+		if at, ok := g.runClock(); ok {
+			if err := g.saveGame(at); err != nil && !errors.Is(err, errNotSaveable) {
+				log.Printf("saving game: %v", err)
+			}
 		}
+		g.rememberWindowSize()
+		return ebiten.Termination
 	}
 
-	// TIME-BASED UPDATE
-	// Only update game logic at gameSpeed intervals, not every frame
-	// This decouples game speed from render speed
-	if time.Since(g.lastUpdate) < gameSpeed {
-		return nil // Not enough time has passed, skip this update
+	// The developer console takes the keys while it is open, and the
+	// scene waits under it
+	if g.console.open || (g.isJustPressed(ActionConsole) && !g.typing() && !g.rebinding()) {
+		g.updateConsole()
+		return nil
 	}
 
-	// Update the timer for the next movement
-	g.lastUpdate = time.Now()
+	// A scene change swallows the keys that caused it (see Input)
+	scene := g.scenes.Current()
+	err := g.scenes.Update()
+	if g.scenes.Current() != scene {
+		g.input.ignoreHeld()
+	}
+	if err == ebiten.Termination {
+		g.rememberWindowSize()
+	}
+	return err
+}
 
-	// CORE GAME LOGIC
-	// Move the snake in the current direction
-	g.updateSnake(&g.snake, g.direction)
+// rememberWindowSize saves the window's size when the game exits, so the
+// next session opens it the size the player left it at
+func (g *Game) rememberWindowSize() {
+	if isWeb || ebiten.IsFullscreen() {
+		return
+	}
+	w, h := ebiten.WindowSize()
+	if w < minWindowWidth || h < minWindowHeight || (WindowSize{w, h}) == g.settings.Window {
+		return
+	}
+	g.settings.Window = WindowSize{Width: w, Height: h}
+	if err := g.settings.Save(); err != nil {
+		log.Printf("saving settings: %v", err)
+	}
+}
+
+// typing reports whether the player is typing text, so letter keys
+// shouldn't trigger shortcuts
+func (g *Game) typing() bool {
+	if g.console.open {
+		return true
+	}
+	switch g.scenes.Current().(type) {
+	case *TextEntryScene, *NameEntryScene:
+		return true
+	}
+	return false
+}
 
-	return nil
+// rebinding reports whether the controls screen is waiting for a key, so
+// no key should trigger a shortcut
+func (g *Game) rebinding() bool {
+	s, ok := g.scenes.Current().(*ControlsScene)
+	return ok && s.waiting
 }
 
-// updateSnake handles the core snake movement logic
-// This is where the "snake grows when eating" mechanic is implemented
-func (g *Game) updateSnake(snake *[]Point, direction Point) {
-	// Calculate the new head position based on current direction
-	head := (*snake)[0]
-	newHead := Point{
-		x: head.x + direction.x,
-		y: head.y + direction.y,
+// toggleFullscreen switches between fullscreen and windowed mode and
+// remembers the choice for next time
+func (g *Game) toggleFullscreen() {
+	g.settings.Fullscreen = !ebiten.IsFullscreen()
+	ebiten.SetFullscreen(g.settings.Fullscreen)
+	if err := g.settings.Save(); err != nil {
+		log.Printf("saving settings: %v", err)
 	}
+}
 
-	// COLLISION DETECTION
-	// Check if the new head position causes game over
-	if g.isBadCollision(newHead, *snake) {
-		g.gameOver = true
-		return
+// toggleMute mutes or unmutes every sound and remembers it, so the game
+// starts muted next time too
+func (g *Game) toggleMute() {
+	g.settings.Muted = !g.settings.Muted
+	g.audio.setMuted(g.settings.Muted)
+	if err := g.settings.Save(); err != nil {
+		log.Printf("saving settings: %v", err)
 	}
+}
 
-	// FOOD CONSUMPTION
-	// If snake eats food, grow by keeping the tail
-	if newHead == g.food {
-		// Prepend new head, keep entire body (snake grows)
-		*snake = append([]Point{newHead}, *snake...)
-		g.spawnFood() // Spawn new food at random location
-	} else {
-		// NORMAL MOVEMENT
-		// Prepend new head, remove tail (snake moves without growing)
-		// This creates the illusion of movement
-		*snake = append(
-			[]Point{newHead},
-			(*snake)[:len(*snake)-1]...,
-		)
+// shiftTimers pushes every running game timer forward by d
+// Used when resuming from pause so the time spent paused doesn't count
+func (g *Game) shiftTimers(d time.Duration) {
+	g.runStart = g.runStart.Add(d)
+	g.eachBoard(func() {
+		g.lastUpdate = g.lastUpdate.Add(d)
+		g.world.Shift(d)
+	})
+}
+
+// step advances the world by one tick and plays the sounds for what
+// happened, ending the game if the round is over
+func (g *Game) step(now time.Time) {
+	// Remember where the snakes were, so drawing can glide them from
+	// there to their new cells over the next tick
+	// The copies reuse last tick's buffers
+	for i, p := range g.world.Players {
+		if i < len(g.prevSnakes) {
+			g.prevSnakes[i] = p.Snake.AppendTo(g.prevSnakes[i][:0])
+		} else {
+			g.prevSnakes = append(g.prevSnakes, p.Snake.Points())
+		}
 	}
+
+	events := g.world.Step(now)
+	g.tickMods(now)
+	g.handleEvents(now, events)
+	g.minimap.invalidate()
+	if g.settings.Trails {
+		g.stampTrails()
+	}
+	g.findHints()
 }
 
-// isBadCollision checks if a point causes game over
-// Returns true if the point is:
-// 1. Outside the game boundaries (wall collision)
-// 2. Overlapping with the snake's body (self collision)
-func (g *Game) isBadCollision(p Point, snake []Point) bool {
-	// BOUNDARY CHECK
-	// Check if point is outside the grid
-	if p.x < 0 || p.y < 0 || p.x >= screenWidth/gridSize || p.y >= screenHeight/gridSize {
-		return true
+// handleEvents publishes what happened in the world on the event bus,
+// then ends the game if the run is over. Returns true if it is.
+func (g *Game) handleEvents(now time.Time, events []snake.Event) bool {
+	g.runMods(now, events)
+
+	// Sounds, particles and the like subscribe to the events they react
+	// to (see events.go)
+	for _, e := range events {
+		g.events.publish(e)
 	}
 
-	// SELF-COLLISION CHECK
-	// Check if point overlaps with any part of the snake's body
-	for _, sp := range snake {
-		if sp == p {
-			return true
+	// A checkpoint is only kept if the snake lived through the tick it
+	// reached it on, or going back to it would only die again
+	for _, e := range events {
+		if e.Kind == snake.EventCheckpoint && !g.roundOver() {
+			g.saveCheckpoint(now)
+			break
 		}
 	}
 
-	return false
+	// Reaching the variant's goal ends the run as well as dying does,
+	// unless a checkpoint was reached to go back to.
+	// The death sound is played once by endGame, even if both snakes died.
+	switch {
+	case g.goalReached(now):
+		g.finished = true
+		g.events.publish(snake.Event{Kind: EventRunFinished, Player: -1})
+		g.endGame(now)
+	case g.roundOver() && g.checkpoint != nil:
+		g.resumeCheckpoint(now)
+	case g.roundOver():
+		g.endGame(now)
+	default:
+		return false
+	}
+	return true
+}
+
+// endGame records the run, then starts the death animation (which then
+// shows the results), or goes straight to the results when the run was
+// finished
+func (g *Game) endGame(now time.Time) {
+	g.runTime = g.elapsed(now).Round(time.Millisecond)
+	if g.variant == VariantTimed {
+		// The clock may have run a frame past the limit
+		g.runTime = min(g.runTime, timedDuration)
+	}
+
+	// A TAS run stays on its scene when it ends, so a savestate can be
+	// loaded to try again, and never makes the high scores
+	if g.tas != nil {
+		g.tas.end()
+		return
+	}
+
+	// A finished run can't be continued
+	g.deleteSavedGame()
+	g.recordRun(now)
+	g.recordStats(now)
+
+	if g.finished {
+		g.scenes.Switch(g.resultsScene())
+	} else {
+		g.scenes.Switch(newDyingScene(g))
+		g.audio.play(SoundDie)
+	}
+}
+
+// recordRun adds a solo run to its variant's high-score table, saving it
+// to disk if it made the cut
+// Versus rounds aren't recorded: their scores depend on the opponent.
+func (g *Game) recordRun(now time.Time) {
+	g.lastRank = -1
+	if g.highScores == nil || g.mode != ModeSolo || g.cheated {
+		return
+	}
+	p := g.world.Players[0]
+	entry := HighScore{
+		Score:  p.Score,
+		Length: p.Snake.Len(),
+		Date:   now,
+	}
+	if g.variant == VariantDaily {
+		entry.Challenge = g.daily
+	}
+	// Only runs that reached the goal have a time worth ranking
+	if g.variant.ranksByTime() && g.finished {
+		entry.Time = g.runTime
+	}
+	g.lastRank = g.highScores.Add(g.variant, g.runDifficulty, entry)
+	if g.lastRank < 0 {
+		return
+	}
+	if err := g.highScores.Save(); err != nil {
+		// Not fatal: the score still shows for this session
+		log.Printf("saving high scores: %v", err)
+	}
 }
 
 // Draw renders the current game state to the screen
 // Called every frame by Ebiten
+// Like Update, drawing is handled by the active scene
+// While the screen shakes, the scene is drawn offscreen and then copied
+// over, offset by the camera.
 func (g *Game) Draw(screen *ebiten.Image) {
-	// DRAW SNAKE
-	// Render each segment of the snake as a white square
-	for _, p := range g.snake {
-		vector.FillRect(screen,
-			float32(p.x*gridSize), // Convert grid coords to pixels
-			float32(p.y*gridSize),
-			gridSize,
-			gridSize,
-			color.White,
-			true,
-		)
-	}
-
-	// DRAW FOOD
-	// Render food as a red square
-	vector.FillRect(screen,
-		float32(g.food.x*gridSize),
-		float32(g.food.y*gridSize),
-		gridSize,
-		gridSize,
-		color.RGBA{255, 0, 0, 255}, // Red color (alpha was 0, fixed to 255)
-		true,
-	)
-
-	// DRAW GAME OVER SCREEN
-	if g.gameOver {
-		// Create font face for game over text
-		face := &text.GoTextFace{
-			Source: mplusFaceSource,
-			Size:   48,
+	defer g.prof.drawn(g.prof.start())
+
+	screen.Fill(g.theme.Background)
+	target := g.camera.target(screen)
+	if target != screen {
+		target.Fill(g.theme.Background)
+	}
+	g.scenes.Draw(target)
+	g.camera.present(screen)
+	g.ambient.drawOverlay(screen, g.settings.Vignette, g.settings.Scanlines)
+	g.clip.capture(screen, g.clock.Now())
+	g.drawDebug(screen)
+	g.drawConsole(screen)
+}
+
+// drawBoard renders the play field: obstacles, snake, food and power-ups
+// Scenes draw their own HUD or overlays on top of it. now is the time
+// that timers on the board, like golden apples, are shown at. With
+// moving, the snakes are drawn part of the way through their current
+// move, as far as now is through it (see tickProgress); otherwise they
+// are drawn on the cells they are on.
+// Each board is drawn in its own viewport, clipped to it. In mirror mode
+// it is drawn into a layer and flipped left to right onto screen; only
+// the picture is mirrored, so the snakes still move the way their keys
+// say in the world, which is the puzzle.
+func (g *Game) drawBoard(screen *ebiten.Image, now time.Time, moving bool) {
+	g.eachBoard(func() {
+		area := screen.SubImage(g.view.area.bounds()).(*ebiten.Image)
+		dst := area
+		if g.settings.Mirror {
+			dst = g.mirror.begin(area, g.theme.Background)
+		}
+		g.drawBackground(dst)
+		if g.settings.Starfield {
+			g.ambient.drawStars(dst)
+		}
+
+		// Each kind of object on the board is drawn by its system, in
+		// order (see systems.go)
+		f := boardFrame{progress: 1, now: now}
+		if moving {
+			f.progress = g.tickProgress(now)
 		}
+		for i, s := range g.systems {
+			start := g.prof.start()
+			s.draw(g, dst, f)
+			g.prof.system(i, start)
+		}
+
+		if g.settings.Mirror {
+			g.mirror.end(area, mirrorX(area.Bounds()))
+		}
+	})
+}
+
+// tickProgress returns how far through the current tick interval now
+// is, from 0 (the snakes just moved) to 1 (they are about to move again)
+func (g *Game) tickProgress(now time.Time) float64 {
+	if g.tickInterval <= 0 {
+		return 1
+	}
+	return min(float64(now.Sub(g.lastUpdate))/float64(g.tickInterval), 1)
+}
 
-		// GAME OVER TEXT
-		gameOverText := "Game Over!"
-		w, h := text.Measure(gameOverText, face, face.Size)
+// lerp returns the point a fraction t of the way from a to b
+func lerp(a, b float32, t float64) float32 {
+	return a + (b-a)*float32(t)
+}
 
-		// Center the text on screen
-		op := &text.DrawOptions{}
-		op.GeoM.Translate(screenWidth/2-w/2, screenHeight/2-h/2)
-		op.ColorScale.ScaleWithColor(color.White)
+// drawCenteredText draws a line of text horizontally centered with its
+// top edge at y
+func drawCenteredText(screen *ebiten.Image, txt string, size, y float64, clr color.Color) {
+	drawText(screen, txt, size, float64(screenWidth)/2-measureText(txt, size)/2, y, clr)
+}
 
-		text.Draw(screen, gameOverText, face, op)
+// drawHUD renders the current score, snake length and the time played
+// so far in the top-left corner of each board's viewport
+// In versus mode each player gets a line in their snake's color.
+// now is the moment effect timers are measured against (frozen while paused)
+func (g *Game) drawHUD(screen *ebiten.Image, now time.Time) {
+	g.eachBoard(func() { g.drawBoardHUD(screen, now) })
+	if g.mode == ModeSplit {
+		g.drawSplitDivider(screen)
+	}
+}
+
+// drawBoardHUD renders the HUD of the current board
+func (g *Game) drawBoardHUD(screen *ebiten.Image, now time.Time) {
+	left, y := float64(g.view.area.x)+8, 4.0
+	line := func(s string, clr color.Color) {
+		drawText(screen, s, 16, left, y, clr)
+		y += 20
+	}
 
-		// RESTART INSTRUCTIONS
-		instructionFace := &text.GoTextFace{
-			Source: mplusFaceSource,
-			Size:   24,
+	if g.mode == ModeSolo {
+		p := g.world.Players[0]
+		level := g.levelName()
+		if g.variant == VariantDaily {
+			level = trf("Daily %s", g.daily)
 		}
-		instructionText := "Press ENTER or SPACE to restart"
-		iw, _ := text.Measure(instructionText, instructionFace, instructionFace.Size)
+		line(trf("Score: %d  Length: %d  Level: %s", p.Score, p.Snake.Len(), level), g.theme.HUD)
+		status := trf("Time: %s", formatRunTime(g.elapsed(now)))
+		switch g.variant {
+		case VariantTimeAttack:
+			status += trf("  Goal: length %d", timeAttackLength)
+		case VariantTimed:
+			status = trf("Time left: %s", formatRunTime(max(timedDuration-g.elapsed(now), 0)))
+		case VariantSurvival:
+			if a := g.world.Arena; a != nil && g.world.ShrinkWarning(now, a.Interval) {
+				status += trf("  Walls close in %ds", int(a.NextShrinkAt.Sub(now).Seconds()+0.999))
+			}
+		}
+		line(status, g.theme.HUD)
+	} else {
+		line(trf("Level: %s  Time: %s", g.levelName(), formatRunTime(g.elapsed(now))), g.theme.HUD)
+		switch g.mode {
+		case ModeRoyale:
+			line(trf("Snakes left: %d of %d", g.world.AlivePlayers(), len(g.world.Players)), g.theme.HUD)
+		case ModeDemo:
+			cells := g.world.Width * g.world.Height
+			line(trf("Board filled: %d%%", g.world.Players[0].Snake.Len()*100/cells), g.theme.HUD)
+		}
+		for i, p := range g.trackedPlayers() {
+			msg := trf("%s  Score: %d  Length: %d", tr(p.Name), p.Score, p.Snake.Len())
+			if g.mode == ModeSplit {
+				msg = trf("%s  Score: %d/%d  Length: %d", tr(p.Name), p.Score, splitTargetScore, p.Snake.Len())
+			}
+			line(msg, g.playerColor(i))
+		}
+	}
 
-		instructionOp := &text.DrawOptions{}
-		instructionOp.GeoM.Translate(screenWidth/2-iw/2, screenHeight/2+h)
-		instructionOp.ColorScale.ScaleWithColor(color.RGBA{200, 200, 200, 255})
+	// ACTIVE EFFECTS
+	// One line per running effect with its remaining time, in its color,
+	// then one for each snake with a shield
+	for kind := range snake.PowerUpKindCount {
+		left := g.world.Effects.Remaining(kind, now)
+		if left <= 0 {
+			continue
+		}
+		line(fmt.Sprintf("%s %.1fs", label(kind), left.Seconds()), powerUpColors[kind])
+	}
+	for _, p := range g.trackedPlayers() {
+		if !p.Shield || p.Dead {
+			continue
+		}
+		msg := tr("Shield")
+		if g.mode != ModeSolo {
+			msg = tr(p.Name) + " " + msg
+		}
+		line(msg, powerUpColors[snake.PowerUpShield])
+	}
 
-		text.Draw(screen, instructionText, instructionFace, instructionOp)
+	// DASH
+	// A bar for every snake its player can dash with, filling up as the
+	// cooldown runs out
+	for i, p := range g.trackedPlayers() {
+		if _, ok := p.Controller.(snake.Dasher); !ok || p.Dead {
+			continue
+		}
+		msg := tr("Dash")
+		if g.mode != ModeSolo {
+			msg = tr(p.Name) + " " + msg
+		}
+		charge := p.DashCharge(now)
+		clr := g.playerColor(i)
+		if charge < 1 {
+			clr = faded(clr, 0.5)
+		}
+		drawText(screen, msg, 16, left, y, clr)
+		x := float32(left + 8 + measureText(msg, 16))
+		drawFrame(screen, x, float32(y)+4, dashBarWidth, 12, 1, clr)
+		fillRect(screen, x+2, float32(y)+6, (dashBarWidth-4)*float32(charge), 8, clr, false)
+		y += 20
+	}
+
+	// COMBOS
+	// The multiplier of every snake on a combo, with the time left to
+	// keep it going
+	for _, p := range g.trackedPlayers() {
+		m := p.Combo.Multiplier(now)
+		if m <= 1 {
+			continue
+		}
+		msg := trf("Combo x%d %.1fs", m, p.Combo.Remaining(now).Seconds())
+		if g.mode != ModeSolo {
+			msg = tr(p.Name) + " " + msg
+		}
+		line(msg, comboColor)
 	}
 }
 
-// Layout defines the screen size
-// Called by Ebiten to determine the game's logical screen dimensions
-func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return screenWidth, screenHeight
+// dashBarWidth is the width of the dash cooldown bar in the HUD
+const dashBarWidth = 60
+
+// comboColor is the HUD color of the combo multiplier
+var comboColor = color.RGBA{255, 215, 0, 255}
+
+// levelName returns the display name of the current level, including the
+// seed for generated levels so a good maze can be shared and replayed
+func (g *Game) levelName() string {
+	lvl := g.levels[g.level]
+	if lvl.Seeded {
+		return fmt.Sprintf("%s #%d", tr(lvl.Name), g.mazeSeed)
+	}
+	return tr(lvl.Name)
+}
+
+// enemyCount returns how many enemies the next run starts with
+// The daily challenge has none, so it is the same for everyone, and nor
+// does the demo, whose bot can't get around them. A TAS run has as many
+// as its replay says.
+func (g *Game) enemyCount() int {
+	if g.tas != nil {
+		return g.tas.replay.Enemies
+	}
+	if g.variant == VariantDaily || g.mode == ModeDemo {
+		return 0
+	}
+	return g.settings.Enemies
+}
+
+// foodLifetime returns how long food lasts before moving in this run
+// Timed runs put food on the clock even when the difficulty doesn't, to
+// keep the pressure on; the daily challenge is the same for everyone, so
+// its food never moves.
+func (g *Game) foodLifetime() time.Duration {
+	switch {
+	case g.variant == VariantDaily:
+		return 0
+	case g.difficulty.FoodLifetime > 0:
+		return g.difficulty.FoodDuration()
+	case g.variant == VariantTimed || g.variant == VariantTimeAttack:
+		return timedFoodLifetime
+	}
+	return 0
+}
+
+// foodSpread returns how strongly food is placed away from the snakes in
+// this run (see snake.World.FoodSpread); not in the daily challenge,
+// which is the same for everyone
+func (g *Game) foodSpread() int {
+	if g.variant == VariantDaily {
+		return 0
+	}
+	return g.difficulty.FoodSpread
 }
 
-// spawnFood generates a new food position at a random grid location
-// Note: This doesn't check if food spawns on the snake (could be improved)
-func (g *Game) spawnFood() {
-	g.food = Point{
-		x: rand.IntN(screenWidth / gridSize),
-		y: rand.IntN(screenHeight / gridSize),
+// boardCells returns the board size in cells for a run on lvl
+// Levels with fixed dimensions use those and the daily challenge has its
+// own, as does a TAS run (its replay's); the rest follow the -board or
+// -grid flag, then the custom size or the board size from the settings.
+// Battle royale has its own big board.
+// In split screen, where each board gets half the screen, boards are half
+// as wide, and the demo needs an even number of cells.
+func (g *Game) boardCells(lvl Level) (int, int) {
+	size := g.settings.boardCells()
+	switch {
+	case g.tas != nil:
+		size = [2]int{g.tas.replay.Width, g.tas.replay.Height}
+	case g.mode == ModeRoyale:
+		size = royaleBoard
+	case g.variant == VariantDaily:
+		size = boardSizeCells[dailyBoard]
+	case g.launch.board != GridSize{}:
+		size = [2]int{g.launch.board.Width, g.launch.board.Height}
+	case g.launch.cellSize > 0:
+		size = [2]int{screenWidth / g.launch.cellSize, screenHeight / g.launch.cellSize}
+	}
+	switch {
+	case g.mode == ModeSplit:
+		size[0] = max(size[0]/2, minLevelCells)
+	case g.mode == ModeDemo && size[0]*size[1]%2 != 0:
+		size[0]--
 	}
+	return lvl.boardSize(size[0], size[1])
 }
 
 // resetGame resets all game state to initial conditions for a new game
 func (g *Game) resetGame() {
-	// Reset snake to starting position (center of screen, length 2)
-	g.snake = []Point{
-		{
-			x: screenWidth / gridSize / 2,
-			y: screenHeight / gridSize / 2,
-		},
-		{
-			x: screenWidth/gridSize/2 - 1,
-			y: screenHeight / gridSize / 2,
-		},
+	lvl := g.levels[g.level]
+
+	// Size the board (see boardCells)
+	w, h := g.boardCells(lvl)
+
+	// Reset the snakes to the level's starting positions (center of
+	// screen, moving right, unless the level says otherwise) with no
+	// points
+	keys1 := newKeyboardController(g, playerSteering[0])
+	keys2 := newKeyboardController(g, playerSteering[1])
+	bot := newBot(g.settings.BotLevel)
+	start1, start2 := lvl.versusStarts(w, h)
+	var players []*snake.Player
+	switch g.mode {
+	case ModeSplit:
+		// Each player gets a board of their own (see resetSplit)
+	case ModeVersus:
+		players = []*snake.Player{newPlayer("Player 1", start1, keys1), newPlayer("Player 2", start2, keys2)}
+	case ModeVsComputer:
+		players = []*snake.Player{newPlayer("You", start1, keys1), newPlayer("Computer", start2, bot)}
+	case ModeBotMatch:
+		// Bots keep no state between ticks, so both snakes can share one
+		players = []*snake.Player{newPlayer("Bot 1", start1, bot), newPlayer("Bot 2", start2, bot)}
+	case ModeRoyale:
+		players = g.royalePlayers(w, h, keys1, bot)
+	case ModeDemo:
+		players = []*snake.Player{newPlayer("Demo", demoStart(w, h), &snake.HamiltonBot{})}
+	default:
+		players = []*snake.Player{newPlayer("Player 1", lvl.snakeStart(w, h), keys1)}
 	}
 
-	// Reset direction to moving right
-	g.direction = Point{x: 1, y: 0}
+	// A new run gets a new random sequence (the same one, for fixed seeds)
+	g.seedRun()
+	g.runStart = g.clock.Now()
+	g.runStats = Stats{}
+	g.finished = false
+	g.checkpoint = nil
 
-	// Clear game over flag
-	g.gameOver = false
+	if g.mode == ModeSplit {
+		g.resetSplit(lvl, w, h, keys1, keys2)
+	} else {
+		g.setBoards(1)
+		g.resetBoard(lvl, w, h, fullScreen, players, rand.New(g.rngSource))
+		g.world.DeadLeaveFood = g.mode == ModeRoyale
+		g.world.NoFleeing = g.mode == ModeDemo
+	}
+	g.applyCheats()
+}
+
+// resetBoard sets the current board up for a new run of players on lvl,
+// w×h cells big and fitted into area, with its random choices drawn from
+// rnd
+func (g *Game) resetBoard(lvl Level, w, h int, area viewport, players []*snake.Player, rnd *rand.Rand) {
+	g.view = newBoardView(w, h, area)
 
 	// Reset last update time to prevent immediate movement
-	g.lastUpdate = time.Now()
+	g.lastUpdate = g.runStart
+	g.prevSnakes = nil
+	g.ticks = 0
+	g.vanished = nil
+	g.particles.clear()
 
-	// Spawn new food
-	g.spawnFood()
+	// Build the board with the walls for the selected level, then let
+	// the world place the first food and schedule the first power-up
+	g.world = &snake.World{
+		Width:        w,
+		Height:       h,
+		Obstacles:    g.obstacleSet(lvl, w, h),
+		FoodSpawns:   lvl.FoodSpawns,
+		Portals:      lvl.portals(w, h),
+		Checkpoints:  g.checkpoints(lvl),
+		Players:      players,
+		Arena:        g.arena(),
+		EnemyCount:   g.enemyCount(),
+		FoodLifetime: g.foodLifetime(),
+		FoodSpread:   g.foodSpread(),
+		Rand:         rnd,
+	}
+	if b := g.boss(lvl, w, h); b != nil {
+		g.world.Entities.Add(b)
+	}
+	g.world.Start(g.lastUpdate)
+	g.followSnakes(true)
+	g.minimap.invalidate()
+	bw, bh := g.view.size()
+	g.trail.reset(int(bw), int(bh))
+	g.findHints()
 }
 
 // main is the entry point of the program
 // Sets up the game and starts the game loop
 func main() {
+	// COMMAND-LINE FLAGS
+	// e.g. -grid 32 -tps 10 -seed 42 -fullscreen (see flags.go)
+	opts, err := parseFlags(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// ASSETS
+	// The sprites, sounds and font are built in, but can be replaced by
+	// files in ./assets (see assets.go). The browser has no assets folder
+	// to read.
+	assetsDir := assetsDirName
+	if isWeb {
+		assetsDir = ""
+	}
+	assets := newAssets(assetsDir)
+
 	// FONT INITIALIZATION
-	// Load the embedded font for rendering text
-	s, err := text.NewGoTextFaceSource(
-		bytes.NewReader(fonts.MPlus1pRegular_ttf),
-	)
+	// Load the font for rendering text
+	mplusFaceSource, err = assets.Font(mplusFontName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The translations are embedded, like the assets; the language is
+	// picked from the settings when the profile is loaded
+	languages, err = loadLanguages()
 	if err != nil {
 		log.Fatal(err)
 	}
-	mplusFaceSource = s
 
 	// GAME INITIALIZATION
-	// Create initial game state with snake in center
+	// Settings that survive restarts are set here; everything per-run
+	// (snake in the center, food, timers) is set up by resetGame
 	g := &Game{
-		snake: []Point{
-			{
-				x: screenWidth / gridSize / 2,
-				y: screenHeight / gridSize / 2,
-			},
-			{
-				x: screenWidth/gridSize/2 - 1,
-				y: screenHeight/gridSize/2 - 1,
-			},
-		},
-		direction:  Point{x: 1, y: 0}, // Start moving right
-		lastUpdate: time.Now(),        // Initialize timer
-	}
-
-	// Spawn initial food
-	g.spawnFood()
+		clock:         snake.RealClock{},
+		settings:      defaultSettings(),
+		runDifficulty: DifficultyNormal,
+		lastRank:      -1,
+		levels:        builtInLevels,
+		systems:       newSystems(),
+	}
+	g.setBoards(1)
+	g.subscribeEffects()
+	g.subscribeStats()
+
+	// SPRITES
+	// The atlas is built in, so failing to load it is a build problem
+	img, err := assets.Image(atlasFileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	atlas, err := loadAtlas(img)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g.sprites = atlas
+
+	// AUDIO
+	// Without a working audio device the game just plays silently
+	if a, err := newAudio(assets); err != nil {
+		log.Printf("sound disabled: %v", err)
+	} else {
+		g.audio = a
+	}
+
+	// PROFILE
+	// Settings, high scores and the saved game all belong to the profile
+	// used last (see profile.go)
+	g.profiles = &profileIndex{Current: defaultProfileName, Names: []string{defaultProfileName}}
+	if path, err := profileIndexPath(); err != nil {
+		log.Printf("profiles won't be saved: %v", err)
+	} else {
+		ix, err := loadProfileIndex(path)
+		if err != nil {
+			log.Printf("loading profiles: %v", err)
+		}
+		g.profiles = ix
+	}
+	g.applyLaunchOptions(opts)
+	if opts.pprof != "" {
+		g.prof = startProfiler(opts.pprof, g.systems)
+	}
+	g.loadProfile(g.profiles.Current)
+	g.mazeSeed = g.newMazeSeed()
+
+	// Boot into the title screen
+	g.scenes = NewSceneManager(newTitleScene(g))
+
+	// CUSTOM LEVELS
+	// Level files dropped into ./levels are added after the built-in ones;
+	// broken files are reported and skipped. The browser has no levels
+	// folder to read.
+	if !isWeb {
+		custom, err := loadLevelsDir(levelsDirName)
+		if err != nil {
+			log.Printf("loading custom levels: %v", err)
+		}
+		g.levels = append(g.levels[:len(g.levels):len(g.levels)], custom...)
+
+		// Mods in ./mods are loaded the same way
+		g.mods, err = loadModsDir(modsDirName)
+		if err != nil {
+			log.Printf("loading mods: %v", err)
+		}
+	}
+
+	g.resetGame()
+
+	// -replay, -tas and -demo skip the title screen
+	switch {
+	case opts.replay != "":
+		r, err := loadReplay(opts.replay)
+		if err == nil {
+			err = g.startTAS(r)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case opts.tas:
+		if err := g.startTAS(nil); err != nil {
+			log.Fatal(err)
+		}
+	case opts.demo:
+		g.startGame(ModeDemo)
+	}
 
 	// WINDOW SETUP
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("Snake Game - WASD to move")
+	// The logical screen is screenWidth×screenHeight; a different
+	// window size from the settings, or resizing the window, just scales it
+	ebiten.SetWindowSize(g.settings.Window.Width, g.settings.Window.Height)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetWindowSizeLimits(minWindowWidth, minWindowHeight, -1, -1)
+	ebiten.SetFullscreen(opts.fullscreen || g.settings.Fullscreen)
+	ebiten.SetWindowTitle(tr("Snake Game - WASD/Arrows to move, P to pause"))
+	// Update sees the close request first, so it can save a run in progress
+	ebiten.SetWindowClosingHandled(true)
+
+	// The music starts with the title screen and loops from then on
+	g.audio.playMusic()
 
 	// START GAME LOOP
 	// This blocks until the game window is closed
 	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"math/rand/v2"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+const (
+	// mazeRoomSize is the width/height in grid cells of one maze "room":
+	// three open cells plus one wall cell. Single-cell corridors would be
+	// unplayable for a snake, so the maze is carved on this coarser grid.
+	mazeRoomSize = 4
+
+	// mazeExtraOpenings is the fraction of remaining interior walls that
+	// get knocked out after carving. A perfect maze has exactly one route
+	// between any two rooms, which turns into a dead end for a long snake;
+	// the extra openings add loops.
+	mazeExtraOpenings = 0.25
+
+	// mazeStartClearance is how many cells around the snake's starting
+	// position are always left open
+	mazeStartClearance = 2
+)
+
+// generateMaze builds the wall cells of a randomized maze using a
+// depth-first search over the room grid. The same seed always produces
+// the same maze.
+//
+// The result guarantees that:
+//   - the area around the snake's starting position is open
+//   - every open cell is reachable from the start, so food can never
+//     spawn somewhere the snake can't get to
+func generateMaze(seed uint64, gridW, gridH int, start snake.Point) []snake.Point {
+	rng := rand.New(rand.NewPCG(seed, seed^0x5DEECE66D))
+
+	roomsW := gridW / mazeRoomSize
+	roomsH := gridH / mazeRoomSize
+
+	// openRight[y][x] / openDown[y][x] record which walls of room (x, y)
+	// have been removed
+	openRight := make([][]bool, roomsH)
+	openDown := make([][]bool, roomsH)
+	visited := make([][]bool, roomsH)
+	for y := range roomsH {
+		openRight[y] = make([]bool, roomsW)
+		openDown[y] = make([]bool, roomsW)
+		visited[y] = make([]bool, roomsW)
+	}
+
+	// RANDOMIZED DEPTH-FIRST SEARCH
+	// Walk from room to room, knocking down the wall to a random unvisited
+	// neighbor, and backtrack when stuck. This visits every room, so the
+	// whole maze is connected.
+	stack := []snake.Point{{X: rng.IntN(roomsW), Y: rng.IntN(roomsH)}}
+	visited[stack[0].Y][stack[0].X] = true
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+
+		var next []snake.Point
+		for _, d := range snake.Directions {
+			n := cur.Add(d)
+			if n.X >= 0 && n.Y >= 0 && n.X < roomsW && n.Y < roomsH && !visited[n.Y][n.X] {
+				next = append(next, n)
+			}
+		}
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		n := next[rng.IntN(len(next))]
+		openWallBetween(openRight, openDown, cur, n)
+		visited[n.Y][n.X] = true
+		stack = append(stack, n)
+	}
+
+	// BRAIDING
+	// Remove some extra walls to create loops
+	for y := range roomsH {
+		for x := range roomsW {
+			if x < roomsW-1 && !openRight[y][x] && rng.Float64() < mazeExtraOpenings {
+				openRight[y][x] = true
+			}
+			if y < roomsH-1 && !openDown[y][x] && rng.Float64() < mazeExtraOpenings {
+				openDown[y][x] = true
+			}
+		}
+	}
+
+	// RASTERIZE
+	// Each room's right and bottom edges become wall cells unless opened.
+	// The outermost edges are skipped: the screen border is already a wall.
+	walls := make(map[snake.Point]bool)
+	for ry := range roomsH {
+		for rx := range roomsW {
+			wx := rx*mazeRoomSize + mazeRoomSize - 1
+			wy := ry*mazeRoomSize + mazeRoomSize - 1
+			lastCol := rx == roomsW-1
+			lastRow := ry == roomsH-1
+
+			if !lastCol && !openRight[ry][rx] {
+				for y := ry * mazeRoomSize; y < wy; y++ {
+					walls[snake.Point{X: wx, Y: y}] = true
+				}
+			}
+			if !lastRow && !openDown[ry][rx] {
+				for x := rx * mazeRoomSize; x < wx; x++ {
+					walls[snake.Point{X: x, Y: wy}] = true
+				}
+			}
+			// Corner posts keep the wall lines joined up
+			if !lastCol && !lastRow {
+				walls[snake.Point{X: wx, Y: wy}] = true
+			}
+		}
+	}
+
+	// START CLEARANCE
+	// The start position doesn't line up with the room grid, so clear a
+	// pocket around the starting snake and the cells ahead of it
+	for y := start.Y - mazeStartClearance; y <= start.Y+mazeStartClearance; y++ {
+		for x := start.X - mazeStartClearance - 1; x <= start.X+mazeStartClearance*2; x++ {
+			delete(walls, snake.Point{X: x, Y: y})
+		}
+	}
+
+	// REACHABILITY
+	// Flood fill from the start; any open cell the fill can't reach is
+	// turned into wall so food never spawns in a sealed pocket
+	reachable := snake.FloodFill(start, gridW, gridH, walls)
+	for y := range gridH {
+		for x := range gridW {
+			p := snake.Point{X: x, Y: y}
+			if !walls[p] && !reachable[p] {
+				walls[p] = true
+			}
+		}
+	}
+
+	pts := make([]snake.Point, 0, len(walls))
+	for y := range gridH {
+		for x := range gridW {
+			if p := (snake.Point{X: x, Y: y}); walls[p] {
+				pts = append(pts, p)
+			}
+		}
+	}
+	return pts
+}
+
+// openWallBetween records that the wall between adjacent rooms a and b
+// has been removed
+func openWallBetween(openRight, openDown [][]bool, a, b snake.Point) {
+	switch {
+	case b.X == a.X+1:
+		openRight[a.Y][a.X] = true
+	case b.X == a.X-1:
+		openRight[b.Y][b.X] = true
+	case b.Y == a.Y+1:
+		openDown[a.Y][a.X] = true
+	case b.Y == a.Y-1:
+		openDown[b.Y][b.X] = true
+	}
+}
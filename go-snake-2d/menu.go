@@ -0,0 +1,702 @@
+package main
+
+import (
+	"cmp"
+	"image/color"
+	"log"
+	"unicode"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Title menu entries, in display order
+const (
+	titleContinue = iota
+	titlePlay
+	titleVersus
+	titleSplit
+	titleVsComputer
+	titleBotMatch
+	titleRoyale
+	titleDemo
+	titleOptions
+	titleLeaderboards
+	titleStats
+	titleProfile
+	titleQuit
+	titleCount
+)
+
+// titleItems are the labels of the title menu entries
+var titleItems = [titleCount]string{
+	titleContinue:     "Continue",
+	titlePlay:         "Play",
+	titleVersus:       "2 Players",
+	titleSplit:        "Split Screen",
+	titleVsComputer:   "Vs Computer",
+	titleBotMatch:     "Bot Match",
+	titleRoyale:       "Battle Royale",
+	titleDemo:         "Demo",
+	titleOptions:      "Options",
+	titleLeaderboards: "Leaderboards",
+	titleStats:        "Stats",
+	titleProfile:      "Profile",
+	titleQuit:         "Quit",
+}
+
+// Options menu entries, in display order
+const (
+	optionsLanguage = iota
+	optionsLevel
+	optionsDifficulty
+	optionsBoard
+	optionsBackground
+	optionsSFXVolume
+	optionsMusicVolume
+	optionsControls
+	optionsKeys
+	optionsTouchDPad
+	optionsPalette
+	optionsPatterns
+	optionsSnakeStyle
+	optionsTrails
+	optionsStarfield
+	optionsVignette
+	optionsScanlines
+	optionsDayNight
+	optionsMirror
+	optionsHints
+	optionsBotLevel
+	optionsEnemies
+	optionsRivals
+	optionsResolution
+	optionsUpdateRate
+	optionsVSync
+	optionsBack
+	optionsCount
+)
+
+// menuTextColor and menuSelectedColor are the theme's Text and Highlight
+// colors, set by applySettings
+var (
+	menuTextColor     = color.RGBA{200, 200, 200, 255}
+	menuSelectedColor = color.RGBA{255, 215, 0, 255}
+)
+
+// Default menu text size and the vertical spacing between entries, in
+// pixels
+const (
+	menuTextSize   = 28
+	menuItemHeight = 44
+)
+
+// Menu is a vertical list of entries navigated with the keyboard or by
+// tapping
+type Menu struct {
+	Items    []string
+	Selected int
+
+	// TextSize and ItemHeight override the defaults for long menus
+	// (zero = menuTextSize and menuItemHeight)
+	TextSize   float64
+	ItemHeight float64
+
+	// Rows, when set, is how many entries fit on screen; a longer menu
+	// scrolls to keep the selected entry in view
+	Rows int
+
+	// top is where the first entry was last drawn, for hit-testing taps,
+	// and scroll the index of the first entry shown
+	top    float64
+	scroll int
+}
+
+// update moves the selection with the up/down bindings (wrapping around)
+// and reports the selected index when the player confirms
+// Either player's up/down works, so the arrow keys still navigate after
+// a versus round, and holding one repeats. Tapping an entry selects and
+// confirms it in one go.
+func (m *Menu) update(in actionInput) (chosen int, ok bool) {
+	if _, y, tapped := in.tap(); tapped {
+		// Entries are drawn from their top edge; the band around each
+		// one is a little taller than the text so it is easy to hit
+		h := m.itemHeight()
+		row := int((float64(y) - m.top + h/4) / h)
+		i := m.scroll + row
+		if float64(y) >= m.top-h/4 && row < m.rows() && i >= 0 && i < len(m.Items) {
+			m.Selected = i
+			return i, true
+		}
+		return 0, false
+	}
+	if in.isRepeated(ActionMoveUp) || in.isRepeated(ActionP2MoveUp) {
+		m.Selected = (m.Selected - 1 + len(m.Items)) % len(m.Items)
+	}
+	if in.isRepeated(ActionMoveDown) || in.isRepeated(ActionP2MoveDown) {
+		m.Selected = (m.Selected + 1) % len(m.Items)
+	}
+	if in.isJustPressed(ActionSelect) {
+		return m.Selected, true
+	}
+	return 0, false
+}
+
+// itemHeight returns the spacing between entries
+func (m *Menu) itemHeight() float64 {
+	return cmp.Or(m.ItemHeight, menuItemHeight)
+}
+
+// rows returns how many entries are shown at once
+func (m *Menu) rows() int {
+	if m.Rows <= 0 {
+		return len(m.Items)
+	}
+	return min(m.Rows, len(m.Items))
+}
+
+// updateMenu updates m with sound feedback: a tick when the selection
+// moves and a chime when an entry is chosen
+func (g *Game) updateMenu(m *Menu) (chosen int, ok bool) {
+	prev := m.Selected
+	chosen, ok = m.update(g)
+	switch {
+	case ok:
+		g.audio.play(SoundMenuSelect)
+	case m.Selected != prev:
+		g.audio.play(SoundMenuMove)
+	}
+	return chosen, ok
+}
+
+// draw renders the entries centered, starting at y, with the selected
+// entry highlighted and marked
+// A menu that scrolls shows arrows above and below when there are more
+// entries that way.
+func (m *Menu) draw(screen *ebiten.Image, y float64) {
+	m.top = y
+	rows := m.rows()
+	m.scroll = min(max(m.scroll, m.Selected-rows+1), m.Selected)
+	m.scroll = min(max(m.scroll, 0), len(m.Items)-rows)
+	if m.scroll > 0 {
+		drawCenteredText(screen, "^", 16, y-m.itemHeight()*0.9, menuTextColor)
+	}
+	for i := m.scroll; i < m.scroll+rows; i++ {
+		item := m.Items[i]
+		clr := menuTextColor
+		if i == m.Selected {
+			clr = menuSelectedColor
+			item = "> " + item + " <"
+		}
+		drawCenteredText(screen, item, cmp.Or(m.TextSize, menuTextSize), y, clr)
+		y += m.itemHeight()
+	}
+	if m.scroll+rows < len(m.Items) {
+		drawCenteredText(screen, "v", 16, y, menuTextColor)
+	}
+}
+
+// TitleScene is the main menu shown at startup
+type TitleScene struct {
+	g    *Game
+	menu Menu
+
+	// entries maps each menu row to its title* entry, since some entries
+	// are only shown sometimes
+	entries []int
+}
+
+// newTitleScene creates the title screen with the first entry selected
+// "Continue" is only offered when there is a saved run, and in the
+// browser there is no window to close, so "Quit" is left out.
+func newTitleScene(g *Game) *TitleScene {
+	s := &TitleScene{g: g, menu: Menu{TextSize: 24, ItemHeight: 26, Rows: 10}}
+	for id, item := range titleItems {
+		if id == titleContinue && !g.hasSavedGame() || id == titleQuit && isWeb {
+			continue
+		}
+		s.entries = append(s.entries, id)
+		s.menu.Items = append(s.menu.Items, tr(item))
+	}
+	return s
+}
+
+// Update handles menu navigation on the title screen
+// Left/right on "Play" picks the variant of solo runs.
+func (s *TitleScene) Update() error {
+	if s.entries[s.menu.Selected] == titlePlay {
+		delta := 0
+		if s.g.isRepeated(ActionMoveRight) || s.g.isRepeated(ActionP2MoveRight) {
+			delta = 1
+		}
+		if s.g.isRepeated(ActionMoveLeft) || s.g.isRepeated(ActionP2MoveLeft) {
+			delta = -1
+		}
+		if delta != 0 {
+			s.g.settings.Variant = Variant(cycle(int(s.g.settings.Variant), delta, int(variantCount)))
+			s.g.audio.play(SoundMenuMove)
+			if err := s.g.settings.Save(); err != nil {
+				log.Printf("saving settings: %v", err)
+			}
+		}
+	}
+
+	chosen, ok := s.g.updateMenu(&s.menu)
+	if !ok {
+		return nil
+	}
+
+	switch s.entries[chosen] {
+	case titleContinue:
+		if err := s.g.continueGame(); err != nil {
+			// A save that can't be restored is dropped, so it isn't
+			// offered again
+			log.Printf("continuing saved game: %v", err)
+			s.g.deleteSavedGame()
+			s.g.scenes.Switch(newTitleScene(s.g))
+		}
+	case titlePlay:
+		s.g.startGame(ModeSolo)
+	case titleVersus:
+		s.g.startGame(ModeVersus)
+	case titleSplit:
+		s.g.startGame(ModeSplit)
+	case titleVsComputer:
+		s.g.startGame(ModeVsComputer)
+	case titleBotMatch:
+		s.g.startGame(ModeBotMatch)
+	case titleRoyale:
+		s.g.startGame(ModeRoyale)
+	case titleDemo:
+		s.g.startGame(ModeDemo)
+	case titleOptions:
+		s.g.scenes.Switch(newOptionsScene(s.g, s))
+	case titleLeaderboards:
+		s.g.scenes.Switch(newLeaderboardScene(s.g))
+	case titleStats:
+		s.g.scenes.Switch(newStatsScene(s.g))
+	case titleProfile:
+		s.g.scenes.Switch(newProfileScene(s.g))
+	case titleQuit:
+		// Returning ebiten.Termination ends RunGame cleanly
+		return ebiten.Termination
+	}
+	return nil
+}
+
+// Draw renders the title screen
+func (s *TitleScene) Draw(screen *ebiten.Image) {
+	drawCenteredText(screen, "SNAKE", 72, 60, color.RGBA{0, 220, 0, 255})
+
+	// The best run of the selected variant and difficulty (today's, for
+	// the daily challenge)
+	variant, difficulty := s.g.settings.Variant, s.g.settings.Difficulty
+	if hs := s.g.highScores; hs != nil && len(hs.Table(variant, difficulty)) > 0 {
+		top := hs.Table(variant, difficulty)[0]
+		best := trf("Best: %d (%s)", top.Score, label(difficulty))
+		switch {
+		case variant.ranksByTime():
+			best = trf("Best time: %s (%s)", formatRunTime(top.Time), label(difficulty))
+		case variant == VariantDaily:
+			best = ""
+			if today := dailyDate(s.g.clock.Now()); top.Challenge == today {
+				best = trf("Today's best: %d", top.Score)
+			}
+		}
+		drawCenteredText(screen, best, 20, 150, menuTextColor)
+	}
+
+	// The labels are set every frame, so they follow a language change
+	for i, id := range s.entries {
+		switch id {
+		case titlePlay:
+			s.menu.Items[i] = trf("Play: < %s >", label(variant))
+		case titleProfile:
+			s.menu.Items[i] = trf("Profile: %s", s.g.profile)
+		default:
+			s.menu.Items[i] = tr(titleItems[id])
+		}
+	}
+	s.menu.draw(screen, 176)
+
+	drawCenteredText(screen, tr("Up/Down to choose, ENTER to select"), 16, screenHeight-40, menuTextColor)
+}
+
+// OptionsScene lets the player change settings
+// Changes apply to the game straight away and are saved when leaving
+// the screen.
+type OptionsScene struct {
+	g    *Game
+	menu Menu
+
+	// back is the scene to return to: the title screen, or the pause
+	// menu when opened during a run
+	back Scene
+
+	// midRun is set when opened during a run, which locks the level: the
+	// run (and a save of it) belongs to the level it started on
+	midRun bool
+}
+
+// newOptionsScene creates the options screen, returning to back when
+// closed
+func newOptionsScene(g *Game, back Scene) *OptionsScene {
+	_, midRun := back.(*PausedScene)
+	return &OptionsScene{
+		g:      g,
+		menu:   Menu{Items: make([]string, optionsCount), TextSize: 20, ItemHeight: 24, Rows: 13},
+		back:   back,
+		midRun: midRun,
+	}
+}
+
+// Update handles the options screen
+// Left/right changes the highlighted setting, as does selecting it (which
+// steps forward, so settings can be changed by tapping); Back or Escape
+// returns to the title screen.
+func (s *OptionsScene) Update() error {
+	if s.g.isJustPressed(ActionBack) {
+		s.close()
+		return nil
+	}
+
+	delta := 0
+	if s.g.isRepeated(ActionMoveRight) || s.g.isRepeated(ActionP2MoveRight) {
+		delta = 1
+	}
+	if s.g.isRepeated(ActionMoveLeft) || s.g.isRepeated(ActionP2MoveLeft) {
+		delta = -1
+	}
+	if delta != 0 {
+		s.change(s.menu.Selected, delta)
+		s.g.audio.play(SoundMenuMove)
+	}
+
+	chosen, ok := s.g.updateMenu(&s.menu)
+	if !ok {
+		return nil
+	}
+	switch chosen {
+	case optionsBack:
+		s.close()
+		return nil
+	case optionsKeys:
+		s.g.scenes.Switch(newControlsScene(s.g, s))
+		return nil
+	}
+	s.change(chosen, 1)
+	return nil
+}
+
+// change steps the setting at menu index item by delta and applies it
+func (s *OptionsScene) change(item, delta int) {
+	g := s.g
+	switch item {
+	case optionsLanguage:
+		i := cycle(findLanguage(g.settings.Language), delta, len(languages))
+		g.settings.Language = languages[i].Code
+	case optionsLevel:
+		if !s.midRun {
+			g.selectLevel(g.level + delta)
+		}
+	case optionsDifficulty:
+		if s.midRun {
+			break
+		}
+		g.settings.Difficulty = Difficulty(cycle(int(g.settings.Difficulty), delta, int(difficultyCount)))
+	case optionsBoard:
+		// Stepping away from a custom grid goes back to the presets
+		if g.settings.Grid != nil {
+			g.settings.Grid = nil
+			break
+		}
+		g.settings.Board = BoardSize(cycle(int(g.settings.Board), delta, int(boardSizeCount)))
+	case optionsBackground:
+		g.settings.Background = BackgroundStyle(cycle(int(g.settings.Background), delta, int(backgroundStyleCount)))
+	case optionsSFXVolume:
+		g.settings.SFXVolume = stepVolume(g.settings.SFXVolume, delta)
+	case optionsMusicVolume:
+		g.settings.MusicVolume = stepVolume(g.settings.MusicVolume, delta)
+	case optionsControls:
+		g.settings.Controls = ControlScheme(cycle(int(g.settings.Controls), delta, int(controlSchemeCount)))
+	case optionsTouchDPad:
+		g.settings.TouchDPad = !g.settings.TouchDPad
+	case optionsPalette:
+		g.settings.Palette = Palette(cycle(int(g.settings.Palette), delta, int(paletteCount)))
+	case optionsPatterns:
+		g.settings.Patterns = !g.settings.Patterns
+	case optionsSnakeStyle:
+		g.settings.SnakeStyle = SnakeStyle(cycle(int(g.settings.SnakeStyle), delta, int(snakeStyleCount)))
+	case optionsTrails:
+		g.settings.Trails = !g.settings.Trails
+	case optionsStarfield:
+		g.settings.Starfield = !g.settings.Starfield
+	case optionsVignette:
+		g.settings.Vignette = !g.settings.Vignette
+	case optionsScanlines:
+		g.settings.Scanlines = !g.settings.Scanlines
+	case optionsDayNight:
+		g.settings.DayNight = DayNightCycle(cycle(int(g.settings.DayNight), delta, int(dayNightCycleCount)))
+	case optionsMirror:
+		g.settings.Mirror = !g.settings.Mirror
+	case optionsHints:
+		g.settings.Hints = !g.settings.Hints
+	case optionsBotLevel:
+		g.settings.BotLevel = BotLevel(cycle(int(g.settings.BotLevel), delta, int(botLevelCount)))
+	case optionsEnemies:
+		g.settings.Enemies = cycle(g.settings.Enemies, delta, maxEnemies+1)
+	case optionsRivals:
+		g.settings.Rivals = minRivals + cycle(g.settings.Rivals-minRivals, delta, maxRivals-minRivals+1)
+	case optionsResolution:
+		g.settings.Resolution = Resolution(cycle(int(g.settings.Resolution), delta, int(resolutionCount)))
+	case optionsUpdateRate:
+		g.settings.UpdateRate = g.settings.UpdateRate.step(delta)
+	case optionsVSync:
+		g.settings.VSync = !g.settings.VSync
+	}
+	g.applySettings()
+}
+
+// close saves the settings and returns to the screen the options were
+// opened from
+func (s *OptionsScene) close() {
+	if err := s.g.settings.Save(); err != nil {
+		log.Printf("saving settings: %v", err)
+	}
+	s.g.scenes.Switch(s.back)
+}
+
+// Draw renders the options screen
+func (s *OptionsScene) Draw(screen *ebiten.Image) {
+	g := s.g
+	drawCenteredText(screen, tr("Options"), 48, 40, color.White)
+
+	s.menu.Items[optionsLanguage] = trf("Language: < %s >", lang.Name)
+	s.menu.Items[optionsLevel] = trf("Level: < %s >", g.levelName())
+	if s.midRun {
+		s.menu.Items[optionsLevel] = trf("Level: %s (fixed for this run)", g.levelName())
+	}
+	s.menu.Items[optionsDifficulty] = trf("Difficulty: < %s >", label(g.settings.Difficulty))
+	if s.midRun {
+		s.menu.Items[optionsDifficulty] = trf("Difficulty: %s (fixed for this run)", label(g.runDifficulty))
+	}
+	s.menu.Items[optionsBoard] = trf("Board: < %s >", label(g.settings.Board))
+	if g.settings.Grid != nil {
+		s.menu.Items[optionsBoard] = trf("Board: < Custom %s >", g.settings.Grid)
+	}
+	s.menu.Items[optionsBackground] = trf("Background: < %s >", label(g.settings.Background))
+	s.menu.Items[optionsSFXVolume] = trf("Sound: < %d%% >", g.settings.SFXVolume)
+	s.menu.Items[optionsMusicVolume] = trf("Music: < %d%% >", g.settings.MusicVolume)
+	if g.audio.isMuted() {
+		s.menu.Items[optionsSFXVolume] += tr(" (muted, M)")
+		s.menu.Items[optionsMusicVolume] += tr(" (muted, M)")
+	}
+	s.menu.Items[optionsControls] = trf("Controls: < %s >", label(g.settings.Controls))
+	s.menu.Items[optionsKeys] = tr("Key bindings...")
+	s.menu.Items[optionsTouchDPad] = trf("Touch D-pad: < %s >", onOff(g.settings.TouchDPad))
+	s.menu.Items[optionsPalette] = trf("Colors: < %s >", label(g.settings.Palette))
+	s.menu.Items[optionsPatterns] = trf("Shape patterns: < %s >", onOff(g.settings.Patterns))
+	s.menu.Items[optionsSnakeStyle] = trf("Snake: < %s >", label(g.settings.SnakeStyle))
+	s.menu.Items[optionsTrails] = trf("Motion trails: < %s >", onOff(g.settings.Trails))
+	s.menu.Items[optionsStarfield] = trf("Starfield: < %s >", onOff(g.settings.Starfield))
+	s.menu.Items[optionsVignette] = trf("Vignette: < %s >", onOff(g.settings.Vignette))
+	s.menu.Items[optionsScanlines] = trf("Scanlines: < %s >", onOff(g.settings.Scanlines))
+	s.menu.Items[optionsDayNight] = trf("Day/night: < %s >", label(g.settings.DayNight))
+	s.menu.Items[optionsMirror] = trf("Mirror mode: < %s >", onOff(g.settings.Mirror))
+	s.menu.Items[optionsHints] = trf("Route hints: < %s >", onOff(g.settings.Hints))
+	s.menu.Items[optionsBotLevel] = trf("Computer: < %s >", label(g.settings.BotLevel))
+	s.menu.Items[optionsEnemies] = trf("Enemies: < %s >", onOff(false))
+	if n := g.settings.Enemies; n > 0 {
+		s.menu.Items[optionsEnemies] = trf("Enemies: < %d >", n)
+	}
+	s.menu.Items[optionsRivals] = trf("Battle royale bots: < %d >", g.settings.Rivals)
+	s.menu.Items[optionsResolution] = trf("Screen: < %s >", label(g.settings.Resolution))
+	s.menu.Items[optionsUpdateRate] = trf("Update rate: < %d/s >", g.settings.UpdateRate)
+	s.menu.Items[optionsVSync] = trf("VSync: < %s >", onOff(g.settings.VSync))
+	s.menu.Items[optionsBack] = tr("Back")
+	s.menu.draw(screen, 108)
+
+	drawCenteredText(screen, tr("Left/Right to change, ESC to go back"), 16, screenHeight-40, menuTextColor)
+}
+
+// onOff formats a toggle setting for display
+func onOff(b bool) string {
+	if b {
+		return tr("On")
+	}
+	return tr("Off")
+}
+
+// startGame begins a fresh run in the given mode, after a countdown (see
+// newRunScene)
+// Solo runs play the variant chosen on the title screen.
+func (g *Game) startGame(mode GameMode) {
+	g.mode = mode
+	g.variant = VariantClassic
+	if mode == ModeSolo {
+		g.variant = g.settings.Variant
+	}
+	g.daily = ""
+	g.runDifficulty = g.settings.Difficulty
+	g.rivals = g.settings.Rivals
+	g.tas = nil
+	switch {
+	case g.variant == VariantDaily:
+		g.startDaily()
+	case mode == ModeDemo:
+		g.startDemo()
+	}
+	g.applySettings()
+	g.resetGame()
+	g.scenes.Switch(g.newRunScene())
+}
+
+// selectLevel switches to level i (wrapping around the list)
+// A new maze seed is rolled so generated levels look different each time
+// (unless the seed is fixed)
+func (g *Game) selectLevel(i int) {
+	g.level = (i + len(g.levels)) % len(g.levels)
+	g.mazeSeed = g.newMazeSeed()
+}
+
+// ProfileScene lists the player profiles to switch between, and creates
+// new ones
+type ProfileScene struct {
+	g    *Game
+	menu Menu
+}
+
+// newProfileScene creates the profile screen with the current profile
+// selected
+// The entries are the profiles, then "New Profile" and "Back".
+func newProfileScene(g *Game) *ProfileScene {
+	s := &ProfileScene{g: g, menu: Menu{TextSize: 22, ItemHeight: 30}}
+	for i, name := range g.profiles.Names {
+		if name == g.profile {
+			s.menu.Selected = i
+		}
+		s.menu.Items = append(s.menu.Items, name)
+	}
+	s.menu.Items = append(s.menu.Items, tr("New Profile"), tr("Back"))
+	return s
+}
+
+// Update handles the profile screen
+func (s *ProfileScene) Update() error {
+	g := s.g
+	if g.isJustPressed(ActionBack) {
+		g.scenes.Switch(newTitleScene(g))
+		return nil
+	}
+	chosen, ok := g.updateMenu(&s.menu)
+	if !ok {
+		return nil
+	}
+
+	names := g.profiles.Names
+	switch {
+	case chosen < len(names):
+		if names[chosen] != g.profile {
+			g.switchProfile(names[chosen])
+		}
+		g.scenes.Switch(newTitleScene(g))
+	case chosen == len(names):
+		g.scenes.Switch(newTextEntryScene(g, tr("New profile name"), maxProfileNameLength, s.create, s))
+	default:
+		g.scenes.Switch(newTitleScene(g))
+	}
+	return nil
+}
+
+// create adds a profile called name and switches to it
+func (s *ProfileScene) create(name string) error {
+	if err := s.g.profiles.add(name); err != nil {
+		return err
+	}
+	s.g.switchProfile(name)
+	s.g.scenes.Switch(newTitleScene(s.g))
+	return nil
+}
+
+// Draw renders the profile screen
+func (s *ProfileScene) Draw(screen *ebiten.Image) {
+	drawCenteredText(screen, tr("Profiles"), 48, 40, color.White)
+	s.menu.draw(screen, 120)
+	drawCenteredText(screen, tr("Each profile has its own settings, scores and saved game"), 16, screenHeight-64, menuTextColor)
+	drawCenteredText(screen, tr("ENTER to select, ESC to go back"), 16, screenHeight-40, menuTextColor)
+}
+
+// TextEntryScene asks the player to type a line of text
+// Keys are read directly rather than through the bindings: in a text
+// field, letters are letters, Backspace deletes and Escape cancels, and
+// global shortcuts like M for mute are off (see Game.typing).
+type TextEntryScene struct {
+	g      *Game
+	prompt string
+	maxLen int
+	text   []rune
+
+	// done is called with the text when the player confirms; an error
+	// is shown and the player can correct the text
+	done func(string) error
+
+	// back is the scene to return to when cancelled
+	back Scene
+
+	// problem is the last error from done
+	problem string
+
+	// chars is reused between frames to collect typed characters
+	chars []rune
+}
+
+// newTextEntryScene creates a text prompt for up to maxLen characters
+func newTextEntryScene(g *Game, prompt string, maxLen int, done func(string) error, back Scene) *TextEntryScene {
+	return &TextEntryScene{g: g, prompt: prompt, maxLen: maxLen, done: done, back: back}
+}
+
+// Update collects typed characters until Enter or Escape
+func (s *TextEntryScene) Update() error {
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		s.g.scenes.Switch(s.back)
+		return nil
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter):
+		if err := s.done(string(s.text)); err != nil {
+			s.problem = err.Error()
+			s.g.audio.play(SoundPoison)
+		}
+		return nil
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace) || repeatingKey(ebiten.KeyBackspace):
+		s.problem = ""
+		if len(s.text) > 0 {
+			s.text = s.text[:len(s.text)-1]
+		}
+	}
+
+	s.chars = ebiten.AppendInputChars(s.chars[:0])
+	for _, r := range s.chars {
+		if len(s.text) < s.maxLen && unicode.IsPrint(r) {
+			s.text = append(s.text, r)
+		}
+	}
+	return nil
+}
+
+// repeatingKey reports whether a held key should repeat this frame, with
+// the same timing as held menu actions
+func repeatingKey(k ebiten.Key) bool {
+	d := inpututil.KeyPressDuration(k)
+	delay, interval := updates(repeatDelay), updates(repeatInterval)
+	return d >= delay && (d-delay)%interval == 0
+}
+
+// Draw renders the prompt and the text typed so far with a cursor
+func (s *TextEntryScene) Draw(screen *ebiten.Image) {
+	drawCenteredText(screen, s.prompt, 28, 140, color.White)
+	drawCenteredText(screen, string(s.text)+"_", 32, 200, menuSelectedColor)
+	if s.problem != "" {
+		drawCenteredText(screen, s.problem, 16, 260, color.RGBA{255, 100, 100, 255})
+	}
+	drawCenteredText(screen, tr("ENTER to confirm, ESC to cancel"), 16, screenHeight-40, menuTextColor)
+}
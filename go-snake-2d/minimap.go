@@ -0,0 +1,106 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Minimap layout: the map fits in a minimapWidth×minimapHeight box in the
+// top-right corner of the board's viewport, minimapMargin pixels from the
+// edges
+const (
+	minimapWidth  = 120
+	minimapHeight = 90
+	minimapMargin = 8
+)
+
+var (
+	minimapBackground = color.RGBA{0, 0, 0, 180}
+	minimapViewport   = color.RGBA{255, 255, 255, 200}
+)
+
+// Minimap is a small overview of a board too big for the screen, showing
+// the walls, food, enemies and snakes with the part of the board in view
+// outlined
+// The board is drawn into an offscreen image that is only redrawn when
+// something on it has changed, i.e. after a tick; the outline follows the
+// view every frame on top of it.
+type Minimap struct {
+	img *ebiten.Image
+
+	// dirty is set when the board has changed since img was drawn
+	dirty bool
+
+	// scale is the displayScale img was drawn at
+	scale float64
+}
+
+// invalidate marks the minimap for redrawing
+func (m *Minimap) invalidate() {
+	m.dirty = true
+}
+
+// drawMinimap draws the minimap for a scrolling board
+func (g *Game) drawMinimap(screen *ebiten.Image) {
+	v := g.view
+	if !v.scrolls() {
+		return
+	}
+
+	// Each cell becomes a small square, as big as fits the box
+	cell := min(float32(minimapWidth)/float32(v.w), float32(minimapHeight)/float32(v.h))
+	mw, mh := float32(v.w)*cell, float32(v.h)*cell
+	x, y := v.area.x+v.area.w-minimapMargin-mw, v.area.y+minimapMargin
+
+	m := &g.minimap
+	if m.img == nil || m.dirty || m.scale != displayScale {
+		g.renderMinimap(cell, mw, mh)
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(x)*displayScale, float64(y)*displayScale)
+	screen.DrawImage(m.img, op)
+
+	// The part of the board in view
+	bw, bh := v.size()
+	vx, vy := (v.area.x-v.x)/bw*mw, (v.area.y-v.y)/bh*mh
+	vw, vh := min(v.area.w/bw, 1)*mw, min(v.area.h/bh, 1)*mh
+	drawFrame(screen, x+max(vx, 0), y+max(vy, 0), vw, vh, 1, minimapViewport)
+}
+
+// renderMinimap redraws the board into the minimap image, with cells of
+// the given size and the map mw×mh pixels in all
+func (g *Game) renderMinimap(cell, mw, mh float32) {
+	m := &g.minimap
+	w, h := int(float64(mw)*displayScale), int(float64(mh)*displayScale)
+	if m.img == nil || m.img.Bounds().Dx() != w || m.img.Bounds().Dy() != h {
+		if m.img != nil {
+			m.img.Deallocate()
+		}
+		m.img = ebiten.NewImage(w, h)
+	}
+	m.img.Clear()
+	m.dirty, m.scale = false, displayScale
+
+	// Cells are at least a pixel, so single ones don't vanish
+	dot := max(cell, 1)
+	world := g.world
+	fillRect(m.img, 0, 0, mw, mh, minimapBackground, false)
+	for p := range world.Obstacles {
+		fillRect(m.img, float32(p.X)*cell, float32(p.Y)*cell, dot, dot, g.theme.Obstacle, false)
+	}
+	for _, f := range world.Foods {
+		fillRect(m.img, float32(f.Pos.X)*cell, float32(f.Pos.Y)*cell, dot, dot, g.theme.foodColor(f.Kind), false)
+	}
+	for e := range world.Enemies() {
+		fillRect(m.img, float32(e.Pos.X)*cell, float32(e.Pos.Y)*cell, dot, dot, g.theme.Enemy, false)
+	}
+	for i, pl := range world.Players {
+		if pl.Dead {
+			continue
+		}
+		for _, p := range pl.Snake.All() {
+			fillRect(m.img, float32(p.X)*cell, float32(p.Y)*cell, dot, dot, g.playerColor(i), false)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/obliviousorion/go-basics/pkg/script"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// modsDirName is the folder (relative to the working directory) that mods
+// are loaded from
+const modsDirName = "mods"
+
+// MODS
+//
+// Mods are scripts (see package script) with a .mod extension that tweak
+// scoring and spawning, e.g.
+//
+//	# Golden apples are worth triple
+//	on foodEaten
+//	    points = if(food == golden, points * 3, points)
+//
+//	# A bonus apple every 100 ticks
+//	on tick
+//	    spawn = if(tick % 100 == 0, 1, 0)
+//
+// Every hook can read the snake's score and length, tick (the ticks
+// played in the run) and time (the seconds played). The hooks, and what
+// else they can read and set, are:
+//
+//   - foodEaten, after a snake eats: food is the kind eaten (normal,
+//     fleeing, golden or remains), points what it scored, which the mod
+//     may change, and spawn how much more food to put down
+//   - tick, after every move, for each live snake: spawn as above, and
+//     bonus points to add to the score
+//   - death, when a snake dies: bonus points to add to its final score
+//
+// Mods run in the order of their file names, in every run except the
+// daily challenge, TAS runs and the demo, which have to play by the
+// normal rules. Runs with mods don't make the high scores. A mod that
+// fails while running is switched off for the rest of the session.
+
+const (
+	// maxModSpawn is the most food a hook can put down at once
+	maxModSpawn = 10
+
+	// maxModBonus bounds the points a hook can award or take at once
+	maxModBonus = 1_000_000
+)
+
+// modReads are the variables every hook can read
+var modReads = []string{"score", "length", "tick", "time"}
+
+// foodNames are the names mods use for the kinds of food
+var foodNames = map[string]snake.FoodKind{
+	"normal":  snake.FoodNormal,
+	"fleeing": snake.FoodFleeing,
+	"golden":  snake.FoodGolden,
+	"remains": snake.FoodRemains,
+}
+
+// modHooks are the hooks mods can use
+var modHooks = map[string]script.Hook{
+	"foodEaten": {Reads: append(append([]string{"food"}, modReads...), "normal", "fleeing", "golden", "remains"), Writes: []string{"points", "spawn"}},
+	"tick":      {Reads: modReads, Writes: []string{"spawn", "bonus"}},
+	"death":     {Reads: modReads, Writes: []string{"bonus"}},
+}
+
+// loadModsDir loads every .mod file in dir, sorted by name
+// A missing directory just means there are no mods. Broken files are
+// skipped, and reported together in the returned error.
+func loadModsDir(dir string) ([]*script.Script, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading mods dir: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var mods []*script.Script
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".mod") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading mod: %w", err))
+			continue
+		}
+		s, err := script.Parse(e.Name(), string(data), modHooks)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mod %w", err))
+			continue
+		}
+		mods = append(mods, s)
+	}
+	return mods, errors.Join(errs...)
+}
+
+// modsActive reports whether the current run plays with the mods
+func (g *Game) modsActive() bool {
+	return len(g.mods) > 0 && g.tas == nil && g.variant != VariantDaily && g.mode != ModeDemo
+}
+
+// runMods runs the mods' foodEaten and death hooks for what happened in
+// the world at now
+// It comes before anything else sees the events, so the scores shown and
+// recorded are the modded ones.
+func (g *Game) runMods(now time.Time, events []snake.Event) {
+	if !g.modsActive() {
+		return
+	}
+	g.cheated = true
+	w := g.world
+	for _, e := range events {
+		switch e.Kind {
+		case snake.EventAte:
+			vars := g.modVars(now, e.Player)
+			vars["food"], vars["points"], vars["spawn"] = float64(e.Food), float64(e.Points), 0
+			for name, kind := range foodNames {
+				vars[name] = float64(kind)
+			}
+			if g.runHook("foodEaten", vars) {
+				w.Players[e.Player].Score += modPoints(vars["points"]) - e.Points
+				w.AddFood(modSpawn(vars["spawn"]), now)
+			}
+		case snake.EventDied:
+			vars := g.modVars(now, e.Player)
+			vars["bonus"] = 0
+			if g.runHook("death", vars) {
+				w.Players[e.Player].Score += modPoints(vars["bonus"])
+			}
+		}
+	}
+}
+
+// tickMods runs the mods' tick hook for each live snake, after the world
+// has moved them at now
+func (g *Game) tickMods(now time.Time) {
+	g.ticks++
+	if !g.modsActive() {
+		return
+	}
+	g.cheated = true
+	for i, p := range g.world.Players {
+		if p.Dead {
+			continue
+		}
+		vars := g.modVars(now, i)
+		vars["spawn"], vars["bonus"] = 0, 0
+		if g.runHook("tick", vars) {
+			p.Score += modPoints(vars["bonus"])
+			g.world.AddFood(modSpawn(vars["spawn"]), now)
+		}
+	}
+}
+
+// modVars returns the variables every hook reads, for player i at now
+func (g *Game) modVars(now time.Time, i int) map[string]float64 {
+	p := g.world.Players[i]
+	return map[string]float64{
+		"score":  float64(p.Score),
+		"length": float64(p.Snake.Len()),
+		"tick":   float64(g.ticks),
+		"time":   g.elapsed(now).Seconds(),
+	}
+}
+
+// runHook runs the hook of every mod that has it on vars, each seeing the
+// ones before's changes, and reports whether any did
+// A mod that fails is logged and switched off, and none of its changes
+// are kept: each mod runs on a copy of vars, copied back if it succeeds.
+func (g *Game) runHook(hook string, vars map[string]float64) bool {
+	ran := false
+	for i := 0; i < len(g.mods); i++ {
+		s := g.mods[i]
+		if !s.Has(hook) {
+			continue
+		}
+		out := maps.Clone(vars)
+		if err := s.Run(hook, out, g.world.Rand); err != nil {
+			log.Printf("mod switched off: %v", err)
+			g.notify(trf("Mod %s switched off", s.Name))
+			g.mods = slices.Delete(g.mods, i, i+1)
+			i--
+			continue
+		}
+		maps.Copy(vars, out)
+		ran = true
+	}
+	return ran
+}
+
+// modPoints turns points from a mod into a score change
+func modPoints(v float64) int {
+	return int(math.Round(min(max(v, -maxModBonus), maxModBonus)))
+}
+
+// modSpawn turns spawn from a mod into how much food to put down
+func modSpawn(v float64) int {
+	return int(min(max(v, 0), maxModSpawn))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/obliviousorion/go-basics/pkg/script"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// newModGame returns a game running the given mods, parsed from their
+// sources in order
+func newModGame(t *testing.T, srcs ...string) *Game {
+	t.Helper()
+	g := &Game{
+		Board: &Board{world: &snake.World{Rand: rand.New(rand.NewPCG(1, 2))}},
+		clock: snake.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)),
+	}
+	for i, src := range srcs {
+		s, err := script.Parse(string(rune('a'+i))+".mod", src, modHooks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.mods = append(g.mods, s)
+	}
+	return g
+}
+
+func TestRunHook(t *testing.T) {
+	// Each mod sees the changes of the ones before it
+	g := newModGame(t,
+		"on tick\nbonus = 5\nspawn = 1\n",
+		"on tick\nbonus = bonus * 2\n",
+	)
+	vars := map[string]float64{"score": 0, "length": 3, "tick": 1, "time": 0, "spawn": 0, "bonus": 0}
+	if !g.runHook("tick", vars) {
+		t.Fatal("runHook() = false, want true")
+	}
+	if vars["bonus"] != 10 || vars["spawn"] != 1 {
+		t.Errorf("bonus, spawn = %v, %v; want 10, 1", vars["bonus"], vars["spawn"])
+	}
+	if g.runHook("death", vars) {
+		t.Error(`runHook("death") = true, but no mod has it`)
+	}
+}
+
+func TestRunHookDropsAFailedModsChanges(t *testing.T) {
+	// The second mod sets bonus and spawn, then fails: neither change may
+	// be kept, though the first mod's stands
+	g := newModGame(t,
+		"on tick\nbonus = 3\n",
+		"on tick\nbonus = 1000\nspawn = 10\nbonus = 1 / (tick - tick)\n",
+	)
+	vars := map[string]float64{"score": 0, "length": 3, "tick": 1, "time": 0, "spawn": 0, "bonus": 0}
+	if !g.runHook("tick", vars) {
+		t.Fatal("runHook() = false, want true for the mod that ran")
+	}
+	if vars["bonus"] != 3 || vars["spawn"] != 0 {
+		t.Errorf("bonus, spawn = %v, %v; want 3, 0", vars["bonus"], vars["spawn"])
+	}
+	if len(g.mods) != 1 || g.mods[0].Name != "a.mod" {
+		t.Errorf("mods left = %d, want only a.mod", len(g.mods))
+	}
+
+	// With no mod left that succeeds, nothing is applied at all
+	g = newModGame(t, "on tick\nspawn = 10\nbonus = 1 / (tick - tick)\n")
+	vars = map[string]float64{"score": 0, "length": 3, "tick": 1, "time": 0, "spawn": 0, "bonus": 0}
+	if g.runHook("tick", vars) {
+		t.Error("runHook() = true, want false when the only mod failed")
+	}
+	if vars["spawn"] != 0 || vars["bonus"] != 0 {
+		t.Errorf("spawn, bonus = %v, %v; want 0, 0", vars["spawn"], vars["bonus"])
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Particle effect tuning
+// Lifetimes are in updates, and speeds in pixels per update, at baseTPS
+// (see timing.go).
+const (
+	// maxParticles caps the pool; bursts beyond it reuse the oldest slots
+	maxParticles = 512
+
+	eatBurstCount   = 16
+	eatBurstSpeed   = 2.0
+	eatBurstLife    = 30
+	deathBurstCount = 48
+	deathBurstSpeed = 3.5
+	deathBurstLife  = 60
+
+	// A small burst comes off each segment as it crumbles in the death
+	// animation
+	segmentBurstCount = 6
+	segmentBurstSpeed = 1.5
+	segmentBurstLife  = 40
+
+	// particleDrag is the fraction of its speed a particle keeps each update
+	particleDrag = 0.92
+)
+
+// particle is one fading speck in a burst
+type particle struct {
+	x, y   float32
+	vx, vy float32
+	size   float32
+	clr    color.RGBA
+
+	// life counts down to zero, when the particle disappears; maxLife is
+	// where it started, for fading
+	life, maxLife float32
+}
+
+// Particles is a fixed pool of particles
+// Particles are purely cosmetic: they draw their randomness from the
+// global source rather than the world's, so they never change how a
+// seeded run plays out. Dead particles keep their slot and are reused by
+// later bursts, so nothing is allocated once the pool has been created.
+type Particles struct {
+	pool []particle
+
+	// next is the slot the next spawned particle goes into
+	next int
+}
+
+// newParticles creates an empty pool
+func newParticles() *Particles {
+	return &Particles{pool: make([]particle, maxParticles)}
+}
+
+// burst spawns n particles flying out from (x, y) in every direction
+func (ps *Particles) burst(x, y float32, n int, speed float64, life int, clr color.RGBA) {
+	for range n {
+		angle := rand.Float64() * 2 * math.Pi
+		v := speed * (0.3 + 0.7*rand.Float64())
+		l := float32(life/2 + rand.IntN(life/2+1))
+		ps.spawn(particle{
+			x:       x,
+			y:       y,
+			vx:      float32(math.Cos(angle) * v),
+			vy:      float32(math.Sin(angle) * v),
+			size:    2 + rand.Float32()*2,
+			clr:     clr,
+			life:    l,
+			maxLife: l,
+		})
+	}
+}
+
+// spawn puts p in the next free slot
+// When every slot is in use the one after the last spawned (the oldest,
+// give or take lifetimes) is overwritten.
+func (ps *Particles) spawn(p particle) {
+	n := len(ps.pool)
+	for i := range n {
+		slot := (ps.next + i) % n
+		if ps.pool[slot].life <= 0 {
+			ps.pool[slot] = p
+			ps.next = (slot + 1) % n
+			return
+		}
+	}
+	ps.pool[ps.next] = p
+	ps.next = (ps.next + 1) % n
+}
+
+// update moves every live particle and ages it by one update
+func (ps *Particles) update() {
+	step, drag := float32(updateStep()), float32(perUpdate(particleDrag))
+	for i := range ps.pool {
+		p := &ps.pool[i]
+		if p.life <= 0 {
+			continue
+		}
+		p.x += p.vx * step
+		p.y += p.vy * step
+		p.vx *= drag
+		p.vy *= drag
+		p.life -= step
+	}
+}
+
+// clear removes every particle, e.g. when a new run starts
+func (ps *Particles) clear() {
+	for i := range ps.pool {
+		ps.pool[i].life = 0
+	}
+}
+
+// draw renders the live particles, fading out as they age
+// Particle positions are relative to the board, so they stay in place
+// when it scrolls; (ox, oy) is where the board is drawn.
+func (ps *Particles) draw(screen *ebiten.Image, ox, oy float32) {
+	for _, p := range ps.pool {
+		if p.life <= 0 {
+			continue
+		}
+		// Colors are premultiplied, so fading scales every channel
+		a := p.life / p.maxLife
+		clr := color.RGBA{
+			R: uint8(float32(p.clr.R) * a),
+			G: uint8(float32(p.clr.G) * a),
+			B: uint8(float32(p.clr.B) * a),
+			A: uint8(float32(p.clr.A) * a),
+		}
+		fillRect(screen, ox+p.x-p.size/2, oy+p.y-p.size/2, p.size, p.size, clr, false)
+	}
+}
+
+// burstAt spawns a burst from the center of board cell c
+func (g *Game) burstAt(c snake.Point, n int, speed float64, life int, clr color.Color) {
+	r, gr, b, a := clr.RGBA()
+	rgba := color.RGBA{uint8(r >> 8), uint8(gr >> 8), uint8(b >> 8), uint8(a >> 8)}
+	x, y := g.view.local(c)
+	g.particles.burst(x, y, n, speed, life, rgba)
+}
+
+// subscribeBursts bursts particles for the world's events: eating bursts
+// them where the food was (under the new head), dying from the head that
+// crashed, and dashing puffs a few from the head
+func (g *Game) subscribeBursts() {
+	burst := func(n int, speed float64, life int, clr func(e snake.Event) color.Color) func(snake.Event) {
+		return func(e snake.Event) {
+			if e.Player >= 0 {
+				g.burstAt(g.world.Players[e.Player].Head(), n, speed, life, clr(e))
+			}
+		}
+	}
+	food := func(kind snake.FoodKind) func(snake.Event) color.Color {
+		return func(snake.Event) color.Color { return g.theme.foodColor(kind) }
+	}
+	player := func(e snake.Event) color.Color { return g.playerColor(e.Player) }
+	shield := func(snake.Event) color.Color { return powerUpColors[snake.PowerUpShield] }
+	checkpoint := func(snake.Event) color.Color { return checkpointColor }
+	boss := func(snake.Event) color.Color { return bossColor }
+	shot := func(snake.Event) color.Color { return shotColor }
+
+	g.events.subscribe(burst(eatBurstCount, eatBurstSpeed, eatBurstLife, food(snake.FoodNormal)), snake.EventAte)
+	g.events.subscribe(burst(eatBurstCount, eatBurstSpeed, eatBurstLife, food(snake.FoodPoison)), snake.EventPoisoned)
+	g.events.subscribe(burst(eatBurstCount, eatBurstSpeed, eatBurstLife, shield), snake.EventShieldBroken)
+	g.events.subscribe(burst(eatBurstCount, eatBurstSpeed, eatBurstLife, checkpoint), snake.EventCheckpoint)
+	g.events.subscribe(burst(eatBurstCount, eatBurstSpeed, eatBurstLife, boss), snake.EventBossBitten)
+	g.events.subscribe(burst(eatBurstCount, eatBurstSpeed, eatBurstLife, shot), snake.EventShot)
+	g.events.subscribe(func(snake.Event) {
+		g.burstAt(g.world.Boss().Center(), deathBurstCount, deathBurstSpeed, deathBurstLife, bossColor)
+	}, snake.EventBossDefeated)
+	g.events.subscribe(burst(deathBurstCount, deathBurstSpeed, deathBurstLife, player), snake.EventDied, snake.EventBoardFull)
+	g.events.subscribe(burst(segmentBurstCount, segmentBurstSpeed, segmentBurstLife, player), snake.EventDashed)
+}
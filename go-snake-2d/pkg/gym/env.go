@@ -0,0 +1,192 @@
+// Package gym exposes the rules of Snake (package snake) as a
+// reinforcement-learning environment, in the style of OpenAI Gym: Reset
+// starts an episode and returns what the agent sees, and Step plays one
+// action and returns what it sees next, the reward for it and whether
+// the episode is over.
+//
+// The environment runs the same World the game does, on a clock of its
+// own that moves one tick per Step, so an agent trained here plays by
+// exactly the game's rules, as fast as it can decide.
+package gym
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// DefaultTick is how far the environment's clock moves on each Step when
+// Config.Tick is zero: the game's normal speed
+const DefaultTick = 150 * time.Millisecond
+
+// DeathReward is the reward for the step the snake dies on
+const DeathReward = -1.0
+
+// MinBoard is the smallest board side New accepts, leaving room for the
+// snake to start in the middle
+const MinBoard = 4
+
+// ErrNoEpisode is returned by Step when no episode is running: before the
+// first Reset, or once the episode is over
+var ErrNoEpisode = errors.New("gym: no episode running, call Reset")
+
+// Action is what the agent can do on a step: turn the snake, or keep it
+// going
+type Action int
+
+const (
+	ActionUp Action = iota
+	ActionDown
+	ActionLeft
+	ActionRight
+
+	ActionCount // keep last
+)
+
+// actionDirections maps each action to the direction it turns the snake
+var actionDirections = [ActionCount]snake.Point{snake.Up, snake.Down, snake.Left, snake.Right}
+
+// Config describes the episodes an Env plays
+type Config struct {
+	// Width and Height are the board size in cells
+	Width, Height int
+
+	// Seed seeds the episodes: the same seed gives the same sequence of
+	// episodes for the same actions
+	Seed uint64
+
+	// Walls are the board's obstacle cells
+	Walls []snake.Point
+
+	// Enemies is how many enemies wander the board
+	Enemies int
+
+	// MaxSteps ends an episode after this many steps (0 = no limit), so an
+	// agent that learns to loop forever still gets reset
+	MaxSteps int
+
+	// Tick is how far the clock moves on each step (DefaultTick when
+	// zero); power-ups and golden apples time out by it
+	Tick time.Duration
+}
+
+// Env is a Snake environment for one agent, steering one snake
+// It is not safe for concurrent use; run one Env per worker.
+type Env struct {
+	cfg   Config
+	rng   *rand.Rand
+	world *snake.World
+	agent agent
+	now   time.Time
+	steps int
+	done  bool
+}
+
+// agent is the Controller for the agent's snake: it goes where the last
+// action said
+type agent struct {
+	dir snake.Point
+}
+
+// NextDirection returns the direction of the last action
+func (a *agent) NextDirection(s snake.GameState) snake.Point {
+	return a.dir
+}
+
+// New returns an environment playing episodes described by cfg, or an
+// error if the board is smaller than MinBoard on either side
+// Call Reset to start the first one.
+func New(cfg Config) (*Env, error) {
+	if cfg.Width < MinBoard || cfg.Height < MinBoard {
+		return nil, fmt.Errorf("gym: board %dx%d is smaller than %dx%d", cfg.Width, cfg.Height, MinBoard, MinBoard)
+	}
+	if cfg.Tick <= 0 {
+		cfg.Tick = DefaultTick
+	}
+	return &Env{
+		cfg:  cfg,
+		rng:  rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		done: true,
+	}, nil
+}
+
+// Reset starts a new episode and returns the first observation
+// The snake starts InitialLength long in the middle of the board, heading
+// right. Every episode draws its own seed from the Config's.
+func (e *Env) Reset() Observation {
+	w, h := e.cfg.Width, e.cfg.Height
+	head := snake.Point{X: w / 2, Y: h / 2}
+	body := make([]snake.Point, snake.InitialLength)
+	for i := range body {
+		body[i] = snake.Point{X: head.X - i, Y: head.Y}
+	}
+
+	walls := make(map[snake.Point]bool, len(e.cfg.Walls))
+	for _, p := range e.cfg.Walls {
+		if !snake.ContainsPoint(body, p) {
+			walls[p] = true
+		}
+	}
+
+	e.agent.dir = snake.Right
+	e.world = &snake.World{
+		Width:      w,
+		Height:     h,
+		Obstacles:  walls,
+		Players:    []*snake.Player{snake.NewPlayer("Agent", body, snake.Right, &e.agent)},
+		EnemyCount: e.cfg.Enemies,
+		Rand:       rand.New(rand.NewPCG(e.rng.Uint64(), e.rng.Uint64())),
+	}
+	e.now = time.Unix(0, 0)
+	e.steps, e.done = 0, false
+	e.world.Start(e.now)
+	return e.Observe()
+}
+
+// Step turns the snake as action says and moves it one tick, returning
+// what the agent sees then, the reward and whether the episode is over
+// An action outside the ones defined keeps the snake going the way it
+// is; so does turning straight back, as in the game. The reward is the
+// points scored in units of normal food (FoodPoints), or DeathReward when
+// the snake dies. Once the episode is over, Step changes nothing and
+// returns ErrNoEpisode until Reset is called.
+func (e *Env) Step(action Action) (obs Observation, reward float64, done bool, err error) {
+	if e.done {
+		return Observation{}, 0, true, ErrNoEpisode
+	}
+
+	p := e.world.Players[0]
+	e.agent.dir = p.Direction
+	if action >= 0 && action < ActionCount {
+		e.agent.dir = actionDirections[action]
+	}
+
+	score := p.Score
+	e.world.Update(e.now)
+	if !e.world.RoundOver() {
+		e.world.Step(e.now)
+	}
+	e.now = e.now.Add(e.cfg.Tick)
+	e.steps++
+
+	reward = float64(p.Score-score) / snake.FoodPoints
+	if p.Dead {
+		reward = DeathReward
+	}
+	e.done = p.Dead || e.world.Full || (e.cfg.MaxSteps > 0 && e.steps >= e.cfg.MaxSteps)
+	return e.Observe(), reward, e.done, nil
+}
+
+// Done reports whether the episode is over
+func (e *Env) Done() bool {
+	return e.done
+}
+
+// World returns the world of the current episode, for agents and tools
+// that want more than an Observation holds; it must not be modified
+func (e *Env) World() *snake.World {
+	return e.world
+}
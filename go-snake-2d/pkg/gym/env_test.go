@@ -0,0 +1,157 @@
+package gym
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// newEnv returns an environment for cfg, failing t if New refuses it
+func newEnv(t *testing.T, cfg Config) *Env {
+	t.Helper()
+	e, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestNewChecksTheBoard(t *testing.T) {
+	for _, cfg := range []Config{
+		{},
+		{Width: MinBoard - 1, Height: 10},
+		{Width: 10, Height: MinBoard - 1},
+		{Width: -5, Height: -5},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%dx%d) = nil error, want the board refused", cfg.Width, cfg.Height)
+		}
+	}
+
+	// The smallest board plays
+	e := newEnv(t, Config{Width: MinBoard, Height: MinBoard, Enemies: 1})
+	obs := e.Reset()
+	if obs.Width != MinBoard || obs.Height != MinBoard || obs.At(obs.Head) != CellHead {
+		t.Errorf("Reset on %dx%d: %+v", MinBoard, MinBoard, obs)
+	}
+}
+
+// episode plays actions from a Reset, and returns every observation and
+// reward until the episode ends
+func episode(t *testing.T, e *Env, actions []Action) ([]Observation, []float64) {
+	t.Helper()
+	obs := []Observation{e.Reset()}
+	var rewards []float64
+	for _, a := range actions {
+		o, r, done, err := e.Step(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		obs, rewards = append(obs, o), append(rewards, r)
+		if done {
+			break
+		}
+	}
+	return obs, rewards
+}
+
+func TestSameSeedSameEpisodes(t *testing.T) {
+	cfg := Config{Width: 12, Height: 10, Seed: 7, Enemies: 2, Walls: []snake.Point{{X: 2, Y: 2}, {X: 9, Y: 7}}}
+	actions := []Action{ActionRight, ActionDown, ActionDown, ActionLeft, ActionLeft, ActionUp, ActionRight, ActionRight}
+
+	a, b := newEnv(t, cfg), newEnv(t, cfg)
+	for ep := range 3 {
+		obsA, rewardsA := episode(t, a, actions)
+		obsB, rewardsB := episode(t, b, actions)
+		if !reflect.DeepEqual(obsA, obsB) || !reflect.DeepEqual(rewardsA, rewardsB) {
+			t.Fatalf("episode %d differs between two environments with seed %d", ep+1, cfg.Seed)
+		}
+	}
+
+	// Another seed lays the board out differently
+	c := newEnv(t, Config{Width: 12, Height: 10, Seed: 8, Enemies: 2})
+	d := newEnv(t, Config{Width: 12, Height: 10, Seed: 7, Enemies: 2})
+	if reflect.DeepEqual(c.Reset(), d.Reset()) {
+		t.Error("seeds 7 and 8 started the same episode")
+	}
+}
+
+func TestRewards(t *testing.T) {
+	e := newEnv(t, Config{Width: 8, Height: 8, Seed: 1})
+	obs := e.Reset()
+
+	// Food straight ahead scores one unit of normal food
+	ahead := obs.Head.Add(snake.Right)
+	e.world.Foods = []snake.Food{{Pos: ahead, Kind: snake.FoodNormal}}
+	obs, reward, done, err := e.Step(ActionRight)
+	if err != nil || done || reward != 1 {
+		t.Fatalf("eating: reward %v, done %v, err %v; want 1, false, nil", reward, done, err)
+	}
+	if obs.Head != ahead || obs.Length != snake.InitialLength+1 || obs.Score != snake.FoodPoints {
+		t.Errorf("after eating: head %v, length %d, score %d", obs.Head, obs.Length, obs.Score)
+	}
+
+	// Moving without eating scores nothing
+	e.world.Foods = nil
+	if _, reward, done, _ = e.Step(ActionDown); reward != 0 || done {
+		t.Errorf("moving: reward %v, done %v; want 0, false", reward, done)
+	}
+
+	// Running into the wall is DeathReward, and ends the episode
+	for range 8 {
+		_, reward, done, err = e.Step(ActionDown)
+		if err != nil || done {
+			break
+		}
+		if reward != 0 {
+			t.Fatalf("moving down: reward %v, want 0", reward)
+		}
+	}
+	if err != nil || !done || reward != DeathReward {
+		t.Errorf("crashing: reward %v, done %v, err %v; want %v, true, nil", reward, done, err, DeathReward)
+	}
+}
+
+func TestMaxSteps(t *testing.T) {
+	e := newEnv(t, Config{Width: 20, Height: 20, MaxSteps: 3})
+	_, rewards := episode(t, e, []Action{ActionUp, ActionUp, ActionUp, ActionUp, ActionUp})
+	if len(rewards) != 3 || !e.Done() {
+		t.Errorf("episode lasted %d steps, Done() = %v; want 3, true", len(rewards), e.Done())
+	}
+}
+
+func TestStepWithoutEpisode(t *testing.T) {
+	e := newEnv(t, Config{Width: 6, Height: 6})
+	if _, _, done, err := e.Step(ActionRight); !errors.Is(err, ErrNoEpisode) || !done {
+		t.Errorf("Step before Reset: done %v, err %v; want true, ErrNoEpisode", done, err)
+	}
+
+	e.Reset()
+	for {
+		_, _, done, err := e.Step(ActionRight)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done {
+			break
+		}
+	}
+	world := e.World()
+	head := world.Players[0].Head()
+	for range 2 {
+		if _, reward, done, err := e.Step(ActionUp); !errors.Is(err, ErrNoEpisode) || !done || reward != 0 {
+			t.Errorf("Step after the episode ended: reward %v, done %v, err %v; want 0, true, ErrNoEpisode", reward, done, err)
+		}
+	}
+	if e.World() != world || world.Players[0].Head() != head {
+		t.Error("Step after the episode ended changed the world")
+	}
+
+	// Reset starts a new one
+	e.Reset()
+	if _, _, _, err := e.Step(ActionRight); err != nil {
+		t.Errorf("Step after Reset: %v", err)
+	}
+}
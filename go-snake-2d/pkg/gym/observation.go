@@ -0,0 +1,134 @@
+package gym
+
+import "github.com/obliviousorion/go-basics/pkg/snake"
+
+// Cell is what an Observation shows on one cell of the board
+type Cell byte
+
+const (
+	CellEmpty Cell = iota
+	CellWall
+	CellBody
+	CellHead
+	CellFood
+	CellPoison
+	CellGolden
+	CellEnemy
+	CellPowerUp
+	CellPortal
+
+	CellCount // keep last
+)
+
+// cellNames are the names of the cells, for tools printing the board
+var cellNames = [CellCount]string{"empty", "wall", "body", "head", "food", "poison", "golden", "enemy", "power-up", "portal"}
+
+// String returns the cell's name
+func (c Cell) String() string {
+	if c >= CellCount {
+		return "unknown"
+	}
+	return cellNames[c]
+}
+
+// Observation is what the agent sees after a Reset or Step
+// It serializes to JSON as it is; Cells is a byte slice, so it is
+// base64-encoded there, one byte per cell.
+type Observation struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Cells holds the board row by row from the top left, Width cells to
+	// a row, so the cell at (x, y) is Cells[y*Width+x]
+	Cells []Cell `json:"cells"`
+
+	// Head is where the snake's head is, and Direction the way it is
+	// heading (one of the direction vectors)
+	Head      snake.Point `json:"head"`
+	Direction snake.Point `json:"direction"`
+
+	Length int `json:"length"`
+	Score  int `json:"score"`
+
+	// Steps is how many steps the episode has run
+	Steps int `json:"steps"`
+}
+
+// At returns the cell at p, or CellWall off the board
+func (o Observation) At(p snake.Point) Cell {
+	if p.X < 0 || p.Y < 0 || p.X >= o.Width || p.Y >= o.Height {
+		return CellWall
+	}
+	return o.Cells[p.Y*o.Width+p.X]
+}
+
+// Observe returns what the agent sees now
+// Later layers win where two things share a cell: items under a snake
+// show as the snake.
+func (e *Env) Observe() Observation {
+	w := e.world
+	if w == nil {
+		return Observation{}
+	}
+	obs := Observation{
+		Width:  w.Width,
+		Height: w.Height,
+		Cells:  make([]Cell, w.Width*w.Height),
+		Steps:  e.steps,
+	}
+	set := func(p snake.Point, c Cell) {
+		if w.InBounds(p) {
+			obs.Cells[p.Y*w.Width+p.X] = c
+		}
+	}
+
+	// BOARD
+	// Closed arena rings count as walls
+	for y := range w.Height {
+		for x := range w.Width {
+			if p := (snake.Point{X: x, Y: y}); !w.InBounds(p) {
+				obs.Cells[y*w.Width+x] = CellWall
+			}
+		}
+	}
+	for p := range w.Obstacles {
+		set(p, CellWall)
+	}
+	for _, pt := range w.Portals {
+		set(pt.A, CellPortal)
+		set(pt.B, CellPortal)
+	}
+
+	// ITEMS
+	for _, f := range w.Foods {
+		switch f.Kind {
+		case snake.FoodPoison:
+			set(f.Pos, CellPoison)
+		case snake.FoodGolden:
+			set(f.Pos, CellGolden)
+		default:
+			set(f.Pos, CellFood)
+		}
+	}
+	if w.PowerUp != nil {
+		set(w.PowerUp.Pos, CellPowerUp)
+	}
+	for en := range w.Enemies() {
+		set(en.Pos, CellEnemy)
+	}
+
+	// SNAKE
+	// The head last, so it shows where it has run into the body
+	p := w.Players[0]
+	for _, c := range p.Snake.All() {
+		set(c, CellBody)
+	}
+	if p.Snake.Len() > 0 {
+		obs.Head = p.Head()
+		set(obs.Head, CellHead)
+	}
+	obs.Direction = p.Direction
+	obs.Length = p.Snake.Len()
+	obs.Score = p.Score
+	return obs
+}
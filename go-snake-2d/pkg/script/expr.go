@@ -0,0 +1,435 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// node is a parsed expression
+type node interface {
+	eval(e *env) (float64, error)
+}
+
+// env is what expressions are evaluated in
+type env struct {
+	vars map[string]float64
+	rnd  *rand.Rand
+}
+
+type (
+	// number is a literal
+	number float64
+
+	// variable is read from the environment
+	variable string
+
+	// unary is -x or !x
+	unary struct {
+		op string
+		x  node
+	}
+
+	// binary is x op y
+	binary struct {
+		op   string
+		x, y node
+	}
+
+	// call is a function call
+	call struct {
+		fn   function
+		args []node
+	}
+)
+
+// function is a built-in function: its arguments are evaluated lazily, so
+// if only evaluates the branch it takes
+type function struct {
+	minArgs int
+	maxArgs int // -1 for any number
+	eval    func(e *env, args []node) (float64, error)
+}
+
+// functions are the built-in functions scripts can call
+var functions = map[string]function{
+	// if(cond, a, b) is a when cond is true (not 0), else b
+	"if": {3, 3, func(e *env, args []node) (float64, error) {
+		c, err := args[0].eval(e)
+		if err != nil {
+			return 0, err
+		}
+		if c != 0 {
+			return args[1].eval(e)
+		}
+		return args[2].eval(e)
+	}},
+	"min":   {1, -1, fold(math.Min)},
+	"max":   {1, -1, fold(math.Max)},
+	"abs":   {1, 1, apply(math.Abs)},
+	"floor": {1, 1, apply(math.Floor)},
+	"ceil":  {1, 1, apply(math.Ceil)},
+	"round": {1, 1, apply(math.Round)},
+	// clamp(x, lo, hi) is x kept between lo and hi
+	"clamp": {3, 3, func(e *env, args []node) (float64, error) {
+		v, err := evalAll(e, args)
+		if err != nil {
+			return 0, err
+		}
+		return math.Min(math.Max(v[0], v[1]), v[2]), nil
+	}},
+	// rand(n) is a whole number from 0 to n-1
+	"rand": {1, 1, func(e *env, args []node) (float64, error) {
+		n, err := args[0].eval(e)
+		if err != nil {
+			return 0, err
+		}
+		if n < 1 || n > math.MaxInt32 {
+			return 0, fmt.Errorf("rand(%g): n must be from 1 to %d", n, math.MaxInt32)
+		}
+		return float64(e.rnd.IntN(int(n))), nil
+	}},
+}
+
+// fold returns a function combining all its arguments with f
+func fold(f func(a, b float64) float64) func(*env, []node) (float64, error) {
+	return func(e *env, args []node) (float64, error) {
+		v, err := evalAll(e, args)
+		if err != nil {
+			return 0, err
+		}
+		r := v[0]
+		for _, x := range v[1:] {
+			r = f(r, x)
+		}
+		return r, nil
+	}
+}
+
+// apply returns a function of one argument applying f
+func apply(f func(float64) float64) func(*env, []node) (float64, error) {
+	return func(e *env, args []node) (float64, error) {
+		x, err := args[0].eval(e)
+		return f(x), err
+	}
+}
+
+// evalAll evaluates every argument
+func evalAll(e *env, args []node) ([]float64, error) {
+	v := make([]float64, len(args))
+	for i, a := range args {
+		x, err := a.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = x
+	}
+	return v, nil
+}
+
+func (n number) eval(*env) (float64, error) { return float64(n), nil }
+
+func (v variable) eval(e *env) (float64, error) {
+	x, ok := e.vars[string(v)]
+	if !ok {
+		// Parse only lets through variables the hook has, so this is the
+		// host not passing one in
+		return 0, fmt.Errorf("variable %q isn't set", string(v))
+	}
+	return x, nil
+}
+
+func (u unary) eval(e *env) (float64, error) {
+	x, err := u.x.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	if u.op == "!" {
+		return truth(x == 0), nil
+	}
+	return -x, nil
+}
+
+func (b binary) eval(e *env) (float64, error) {
+	x, err := b.x.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	// && and || only evaluate the right side when they need it
+	switch {
+	case b.op == "&&" && x == 0:
+		return 0, nil
+	case b.op == "||" && x != 0:
+		return 1, nil
+	}
+	y, err := b.y.eval(e)
+	if err != nil {
+		return 0, err
+	}
+
+	var r float64
+	switch b.op {
+	case "+":
+		r = x + y
+	case "-":
+		r = x - y
+	case "*":
+		r = x * y
+	case "/", "%":
+		if y == 0 {
+			return 0, errors.New("division by zero")
+		}
+		r = x / y
+		if b.op == "%" {
+			r = math.Mod(x, y)
+		}
+	case "==":
+		r = truth(x == y)
+	case "!=":
+		r = truth(x != y)
+	case "<":
+		r = truth(x < y)
+	case "<=":
+		r = truth(x <= y)
+	case ">":
+		r = truth(x > y)
+	case ">=":
+		r = truth(x >= y)
+	case "&&", "||":
+		r = truth(y != 0)
+	}
+	if math.IsInf(r, 0) || math.IsNaN(r) {
+		return 0, fmt.Errorf("%g %s %g is out of range", x, b.op, y)
+	}
+	return r, nil
+}
+
+func (c call) eval(e *env) (float64, error) {
+	return c.fn.eval(e, c.args)
+}
+
+// truth is 1 for true and 0 for false
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// precedence gives each binary operator's binding strength, higher binding
+// tighter
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+// unaryPrecedence binds - and ! tighter than any binary operator
+const unaryPrecedence = 7
+
+// parser parses one expression, token by token
+type parser struct {
+	tokens []string
+	pos    int
+
+	// known reports whether a variable may be used
+	known func(string) bool
+}
+
+// parseExpr parses src as an expression, which may only read the
+// variables known allows
+func parseExpr(src string, known func(string) bool) (node, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, known: known}
+	n, err := p.expr(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return n, nil
+}
+
+// peek returns the next token, or "" at the end
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// next returns the next token and moves past it
+func (p *parser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// expect moves past the next token, which must be t
+func (p *parser) expect(t string) error {
+	if got := p.next(); got != t {
+		if got == "" {
+			return fmt.Errorf("expected %q at the end", t)
+		}
+		return fmt.Errorf("expected %q, found %q", t, got)
+	}
+	return nil
+}
+
+// expr parses operators binding tighter than min, depth levels deep
+func (p *parser) expr(min, depth int) (node, error) {
+	if depth > maxDepth {
+		return nil, errors.New("expression nested too deeply")
+	}
+	x, err := p.operand(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		prec, ok := precedence[op]
+		if !ok || prec <= min {
+			return x, nil
+		}
+		p.next()
+		y, err := p.expr(prec, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		x = binary{op, x, y}
+	}
+}
+
+// operand parses a number, variable, call, parenthesized expression or
+// unary operator
+func (p *parser) operand(depth int) (node, error) {
+	t := p.next()
+	switch {
+	case t == "":
+		return nil, errors.New("expression ends too soon")
+	case t == "-" || t == "!":
+		x, err := p.expr(unaryPrecedence, depth+1)
+		return unary{t, x}, err
+	case t == "(":
+		x, err := p.expr(0, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case unicode.IsDigit(rune(t[0])) || t[0] == '.':
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", t)
+		}
+		return number(v), nil
+	case isIdent(t) && p.peek() == "(":
+		return p.call(t, depth)
+	case isIdent(t):
+		if !p.known(t) {
+			return nil, fmt.Errorf("unknown variable %q", t)
+		}
+		return variable(t), nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t)
+}
+
+// call parses the arguments of a call to the function name
+func (p *parser) call(name string, depth int) (node, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	p.next() // (
+	var args []node
+	for p.peek() != ")" {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		a, err := p.expr(0, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	p.next() // )
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments to %s", name)
+	}
+	return call{fn, args}, nil
+}
+
+// tokenize splits src into numbers, names, operators and punctuation
+func tokenize(src string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		case isIdentByte(src[i]):
+			j := i
+			for j < len(src) && isIdentByte(src[j]) {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		default:
+			// Two-character operators first
+			if i+1 < len(src) {
+				if op := src[i : i+2]; slices.Contains([]string{"==", "!=", "<=", ">=", "&&", "||"}, op) {
+					tokens = append(tokens, op)
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("+-*/%<>!(),", c) {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, fmt.Errorf("unexpected %q", r)
+			}
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// isIdent reports whether s is a name: letters, digits and underscores,
+// not starting with a digit
+func isIdent(s string) bool {
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		return false
+	}
+	for i := range len(s) {
+		if !isIdentByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdentByte reports whether c may be part of a name
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,107 @@
+package script
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// eval parses and evaluates src with the variables vars
+func eval(t *testing.T, src string, vars map[string]float64) (float64, error) {
+	t.Helper()
+	n, err := parseExpr(src, func(v string) bool { _, ok := vars[v]; return ok })
+	if err != nil {
+		t.Fatalf("parseExpr(%q): %v", src, err)
+	}
+	return n.eval(&env{vars, rand.New(rand.NewPCG(1, 2))})
+}
+
+func TestEval(t *testing.T) {
+	vars := map[string]float64{"x": 3, "y": 4, "zero": 0}
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"1", 1},
+		{".5", 0.5},
+		{"x + y * 2", 11},
+		{"(x + y) * 2", 14},
+		{"10 - 4 - 3", 3},
+		{"24 / 4 / 2", 3},
+		{"7 % 4", 3},
+		{"-x * 2", -6},
+		{"--x", 3},
+		{"!zero", 1},
+		{"!x", 0},
+		{"x < y && y < 5", 1},
+		{"x > y || zero", 0},
+		{"x == 3 && y != 3", 1},
+		{"x <= 3 && y >= 5", 0},
+		{"1 + 2 == 3", 1},
+		{"1 < 2 == 1", 1},
+
+		// && and || short-circuit, so the division isn't reached
+		{"zero && 1 / zero", 0},
+		{"x || 1 / zero", 1},
+		// if only evaluates the branch it takes
+		{"if(x > 2, 1, 1 / zero)", 1},
+		{"if(zero, 1 / zero, 2)", 2},
+
+		{"min(x, y, 1)", 1},
+		{"max(x, y, 1)", 4},
+		{"min(x)", 3},
+		{"abs(0 - x)", 3},
+		{"floor(2.7)", 2},
+		{"ceil(2.2)", 3},
+		{"round(2.5)", 3},
+		{"clamp(x, 5, 9)", 5},
+		{"clamp(x * 10, 5, 9)", 9},
+		{"clamp(x, 1, 9)", 3},
+	}
+	for _, tt := range tests {
+		got, err := eval(t, tt.src, vars)
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %v, %v; want %v", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestEvalRand(t *testing.T) {
+	n, err := parseExpr("rand(6)", func(string) bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same seed gives the same rolls
+	a, b := rand.New(rand.NewPCG(7, 8)), rand.New(rand.NewPCG(7, 8))
+	for range 100 {
+		x, err := n.eval(&env{nil, a})
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, _ := n.eval(&env{nil, b})
+		if x != y {
+			t.Fatalf("rand(6) gave %v and %v from the same seed", x, y)
+		}
+		if x < 0 || x > 5 || x != float64(int(x)) {
+			t.Fatalf("rand(6) = %v, want a whole number from 0 to 5", x)
+		}
+	}
+}
+
+func TestEvalOutOfRange(t *testing.T) {
+	vars := map[string]float64{"big": 1e308, "zero": 0}
+	for _, src := range []string{
+		"big * 10",
+		"big + big",
+		"0 - big - big",
+		"1 / zero",
+		"zero / zero",
+		"1 % zero",
+		"rand(0)",
+		"rand(zero - 1)",
+		"rand(big)",
+	} {
+		if got, err := eval(t, src, vars); err == nil {
+			t.Errorf("%s = %v, want an error", src, got)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package script is a small language for mods: scripts that tweak how a
+// game plays without recompiling it. A script is a list of hooks, each a
+// list of assignments the host runs when something happens in the game:
+//
+//	# Golden apples are worth triple, and long snakes get extra food
+//	on foodEaten
+//	    points = if(food == golden, points * 3, points)
+//	    spawn = if(length > 20, 1, 0)
+//
+// Lines starting with # are comments. "on name" starts a hook; the lines
+// after it, up to the next hook, are its assignments, "variable =
+// expression", run in order. Expressions work on numbers, with + - * / %,
+// comparisons (== != < <= > >=), && || and ! (true is 1, false 0), and
+// the functions listed in functions.
+//
+// Scripts are sandboxed: the host says which hooks exist and which
+// variables each can read and set (see Hook), and a script can do nothing
+// else. There are no loops, no way to reach files or the network, and
+// the size of a script and the nesting of its expressions are capped, so
+// running a hook always takes a short, bounded time.
+package script
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+const (
+	// MaxSize is the largest script, in bytes
+	MaxSize = 64 << 10
+
+	// maxDepth is how deeply expressions may nest
+	maxDepth = 32
+)
+
+// Hook describes a hook a host runs scripts on: the variables its
+// scripts can read, and those they can set too, which the host reads back
+// afterwards
+type Hook struct {
+	Reads  []string
+	Writes []string
+}
+
+// Script is a parsed script, ready to run
+type Script struct {
+	// Name identifies the script in errors, e.g. its file name
+	Name string
+
+	hooks map[string][]assignment
+}
+
+// assignment is one line of a hook: target = value
+type assignment struct {
+	line   int
+	target string
+	value  node
+}
+
+// Error is a problem with a script, found parsing or running it
+type Error struct {
+	Name string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.Name, e.Line, e.Msg)
+}
+
+// Parse parses the script src, which may only use the hooks, and in each
+// the variables, that hooks allows
+func Parse(name, src string, hooks map[string]Hook) (*Script, error) {
+	if len(src) > MaxSize {
+		return nil, &Error{name, 0, fmt.Sprintf("script is over %d bytes", MaxSize)}
+	}
+	s := &Script{Name: name, hooks: map[string][]assignment{}}
+	var hook string
+	for i, line := range strings.Split(src, "\n") {
+		n := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fail := func(format string, args ...any) error {
+			return &Error{name, n, fmt.Sprintf(format, args...)}
+		}
+
+		// HOOKS
+		if rest, ok := strings.CutPrefix(line, "on "); ok {
+			hook = strings.TrimSpace(rest)
+			if _, ok := hooks[hook]; !ok {
+				return nil, fail("unknown hook %q", hook)
+			}
+			if _, ok := s.hooks[hook]; ok {
+				return nil, fail("hook %q is already defined", hook)
+			}
+			s.hooks[hook] = nil
+			continue
+		}
+
+		// ASSIGNMENTS
+		if hook == "" {
+			return nil, fail(`expected "on hook" before the first assignment`)
+		}
+		target, expr, ok := strings.Cut(line, "=")
+		target = strings.TrimSpace(target)
+		if !ok || !isIdent(target) || strings.HasPrefix(expr, "=") {
+			return nil, fail("expected variable = expression")
+		}
+		h := hooks[hook]
+		if !slices.Contains(h.Writes, target) {
+			return nil, fail("%s can't set %q", hook, target)
+		}
+		value, err := parseExpr(expr, func(v string) bool { return slices.Contains(h.Reads, v) || slices.Contains(h.Writes, v) })
+		if err != nil {
+			return nil, fail("%v", err)
+		}
+		s.hooks[hook] = append(s.hooks[hook], assignment{n, target, value})
+	}
+	return s, nil
+}
+
+// Has reports whether the script has the hook
+func (s *Script) Has(hook string) bool {
+	_, ok := s.hooks[hook]
+	return ok
+}
+
+// Run runs the hook's assignments on vars, which must hold every variable
+// the hook reads or writes; the variables set are updated in place
+// Random numbers (see rand) come from rnd, so a seeded game stays
+// repeatable. A run that fails part way leaves the variables set so far.
+func (s *Script) Run(hook string, vars map[string]float64, rnd *rand.Rand) error {
+	for _, a := range s.hooks[hook] {
+		v, err := a.value.eval(&env{vars, rnd})
+		if err != nil {
+			return &Error{s.Name, a.line, err.Error()}
+		}
+		vars[a.target] = v
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package script
+
+import (
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// testHooks are the hooks the tests' scripts may use
+var testHooks = map[string]Hook{
+	"foodEaten": {Reads: []string{"food", "length"}, Writes: []string{"points", "spawn"}},
+	"tick":      {Reads: []string{"length"}, Writes: []string{"speed"}},
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+		msg  string
+	}{
+		{"too big", "# " + strings.Repeat("x", MaxSize), 0, "script is over"},
+		{"unknown hook", "\non levelUp\n", 2, `unknown hook "levelUp"`},
+		{"hook twice", "on tick\non foodEaten\non tick\n", 3, `hook "tick" is already defined`},
+		{"assignment before a hook", "# comment\npoints = 1\n", 2, `expected "on hook"`},
+		{"not an assignment", "on tick\nspeed\n", 2, "expected variable = expression"},
+		{"comparison, not assignment", "on tick\nspeed == 1\n", 2, "expected variable = expression"},
+		{"bad target", "on tick\n2speed = 1\n", 2, "expected variable = expression"},
+		{"variable the hook can't set", "on foodEaten\nlength = 1\n", 2, `foodEaten can't set "length"`},
+		{"variable of another hook", "on tick\npoints = 1\n", 2, `tick can't set "points"`},
+		{"variable the hook can't read", "on tick\nspeed = food\n", 2, `unknown variable "food"`},
+		{"unknown function", "on tick\nspeed = sqrt(length)\n", 2, `unknown function "sqrt"`},
+		{"too few arguments", "on tick\nspeed = if(1, 2)\n", 2, "wrong number of arguments to if"},
+		{"too many arguments", "on tick\nspeed = abs(1, 2)\n", 2, "wrong number of arguments to abs"},
+		{"unclosed parenthesis", "on tick\nspeed = (1 + 2\n", 2, `expected ")" at the end`},
+		{"trailing token", "on tick\nspeed = 1 2\n", 2, `unexpected "2"`},
+		{"missing operand", "on tick\nspeed = 1 +\n", 2, "expression ends too soon"},
+		{"bad character", "on tick\nspeed = 1 $ 2\n", 2, `unexpected '$'`},
+		{"bad number", "on tick\nspeed = 1.2.3\n", 2, `bad number "1.2.3"`},
+		{"number out of range", "on tick\nspeed = 1" + strings.Repeat("0", 400) + "\n", 2, "bad number"},
+		{"too deep", "on tick\nspeed = " + strings.Repeat("(", maxDepth+1) + "1" + strings.Repeat(")", maxDepth+1) + "\n", 2, "nested too deeply"},
+		{"too deep unary", "on tick\nspeed = " + strings.Repeat("-", maxDepth+1) + "1\n", 2, "nested too deeply"},
+		{"too deep calls", "on tick\nspeed = " + strings.Repeat("abs(", maxDepth+1) + "1" + strings.Repeat(")", maxDepth+1) + "\n", 2, "nested too deeply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse("test.mod", tt.src, testHooks)
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Parse() = %v, %v; want an *Error", s, err)
+			}
+			if e.Name != "test.mod" || e.Line != tt.line || !strings.Contains(e.Msg, tt.msg) {
+				t.Errorf("error = %q, want test.mod:%d: ...%s...", err, tt.line, tt.msg)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	// Exactly MaxSize bytes is allowed
+	src := "on tick\nspeed = 1\n"
+	src += "#" + strings.Repeat("x", MaxSize-len(src)-1)
+	if _, err := Parse("test.mod", src, testHooks); err != nil {
+		t.Errorf("script of MaxSize bytes: %v", err)
+	}
+
+	// As is nesting right up to maxDepth
+	deep := "on tick\nspeed = " + strings.Repeat("(", maxDepth) + "1" + strings.Repeat(")", maxDepth)
+	if _, err := Parse("test.mod", deep, testHooks); err != nil {
+		t.Errorf("expression nested %d deep: %v", maxDepth, err)
+	}
+
+	// A long flat expression doesn't count as deep
+	long := "on tick\nspeed = 1" + strings.Repeat(" + 1", 10*maxDepth)
+	s, err := Parse("test.mod", long, testHooks)
+	if err != nil {
+		t.Fatalf("long flat expression: %v", err)
+	}
+	vars := map[string]float64{"length": 0, "speed": 0}
+	if err := s.Run("tick", vars, nil); err != nil || vars["speed"] != float64(1+10*maxDepth) {
+		t.Errorf("long flat expression: speed = %v, %v; want %d", vars["speed"], err, 1+10*maxDepth)
+	}
+}
+
+func TestRun(t *testing.T) {
+	src := `# Golden apples are worth triple, and long snakes get extra food
+on foodEaten
+    points = if(food == 2, points * 3, points)
+    spawn = if(length > 20, 1, 0)
+    # later lines see earlier ones
+    points = points + spawn
+
+on tick
+speed = clamp(length / 10, 1, 3)
+`
+	s, err := Parse("test.mod", src, testHooks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Has("foodEaten") || !s.Has("tick") {
+		t.Error("Has() = false for a hook the script defines")
+	}
+
+	vars := map[string]float64{"food": 2, "length": 25, "points": 10, "spawn": 0}
+	if err := s.Run("foodEaten", vars, nil); err != nil {
+		t.Fatal(err)
+	}
+	if vars["points"] != 31 || vars["spawn"] != 1 {
+		t.Errorf("after foodEaten: points = %v, spawn = %v; want 31, 1", vars["points"], vars["spawn"])
+	}
+	if vars["food"] != 2 || vars["length"] != 25 {
+		t.Errorf("foodEaten changed the variables it only reads: %v", vars)
+	}
+
+	vars = map[string]float64{"length": 45, "speed": 0}
+	if err := s.Run("tick", vars, nil); err != nil || vars["speed"] != 3 {
+		t.Errorf("after tick: speed = %v, %v; want 3", vars["speed"], err)
+	}
+}
+
+func TestRunHookNotInScript(t *testing.T) {
+	s, err := Parse("test.mod", "on tick\nspeed = 2\n", testHooks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Has("foodEaten") {
+		t.Error(`Has("foodEaten") = true for a hook the script leaves out`)
+	}
+	vars := map[string]float64{"points": 10}
+	if err := s.Run("foodEaten", vars, nil); err != nil || vars["points"] != 10 {
+		t.Errorf("running a missing hook: points = %v, %v; want it untouched", vars["points"], err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		vars map[string]float64
+		line int
+		msg  string
+	}{
+		{"division by zero", "on tick\n\nspeed = 1\nspeed = length / 0\n", map[string]float64{"length": 4}, 4, "division by zero"},
+		{"modulo by zero", "on tick\nspeed = length % (length - 4)\n", map[string]float64{"length": 4}, 2, "division by zero"},
+		{"infinity", "on tick\nspeed = length * 1" + strings.Repeat("0", 300) + " * 1" + strings.Repeat("0", 300) + "\n", map[string]float64{"length": 4}, 2, "out of range"},
+		{"overflow", "on tick\nspeed = 0 - 1" + strings.Repeat("0", 308) + " - 1" + strings.Repeat("0", 308) + "\n", map[string]float64{"length": 4}, 2, "out of range"},
+		{"bad rand", "on tick\nspeed = rand(length - 4)\n", map[string]float64{"length": 4}, 2, "n must be from 1"},
+		{"variable not passed in", "on tick\nspeed = length\n", map[string]float64{}, 2, `variable "length" isn't set`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse("test.mod", tt.src, testHooks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.Run("tick", tt.vars, rand.New(rand.NewPCG(1, 2)))
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Run() = %v, want an *Error", err)
+			}
+			if e.Name != "test.mod" || e.Line != tt.line || !strings.Contains(e.Msg, tt.msg) {
+				t.Errorf("error = %q, want test.mod:%d: ...%s...", err, tt.line, tt.msg)
+			}
+		})
+	}
+}
+
+func TestRunStopsAtTheFailingLine(t *testing.T) {
+	s, err := Parse("test.mod", "on foodEaten\npoints = 5\nspawn = 1 / 0\npoints = 7\n", testHooks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]float64{"points": 0, "spawn": 0}
+	if err := s.Run("foodEaten", vars, nil); err == nil {
+		t.Fatal("Run() = nil, want division by zero")
+	}
+	if vars["points"] != 5 || vars["spawn"] != 0 {
+		t.Errorf("after a failed run: points = %v, spawn = %v; want 5, 0", vars["points"], vars["spawn"])
+	}
+}
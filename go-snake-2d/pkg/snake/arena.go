@@ -0,0 +1,84 @@
+package snake
+
+import "time"
+
+// Arena makes the board shrink over time (survival mode): every Interval
+// the outermost open ring of cells closes and becomes lethal wall
+type Arena struct {
+	// Interval is the time between shrinks
+	Interval time.Duration
+
+	// MinWidth and MinHeight are the smallest the open area gets; the
+	// arena stops shrinking once another ring would take it below them
+	MinWidth, MinHeight int
+
+	// Inset is how many rings have closed so far
+	Inset int
+
+	// NextShrinkAt is when the next ring closes
+	NextShrinkAt time.Time
+}
+
+// Bounds returns the open area of the board: from lo (inclusive) to hi
+// (exclusive). Without an arena it is the whole board.
+func (w *World) Bounds() (lo, hi Point) {
+	inset := 0
+	if w.Arena != nil {
+		inset = w.Arena.Inset
+	}
+	return Point{inset, inset}, Point{w.Width - inset, w.Height - inset}
+}
+
+// canShrink reports whether another ring can close without taking the
+// open area below the arena's minimum size
+func (w *World) canShrink() bool {
+	a := w.Arena
+	inset := a.Inset + 1
+	return w.Width-2*inset >= a.MinWidth && w.Height-2*inset >= a.MinHeight
+}
+
+// updateArena closes the next ring when it is due
+// Snakes with any segment on the closed ring die, and items on it are
+// moved back inside. Returns the events for what happened.
+func (w *World) updateArena(now time.Time, events []Event) []Event {
+	a := w.Arena
+	if a == nil || now.Before(a.NextShrinkAt) || !w.canShrink() {
+		return events
+	}
+	a.Inset++
+	a.NextShrinkAt = now.Add(a.Interval)
+	events = append(events, Event{Kind: EventArenaShrank, Player: -1})
+
+	for i, p := range w.Players {
+		if p.Dead || p.Invincible {
+			continue
+		}
+		for _, c := range p.Snake.All() {
+			if !w.InBounds(c) {
+				p.Dead = true
+				events = append(events, Event{Kind: EventDied, Player: i})
+				break
+			}
+		}
+	}
+
+	// Food caught by the ring is replaced, and a power-up just vanishes
+	for i := len(w.Foods) - 1; i >= 0; i-- {
+		if f := w.Foods[i]; !w.InBounds(f.Pos) {
+			w.removeFood(i)
+			w.spawnFood(f.Kind, now)
+		}
+	}
+	if w.PowerUp != nil && !w.InBounds(w.PowerUp.Pos) {
+		w.PowerUp = nil
+		w.schedulePowerUp(now)
+	}
+	return events
+}
+
+// ShrinkWarning reports whether the arena will close its next ring
+// within d of now, so the front end can warn the players
+func (w *World) ShrinkWarning(now time.Time, d time.Duration) bool {
+	a := w.Arena
+	return a != nil && w.canShrink() && a.NextShrinkAt.Sub(now) <= d
+}
@@ -0,0 +1,141 @@
+package snake
+
+import (
+	"iter"
+	"slices"
+)
+
+// minBodyCap is the smallest buffer a body starts with
+const minBodyCap = 16
+
+// Body is a snake's segments, head first
+// The segments live in a circular buffer: moving adds a head and drops
+// the tail by stepping the head index back one slot, over the old tail,
+// so a move costs the same at any length and allocates nothing. The
+// buffer only grows (doubling) when the snake outgrows it.
+type Body struct {
+	// cells is the buffer; the head is at cells[head] and the rest of the
+	// body follows it, wrapping around the end
+	cells []Point
+	head  int
+	n     int
+}
+
+// NewBody returns a body with the given segments, head first
+func NewBody(points []Point) Body {
+	b := Body{cells: make([]Point, max(len(points)*2, minBodyCap)), n: len(points)}
+	copy(b.cells, points)
+	return b
+}
+
+// Len returns the number of segments
+func (b *Body) Len() int {
+	return b.n
+}
+
+// index returns the buffer slot of segment i
+func (b *Body) index(i int) int {
+	return (b.head + i) % len(b.cells)
+}
+
+// At returns segment i, where 0 is the head and Len()-1 the tail
+func (b *Body) At(i int) Point {
+	if i < 0 || i >= b.n {
+		panic("snake: body index out of range")
+	}
+	return b.cells[b.index(i)]
+}
+
+// Head returns the first segment
+func (b *Body) Head() Point {
+	return b.At(0)
+}
+
+// Tail returns the last segment
+func (b *Body) Tail() Point {
+	return b.At(b.n - 1)
+}
+
+// Grow adds a new head and keeps the tail, so the snake gets one longer
+func (b *Body) Grow(head Point) {
+	if b.n == len(b.cells) {
+		b.resize(max(2*len(b.cells), minBodyCap))
+	}
+	b.head = (b.head - 1 + len(b.cells)) % len(b.cells)
+	b.cells[b.head] = head
+	b.n++
+}
+
+// Move adds a new head and drops the tail, so the snake keeps its length
+func (b *Body) Move(head Point) {
+	if b.n == 0 {
+		b.Grow(head)
+		return
+	}
+	// The tail's slot is free once the tail is dropped; when the buffer
+	// is full, it's the very slot before the head
+	b.n--
+	b.Grow(head)
+}
+
+// Truncate drops segments off the tail until n are left
+func (b *Body) Truncate(n int) {
+	b.n = max(min(n, b.n), 0)
+}
+
+// resize moves the segments into a new buffer of size c, head first
+func (b *Body) resize(c int) {
+	cells := make([]Point, c)
+	b.copyTo(cells)
+	b.cells, b.head = cells, 0
+}
+
+// copyTo copies the segments, head first, to the start of dst, which
+// must be long enough
+func (b *Body) copyTo(dst []Point) {
+	if b.n == 0 {
+		return
+	}
+	end := b.head + b.n
+	if end <= len(b.cells) {
+		copy(dst, b.cells[b.head:end])
+		return
+	}
+	k := copy(dst, b.cells[b.head:])
+	copy(dst[k:], b.cells[:end-len(b.cells)])
+}
+
+// Contains reports whether any segment is on cell c
+func (b *Body) Contains(c Point) bool {
+	for _, p := range b.All() {
+		if p == c {
+			return true
+		}
+	}
+	return false
+}
+
+// All iterates over the segments with their index, head first
+func (b *Body) All() iter.Seq2[int, Point] {
+	return func(yield func(int, Point) bool) {
+		for i := range b.n {
+			if !yield(i, b.cells[b.index(i)]) {
+				return
+			}
+		}
+	}
+}
+
+// AppendTo appends the segments, head first, to dst and returns the
+// extended slice, so a caller can reuse its own buffer
+func (b *Body) AppendTo(dst []Point) []Point {
+	start := len(dst)
+	dst = slices.Grow(dst, b.n)[:start+b.n]
+	b.copyTo(dst[start:])
+	return dst
+}
+
+// Points returns a copy of the segments, head first
+func (b *Body) Points() []Point {
+	return b.AppendTo(make([]Point, 0, b.n))
+}
@@ -0,0 +1,139 @@
+package snake
+
+import (
+	"slices"
+	"testing"
+)
+
+// line returns n cells going left from (n-1, 0), head first
+func line(n int) []Point {
+	pts := make([]Point, n)
+	for i := range pts {
+		pts[i] = Point{n - 1 - i, 0}
+	}
+	return pts
+}
+
+func TestBody(t *testing.T) {
+	type op struct {
+		grow bool // Grow rather than Move
+		head Point
+	}
+	tests := []struct {
+		name  string
+		start []Point
+		ops   []op
+		want  []Point
+	}{
+		{
+			name:  "move keeps the length",
+			start: []Point{{2, 0}, {1, 0}, {0, 0}},
+			ops:   []op{{false, Point{3, 0}}, {false, Point{4, 0}}},
+			want:  []Point{{4, 0}, {3, 0}, {2, 0}},
+		},
+		{
+			name:  "grow keeps the tail",
+			start: []Point{{1, 0}, {0, 0}},
+			ops:   []op{{true, Point{2, 0}}, {true, Point{3, 0}}},
+			want:  []Point{{3, 0}, {2, 0}, {1, 0}, {0, 0}},
+		},
+		{
+			name:  "moves wrap around the buffer",
+			start: []Point{{1, 0}, {0, 0}},
+			ops: func() []op {
+				var ops []op
+				for x := 2; x < 2+3*minBodyCap; x++ {
+					ops = append(ops, op{false, Point{x, 0}})
+				}
+				return ops
+			}(),
+			want: []Point{{1 + 3*minBodyCap, 0}, {3 * minBodyCap, 0}},
+		},
+		{
+			name:  "growing past the buffer",
+			start: line(minBodyCap),
+			ops: func() []op {
+				// Move first so the head isn't at the start of the
+				// buffer when it has to grow
+				ops := []op{{false, Point{minBodyCap, 0}}}
+				for x := minBodyCap + 1; x < 3*minBodyCap; x++ {
+					ops = append(ops, op{true, Point{x, 0}})
+				}
+				return ops
+			}(),
+			want: slices.Delete(line(3*minBodyCap), 3*minBodyCap-1, 3*minBodyCap),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBody(tt.start)
+			for _, o := range tt.ops {
+				if o.grow {
+					b.Grow(o.head)
+				} else {
+					b.Move(o.head)
+				}
+			}
+			if got := b.Points(); !slices.Equal(got, tt.want) {
+				t.Errorf("Points() = %v, want %v", got, tt.want)
+			}
+			if b.Len() != len(tt.want) {
+				t.Errorf("Len() = %d, want %d", b.Len(), len(tt.want))
+			}
+			if b.Head() != tt.want[0] || b.Tail() != tt.want[len(tt.want)-1] {
+				t.Errorf("Head(), Tail() = %v, %v, want %v, %v", b.Head(), b.Tail(), tt.want[0], tt.want[len(tt.want)-1])
+			}
+			for i, p := range b.All() {
+				if p != tt.want[i] || b.At(i) != tt.want[i] {
+					t.Errorf("segment %d = %v (At: %v), want %v", i, p, b.At(i), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBodyTruncate(t *testing.T) {
+	b := NewBody(line(5))
+	// Wrap the head round the buffer first
+	for x := 5; x < 5+minBodyCap; x++ {
+		b.Move(Point{x, 0})
+	}
+	b.Truncate(2)
+	want := []Point{{4 + minBodyCap, 0}, {3 + minBodyCap, 0}}
+	if got := b.Points(); !slices.Equal(got, want) {
+		t.Errorf("after Truncate(2): Points() = %v, want %v", got, want)
+	}
+	if b.Contains(Point{2 + minBodyCap, 0}) {
+		t.Error("Contains reports a segment that was cut off")
+	}
+
+	// Growing again reuses the slots that were cut off
+	b.Grow(Point{5 + minBodyCap, 0})
+	want = slices.Insert(want, 0, Point{5 + minBodyCap, 0})
+	if got := b.Points(); !slices.Equal(got, want) {
+		t.Errorf("after Grow: Points() = %v, want %v", got, want)
+	}
+
+	b.Truncate(10)
+	if b.Len() != 3 {
+		t.Errorf("Truncate to more than Len: Len() = %d, want 3", b.Len())
+	}
+	b.Truncate(-1)
+	if b.Len() != 0 {
+		t.Errorf("Truncate(-1): Len() = %d, want 0", b.Len())
+	}
+}
+
+func TestBodyAppendToReusesBuffer(t *testing.T) {
+	b := NewBody(line(3))
+	buf := make([]Point, 0, 8)
+	buf = append(buf, Point{-1, -1})
+	got := b.AppendTo(buf)
+	want := append([]Point{{-1, -1}}, line(3)...)
+	if !slices.Equal(got, want) {
+		t.Errorf("AppendTo = %v, want %v", got, want)
+	}
+	if &got[0] != &buf[0] {
+		t.Error("AppendTo allocated although the buffer had room")
+	}
+}
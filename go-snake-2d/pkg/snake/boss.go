@@ -0,0 +1,325 @@
+package snake
+
+import (
+	"iter"
+	"time"
+)
+
+const (
+	// BiteShrink is how many segments a snake loses biting the boss; a
+	// snake too short to lose them dies trying
+	BiteShrink = 2
+
+	// BitePoints is how many points each bite is worth
+	BitePoints = 50
+
+	// BossMoveInterval is how many ticks the boss waits between steps
+	BossMoveInterval = 3
+
+	// BossShotInterval is how many ticks the boss waits between volleys
+	BossShotInterval = 10
+
+	// BossHurtTicks is how many ticks a bitten boss can't be bitten again
+	BossHurtTicks = 6
+
+	// ShotShrink is how many segments a snake loses to a shot; a snake
+	// too short to lose them dies
+	ShotShrink = 1
+)
+
+// Boss is a large target that wanders the board, shooting at the snakes
+// A snake running into it takes a bite out of it instead of crashing,
+// losing BiteShrink segments for it and stopping for the tick, so it has
+// to eat between bites. The boss is beaten when its health runs out.
+// Everything the boss does is counted in ticks, so it has no timers.
+type Boss struct {
+	// Pos is the boss's top left cell; it covers Size×Size cells
+	Pos  Point `json:"pos"`
+	Size int   `json:"size"`
+
+	Health    int `json:"health"`
+	MaxHealth int `json:"maxHealth"`
+
+	// Dir is the way it is wandering
+	Dir Point `json:"dir"`
+
+	// Ticks and ShotTicks count the ticks since it last moved and fired
+	Ticks     int `json:"ticks,omitempty"`
+	ShotTicks int `json:"shotTicks,omitempty"`
+
+	// Hurt is how many ticks are left until it can be bitten again
+	Hurt int `json:"hurt,omitempty"`
+}
+
+// Projectile is a shot fired by the boss, flying a cell per tick until it
+// hits a snake, a wall or the edge of the board
+type Projectile struct {
+	Pos Point `json:"pos"`
+	Dir Point `json:"dir"`
+}
+
+// Cells returns the boss's cells while it is still standing
+func (b *Boss) Cells() []Point {
+	if b.Defeated() {
+		return nil
+	}
+	return b.CellsAt(b.Pos)
+}
+
+// Cells returns nil: shots fly over everything, so they take up no cells
+func (s *Projectile) Cells() []Point {
+	return nil
+}
+
+// NewBoss returns a boss at pos, size cells square, with health bites
+// left to take
+func NewBoss(pos Point, size, health int) *Boss {
+	return &Boss{Pos: pos, Size: size, Health: health, MaxHealth: health, Dir: Down}
+}
+
+// Covers reports whether p is one of the boss's cells
+func (b *Boss) Covers(p Point) bool {
+	return p.X >= b.Pos.X && p.Y >= b.Pos.Y && p.X < b.Pos.X+b.Size && p.Y < b.Pos.Y+b.Size
+}
+
+// CellsAt returns the cells the boss would take up at pos, row by row
+func (b *Boss) CellsAt(pos Point) []Point {
+	cells := make([]Point, 0, b.Size*b.Size)
+	for y := range b.Size {
+		for x := range b.Size {
+			cells = append(cells, Point{X: pos.X + x, Y: pos.Y + y})
+		}
+	}
+	return cells
+}
+
+// Center returns the boss's middle cell
+func (b *Boss) Center() Point {
+	return Point{X: b.Pos.X + b.Size/2, Y: b.Pos.Y + b.Size/2}
+}
+
+// Defeated reports whether the boss's health has run out
+func (b *Boss) Defeated() bool {
+	return b.Health <= 0
+}
+
+// Enraged reports whether the boss is down to half its health or less,
+// when it fires in every direction at once
+func (b *Boss) Enraged() bool {
+	return b.Health*2 <= b.MaxHealth
+}
+
+// Boss returns the boss of a boss level, or nil if there is none
+func (w *World) Boss() *Boss {
+	return FirstOf[*Boss](&w.Entities)
+}
+
+// Projectiles returns an iterator over the shots the boss has fired
+func (w *World) Projectiles() iter.Seq[*Projectile] {
+	return Of[*Projectile](&w.Entities)
+}
+
+// BossDefeated reports whether the world has a boss and it is beaten
+func (w *World) BossDefeated() bool {
+	b := w.Boss()
+	return b != nil && b.Defeated()
+}
+
+// bossAt reports whether p is on the boss, while it is still standing
+func (w *World) bossAt(p Point) bool {
+	b := w.Boss()
+	return b != nil && !b.Defeated() && b.Covers(p)
+}
+
+// clearProjectiles removes every shot
+func (w *World) clearProjectiles() {
+	DeleteOf(&w.Entities, func(*Projectile) bool { return true })
+}
+
+// biteBoss has player i bite the boss, appending what happened to events
+// A boss still hurt from the last bite shrugs it off; the snake stops
+// all the same.
+func (w *World) biteBoss(i int, events []Event) []Event {
+	b, p := w.Boss(), w.Players[i]
+	if b.Hurt > 0 {
+		return events
+	}
+	if p.Snake.Len() <= BiteShrink {
+		if p.Invincible {
+			return events
+		}
+		p.Dead = true
+		return append(events, Event{Kind: EventDied, Player: i})
+	}
+	p.Snake.Truncate(p.Snake.Len() - BiteShrink)
+	p.Score += BitePoints
+	b.Health--
+	b.Hurt = BossHurtTicks
+	if b.Defeated() {
+		// Its shots go with it
+		w.clearProjectiles()
+		return append(events,
+			Event{Kind: EventBossDefeated, Player: i},
+			Event{Kind: EventLevelCompleted, Player: i})
+	}
+
+	// It recoils the way the snake was heading, on this very tick
+	b.Dir = p.Direction
+	b.Ticks = BossMoveInterval - 1
+	return append(events, Event{Kind: EventBossBitten, Player: i})
+}
+
+// bossSystem gives the boss and its shots their turn after the snakes
+// have moved: the shots fly on, then every BossMoveInterval ticks the boss
+// takes a step and every BossShotInterval ticks it fires
+type bossSystem struct{}
+
+func (bossSystem) Update(w *World, now time.Time, events []Event) []Event {
+	b := w.Boss()
+	if b == nil || b.Defeated() {
+		return events
+	}
+
+	// SHOTS
+	// Snakes that moved onto a shot are hit before it flies on
+	events = w.hitSnakes(events)
+	for s := range w.Projectiles() {
+		s.Pos = s.Pos.Add(s.Dir)
+	}
+	DeleteOf(&w.Entities, func(s *Projectile) bool {
+		return !w.InBounds(s.Pos) || w.Obstacles[s.Pos]
+	})
+	events = w.hitSnakes(events)
+
+	// MOVEMENT
+	b.Hurt = max(b.Hurt-1, 0)
+	b.Ticks++
+	if b.Ticks >= BossMoveInterval {
+		b.Ticks = 0
+		w.moveBoss(b)
+	}
+
+	// FIRING
+	b.ShotTicks++
+	if b.ShotTicks >= BossShotInterval {
+		b.ShotTicks = 0
+		events = w.fireBoss(b, events)
+	}
+	return events
+}
+
+// moveBoss moves the boss a cell on its way, or a random other way when
+// that is blocked; now and then it turns of its own accord
+func (w *World) moveBoss(b *Boss) {
+	if w.Rand.IntN(4) == 0 {
+		b.Dir = Directions[w.Rand.IntN(len(Directions))]
+	}
+	if w.bossFits(b, b.Pos.Add(b.Dir)) {
+		b.Pos = b.Pos.Add(b.Dir)
+		return
+	}
+	for _, k := range w.Rand.Perm(len(Directions)) {
+		if d := Directions[k]; w.bossFits(b, b.Pos.Add(d)) {
+			b.Dir, b.Pos = d, b.Pos.Add(d)
+			return
+		}
+	}
+}
+
+// bossFits reports whether boss b could stand at pos: it goes around
+// walls, snakes, food, portals and other entities rather than over them
+func (w *World) bossFits(b *Boss, pos Point) bool {
+	for _, c := range b.CellsAt(pos) {
+		if !w.InBounds(c) || w.Obstacles[c] || w.IsOnSnake(c) || w.FoodAt(c) >= 0 || w.isPortal(c) || w.occupied(c, b) {
+			return false
+		}
+		if w.PowerUp != nil && w.PowerUp.Pos == c {
+			return false
+		}
+	}
+	return true
+}
+
+// fireBoss fires a shot at the nearest snake head, or one every way once
+// the boss is enraged, from the middle of the side it goes out of
+func (w *World) fireBoss(b *Boss, events []Event) []Event {
+	dirs := []Point{w.aim(b.Center())}
+	if b.Enraged() {
+		dirs = Directions[:]
+	}
+	fired := false
+	for _, d := range dirs {
+		start := b.Center()
+		switch {
+		case d.X > 0:
+			start.X = b.Pos.X + b.Size
+		case d.X < 0:
+			start.X = b.Pos.X - 1
+		case d.Y > 0:
+			start.Y = b.Pos.Y + b.Size
+		case d.Y < 0:
+			start.Y = b.Pos.Y - 1
+		}
+		if !w.InBounds(start) || w.Obstacles[start] {
+			continue
+		}
+		w.Entities.Add(&Projectile{Pos: start, Dir: d})
+		fired = true
+	}
+	if !fired {
+		return events
+	}
+	events = append(events, Event{Kind: EventBossFired, Player: -1})
+	return w.hitSnakes(events)
+}
+
+// aim returns the direction from p that points most nearly at the nearest
+// live snake head
+func (w *World) aim(p Point) Point {
+	best, target := -1, p
+	for _, pl := range w.Players {
+		if pl.Dead {
+			continue
+		}
+		h := pl.Head()
+		if d := abs(h.X-p.X) + abs(h.Y-p.Y); best < 0 || d < best {
+			best, target = d, h
+		}
+	}
+	dx, dy := target.X-p.X, target.Y-p.Y
+	if abs(dx) >= abs(dy) && dx != 0 {
+		return Point{X: sign(dx)}
+	}
+	if dy < 0 {
+		return Up
+	}
+	return Down
+}
+
+// hitSnakes removes the shots that are on a snake, which each cost it
+// ShotShrink segments, or its shield if it has one
+func (w *World) hitSnakes(events []Event) []Event {
+	DeleteOf(&w.Entities, func(s *Projectile) bool {
+		for i, p := range w.Players {
+			if p.Dead || !p.Occupies(s.Pos) {
+				continue
+			}
+			switch {
+			case p.Invincible:
+				// The shot is spent on it harmlessly
+			case p.Shield:
+				p.Shield = false
+				events = append(events, Event{Kind: EventShieldBroken, Player: i})
+			case p.Snake.Len() <= ShotShrink:
+				p.Dead = true
+				events = append(events, Event{Kind: EventDied, Player: i})
+			default:
+				p.Snake.Truncate(p.Snake.Len() - ShotShrink)
+				events = append(events, Event{Kind: EventShot, Player: i})
+			}
+			return true
+		}
+		return false
+	})
+	return events
+}
@@ -0,0 +1,134 @@
+package snake
+
+// Bot is a Controller for a computer-controlled snake
+// Every tick it looks for the shortest path from the head to the nearest
+// food that grows it (see FindPath), limited to SearchDepth. If there is
+// no (safe) path it falls back to the move that leaves the most room.
+//
+// Bots keep no state between ticks, so one Bot can steer several snakes.
+type Bot struct {
+	// SearchDepth is how far ahead, in moves, the bot looks for food
+	// (0 = search the whole board)
+	SearchDepth int
+
+	// CheckSpace refuses paths that lead into a pocket too small to fit in
+	CheckSpace bool
+
+	// AvoidHeads keeps out of cells the opponent could move into next
+	AvoidHeads bool
+}
+
+// NextDirection picks the direction the bot's snake should move in
+func (b *Bot) NextDirection(s GameState) Point {
+	grid := s.Grid(b.AvoidHeads)
+	blocked := grid.Occupied()
+	head, heading := s.Head(), s.Direction()
+
+	// Candidate moves: anything but reversing into the neck, trying
+	// straight ahead first
+	var moves []Point
+	for _, d := range []Point{heading, Up, Down, Left, Right} {
+		if IsReverse(d, heading) || ContainsPoint(moves, d) {
+			continue
+		}
+		if next := s.Next(head, d); s.InBounds(next) && !blocked[next] {
+			moves = append(moves, d)
+		}
+	}
+	if len(moves) == 0 {
+		// Trapped: nothing will save the snake, so just carry on
+		return heading
+	}
+
+	// SHORTEST PATH TO FOOD
+	if path, ok := b.foodPath(s, grid, blocked); ok {
+		return path.Moves[0]
+	}
+
+	// FALLBACK: MOST ROOM
+	// Without a (safe) path, survive: pick the move with the largest open
+	// area behind it. Ties keep the earlier move, which prefers going
+	// straight.
+	best, bestRoom := moves[0], -1
+	for _, d := range moves {
+		if room := s.roomAfter(s.Next(head, d), blocked); room > bestRoom {
+			best, bestRoom = d, room
+		}
+	}
+	return best
+}
+
+// FoodPath returns the path the bot would take to food from s, if it
+// has a (safe) one
+func (b *Bot) FoodPath(s GameState) (Path, bool) {
+	grid := s.Grid(b.AvoidHeads)
+	return b.foodPath(s, grid, grid.Occupied())
+}
+
+// foodPath returns the shortest path on grid to food that grows the snake,
+// within SearchDepth moves; with CheckSpace, not if its first move leads
+// into a pocket too small to fit in. blocked is grid.Occupied().
+func (b *Bot) foodPath(s GameState, grid Grid, blocked map[Point]bool) (Path, bool) {
+	var goals []Point
+	for _, f := range s.Foods {
+		if f.Kind.Grows() {
+			goals = append(goals, f.Pos)
+		}
+	}
+	path, ok := FindPath(grid, s.Head(), goals, b.SearchDepth)
+	switch {
+	case !ok:
+		return Path{}, false
+	case IsReverse(path.Moves[0], s.Direction()):
+		// Only possible for a snake one cell long, which the world keeps
+		// going straight instead
+		return Path{}, false
+	case b.CheckSpace && s.roomAfter(path.Cells[0], blocked) < s.Snakes[s.Self].Len():
+		return Path{}, false
+	}
+	return path, true
+}
+
+// Grid returns the board as the steered snake's paths see it: walls,
+// poison, and enemies along with the cells they could step to, can't be
+// entered, and snake bodies move on (see Grid). With avoidHeads, the cells
+// next to other snakes' heads can't be entered either, so the bot doesn't
+// risk a head-on crash.
+func (s GameState) Grid(avoidHeads bool) Grid {
+	g := Grid{
+		Width:   s.Width,
+		Height:  s.Height,
+		Inset:   s.Inset,
+		Walls:   make(map[Point]bool, len(s.Obstacles)),
+		Portals: s.Portals,
+	}
+	for c := range s.Obstacles {
+		g.Walls[c] = true
+	}
+	for i, snake := range s.Snakes {
+		g.AddBody(snake)
+		if avoidHeads && i != s.Self && snake.Len() > 0 {
+			for _, d := range Directions {
+				g.Walls[snake.Head().Add(d)] = true
+			}
+		}
+	}
+	for _, f := range s.Foods {
+		if f.Kind == FoodPoison {
+			g.Walls[f.Pos] = true
+		}
+	}
+	for _, e := range s.Enemies {
+		g.Walls[e.Pos] = true
+		for _, d := range Directions {
+			g.Walls[e.Pos.Add(d)] = true
+		}
+	}
+	return g
+}
+
+// roomAfter counts the open cells reachable from c, i.e. how much space
+// a snake moving onto c would have left
+func (s GameState) roomAfter(c Point, blocked map[Point]bool) int {
+	return len(FloodFill(c, s.Width, s.Height, blocked))
+}
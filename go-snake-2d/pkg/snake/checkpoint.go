@@ -0,0 +1,25 @@
+package snake
+
+// Checkpoint is a tile that saves a run's progress when a snake's head
+// reaches it (see EventCheckpoint)
+// The world only tells the front end; keeping the run as it stood, and
+// putting it back there when the snake dies, is up to the front end.
+type Checkpoint struct {
+	Pos Point `json:"pos"`
+
+	// Reached is set once a snake has reached the tile; each one counts
+	// once
+	Reached bool `json:"reached,omitempty"`
+}
+
+// reachCheckpoint marks the checkpoint at p reached, reporting whether
+// there was one there that hadn't been yet
+func (w *World) reachCheckpoint(p Point) bool {
+	for i := range w.Checkpoints {
+		if c := &w.Checkpoints[i]; c.Pos == p && !c.Reached {
+			c.Reached = true
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package snake
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	c := NewFakeClock(testStart)
+	if got := c.Now(); !got.Equal(testStart) {
+		t.Fatalf("Now() = %v, want %v", got, testStart)
+	}
+	c.Advance(1500 * time.Millisecond)
+	if got := c.Now().Sub(testStart); got != 1500*time.Millisecond {
+		t.Errorf("after Advance: %v since the start, want 1.5s", got)
+	}
+	c.Set(testStart)
+	if got := c.Now(); !got.Equal(testStart) {
+		t.Errorf("after Set: Now() = %v, want %v", got, testStart)
+	}
+}
+
+// collect puts a power-up of the given kind in front of the only snake
+// and steps onto it at the clock's time
+func collect(t *testing.T, w *World, clock *FakeClock, kind PowerUpKind) {
+	t.Helper()
+	p := w.Players[0]
+	w.PowerUp = &PowerUp{
+		Kind:      kind,
+		Pos:       w.Next(p.Head(), p.Direction),
+		Duration:  powerUpSpecs[kind].duration,
+		ExpiresAt: clock.Now().Add(powerUpLifetime),
+	}
+	w.Step(clock.Now())
+	if w.PowerUp != nil {
+		t.Fatalf("%v wasn't collected", kind)
+	}
+}
+
+func TestTickScaleOverTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		kinds []PowerUpKind
+		// scales are the wanted TickScale at each offset from pickup
+		scales map[time.Duration]float64
+	}{
+		{
+			name:  "speed boost",
+			kinds: []PowerUpKind{PowerUpSpeedBoost},
+			scales: map[time.Duration]float64{
+				0:                           0.6,
+				4999 * time.Millisecond:     0.6,
+				5 * time.Second:             1,
+				5*time.Second + time.Minute: 1,
+			},
+		},
+		{
+			name:  "slow motion",
+			kinds: []PowerUpKind{PowerUpSlowMotion},
+			scales: map[time.Duration]float64{
+				0:               1.75,
+				6 * time.Second: 1,
+			},
+		},
+		{
+			name:  "bullet time eases out",
+			kinds: []PowerUpKind{PowerUpBulletTime},
+			scales: map[time.Duration]float64{
+				0:                       2,
+				3 * time.Second:         2,
+				3500 * time.Millisecond: 1.5,
+				3750 * time.Millisecond: 1.25,
+				4 * time.Second:         1,
+			},
+		},
+		{
+			name:  "speed boost and slow motion together",
+			kinds: []PowerUpKind{PowerUpSpeedBoost, PowerUpSlowMotion},
+			scales: map[time.Duration]float64{
+				0:                       0.6 * 1.75,
+				5500 * time.Millisecond: 1.75,
+				6 * time.Second:         1,
+			},
+		},
+		{
+			name:  "ghost doesn't change speed",
+			kinds: []PowerUpKind{PowerUpGhost},
+			scales: map[time.Duration]float64{
+				0: 1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(testStart)
+			w := newTestWorld([]*Player{scripted([]Point{{1, 5}, {0, 5}}, Right)})
+			for _, k := range tt.kinds {
+				collect(t, w, clock, k)
+			}
+			pickup := clock.Now()
+			for d, want := range tt.scales {
+				clock.Set(pickup.Add(d))
+				if got := w.Effects.TickScale(clock.Now()); math.Abs(got-want) > 1e-9 {
+					t.Errorf("TickScale %v after pickup = %v, want %v", d, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEffectsExpire(t *testing.T) {
+	clock := NewFakeClock(testStart)
+	w := newTestWorld([]*Player{scripted([]Point{{1, 5}, {0, 5}}, Right)})
+	collect(t, w, clock, PowerUpGhost)
+
+	clock.Advance(2 * time.Second)
+	if got := w.Effects.Remaining(PowerUpGhost, clock.Now()); got != 4*time.Second {
+		t.Errorf("Remaining after 2s = %v, want 4s", got)
+	}
+
+	clock.Advance(4 * time.Second)
+	w.Update(clock.Now())
+	if w.Effects.Active(PowerUpGhost, clock.Now()) {
+		t.Error("ghost still active after its 6s")
+	}
+	if _, ok := w.Effects[PowerUpGhost]; ok {
+		t.Error("Update didn't remove the expired effect")
+	}
+}
+
+func TestPowerUpLifetime(t *testing.T) {
+	clock := NewFakeClock(testStart)
+	w := newTestWorld([]*Player{scripted([]Point{{1, 5}, {0, 5}}, Right)})
+
+	// Nothing appears before the first power-up is due
+	if d := w.NextPowerUpAt.Sub(clock.Now()); d < powerUpMinDelay || d >= powerUpMaxDelay {
+		t.Fatalf("first power-up due after %v, want within [%v, %v)", d, powerUpMinDelay, powerUpMaxDelay)
+	}
+	clock.Set(w.NextPowerUpAt.Add(-time.Millisecond))
+	w.Update(clock.Now())
+	if w.PowerUp != nil {
+		t.Fatal("power-up spawned early")
+	}
+
+	clock.Advance(time.Millisecond)
+	w.Update(clock.Now())
+	if w.PowerUp == nil {
+		t.Fatal("no power-up once it was due")
+	}
+	spawned := clock.Now()
+	if !w.PowerUp.ExpiresAt.Equal(spawned.Add(powerUpLifetime)) {
+		t.Errorf("power-up expires at %v, want %v", w.PowerUp.ExpiresAt, spawned.Add(powerUpLifetime))
+	}
+
+	// It stays for its lifetime, then goes and the next one is scheduled
+	clock.Set(spawned.Add(powerUpLifetime - time.Millisecond))
+	w.Update(clock.Now())
+	if w.PowerUp == nil {
+		t.Fatal("power-up gone before its lifetime")
+	}
+	clock.Set(spawned.Add(powerUpLifetime))
+	w.Update(clock.Now())
+	if w.PowerUp != nil {
+		t.Fatal("power-up still there after its lifetime")
+	}
+	if d := w.NextPowerUpAt.Sub(clock.Now()); d < powerUpMinDelay || d >= powerUpMaxDelay {
+		t.Errorf("next power-up due after %v, want within [%v, %v)", d, powerUpMinDelay, powerUpMaxDelay)
+	}
+}
+
+func TestPowerUpTimersPauseWithShift(t *testing.T) {
+	clock := NewFakeClock(testStart)
+	w := newTestWorld([]*Player{scripted([]Point{{1, 5}, {0, 5}}, Right)})
+	collect(t, w, clock, PowerUpSpeedBoost)
+
+	// A minute paused doesn't eat into the effect
+	clock.Advance(time.Minute)
+	w.Shift(time.Minute)
+	w.Update(clock.Now())
+	if got := w.Effects.Remaining(PowerUpSpeedBoost, clock.Now()); got != 5*time.Second {
+		t.Errorf("Remaining after a shifted pause = %v, want 5s", got)
+	}
+}
+
+func TestFoodLifetime(t *testing.T) {
+	clock := NewFakeClock(testStart)
+	w := &World{
+		Width:        10,
+		Height:       10,
+		Obstacles:    map[Point]bool{},
+		Players:      []*Player{scripted([]Point{{1, 5}, {0, 5}}, Right)},
+		FoodLifetime: 3 * time.Second,
+		Rand:         rand.New(rand.NewPCG(1, 2)),
+	}
+	w.Start(clock.Now())
+	if len(w.Foods) != 1 {
+		t.Fatalf("foods = %+v, want one piece", w.Foods)
+	}
+	first := w.Foods[0]
+	if !first.ExpiresAt.Equal(clock.Now().Add(3 * time.Second)) {
+		t.Fatalf("food expires at %v, want 3s after the start", first.ExpiresAt)
+	}
+
+	clock.Advance(3*time.Second - time.Millisecond)
+	w.Update(clock.Now())
+	if w.Foods[0] != first {
+		t.Fatalf("food changed before its lifetime: %+v, was %+v", w.Foods[0], first)
+	}
+	if got := first.Remaining(clock.Now()); got != time.Millisecond {
+		t.Errorf("Remaining = %v, want 1ms", got)
+	}
+
+	// Regular food moves on when it runs out, with a fresh lifetime
+	clock.Advance(time.Millisecond)
+	w.Update(clock.Now())
+	if len(w.Foods) != 1 || w.Foods[0].Kind != FoodNormal {
+		t.Fatalf("foods = %+v, want one piece of normal food", w.Foods)
+	}
+	if !w.Foods[0].ExpiresAt.Equal(clock.Now().Add(3 * time.Second)) {
+		t.Errorf("new food expires at %v, want 3s from now", w.Foods[0].ExpiresAt)
+	}
+}
+
+func TestGoldenAppleLifetime(t *testing.T) {
+	clock := NewFakeClock(testStart)
+	w := newTestWorld([]*Player{scripted([]Point{{1, 5}, {0, 5}}, Right)})
+	if !w.spawnFood(FoodGolden, clock.Now()) {
+		t.Fatal("no room for a golden apple")
+	}
+
+	clock.Advance(GoldenLifetime - time.Millisecond)
+	w.Update(clock.Now())
+	if !w.hasFoodKind(FoodGolden) {
+		t.Fatal("golden apple gone before GoldenLifetime")
+	}
+
+	// It isn't replaced when it runs out
+	clock.Advance(time.Millisecond)
+	w.Update(clock.Now())
+	if w.hasFoodKind(FoodGolden) {
+		t.Errorf("golden apple still there after GoldenLifetime: %+v", w.Foods)
+	}
+}
@@ -0,0 +1,53 @@
+package snake
+
+import "time"
+
+const (
+	// ComboWindow is how soon after a meal the next one must be eaten to
+	// keep the combo going
+	ComboWindow = 3 * time.Second
+
+	// MaxCombo caps the score multiplier
+	MaxCombo = 5
+)
+
+// Combo tracks a player's run of meals eaten in quick succession
+// Each normal food eaten within ComboWindow of the last one raises the
+// multiplier by one (up to MaxCombo); letting the window run out drops it
+// back to 1.
+type Combo struct {
+	// Count is the multiplier earned so far (0 or 1 = no combo)
+	Count int
+
+	// ExpiresAt is when the combo lapses unless the player eats again
+	ExpiresAt time.Time
+}
+
+// Multiplier returns the score multiplier in force at now
+func (c Combo) Multiplier(now time.Time) int {
+	if c.Count <= 1 || !now.Before(c.ExpiresAt) {
+		return 1
+	}
+	return c.Count
+}
+
+// Remaining returns how long is left to keep the combo going (0 when
+// there is no combo)
+func (c Combo) Remaining(now time.Time) time.Duration {
+	if c.Multiplier(now) <= 1 {
+		return 0
+	}
+	return c.ExpiresAt.Sub(now)
+}
+
+// eat records a meal at now and returns the multiplier it scores with
+func (c *Combo) eat(now time.Time) int {
+	if now.Before(c.ExpiresAt) {
+		c.Count = min(c.Count+1, MaxCombo)
+	} else {
+		// The first meal of a new combo scores normally
+		c.Count = 1
+	}
+	c.ExpiresAt = now.Add(ComboWindow)
+	return c.Count
+}
@@ -0,0 +1,102 @@
+package snake
+
+import "slices"
+
+// Controller decides where a snake goes
+// The world asks each player's controller once per movement tick, so
+// keyboard players, bots (see bot.go) and scripted players are
+// interchangeable, and any mix of them can share the board.
+type Controller interface {
+	// NextDirection returns the direction to move in this tick (one of
+	// the direction vectors). Reversing into the snake's own neck is
+	// ignored.
+	NextDirection(state GameState) Point
+}
+
+// GameState is the view of the board a Controller decides from
+// The bodies, slices and the map are shared with the world and must not be
+// modified.
+type GameState struct {
+	// Width and Height are the board size in cells
+	Width, Height int
+
+	// Inset is how many rings around the edge a shrinking arena has
+	// closed (0 without one)
+	Inset int
+
+	// Self is the index in Snakes of the snake being steered
+	Self int
+
+	// Snakes are the bodies of every snake and Directions the way each
+	// one is currently heading
+	Snakes     []*Body
+	Directions []Point
+
+	Obstacles map[Point]bool
+	Portals   []Portal
+	Foods     []Food
+	Enemies   []*Enemy
+}
+
+// State captures the board for player i's controller
+func (w *World) State(i int) GameState {
+	s := GameState{
+		Width:     w.Width,
+		Height:    w.Height,
+		Self:      i,
+		Obstacles: w.Obstacles,
+		Portals:   w.Portals,
+		Foods:     w.Foods,
+		Enemies:   slices.Collect(w.Enemies()),
+	}
+	if w.Arena != nil {
+		s.Inset = w.Arena.Inset
+	}
+	for _, p := range w.Players {
+		s.Snakes = append(s.Snakes, &p.Snake)
+		s.Directions = append(s.Directions, p.Direction)
+	}
+	return s
+}
+
+// Head returns the head of the snake being steered
+func (s GameState) Head() Point {
+	return s.Snakes[s.Self].Head()
+}
+
+// Direction returns the heading of the snake being steered
+func (s GameState) Direction() Point {
+	return s.Directions[s.Self]
+}
+
+// InBounds reports whether p lies on the open part of the board
+func (s GameState) InBounds(p Point) bool {
+	return p.X >= s.Inset && p.Y >= s.Inset && p.X < s.Width-s.Inset && p.Y < s.Height-s.Inset
+}
+
+// FoodAt returns the food at p, if there is any
+func (s GameState) FoodAt(p Point) (Food, bool) {
+	for _, f := range s.Foods {
+		if f.Pos == p {
+			return f, true
+		}
+	}
+	return Food{}, false
+}
+
+// ScriptedController plays a fixed list of moves, one per tick, then
+// keeps going straight. Useful for demos and for reproducing a run.
+type ScriptedController struct {
+	Moves []Point
+	next  int
+}
+
+// NextDirection returns the next scripted move
+func (c *ScriptedController) NextDirection(state GameState) Point {
+	if c.next >= len(c.Moves) {
+		return state.Direction()
+	}
+	d := c.Moves[c.next]
+	c.next++
+	return d
+}
@@ -0,0 +1,45 @@
+package snake
+
+import "time"
+
+// DashCooldown is how long a snake has to wait after a dash before it can
+// dash again
+const DashCooldown = 3 * time.Second
+
+// DashCells is how far a dashing snake moves in one tick
+const DashCells = 2
+
+// Dasher is implemented by controllers that can make their snake dash:
+// move DashCells cells in one tick instead of one, every cell checked for
+// collisions and eaten from as usual. Controllers without it never dash.
+type Dasher interface {
+	// WantsDash reports whether the snake should dash on this tick
+	// It is asked once per tick, after NextDirection; a dash asked for
+	// during the cooldown is dropped.
+	WantsDash() bool
+}
+
+// CanDash reports whether the snake's dash has cooled down at now
+func (p *Player) CanDash(now time.Time) bool {
+	return !now.Before(p.DashReadyAt)
+}
+
+// DashCharge returns how far the dash has cooled down at now, from 0
+// (just used) to 1 (ready)
+func (p *Player) DashCharge(now time.Time) float64 {
+	if p.CanDash(now) {
+		return 1
+	}
+	return 1 - float64(p.DashReadyAt.Sub(now))/float64(DashCooldown)
+}
+
+// dashes asks player p's controller whether to dash, starting the
+// cooldown if it does
+func (p *Player) dashes(now time.Time) bool {
+	d, ok := p.Controller.(Dasher)
+	if !ok || !d.WantsDash() || !p.CanDash(now) {
+		return false
+	}
+	p.DashReadyAt = now.Add(DashCooldown)
+	return true
+}
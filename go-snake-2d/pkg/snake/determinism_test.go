@@ -0,0 +1,150 @@
+package snake
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tickInterval is how far apart the ticks of the determinism tests are
+const tickInterval = 150 * time.Millisecond
+
+// newSeededWorld returns a started 16×12 world seeded with seed, with a
+// few walls, enemies and food that runs out, holding a snake for each
+// controller
+func newSeededWorld(seed uint64, controllers ...Controller) *World {
+	w := &World{
+		Width:        16,
+		Height:       12,
+		Obstacles:    map[Point]bool{{7, 5}: true, {8, 5}: true, {8, 6}: true},
+		FoodLifetime: 4 * time.Second,
+		EnemyCount:   2,
+		Rand:         rand.New(rand.NewPCG(seed, seed)),
+	}
+	for i, c := range controllers {
+		y := 2 + 7*i
+		w.Players = append(w.Players, NewPlayer(fmt.Sprint("P", i+1), []Point{{3, y}, {2, y}}, Right, c))
+	}
+	w.Start(testStart)
+	return w
+}
+
+// digest writes down everything about the world a front end could show,
+// to compare two worlds by
+func digest(w *World) string {
+	var b strings.Builder
+	for _, p := range w.Players {
+		fmt.Fprintf(&b, "%s %v dir=%v score=%d dead=%v shield=%v combo=%d dash=%v\n",
+			p.Name, p.Snake.Points(), p.Direction, p.Score, p.Dead, p.Shield, p.Combo.Count, p.DashReadyAt.Sub(testStart))
+	}
+	fmt.Fprintf(&b, "foods=%+v\n", w.Foods)
+	if w.PowerUp != nil {
+		fmt.Fprintf(&b, "powerup=%+v\n", *w.PowerUp)
+	}
+	fmt.Fprintf(&b, "next=%v effects=%v full=%v\n", w.NextPowerUpAt.Sub(testStart), w.Effects, w.Full)
+	for e := range w.Entities.All() {
+		fmt.Fprintf(&b, "%T %v\n", e, e.Cells())
+	}
+	return b.String()
+}
+
+// play runs w for up to ticks ticks, or until the round is over, like a
+// front end would, and returns the digest of every tick with its events
+func play(w *World, ticks int) []string {
+	var history []string
+	now := testStart
+	for range ticks {
+		now = now.Add(tickInterval)
+		events := w.Update(now)
+		events = append(events, w.Step(now)...)
+		history = append(history, fmt.Sprintf("%v\n%s", kinds(events), digest(w)))
+		if w.RoundOver() {
+			break
+		}
+	}
+	return history
+}
+
+// compare fails t at the first tick where the histories a and b differ
+func compare(t *testing.T, a, b []string) {
+	t.Helper()
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			t.Fatalf("tick %d differs:\n%s\nvs\n%s", i+1, a[i], b[i])
+		}
+	}
+	if len(a) != len(b) {
+		t.Fatalf("one run lasted %d ticks, the other %d", len(a), len(b))
+	}
+}
+
+func TestSameSeedSameGame(t *testing.T) {
+	for _, seed := range []uint64{1, 42, 1 << 40} {
+		t.Run(fmt.Sprint(seed), func(t *testing.T) {
+			a := play(newSeededWorld(seed, &Bot{CheckSpace: true, AvoidHeads: true}, &Bot{SearchDepth: 8}), 400)
+			b := play(newSeededWorld(seed, &Bot{CheckSpace: true, AvoidHeads: true}, &Bot{SearchDepth: 8}), 400)
+			if len(a) < 20 {
+				t.Fatalf("run only lasted %d ticks, too short to tell", len(a))
+			}
+			compare(t, a, b)
+		})
+	}
+}
+
+func TestDifferentSeedsDifferentGames(t *testing.T) {
+	a := play(newSeededWorld(1, &Bot{CheckSpace: true}), 50)
+	b := play(newSeededWorld(2, &Bot{CheckSpace: true}), 50)
+	if strings.Join(a, "") == strings.Join(b, "") {
+		t.Error("seeds 1 and 2 played the same game")
+	}
+}
+
+// recorder steers with a bot, dashing every dashEvery ticks, and writes
+// down what it did on each tick, like a TAS run records its inputs
+type recorder struct {
+	bot       Bot
+	dashEvery int
+
+	moves  []Point
+	dashes []bool
+}
+
+func (r *recorder) NextDirection(state GameState) Point {
+	d := r.bot.NextDirection(state)
+	r.moves = append(r.moves, d)
+	r.dashes = append(r.dashes, len(r.moves)%r.dashEvery == 0)
+	return d
+}
+
+func (r *recorder) WantsDash() bool {
+	return r.dashes[len(r.dashes)-1]
+}
+
+// playback plays recorded inputs back, one per tick
+type playback struct {
+	ScriptedController
+	dashes []bool
+}
+
+func (p *playback) WantsDash() bool {
+	return p.dashes[p.next-1]
+}
+
+func TestReplayPlaysTheSameGame(t *testing.T) {
+	for _, seed := range []uint64{3, 99} {
+		t.Run(fmt.Sprint(seed), func(t *testing.T) {
+			rec := &recorder{bot: Bot{CheckSpace: true}, dashEvery: 7}
+			w := newSeededWorld(seed, rec)
+			recorded := play(w, 300)
+			if w.Players[0].DashReadyAt.IsZero() {
+				t.Fatal("the recorded run never dashed")
+			}
+
+			pb := &playback{ScriptedController{Moves: rec.moves}, rec.dashes}
+			replayed := play(newSeededWorld(seed, pb), 300)
+			compare(t, recorded, replayed)
+		})
+	}
+}
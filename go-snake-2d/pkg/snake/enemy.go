@@ -0,0 +1,119 @@
+package snake
+
+import (
+	"iter"
+	"time"
+)
+
+const (
+	// EnemyInterval is how many ticks an enemy waits between steps, so
+	// the snakes can outrun it
+	EnemyInterval = 2
+
+	// EnemySpawnDistance is how close (in cells, Manhattan distance) to a
+	// snake head an enemy may appear
+	EnemySpawnDistance = 10
+)
+
+// Enemy is a hostile creature that hunts the nearest snake head
+// A snake that runs into one, or that one catches, dies.
+type Enemy struct {
+	Pos Point `json:"pos"`
+
+	// Ticks counts the ticks since the enemy last moved
+	Ticks int `json:"ticks,omitempty"`
+}
+
+// Cells returns the enemy's cell
+func (e *Enemy) Cells() []Point {
+	return []Point{e.Pos}
+}
+
+// Enemies returns an iterator over the enemies on the board
+func (w *World) Enemies() iter.Seq[*Enemy] {
+	return Of[*Enemy](&w.Entities)
+}
+
+// spawnEnemies replaces the enemies with EnemyCount new ones, on free
+// cells away from the snakes' heads
+// A board too crowded to find a spot gets fewer enemies.
+func (w *World) spawnEnemies() {
+	DeleteOf(&w.Entities, func(*Enemy) bool { return true })
+	for range w.EnemyCount {
+		for try := 0; try < 100; try++ {
+			p := w.randomCell()
+			if w.IsBadCollision(p) || w.FoodAt(p) >= 0 || w.isPortal(p) || w.headDistance(p) < EnemySpawnDistance {
+				continue
+			}
+			w.Entities.Add(&Enemy{Pos: p})
+			break
+		}
+	}
+}
+
+// enemySystem gives the enemies their turn after the snakes have moved:
+// every EnemyInterval ticks, each takes one step along the shortest path
+// to the nearest snake head. An enemy reaching a head kills that snake.
+type enemySystem struct{}
+
+func (enemySystem) Update(w *World, now time.Time, events []Event) []Event {
+	for e := range w.Enemies() {
+		e.Ticks++
+		if e.Ticks < EnemyInterval {
+			continue
+		}
+		e.Ticks = 0
+
+		next, ok := w.chase(e.Pos)
+		if !ok {
+			continue
+		}
+		e.Pos = next
+		for j, p := range w.Players {
+			if !p.Dead && !p.Invincible && p.Head() == next {
+				p.Dead = true
+				events = append(events, Event{Kind: EventDied, Player: j})
+			}
+		}
+	}
+	return events
+}
+
+// chase runs a breadth-first search from an enemy at from and returns the
+// first step of the shortest path to a live snake head
+// Enemies go around walls, bodies, food, portals and each other.
+func (w *World) chase(from Point) (Point, bool) {
+	heads := map[Point]bool{}
+	for _, p := range w.Players {
+		if !p.Dead {
+			heads[p.Head()] = true
+		}
+	}
+
+	type node struct{ pos, first Point }
+	seen := map[Point]bool{from: true}
+	queue := []node{{pos: from}}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, d := range Directions {
+			next := n.pos.Add(d)
+			if seen[next] {
+				continue
+			}
+			seen[next] = true
+			first := n.first
+			if n.pos == from {
+				first = next
+			}
+			if heads[next] {
+				return first, true
+			}
+			if w.IsBadCollision(next) || w.FoodAt(next) >= 0 || w.isPortal(next) {
+				continue
+			}
+			queue = append(queue, node{pos: next, first: first})
+		}
+	}
+	return from, false
+}
@@ -0,0 +1,109 @@
+package snake
+
+import (
+	"iter"
+	"slices"
+	"time"
+)
+
+// The objects on the board that act on their own, like the enemies, the
+// boss and its shots, are entities: the world keeps them all in one
+// registry (see Entities) rather than a field for each kind, and gives
+// them their turn on every Step through its systems (see System). A new
+// kind of object is a new Entity type and a new System, without new World
+// fields or new steps in Step.
+
+// Entity is an object kept in the world's registry
+type Entity interface {
+	// Cells returns the cells the entity takes up, which items aren't
+	// placed on and paths go around; nil if it takes up none
+	Cells() []Point
+}
+
+// Entities is a registry of entities, kept in the order they were added
+// so the systems go through them the same way every time
+type Entities struct {
+	list []Entity
+}
+
+// Add puts e in the registry
+func (r *Entities) Add(e Entity) {
+	r.list = append(r.list, e)
+}
+
+// Len returns how many entities there are
+func (r *Entities) Len() int {
+	return len(r.list)
+}
+
+// All returns an iterator over every entity
+func (r *Entities) All() iter.Seq[Entity] {
+	return slices.Values(r.list)
+}
+
+// Clear removes every entity
+func (r *Entities) Clear() {
+	r.list = slices.Delete(r.list, 0, len(r.list))
+}
+
+// Of returns an iterator over the entities of type T
+func Of[T Entity](r *Entities) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, e := range r.list {
+			if t, ok := e.(T); ok && !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// FirstOf returns the first entity of type T, or T's zero value (nil for
+// pointers) if there is none
+func FirstOf[T Entity](r *Entities) T {
+	for t := range Of[T](r) {
+		return t
+	}
+	var zero T
+	return zero
+}
+
+// DeleteOf removes the entities of type T for which del returns true
+func DeleteOf[T Entity](r *Entities, del func(T) bool) {
+	r.list = slices.DeleteFunc(r.list, func(e Entity) bool {
+		t, ok := e.(T)
+		return ok && del(t)
+	})
+}
+
+// System gives one kind of object its turn on every Step, once the
+// snakes have moved, appending what happened to events
+type System interface {
+	Update(w *World, now time.Time, events []Event) []Event
+}
+
+// systems are the world's systems in the order they run: the food moves
+// first, then the enemies and the boss, seeing where everything ended up
+var systems = []System{
+	foodSystem{},
+	enemySystem{},
+	bossSystem{},
+}
+
+// foodSystem moves the fleeing food (see updateFoods)
+type foodSystem struct{}
+
+func (foodSystem) Update(w *World, now time.Time, events []Event) []Event {
+	w.updateFoods(now)
+	return events
+}
+
+// occupied reports whether p is one of the cells an entity other than
+// except (nil for none) takes up
+func (w *World) occupied(p Point, except Entity) bool {
+	for e := range w.Entities.All() {
+		if e != except && slices.Contains(e.Cells(), p) {
+			return true
+		}
+	}
+	return false
+}
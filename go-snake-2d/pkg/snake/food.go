@@ -0,0 +1,500 @@
+package snake
+
+import "time"
+
+// FoodKind identifies what happens when the snake eats a piece of food
+type FoodKind int
+
+const (
+	// FoodNormal makes the snake grow and scores points
+	FoodNormal FoodKind = iota
+	// FoodPoison removes segments, or kills a snake that is too short
+	FoodPoison
+	// FoodFleeing is normal food that runs away from the snakes, worth
+	// FleeingPoints instead of FoodPoints
+	FoodFleeing
+	// FoodGolden is a rare bonus worth GoldenPoints that vanishes if it
+	// isn't eaten within GoldenLifetime
+	FoodGolden
+	// FoodRemains is what a dead snake turns into when the world's
+	// DeadLeaveFood is set: one piece per segment, worth RemainsPoints
+	// and not replaced when eaten
+	FoodRemains
+)
+
+const (
+	// FoodPoints is how many points each piece of food is worth
+	FoodPoints = 10
+
+	// PoisonChance is the probability that a poison pellet appears each
+	// time normal food is eaten
+	PoisonChance = 0.3
+
+	// PoisonShrink is how many segments eating poison removes
+	PoisonShrink = 2
+
+	// FleeingChance is the probability that the food replacing an eaten
+	// piece is fleeing food
+	FleeingChance = 0.2
+
+	// FleeingPoints is how many points fleeing food is worth
+	FleeingPoints = 30
+
+	// FleeInterval is how many ticks fleeing food waits between steps, so
+	// the snake can catch it
+	FleeInterval = 3
+
+	// GoldenChance is the probability that a golden apple appears each
+	// time normal food is eaten (if there isn't one already)
+	GoldenChance = 0.1
+
+	// GoldenPoints is how many points a golden apple is worth
+	GoldenPoints = 50
+
+	// GoldenLifetime is how long a golden apple stays on the board
+	GoldenLifetime = 6 * time.Second
+
+	// RemainsPoints is how many points each piece of a dead snake's
+	// remains is worth
+	RemainsPoints = 5
+)
+
+// Food is an edible item on the board
+type Food struct {
+	Pos  Point    `json:"pos"`
+	Kind FoodKind `json:"kind"`
+
+	// Ticks counts the ticks since fleeing food last moved
+	Ticks int `json:"ticks,omitempty"`
+
+	// Lifetime is how long the food stays on the board (0 = for good),
+	// and ExpiresAt when it runs out
+	// ExpiresAt isn't saved as is: a saved game stores the time left
+	// instead.
+	Lifetime  time.Duration `json:"lifetime,omitempty"`
+	ExpiresAt time.Time     `json:"-"`
+}
+
+// Grows reports whether eating the food makes the snake longer
+func (k FoodKind) Grows() bool {
+	return k == FoodNormal || k == FoodFleeing || k == FoodGolden || k == FoodRemains
+}
+
+// Remaining returns how long the food has left on the board at now, or
+// 0 if it never expires (or already has)
+func (f Food) Remaining(now time.Time) time.Duration {
+	if f.ExpiresAt.IsZero() {
+		return 0
+	}
+	return max(f.ExpiresAt.Sub(now), 0)
+}
+
+// lifetime returns how long newly spawned food of the given kind lasts,
+// or 0 if it stays until eaten
+func (w *World) lifetime(kind FoodKind) time.Duration {
+	switch kind {
+	case FoodGolden:
+		return GoldenLifetime
+	case FoodNormal, FoodFleeing:
+		return w.FoodLifetime
+	}
+	return 0
+}
+
+// expireFoods takes food whose time is up off the board
+// Golden apples are gone for good, but the snakes' regular food comes
+// back somewhere else with a fresh lifetime.
+func (w *World) expireFoods(now time.Time) {
+	for i := len(w.Foods) - 1; i >= 0; i-- {
+		f := w.Foods[i]
+		if f.ExpiresAt.IsZero() || now.Before(f.ExpiresAt) {
+			continue
+		}
+		w.removeFood(i)
+		if f.Kind != FoodGolden {
+			w.spawnFood(f.Kind, now)
+		}
+	}
+}
+
+// leaveRemains turns the bodies of the snakes that have died into food,
+// one piece of FoodRemains per segment not on other food, and clears them
+// off the board
+func (w *World) leaveRemains() {
+	for _, p := range w.Players {
+		if !p.Dead || p.Snake.Len() == 0 {
+			continue
+		}
+		for _, c := range p.Snake.All() {
+			if w.InBounds(c) && w.FoodAt(c) < 0 {
+				w.Foods = append(w.Foods, Food{Pos: c, Kind: FoodRemains})
+			}
+		}
+		p.Snake.Truncate(0)
+	}
+}
+
+// hasFoodKind reports whether there is food of the given kind on the
+// board
+func (w *World) hasFoodKind(kind FoodKind) bool {
+	for _, f := range w.Foods {
+		if f.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// FoodAt returns the index of the food at p, or -1 if there is none
+func (w *World) FoodAt(p Point) int {
+	for i, f := range w.Foods {
+		if f.Pos == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeFood takes the food at index i off the board
+func (w *World) removeFood(i int) {
+	w.Foods = append(w.Foods[:i], w.Foods[i+1:]...)
+}
+
+// removeFoodKind takes every food of the given kind off the board
+func (w *World) removeFoodKind(kind FoodKind) {
+	kept := w.Foods[:0]
+	for _, f := range w.Foods {
+		if f.Kind != kind {
+			kept = append(kept, f)
+		}
+	}
+	w.Foods = kept
+}
+
+// spawnFood adds a food item of the given kind on a random free cell
+// Food only goes where it can be eaten and doesn't hide anything: never
+// on walls, snakes, portals, enemies, other items or outside the arena.
+// Boards with fixed food spawns use those cells while any is free. With
+// FoodSpread set, the free cell furthest from the snakes' heads out of a
+// few drawn wins. Food that expires (see lifetime) starts its timer at
+// now.
+// Returns false if there was no free cell left for it.
+func (w *World) spawnFood(kind FoodKind, now time.Time) bool {
+	f := Food{Kind: kind, Lifetime: w.lifetime(kind)}
+	if f.Lifetime > 0 {
+		f.ExpiresAt = now.Add(f.Lifetime)
+	}
+
+	taken := w.takenCells()
+	var cells []Point
+	for _, p := range w.FoodSpawns {
+		if w.InBounds(p) && !taken[p] {
+			cells = append(cells, p)
+		}
+	}
+	if len(cells) == 0 {
+		lo, hi := w.Bounds()
+		for y := lo.Y; y < hi.Y; y++ {
+			for x := lo.X; x < hi.X; x++ {
+				if p := (Point{x, y}); !taken[p] {
+					cells = append(cells, p)
+				}
+			}
+		}
+	}
+	if len(cells) == 0 {
+		return false
+	}
+
+	f.Pos = cells[w.Rand.IntN(len(cells))]
+	for range w.FoodSpread - 1 {
+		p := cells[w.Rand.IntN(len(cells))]
+		if w.headDistance(p) > w.headDistance(f.Pos) {
+			f.Pos = p
+		}
+	}
+	w.Foods = append(w.Foods, f)
+	return true
+}
+
+// AddFood puts n more pieces of normal food on the board, on top of what
+// is there, and returns how many found a free cell
+func (w *World) AddFood(n int, now time.Time) int {
+	added := 0
+	for range n {
+		if !w.spawnFood(FoodNormal, now) {
+			break
+		}
+		added++
+	}
+	return added
+}
+
+// takenCells returns the cells on which no new item may be placed:
+// walls, portals, snakes, entities (the enemies and the boss), food and
+// the power-up
+func (w *World) takenCells() map[Point]bool {
+	taken := make(map[Point]bool, len(w.Obstacles))
+	for p := range w.Obstacles {
+		taken[p] = true
+	}
+	for _, pt := range w.Portals {
+		taken[pt.A], taken[pt.B] = true, true
+	}
+	for _, pl := range w.Players {
+		for _, p := range pl.Snake.All() {
+			taken[p] = true
+		}
+	}
+	for e := range w.Entities.All() {
+		for _, c := range e.Cells() {
+			taken[c] = true
+		}
+	}
+	for _, f := range w.Foods {
+		taken[f.Pos] = true
+	}
+	if w.PowerUp != nil {
+		taken[w.PowerUp.Pos] = true
+	}
+	return taken
+}
+
+// updateFoods gives food its turn after the snakes have moved: every
+// FleeInterval ticks, fleeing food takes one step away from the nearest
+// snake head. While PowerUpMagnet is active the magnet pulls food in
+// instead, fleeing food included.
+func (w *World) updateFoods(now time.Time) {
+	if w.Effects.Active(PowerUpMagnet, now) {
+		w.pullFoods()
+		return
+	}
+	var grid Grid
+	for i := range w.Foods {
+		f := &w.Foods[i]
+		if f.Kind != FoodFleeing {
+			continue
+		}
+		f.Ticks++
+		if f.Ticks < FleeInterval {
+			continue
+		}
+		f.Ticks = 0
+		if grid.Walls == nil {
+			grid = w.grid()
+		}
+		f.Pos = w.fleeFrom(grid, f.Pos)
+	}
+}
+
+// pullFoods moves every food within MagnetRadius of a live snake head one
+// cell toward that head; poison stays where it is
+// Food in reach of several heads only moves toward the first.
+func (w *World) pullFoods() {
+	moved := make(map[int]bool)
+	for _, pl := range w.Players {
+		if pl.Dead {
+			continue
+		}
+		head := pl.Head()
+		for _, i := range w.foodsWithin(head, MagnetRadius) {
+			f := &w.Foods[i]
+			if f.Kind == FoodPoison || moved[i] {
+				continue
+			}
+			f.Pos = w.stepToward(f.Pos, head)
+			moved[i] = true
+		}
+	}
+}
+
+// foodsWithin returns the indexes in Foods of the food within r cells
+// (Manhattan distance) of p
+func (w *World) foodsWithin(p Point, r int) []int {
+	var found []int
+	for i, f := range w.Foods {
+		if abs(f.Pos.X-p.X)+abs(f.Pos.Y-p.Y) <= r {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+// stepToward returns the neighbor of p one cell closer to target, or p
+// itself if the way is blocked
+// The longer axis is tried first, so food comes in on a straight-ish
+// line. Like fleeing food, it can't move onto walls, snakes, portals,
+// other items or the power-up.
+func (w *World) stepToward(p, target Point) Point {
+	dx, dy := sign(target.X-p.X), sign(target.Y-p.Y)
+	steps := []Point{{X: dx}, {Y: dy}}
+	if abs(target.Y-p.Y) > abs(target.X-p.X) {
+		steps[0], steps[1] = steps[1], steps[0]
+	}
+	for _, d := range steps {
+		if d == (Point{}) {
+			continue
+		}
+		n := p.Add(d)
+		if w.IsBadCollision(n) || w.FoodAt(n) >= 0 || w.isPortal(n) || w.PowerUp != nil && w.PowerUp.Pos == n {
+			continue
+		}
+		return n
+	}
+	return p
+}
+
+// fleeFrom returns the neighbor of p furthest from the nearest live
+// snake head, or p itself if no free neighbor is further away
+// Distances are along the paths the snakes would have to take on grid,
+// around walls and bodies (see pathDistance). Food can't step off the open
+// board or onto walls, snakes, portals, other items or the power-up, and
+// never flees where no snake can reach it.
+func (w *World) fleeFrom(grid Grid, p Point) Point {
+	best, bestDist := p, w.pathDistance(grid, p)
+	for _, d := range Directions {
+		n := p.Add(d)
+		if w.IsBadCollision(n) || w.FoodAt(n) >= 0 || w.isPortal(n) || w.PowerUp != nil && w.PowerUp.Pos == n {
+			continue
+		}
+		if dist := w.pathDistance(grid, n); dist > bestDist {
+			best, bestDist = n, dist
+		}
+	}
+	return best
+}
+
+// pathDistance returns the length of the shortest path on grid from the
+// nearest live snake head to p, or -1 if no snake can get there
+func (w *World) pathDistance(grid Grid, p Point) int {
+	dist := -1
+	for _, pl := range w.Players {
+		if pl.Dead {
+			continue
+		}
+		if path, ok := FindPath(grid, pl.Head(), []Point{p}, 0); ok && (dist < 0 || path.Len() < dist) {
+			dist = path.Len()
+		}
+	}
+	return dist
+}
+
+// grid returns the board as the snakes' paths see it: walls and entities
+// (the enemies and the boss) can't be entered, and the snakes' bodies
+// move on (see Grid)
+func (w *World) grid() Grid {
+	g := Grid{
+		Width:   w.Width,
+		Height:  w.Height,
+		Walls:   make(map[Point]bool, len(w.Obstacles)+w.Entities.Len()),
+		Portals: w.Portals,
+	}
+	if w.Arena != nil {
+		g.Inset = w.Arena.Inset
+	}
+	for c := range w.Obstacles {
+		g.Walls[c] = true
+	}
+	for e := range w.Entities.All() {
+		for _, c := range e.Cells() {
+			g.Walls[c] = true
+		}
+	}
+	for _, p := range w.Players {
+		g.AddBody(&p.Snake)
+	}
+	return g
+}
+
+// headDistance returns the Manhattan distance from p to the nearest live
+// snake head
+func (w *World) headDistance(p Point) int {
+	dist := -1
+	for _, pl := range w.Players {
+		if pl.Dead {
+			continue
+		}
+		h := pl.Head()
+		if d := abs(p.X-h.X) + abs(p.Y-h.Y); dist < 0 || d < dist {
+			dist = d
+		}
+	}
+	return dist
+}
+
+// abs returns the absolute value of x
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// sign returns -1, 0 or 1 for negative, zero or positive x
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+	return 0
+}
+
+// eatFood applies the effect of the food at index i, which player p's
+// head has just moved onto. The snake has already moved (and grown, for
+// normal food) when this is called. Normal food scores FoodPoints times
+// the player's combo multiplier, fleeing food FleeingPoints times it and
+// golden apples GoldenPoints times it.
+// Returns the event to report, and false if the food killed the snake.
+func (w *World) eatFood(p *Player, i int, now time.Time) (EventKind, bool) {
+	f := w.Foods[i]
+	w.removeFood(i)
+
+	switch f.Kind {
+	case FoodPoison:
+		// Too short to lose segments: the poison is fatal
+		if p.Snake.Len() <= PoisonShrink {
+			return EventPoisoned, p.Invincible
+		}
+		p.Snake.Truncate(p.Snake.Len() - PoisonShrink)
+		return EventPoisoned, true
+
+	case FoodGolden:
+		// A bonus on top of the normal food, which stays where it is
+		p.Score += GoldenPoints * p.Combo.eat(now)
+		return EventAte, true
+
+	case FoodRemains:
+		// Remains only run out, like the golden apple
+		p.Score += RemainsPoints * p.Combo.eat(now)
+		return EventAte, true
+
+	default:
+		points := FoodPoints
+		if f.Kind == FoodFleeing {
+			points = FleeingPoints
+		}
+		p.Score += points * p.Combo.eat(now)
+
+		// A fresh meal also reshuffles the poison: the old pellet is
+		// cleared and a new one may appear somewhere else
+		w.removeFoodKind(FoodPoison)
+		next := FoodNormal
+		if w.Rand.Float64() < FleeingChance && !w.NoFleeing {
+			next = FoodFleeing
+		}
+		if !w.spawnFood(next, now) {
+			// Nowhere left to put it: the snakes have filled the board
+			w.Full = true
+			return EventAte, true
+		}
+		if w.Rand.Float64() < PoisonChance {
+			w.spawnFood(FoodPoison, now)
+		}
+		if !w.hasFoodKind(FoodGolden) && w.Rand.Float64() < GoldenChance {
+			w.spawnFood(FoodGolden, now)
+		}
+		return EventAte, true
+	}
+}
@@ -0,0 +1,161 @@
+package snake
+
+// hamiltonShortcutShare is the share of the board the snake can cover
+// before HamiltonBot stops taking shortcuts; past it, every cell saved
+// makes the tail harder to keep clear of
+const hamiltonShortcutShare = 0.5
+
+// hamiltonGap is how many free cells HamiltonBot leaves between its head
+// and its tail along the cycle when taking a shortcut, so the snake can
+// grow on the way
+const hamiltonGap = 4
+
+// HamiltonBot is a Controller that plays perfectly on an open board,
+// filling the whole of it
+// It follows a Hamiltonian cycle (see HamiltonCycle), a closed route
+// through every cell of the board. The body always lies along the cycle
+// behind the head, so the snake never runs into itself, however long it
+// gets. Following the whole cycle to every food is slow, so while the
+// snake is short it takes shortcuts toward the food: it skips ahead along
+// the cycle, never past the food and never so far that it closes in on
+// its tail.
+//
+// Poison is stepped around where the cycle allows it. The board must have
+// no walls, portals or enemies, no fleeing food (see World.NoFleeing) and
+// an even number of cells, and only one snake on it. The cycle is worked
+// out once for the board's size and kept, so one HamiltonBot should steer
+// one snake.
+type HamiltonBot struct {
+	width, height int
+
+	// cycle is the route around the board, and order the position of
+	// each cell along it, indexed by y*width+x
+	cycle []Point
+	order []int
+}
+
+// NextDirection follows the cycle, or a shortcut along it toward food
+func (b *HamiltonBot) NextDirection(s GameState) Point {
+	if b.cycle == nil || b.width != s.Width || b.height != s.Height {
+		b.plan(s.Width, s.Height)
+	}
+	if b.cycle == nil {
+		// No cycle on this board: nothing better to do than carry on
+		return s.Direction()
+	}
+
+	// How far ahead along the cycle each cell is from the head; the body
+	// is all behind it, the tail furthest back
+	body := s.Snakes[s.Self]
+	n, head := len(b.cycle), body.Head()
+	ahead := func(c Point) int {
+		return (b.order[c.Y*b.width+c.X] - b.order[head.Y*b.width+head.X] + n) % n
+	}
+	best, bestAhead := b.cycle[(b.order[head.Y*b.width+head.X]+1)%n], 1
+
+	// The neighbors the snake can skip ahead to: ones further along the
+	// cycle that keep clear of the tail, and of poison
+	limit := ahead(body.Tail()) - hamiltonGap
+	if body.Len() == 1 {
+		limit = n - hamiltonGap
+	}
+	var skips []Point
+	for _, d := range Directions {
+		c := head.Add(d)
+		if !s.InBounds(c) || s.Obstacles[c] || isPoison(s, c) {
+			continue
+		}
+		if k := ahead(c); k > 1 && k < limit {
+			skips = append(skips, c)
+		}
+	}
+
+	// How far along the cycle the nearest food is (-1 if there is none)
+	target := -1
+	for _, f := range s.Foods {
+		if f.Kind.Grows() && (target < 0 || ahead(f.Pos) < target) {
+			target = ahead(f.Pos)
+		}
+	}
+
+	// SHORTCUTS
+	// To the neighbor furthest along the cycle that isn't past the food
+	if float64(body.Len()) < hamiltonShortcutShare*float64(n) {
+		for _, c := range skips {
+			if k := ahead(c); k > bestAhead && k <= target {
+				best, bestAhead = c, k
+			}
+		}
+	}
+
+	// POISON
+	// Skip the poison, as little as possible, if there is a way around
+	// Poison stays put until food is eaten, so when going around would
+	// skip the food too, a snake that can afford to eats the poison
+	// instead of going round the cycle for nothing.
+	if isPoison(s, best) {
+		around, aroundAhead := best, -1
+		for _, c := range skips {
+			if k := ahead(c); aroundAhead < 0 || k < aroundAhead {
+				around, aroundAhead = c, k
+			}
+		}
+		if aroundAhead >= 0 && (target < 0 || aroundAhead <= target || body.Len() <= PoisonShrink) {
+			best = around
+		}
+	}
+	return Point{X: best.X - head.X, Y: best.Y - head.Y}
+}
+
+// isPoison reports whether there is poison at c
+func isPoison(s GameState, c Point) bool {
+	f, ok := s.FoodAt(c)
+	return ok && f.Kind == FoodPoison
+}
+
+// plan works out the cycle for a w×h board
+func (b *HamiltonBot) plan(w, h int) {
+	b.width, b.height = w, h
+	b.cycle = HamiltonCycle(w, h)
+	b.order = make([]int, w*h)
+	for i, c := range b.cycle {
+		b.order[c.Y*w+c.X] = i
+	}
+}
+
+// HamiltonCycle returns a closed route through every cell of a w×h board,
+// visiting each once, one cell at a time and from the last cell back to
+// the first; or nil if there is none, when w and h are both odd or the
+// board is a single row or column
+func HamiltonCycle(w, h int) []Point {
+	switch {
+	case w < 2 || h < 2 || w*h%2 != 0:
+		return nil
+	case w%2 != 0:
+		// Build the cycle for the board turned on its side, then turn it
+		// back
+		cycle := HamiltonCycle(h, w)
+		for i, p := range cycle {
+			cycle[i] = Point{X: p.Y, Y: p.X}
+		}
+		return cycle
+	}
+
+	// Down and up the columns below the top row, in turn from the left,
+	// then back along the top row. w is even, so the last column is
+	// climbed, ending next to the top row.
+	cycle := make([]Point, 0, w*h)
+	for x := range w {
+		for i := 1; i < h; i++ {
+			y := i
+			if x%2 != 0 {
+				y = h - i
+			}
+			cycle = append(cycle, Point{X: x, Y: y})
+		}
+	}
+	for x := w - 1; x >= 0; x-- {
+		cycle = append(cycle, Point{X: x, Y: 0})
+	}
+	return cycle
+}
@@ -0,0 +1,196 @@
+package snake
+
+import (
+	"container/heap"
+	"slices"
+)
+
+// Grid is the board as FindPath sees it: where a path can go, and when
+// Snake bodies aren't walls for good. A body follows its head, so a path
+// can cross the cell a tail is on now if it only gets there once the tail
+// has moved on.
+type Grid struct {
+	// Width and Height are the board size in cells, and Inset how many
+	// rings around the edge are closed (see Arena)
+	Width, Height, Inset int
+
+	// Walls are the cells that can never be entered
+	Walls map[Point]bool
+
+	// Bodies holds, for each cell a snake is on, how many moves it takes
+	// for the snake to leave it (see AddBody)
+	Bodies map[Point]int
+
+	// Portals are followed the way the snakes follow them
+	Portals []Portal
+}
+
+// AddBody adds the cells of b to the grid's Bodies
+// Segment i of a snake n long leaves its cell after n-i moves, if the
+// snake doesn't grow on the way. Crashes are checked before the snakes
+// move, so the cell can be entered on the move after that.
+func (g *Grid) AddBody(b *Body) {
+	if g.Bodies == nil {
+		g.Bodies = make(map[Point]int)
+	}
+	n := b.Len()
+	for i, c := range b.All() {
+		g.Bodies[c] = max(g.Bodies[c], n-i)
+	}
+}
+
+// Open reports whether a path can enter c on its nth move (counting from
+// one)
+func (g Grid) Open(c Point, n int) bool {
+	if c.X < g.Inset || c.Y < g.Inset || c.X >= g.Width-g.Inset || c.Y >= g.Height-g.Inset {
+		return false
+	}
+	return !g.Walls[c] && g.Bodies[c] < n
+}
+
+// Occupied returns every cell that can't be entered on the next move: the
+// walls, the snakes' bodies and the closed rings of the arena, as FloodFill
+// takes them
+func (g Grid) Occupied() map[Point]bool {
+	occupied := make(map[Point]bool, len(g.Walls)+len(g.Bodies))
+	for c := range g.Walls {
+		occupied[c] = true
+	}
+	for c := range g.Bodies {
+		occupied[c] = true
+	}
+	if g.Inset > 0 {
+		for y := range g.Height {
+			for x := range g.Width {
+				if c := (Point{x, y}); !g.Open(c, 1) {
+					occupied[c] = true
+				}
+			}
+		}
+	}
+	return occupied
+}
+
+// Path is a route across the board: the cells it enters, in order, and
+// the move into each one (the two don't line up where the route goes
+// through a portal)
+type Path struct {
+	Cells []Point
+	Moves []Point
+}
+
+// Len returns the number of moves along the path
+func (p Path) Len() int {
+	return len(p.Moves)
+}
+
+// FindPath returns the shortest path on g from start to the nearest of
+// goals, at most maxLen moves long (0 = no limit), using A*
+// ok is false when no goal can be reached. Ties are broken the same way
+// every time, so bots following paths play the same game from the same
+// seed.
+func FindPath(g Grid, start Point, goals []Point, maxLen int) (path Path, ok bool) {
+	if len(goals) == 0 {
+		return Path{}, false
+	}
+
+	// Every cell reached is a node, pointing back at the one it was
+	// reached from; fewest holds the fewest moves each cell has been
+	// reached in so far. A cell reached again in fewer moves (through a
+	// portal the estimate got wrong) gets a new node.
+	type node struct {
+		parent      int
+		pos, move   Point
+		moves, cost int
+	}
+	nodes := []node{{parent: -1, pos: start, cost: g.estimate(start, goals)}}
+	fewest := map[Point]int{start: 0}
+	open := &pathQueue{less: func(a, b int) bool {
+		na, nb := nodes[a], nodes[b]
+		if na.cost != nb.cost {
+			return na.cost < nb.cost
+		}
+		// Among equally good nodes, the one furthest along is likely
+		// closest to a goal
+		if na.moves != nb.moves {
+			return na.moves > nb.moves
+		}
+		return a < b
+	}}
+	heap.Push(open, 0)
+
+	for open.Len() > 0 {
+		i := heap.Pop(open).(int)
+		n := nodes[i]
+		if n.moves > fewest[n.pos] {
+			// Reached in fewer moves since
+			continue
+		}
+		if n.moves > 0 && ContainsPoint(goals, n.pos) {
+			for ; n.parent >= 0; n = nodes[n.parent] {
+				path.Cells = append(path.Cells, n.pos)
+				path.Moves = append(path.Moves, n.move)
+			}
+			slices.Reverse(path.Cells)
+			slices.Reverse(path.Moves)
+			return path, true
+		}
+		if maxLen > 0 && n.moves >= maxLen {
+			continue
+		}
+		for _, d := range Directions {
+			next, moves := throughPortals(g.Portals, n.pos.Add(d)), n.moves+1
+			if !g.Open(next, moves) {
+				continue
+			}
+			if m, seen := fewest[next]; seen && m <= moves {
+				continue
+			}
+			fewest[next] = moves
+			nodes = append(nodes, node{parent: i, pos: next, move: d, moves: moves, cost: moves + g.estimate(next, goals)})
+			heap.Push(open, len(nodes)-1)
+		}
+	}
+	return Path{}, false
+}
+
+// estimate returns a lower bound on the moves from p to the nearest of
+// goals: the distance straight there, or through a portal when that is
+// shorter
+func (g Grid) estimate(p Point, goals []Point) int {
+	best := -1
+	for _, goal := range goals {
+		d := distance(p, goal)
+		for _, pt := range g.Portals {
+			d = min(d, distance(p, pt.A)+distance(pt.B, goal), distance(p, pt.B)+distance(pt.A, goal))
+		}
+		if best < 0 || d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+// distance returns the Manhattan distance between a and b: the fewest
+// moves from one to the other on an open board without portals
+func distance(a, b Point) int {
+	return abs(a.X-b.X) + abs(a.Y-b.Y)
+}
+
+// pathQueue is FindPath's open list: a heap of node indexes, ordered by
+// less
+type pathQueue struct {
+	items []int
+	less  func(a, b int) bool
+}
+
+func (q *pathQueue) Len() int           { return len(q.items) }
+func (q *pathQueue) Less(i, j int) bool { return q.less(q.items[i], q.items[j]) }
+func (q *pathQueue) Swap(i, j int)      { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *pathQueue) Push(x any)         { q.items = append(q.items, x.(int)) }
+
+func (q *pathQueue) Pop() any {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last
+}
@@ -0,0 +1,130 @@
+package snake
+
+import "time"
+
+// InitialLength is how many segments a new snake starts with
+const InitialLength = 2
+
+// Player is one snake and everything that belongs to it
+type Player struct {
+	// Name is shown in the HUD and on the game over screen
+	Name string
+
+	// Snake is the body, from the head (At(0)) to the tail
+	Snake Body
+
+	// Direction is the current movement direction (one of the direction
+	// vectors, Up to Right)
+	Direction Point
+
+	// Score is the number of points this player earned in the current run
+	Score int
+
+	// Combo multiplies the points for food eaten in quick succession
+	Combo Combo
+
+	// Shield absorbs the snake's next crash (see PowerUpShield)
+	Shield bool
+
+	// Invincible keeps the snake alive whatever happens to it: a crash
+	// stops it for the tick, like a shield that is never used up, and
+	// poison, shots and enemies can't kill it. It is for testing, not for
+	// play.
+	Invincible bool
+
+	// DashReadyAt is when the snake can dash again (see Dasher)
+	DashReadyAt time.Time
+
+	// Dead is set when the snake crashes or eats poison it can't survive
+	Dead bool
+
+	// Controller decides where the snake goes: the keyboard, a bot or a
+	// script
+	Controller Controller
+}
+
+// NewPlayer creates a player whose snake has the given body, head first,
+// and is heading in direction
+func NewPlayer(name string, body []Point, direction Point, controller Controller) *Player {
+	return &Player{
+		Name:       name,
+		Snake:      NewBody(body),
+		Direction:  direction,
+		Controller: controller,
+	}
+}
+
+// Head returns the snake's first segment
+func (p *Player) Head() Point {
+	return p.Snake.Head()
+}
+
+// Occupies reports whether any segment of the snake is on cell c
+func (p *Player) Occupies(c Point) bool {
+	return p.Snake.Contains(c)
+}
+
+// AlivePlayers returns how many snakes are still in the round
+func (w *World) AlivePlayers() int {
+	n := 0
+	for _, p := range w.Players {
+		if !p.Dead {
+			n++
+		}
+	}
+	return n
+}
+
+// RoundOver reports whether the run has ended: the only snake died, or
+// at most one snake is left when several are playing
+func (w *World) RoundOver() bool {
+	alive := w.AlivePlayers()
+	if len(w.Players) == 1 {
+		return alive == 0
+	}
+	return alive <= 1
+}
+
+// Winner returns the player who won a versus round, or nil for a draw
+// The survivor wins; if the last snakes died together, the higher score
+// breaks the tie.
+func (w *World) Winner() *Player {
+	var best *Player
+	tied := false
+	for _, p := range w.Players {
+		switch {
+		case best == nil:
+			best = p
+		case !p.Dead && best.Dead, p.Dead == best.Dead && p.Score > best.Score:
+			best, tied = p, false
+		case p.Dead == best.Dead && p.Score == best.Score:
+			tied = true
+		}
+	}
+	if tied {
+		return nil
+	}
+	return best
+}
+
+// LongestSnake returns the length of the longest snake still playing
+// The speed ramps with it, so in versus mode both snakes share one pace.
+func (w *World) LongestSnake() int {
+	n := 0
+	for _, p := range w.Players {
+		if !p.Dead {
+			n = max(n, p.Snake.Len())
+		}
+	}
+	return n
+}
+
+// IsOnSnake reports whether any snake segment occupies p
+func (w *World) IsOnSnake(p Point) bool {
+	for _, pl := range w.Players {
+		if pl.Occupies(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package snake
+
+import "encoding/json"
+
+// Point is a cell on the board, in grid coordinates (not pixels)
+type Point struct {
+	X, Y int
+}
+
+// Direction vectors: a snake moves by adding one of these to its head
+var (
+	Up    = Point{X: 0, Y: -1} // Moving up decreases y
+	Down  = Point{X: 0, Y: 1}  // Moving down increases y
+	Left  = Point{X: -1, Y: 0} // Moving left decreases x
+	Right = Point{X: 1, Y: 0}  // Moving right increases x
+)
+
+// Directions lists the four directions a snake can move in
+var Directions = [...]Point{Up, Down, Left, Right}
+
+// Add returns p moved by the direction d
+func (p Point) Add(d Point) Point {
+	return Point{X: p.X + d.X, Y: p.Y + d.Y}
+}
+
+// IsReverse reports whether d points straight back along heading
+func IsReverse(d, heading Point) bool {
+	return d == Point{X: -heading.X, Y: -heading.Y}
+}
+
+// ContainsPoint reports whether pts includes p
+func ContainsPoint(pts []Point, p Point) bool {
+	for _, q := range pts {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+// FloodFill returns every cell reachable from start without crossing a
+// wall or leaving a w×h grid
+func FloodFill(start Point, w, h int, walls map[Point]bool) map[Point]bool {
+	seen := map[Point]bool{start: true}
+	queue := []Point{start}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, d := range Directions {
+			n := cur.Add(d)
+			if n.X < 0 || n.Y < 0 || n.X >= w || n.Y >= h {
+				continue
+			}
+			if walls[n] || seen[n] {
+				continue
+			}
+			seen[n] = true
+			queue = append(queue, n)
+		}
+	}
+	return seen
+}
+
+// MarshalJSON stores a point as a compact [x, y] pair
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int{p.X, p.Y})
+}
+
+// UnmarshalJSON reads a point stored as [x, y]
+func (p *Point) UnmarshalJSON(b []byte) error {
+	var xy [2]int
+	if err := json.Unmarshal(b, &xy); err != nil {
+		return err
+	}
+	p.X, p.Y = xy[0], xy[1]
+	return nil
+}
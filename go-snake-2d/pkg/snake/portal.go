@@ -0,0 +1,51 @@
+package snake
+
+// Portal links two cells: a snake moving onto either end comes out of the
+// other one, still heading the same way. Its body follows it through, so
+// a snake can be split across the board for a while.
+type Portal struct {
+	A Point `json:"a"`
+	B Point `json:"b"`
+}
+
+// Next returns the cell a snake at p reaches by moving in direction d,
+// following a portal if the cell in front of it is one
+func (w *World) Next(p, d Point) Point {
+	return throughPortals(w.Portals, p.Add(d))
+}
+
+// isPortal reports whether p is either end of a portal
+// Items and enemies are kept off portals so they don't hide them.
+func (w *World) isPortal(p Point) bool {
+	return isPortal(w.Portals, p)
+}
+
+// Next returns the cell the steered snake reaches from p by moving in
+// direction d, following portals
+func (s GameState) Next(p, d Point) Point {
+	return throughPortals(s.Portals, p.Add(d))
+}
+
+// throughPortals returns the other end of the portal at p, or p itself
+// if there is none
+func throughPortals(portals []Portal, p Point) Point {
+	for _, pt := range portals {
+		switch p {
+		case pt.A:
+			return pt.B
+		case pt.B:
+			return pt.A
+		}
+	}
+	return p
+}
+
+// isPortal reports whether p is an end of one of the portals
+func isPortal(portals []Portal, p Point) bool {
+	for _, pt := range portals {
+		if p == pt.A || p == pt.B {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package snake
+
+import (
+	"fmt"
+	"time"
+)
+
+// PowerUpKind identifies what a power-up does when collected
+type PowerUpKind int
+
+const (
+	// PowerUpSpeedBoost makes the snake move faster for a while
+	PowerUpSpeedBoost PowerUpKind = iota
+	// PowerUpSlowMotion makes the snake move slower for a while
+	PowerUpSlowMotion
+	// PowerUpShrink instantly removes a few tail segments
+	PowerUpShrink
+	// PowerUpGhost lets snakes pass through their own bodies for a while
+	// (not through each other, or walls)
+	PowerUpGhost
+	// PowerUpShield gives the collector's snake a shield that absorbs its
+	// next crash
+	PowerUpShield
+	// PowerUpMagnet pulls food within MagnetRadius toward the snakes'
+	// heads for a while
+	PowerUpMagnet
+	// PowerUpBulletTime halves how often the snakes move for a while,
+	// easing back to full speed as it wears off
+	PowerUpBulletTime
+
+	PowerUpKindCount // keep last: number of kinds, used for random picks
+)
+
+const (
+	// powerUpMinDelay and powerUpMaxDelay bound the random wait between
+	// one power-up disappearing and the next one spawning
+	powerUpMinDelay = 8 * time.Second
+	powerUpMaxDelay = 15 * time.Second
+
+	// powerUpLifetime is how long an uncollected power-up stays on the board
+	powerUpLifetime = 7 * time.Second
+
+	// shrinkAmount is how many segments PowerUpShrink removes
+	shrinkAmount = 3
+
+	// MagnetRadius is how far (in cells, Manhattan distance) from a head
+	// PowerUpMagnet reaches
+	MagnetRadius = 6
+)
+
+// powerUpSpec holds the tunables for each kind of power-up
+type powerUpSpec struct {
+	name string
+
+	// duration is how long the effect lasts (0 for instant effects)
+	duration time.Duration
+
+	// tickScale multiplies the tick interval while the effect is active
+	// (<1 is faster, >1 is slower, 1 leaves speed unchanged)
+	tickScale float64
+
+	// easeOut is how long before the effect ends its tickScale starts
+	// sliding back to 1, so the speed doesn't jump at the end (0 for none)
+	easeOut time.Duration
+}
+
+var powerUpSpecs = [PowerUpKindCount]powerUpSpec{
+	PowerUpSpeedBoost: {name: "Speed", duration: 5 * time.Second, tickScale: 0.6},
+	PowerUpSlowMotion: {name: "Slow-mo", duration: 6 * time.Second, tickScale: 1.75},
+	PowerUpShrink:     {name: "Shrink", duration: 0, tickScale: 1},
+	PowerUpGhost:      {name: "Ghost", duration: 6 * time.Second, tickScale: 1},
+	PowerUpShield:     {name: "Shield", duration: 0, tickScale: 1},
+	PowerUpMagnet:     {name: "Magnet", duration: 8 * time.Second, tickScale: 1},
+	PowerUpBulletTime: {name: "Bullet time", duration: 4 * time.Second, tickScale: 2, easeOut: time.Second},
+}
+
+// String returns the display name of the power-up kind
+func (k PowerUpKind) String() string {
+	if k < 0 || k >= PowerUpKindCount {
+		return fmt.Sprintf("PowerUpKind(%d)", int(k))
+	}
+	return powerUpSpecs[k].name
+}
+
+// PowerUp is a collectible item on the board
+type PowerUp struct {
+	Kind PowerUpKind
+	Pos  Point
+
+	// Duration is how long the effect lasts once collected
+	Duration time.Duration
+
+	// ExpiresAt is when the power-up vanishes if nobody collects it
+	ExpiresAt time.Time
+}
+
+// Effects tracks the timed effects currently applied to the snake
+// Each kind maps to the moment it wears off; collecting the same kind
+// again restarts its timer rather than stacking.
+type Effects map[PowerUpKind]time.Time
+
+// Active reports whether the given effect is currently running
+func (e Effects) Active(kind PowerUpKind, now time.Time) bool {
+	until, ok := e[kind]
+	return ok && now.Before(until)
+}
+
+// Remaining returns how much time is left on an effect
+func (e Effects) Remaining(kind PowerUpKind, now time.Time) time.Duration {
+	if !e.Active(kind, now) {
+		return 0
+	}
+	return e[kind].Sub(now)
+}
+
+// TickScale combines the speed modifiers of every active effect
+// It only scales the tick interval the difficulty gives for the snake's
+// current length, which the caller works out afresh every frame, so when
+// an effect ends the speed returns to wherever the difficulty ramp has
+// got to in the meantime, not to the speed at pickup.
+func (e Effects) TickScale(now time.Time) float64 {
+	scale := 1.0
+	for kind := range e {
+		scale *= 1 + (powerUpSpecs[kind].tickScale-1)*e.Fading(kind, now)
+	}
+	return scale
+}
+
+// Fading returns how strong an effect still is, from 1 while it runs
+// fully down to 0 as it eases out at the end (see powerUpSpec.easeOut),
+// or 0 if it isn't active
+func (e Effects) Fading(kind PowerUpKind, now time.Time) float64 {
+	left, ease := e.Remaining(kind, now), powerUpSpecs[kind].easeOut
+	switch {
+	case left <= 0:
+		return 0
+	case left < ease:
+		return float64(left) / float64(ease)
+	default:
+		return 1
+	}
+}
+
+// expire removes effects whose time has run out
+func (e Effects) expire(now time.Time) {
+	for kind, until := range e {
+		if !now.Before(until) {
+			delete(e, kind)
+		}
+	}
+}
+
+// shift pushes every timer back by d (used when resuming from pause)
+func (e Effects) shift(d time.Duration) {
+	for kind, until := range e {
+		e[kind] = until.Add(d)
+	}
+}
+
+// UpdatePowerUps expires old effects and spawns or despawns the board
+// power-up. Call it every frame while the game is running, so power-up
+// timing is independent of snake speed.
+func (w *World) UpdatePowerUps(now time.Time) {
+	w.Effects.expire(now)
+
+	// Uncollected power-ups disappear after a while
+	if w.PowerUp != nil && !now.Before(w.PowerUp.ExpiresAt) {
+		w.PowerUp = nil
+		w.schedulePowerUp(now)
+	}
+
+	if w.PowerUp == nil && !now.Before(w.NextPowerUpAt) {
+		w.spawnPowerUp(now)
+	}
+}
+
+// schedulePowerUp picks a random time for the next power-up to appear
+func (w *World) schedulePowerUp(now time.Time) {
+	delay := powerUpMinDelay + time.Duration(w.Rand.Int64N(int64(powerUpMaxDelay-powerUpMinDelay)))
+	w.NextPowerUpAt = now.Add(delay)
+}
+
+// spawnPowerUp places a random power-up on a free cell, by the same rules
+// as food (see takenCells)
+func (w *World) spawnPowerUp(now time.Time) {
+	kind := PowerUpKind(w.Rand.IntN(int(PowerUpKindCount)))
+
+	// A handful of attempts is plenty on a mostly-empty board; if they
+	// all fail we just try again on the next schedule
+	taken := w.takenCells()
+	for range 20 {
+		p := w.randomCell()
+		if taken[p] {
+			continue
+		}
+		w.PowerUp = &PowerUp{
+			Kind:      kind,
+			Pos:       p,
+			Duration:  powerUpSpecs[kind].duration,
+			ExpiresAt: now.Add(powerUpLifetime),
+		}
+		return
+	}
+	w.schedulePowerUp(now)
+}
+
+// applyPowerUp triggers the effect of a power-up collected by player pl
+// Shrinking and shields only affect the collector's snake; timed effects
+// (speed, ghost and magnet) apply to every snake, like the shared pace of the
+// game.
+func (w *World) applyPowerUp(pl *Player, p *PowerUp, now time.Time) {
+	switch p.Kind {
+	case PowerUpShield:
+		// A second shield doesn't stack
+		pl.Shield = true
+	case PowerUpShrink:
+		// Never shrink below the starting length
+		pl.Snake.Truncate(max(pl.Snake.Len()-shrinkAmount, InitialLength))
+	default:
+		w.Effects[p.Kind] = now.Add(p.Duration)
+	}
+}
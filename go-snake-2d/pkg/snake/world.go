@@ -0,0 +1,439 @@
+// Package snake implements the rules of Snake: moving the snakes,
+// collisions, food, power-ups and scoring. It has no rendering or input
+// code, so the same rules can drive the Ebiten game, a terminal front
+// end, tests or a server checking submitted runs.
+//
+// A front end builds a World, calls Start, then calls Update every
+// frame and Step on every movement tick, drawing the World's fields in
+// between. How often to tick is up to the front end (the
+// World only supplies the power-up speed modifiers through Effects).
+package snake
+
+import (
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// World is one run: the board, the snakes on it and the items to collect
+type World struct {
+	// Width and Height are the board size in cells
+	Width, Height int
+
+	// Obstacles is the set of wall cells
+	Obstacles map[Point]bool
+
+	// FoodSpawns, when not empty, restricts food to these cells
+	FoodSpawns []Point
+
+	// Portals are the linked pairs of cells snakes can travel through
+	Portals []Portal
+
+	// Checkpoints are the tiles that save progress when reached
+	Checkpoints []Checkpoint
+
+	// Players holds one snake per player
+	Players []*Player
+
+	// Foods holds every food item on the board: always one normal (or
+	// fleeing) piece, sometimes joined by poison and a golden apple
+	Foods []Food
+
+	// PowerUp is the power-up currently on the board (nil if none)
+	PowerUp *PowerUp
+
+	// NextPowerUpAt is when the next power-up will spawn
+	NextPowerUpAt time.Time
+
+	// Effects holds the timed power-up effects currently active
+	Effects Effects
+
+	// Arena, when set, shrinks the open area of the board over time
+	Arena *Arena
+
+	// FoodLifetime, when set, is how long regular food stays put before
+	// it moves somewhere else, so the snakes can't wait around
+	FoodLifetime time.Duration
+
+	// FoodSpread, when above 1, is how many free cells are drawn for each
+	// new piece of food, the one furthest from the snakes' heads winning,
+	// so food tends to appear away from them
+	FoodSpread int
+
+	// Full is set when the board has no free cell left for new food,
+	// which wins the run
+	Full bool
+
+	// NoFleeing keeps food from ever fleeing, for bots that can't chase
+	// it down (see HamiltonBot)
+	NoFleeing bool
+
+	// DeadLeaveFood turns the snakes that die into food (FoodRemains)
+	// for the others to eat, on the tick after they die. Their bodies
+	// are cleared off the board then, so a dead snake's Snake is empty.
+	DeadLeaveFood bool
+
+	// EnemyCount is how many enemies Start places (see Enemy)
+	EnemyCount int
+
+	// Entities are the objects on the board that act on their own: the
+	// enemies, and on boss levels the boss (added by the front end before
+	// Start) and its shots (see Entity)
+	Entities Entities
+
+	// Rand is the source of every random choice in the run, so the same
+	// seed (and the same moves) give the same game
+	Rand *rand.Rand
+}
+
+// EventKind identifies something that happened during a Step
+type EventKind int
+
+const (
+	// EventAte is reported when a snake eats normal food
+	EventAte EventKind = iota
+	// EventPoisoned is reported when a snake eats poison (whether or not
+	// it survives it)
+	EventPoisoned
+	// EventPowerUp is reported when a snake collects a power-up
+	EventPowerUp
+	// EventDied is reported when a snake dies
+	EventDied
+	// EventArenaShrank is reported when the arena closes a ring; it
+	// happens to no player in particular (Player is -1)
+	EventArenaShrank
+	// EventBoardFull is reported when a snake fills the board so no food
+	// can be placed; Player is the snake that ate last
+	EventBoardFull
+	// EventShieldBroken is reported when a snake's shield absorbs a crash
+	EventShieldBroken
+	// EventDashed is reported when a snake dashes
+	EventDashed
+	// EventCheckpoint is reported when a snake reaches a checkpoint for
+	// the first time, once it has finished moving
+	EventCheckpoint
+	// EventBossBitten is reported when a snake takes a bite out of the
+	// boss, and EventBossDefeated when the bite is its last
+	EventBossBitten
+	EventBossDefeated
+	// EventBossFired is reported when the boss fires; it happens to no
+	// player in particular (Player is -1)
+	EventBossFired
+	// EventShot is reported when a shot hits a snake that survives it
+	EventShot
+	// EventLevelCompleted is reported when a snake clears the level, by
+	// filling the board or beating the boss, right after EventBoardFull or
+	// EventBossDefeated
+	EventLevelCompleted
+
+	// EventKindCount is the number of kinds; a front end can number
+	// events of its own from here
+	EventKindCount // keep last
+)
+
+// Event tells the front end what happened during a Step, e.g. to play a
+// sound
+type Event struct {
+	Kind EventKind
+
+	// Player is the index in Players of the snake it happened to, or -1
+	Player int
+
+	// Food is the kind of food eaten, and Points what it scored, for
+	// EventAte and EventPoisoned
+	Food   FoodKind
+	Points int
+}
+
+// Start clears the board's items and places the first food, ready for
+// the first Step. Players, the board and Rand must be set beforehand.
+func (w *World) Start(now time.Time) {
+	// Clear power-ups and effects, and schedule the first power-up
+	w.PowerUp = nil
+	w.Effects = Effects{}
+	w.schedulePowerUp(now)
+
+	// The arena starts fully open
+	if w.Arena != nil {
+		w.Arena.Inset = 0
+		w.Arena.NextShrinkAt = now.Add(w.Arena.Interval)
+	}
+
+	// Clear the board and spawn new food
+	w.Full = false
+	w.Foods = w.Foods[:0]
+	w.spawnFood(FoodNormal, now)
+	w.spawnEnemies()
+}
+
+// Step advances every snake by one cell (two for a dash) and reports
+// what happened
+// All new heads are worked out before anything moves, so in versus mode
+// neither snake gets an advantage from being updated first.
+func (w *World) Step(now time.Time) []Event {
+	// The snakes that died last tick stayed for the front end to show
+	// them dying; now they make way for their remains
+	if w.DeadLeaveFood {
+		w.leaveRemains()
+	}
+
+	// Every controller decides where to go, looking at the board as it is
+	// now. Turning straight back is never allowed: the snake would run
+	// into its own neck.
+	for i, p := range w.Players {
+		if p.Dead {
+			continue
+		}
+		if d := p.Controller.NextDirection(w.State(i)); !IsReverse(d, p.Direction) {
+			p.Direction = d
+		}
+	}
+
+	// Calculate the cells each head moves through based on the current
+	// directions: one, or DashCells for a dash
+	// A head moving onto a portal comes out of the other end; the body
+	// simply follows the head's path, jump included
+	paths := make([][]Point, len(w.Players))
+	for i, p := range w.Players {
+		if p.Dead {
+			continue
+		}
+		n := 1
+		if p.dashes(now) {
+			n = DashCells
+		}
+		head := p.Head()
+		for range n {
+			head = w.Next(head, p.Direction)
+			paths[i] = append(paths[i], head)
+		}
+	}
+
+	// COLLISION DETECTION
+	// Walls, obstacles and bodies (either snake's) are fatal, unless the
+	// snake has a shield: that is used up instead and the snake stays put
+	// for the tick. A dash crashes if any cell it passes through would.
+	// Running into the boss is a bite rather than a crash, and stops the
+	// snake for the tick too.
+	// Head-on crashes are fatal either way: two heads moving onto the same
+	// cell, or two heads swapping places, kill both snakes. They are all
+	// found before any snake is marked dead, so neither is missed.
+	// Invincible snakes stop instead of crashing.
+	var events []Event
+	stopped := make([]bool, len(w.Players))
+	headOn := make([]bool, len(w.Players))
+	for i, p := range w.Players {
+		headOn[i] = !p.Dead && w.headOn(i, paths)
+	}
+	for i, p := range w.Players {
+		if p.Dead {
+			continue
+		}
+		if slices.ContainsFunc(paths[i], w.bossAt) {
+			stopped[i] = true
+			events = w.biteBoss(i, events)
+			continue
+		}
+		crashed := slices.ContainsFunc(paths[i], func(c Point) bool { return w.collides(i, c, now) })
+		if p.Invincible && (crashed || headOn[i]) {
+			stopped[i] = true
+			continue
+		}
+		if crashed && p.Shield {
+			p.Shield = false
+			stopped[i] = true
+			events = append(events, Event{Kind: EventShieldBroken, Player: i})
+			continue
+		}
+		if crashed || headOn[i] {
+			p.Dead = true
+			events = append(events, Event{Kind: EventDied, Player: i})
+		}
+	}
+
+	// MOVEMENT
+	// A dash moves a cell at a time, so food and power-ups on either cell
+	// are picked up
+	for i, p := range w.Players {
+		if p.Dead || stopped[i] {
+			continue
+		}
+		if len(paths[i]) > 1 {
+			events = append(events, Event{Kind: EventDashed, Player: i})
+		}
+		for _, c := range paths[i] {
+			events = w.moveSnake(i, c, now, events)
+			if p.Dead {
+				break
+			}
+		}
+	}
+
+	// Then everything else on the board takes its turn, seeing where the
+	// snakes ended up (see systems)
+	for _, s := range systems {
+		events = s.Update(w, now, events)
+	}
+	return events
+}
+
+// headOn reports whether player i, moving through paths[i], runs head on
+// into another live snake: both heads moving onto the same cell, or
+// swapping places
+func (w *World) headOn(i int, paths [][]Point) bool {
+	p := w.Players[i]
+	for j, other := range w.Players {
+		if j == i || other.Dead {
+			continue
+		}
+		meet := slices.ContainsFunc(paths[i], func(c Point) bool { return slices.Contains(paths[j], c) })
+		swap := slices.Contains(paths[i], other.Head()) && slices.Contains(paths[j], p.Head())
+		if meet || swap {
+			return true
+		}
+	}
+	return false
+}
+
+// moveSnake moves player i's snake onto newHead, which has already
+// been checked for collisions, appending what happened to events
+// This is where the "snake grows when eating" mechanic is implemented
+func (w *World) moveSnake(i int, newHead Point, now time.Time, events []Event) []Event {
+	p := w.Players[i]
+
+	// FOOD CONSUMPTION
+	// If snake eats normal food, grow by keeping the tail
+	eaten := w.FoodAt(newHead)
+	if eaten >= 0 && w.Foods[eaten].Kind.Grows() {
+		// Add the new head, keep entire body (snake grows)
+		p.Snake.Grow(newHead)
+	} else {
+		// NORMAL MOVEMENT
+		// Add the new head, remove tail (snake moves without growing)
+		// This creates the illusion of movement
+		p.Snake.Move(newHead)
+	}
+
+	// Dispatch on the kind of food: scoring, respawning and the poison
+	// penalty all live in eatFood
+	if eaten >= 0 {
+		food, score := w.Foods[eaten].Kind, p.Score
+		kind, survived := w.eatFood(p, eaten, now)
+		events = append(events, Event{Kind: kind, Player: i, Food: food, Points: p.Score - score})
+		if w.Full {
+			events = append(events,
+				Event{Kind: EventBoardFull, Player: i},
+				Event{Kind: EventLevelCompleted, Player: i})
+		}
+		if !survived {
+			p.Dead = true
+			return append(events, Event{Kind: EventDied, Player: i})
+		}
+	}
+
+	// POWER-UP COLLECTION
+	if w.PowerUp != nil && newHead == w.PowerUp.Pos {
+		w.applyPowerUp(p, w.PowerUp, now)
+		events = append(events, Event{Kind: EventPowerUp, Player: i})
+		w.PowerUp = nil
+		w.schedulePowerUp(now)
+	}
+
+	// CHECKPOINTS
+	if w.reachCheckpoint(newHead) {
+		events = append(events, Event{Kind: EventCheckpoint, Player: i})
+	}
+	return events
+}
+
+// Update runs the timers that don't depend on movement: power-ups, food
+// that expires and the shrinking arena. Call it every frame while the game is running, so
+// their timing is independent of snake speed. Returns what happened; the
+// arena can kill snakes, so check RoundOver afterwards.
+func (w *World) Update(now time.Time) []Event {
+	w.UpdatePowerUps(now)
+	w.expireFoods(now)
+	return w.updateArena(now, nil)
+}
+
+// IsBadCollision checks if moving onto a point is fatal
+// Returns true if the point is:
+// 1. Outside the game boundaries or the arena (wall collision)
+// 2. On one of the obstacle cells
+// 3. Overlapping with any snake's body (its own or another player's)
+// 4. On an enemy or the boss
+// It ignores effects; Step checks each snake's move with collides.
+func (w *World) IsBadCollision(p Point) bool {
+	return w.collides(-1, p, time.Time{})
+}
+
+// collides checks if player i moving onto p at now is fatal, like
+// IsBadCollision but taking effects into account: while PowerUpGhost is
+// active, a snake passes through its own body. Pass i = -1 to check for
+// any snake.
+func (w *World) collides(i int, p Point, now time.Time) bool {
+	// BOUNDARY CHECK
+	if !w.InBounds(p) {
+		return true
+	}
+
+	// OBSTACLE CHECK
+	if w.Obstacles[p] {
+		return true
+	}
+
+	// ENEMY CHECK
+	if w.occupied(p, nil) {
+		return true
+	}
+
+	// SNAKE COLLISION CHECK
+	ghost := i >= 0 && w.Effects.Active(PowerUpGhost, now)
+	for j, pl := range w.Players {
+		if ghost && j == i {
+			continue
+		}
+		if pl.Occupies(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// InBounds reports whether p lies on the open part of the board (see
+// Bounds)
+func (w *World) InBounds(p Point) bool {
+	lo, hi := w.Bounds()
+	return p.X >= lo.X && p.Y >= lo.Y && p.X < hi.X && p.Y < hi.Y
+}
+
+// Shift pushes every timer in the world forward by d
+// Used when resuming from pause so the time spent paused doesn't count
+func (w *World) Shift(d time.Duration) {
+	w.NextPowerUpAt = w.NextPowerUpAt.Add(d)
+	if w.PowerUp != nil {
+		w.PowerUp.ExpiresAt = w.PowerUp.ExpiresAt.Add(d)
+	}
+	w.Effects.shift(d)
+	for _, p := range w.Players {
+		p.Combo.ExpiresAt = p.Combo.ExpiresAt.Add(d)
+		p.DashReadyAt = p.DashReadyAt.Add(d)
+	}
+	for i := range w.Foods {
+		if f := &w.Foods[i]; !f.ExpiresAt.IsZero() {
+			f.ExpiresAt = f.ExpiresAt.Add(d)
+		}
+	}
+	if w.Arena != nil {
+		w.Arena.NextShrinkAt = w.Arena.NextShrinkAt.Add(d)
+	}
+}
+
+// randomCell returns a random cell on the open part of the board
+func (w *World) randomCell() Point {
+	lo, hi := w.Bounds()
+	return Point{
+		X: lo.X + w.Rand.IntN(hi.X-lo.X),
+		Y: lo.Y + w.Rand.IntN(hi.Y-lo.Y),
+	}
+}
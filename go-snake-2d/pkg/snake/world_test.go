@@ -0,0 +1,264 @@
+package snake
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testStart is the time the test worlds start at
+var testStart = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestWorld returns a started 10×10 world with no walls, holding the
+// given players and only the given food
+func newTestWorld(players []*Player, foods ...Food) *World {
+	w := &World{
+		Width:     10,
+		Height:    10,
+		Obstacles: map[Point]bool{},
+		Players:   players,
+		Rand:      rand.New(rand.NewPCG(1, 2)),
+	}
+	w.Start(testStart)
+	w.Foods = append(w.Foods[:0], foods...)
+	return w
+}
+
+// scripted returns a player with the given body heading in dir and
+// then following moves
+func scripted(body []Point, dir Point, moves ...Point) *Player {
+	return NewPlayer("test", body, dir, &ScriptedController{Moves: moves})
+}
+
+// kinds returns the kinds of events, in order
+func kinds(events []Event) []EventKind {
+	var ks []EventKind
+	for _, e := range events {
+		ks = append(ks, e.Kind)
+	}
+	return ks
+}
+
+func TestStepCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		players []*Player
+		dead    []bool
+	}{
+		{
+			name:    "straight ahead",
+			players: []*Player{scripted([]Point{{5, 5}, {4, 5}}, Right)},
+			dead:    []bool{false},
+		},
+		{
+			name:    "right wall",
+			players: []*Player{scripted([]Point{{9, 5}, {8, 5}}, Right)},
+			dead:    []bool{true},
+		},
+		{
+			name:    "top wall",
+			players: []*Player{scripted([]Point{{5, 0}, {5, 1}}, Up)},
+			dead:    []bool{true},
+		},
+		{
+			name: "own body",
+			// Heading right along the top of a loop, then turning down
+			// onto the body
+			players: []*Player{scripted([]Point{{5, 5}, {4, 5}, {4, 6}, {5, 6}, {6, 6}}, Right, Down)},
+			dead:    []bool{true},
+		},
+		{
+			name: "own tail",
+			// The tail is still there when the head checks its way, so
+			// chasing it in a tight square is a crash
+			players: []*Player{scripted([]Point{{5, 5}, {4, 5}, {4, 6}, {5, 6}}, Right, Down)},
+			dead:    []bool{true},
+		},
+		{
+			name:    "turning back is ignored",
+			players: []*Player{scripted([]Point{{5, 5}, {4, 5}}, Right, Left)},
+			dead:    []bool{false},
+		},
+		{
+			name: "other snake's body",
+			players: []*Player{
+				scripted([]Point{{5, 5}, {4, 5}}, Right),
+				scripted([]Point{{6, 4}, {6, 5}, {6, 6}}, Up),
+			},
+			dead: []bool{true, false},
+		},
+		{
+			name: "head on, same cell",
+			players: []*Player{
+				scripted([]Point{{4, 5}, {3, 5}}, Right),
+				scripted([]Point{{6, 5}, {7, 5}}, Left),
+			},
+			dead: []bool{true, true},
+		},
+		{
+			name: "head on, swapping cells",
+			players: []*Player{
+				scripted([]Point{{4, 5}, {3, 5}}, Right),
+				scripted([]Point{{5, 5}, {6, 5}}, Left),
+			},
+			dead: []bool{true, true},
+		},
+		{
+			name: "side by side",
+			players: []*Player{
+				scripted([]Point{{4, 5}, {3, 5}}, Right),
+				scripted([]Point{{4, 6}, {3, 6}}, Right),
+			},
+			dead: []bool{false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWorld(tt.players)
+			events := w.Step(testStart)
+			for i, p := range w.Players {
+				if p.Dead != tt.dead[i] {
+					t.Errorf("player %d: Dead = %v, want %v", i, p.Dead, tt.dead[i])
+				}
+				died := slices.Contains(events, Event{Kind: EventDied, Player: i})
+				if died != tt.dead[i] {
+					t.Errorf("player %d: EventDied reported = %v, want %v", i, died, tt.dead[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStepShieldAndInvincible(t *testing.T) {
+	p := scripted([]Point{{9, 5}, {8, 5}}, Right)
+	p.Shield = true
+	w := newTestWorld([]*Player{p})
+	events := w.Step(testStart)
+	if p.Dead || p.Shield || p.Head() != (Point{9, 5}) {
+		t.Errorf("shield: Dead = %v, Shield = %v, head = %v; want the shield used up and the snake stopped", p.Dead, p.Shield, p.Head())
+	}
+	if !slices.Contains(kinds(events), EventShieldBroken) {
+		t.Errorf("shield: events = %v, want EventShieldBroken", kinds(events))
+	}
+
+	p = scripted([]Point{{9, 5}, {8, 5}}, Right)
+	p.Invincible = true
+	w = newTestWorld([]*Player{p})
+	for range 3 {
+		w.Step(testStart)
+	}
+	if p.Dead || p.Head() != (Point{9, 5}) {
+		t.Errorf("invincible: Dead = %v, head = %v; want the snake stopped at the wall", p.Dead, p.Head())
+	}
+}
+
+func TestStepFood(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   []Point
+		food   FoodKind
+		length int
+		score  int
+		dead   bool
+		event  EventKind
+	}{
+		{"normal", []Point{{5, 5}, {4, 5}}, FoodNormal, 3, FoodPoints, false, EventAte},
+		{"golden", []Point{{5, 5}, {4, 5}}, FoodGolden, 3, GoldenPoints, false, EventAte},
+		{"poison shrinks", []Point{{5, 5}, {4, 5}, {3, 5}, {2, 5}}, FoodPoison, 2, 0, false, EventPoisoned},
+		{"poison kills a short snake", []Point{{5, 5}, {4, 5}}, FoodPoison, 2, 0, true, EventPoisoned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := scripted(tt.body, Right)
+			w := newTestWorld([]*Player{p}, Food{Pos: Point{6, 5}, Kind: tt.food})
+			events := w.Step(testStart)
+
+			if got := p.Snake.Len(); got != tt.length {
+				t.Errorf("length = %d, want %d", got, tt.length)
+			}
+			if p.Score != tt.score {
+				t.Errorf("score = %d, want %d", p.Score, tt.score)
+			}
+			if p.Dead != tt.dead {
+				t.Errorf("Dead = %v, want %v", p.Dead, tt.dead)
+			}
+			if p.Head() != (Point{6, 5}) {
+				t.Errorf("head = %v, want (6, 5)", p.Head())
+			}
+			want := Event{Kind: tt.event, Player: 0, Food: tt.food, Points: tt.score}
+			if !slices.Contains(events, want) {
+				t.Errorf("events = %+v, want %+v among them", events, want)
+			}
+			if i := w.FoodAt(Point{6, 5}); i >= 0 {
+				t.Errorf("the food eaten is still on the board: %+v", w.Foods[i])
+			}
+		})
+	}
+}
+
+func TestStepNormalFoodRespawns(t *testing.T) {
+	p := scripted([]Point{{5, 5}, {4, 5}}, Right)
+	w := newTestWorld([]*Player{p}, Food{Pos: Point{6, 5}, Kind: FoodNormal})
+	w.NoFleeing = true
+	w.Step(testStart)
+	if !w.hasFoodKind(FoodNormal) {
+		t.Fatalf("foods = %+v, want a new piece of normal food", w.Foods)
+	}
+	for _, f := range w.Foods {
+		if p.Occupies(f.Pos) {
+			t.Errorf("food %+v spawned on the snake", f)
+		}
+	}
+}
+
+func TestRoundOverAndWinner(t *testing.T) {
+	player := func(dead bool, score int) *Player {
+		p := scripted([]Point{{0, 0}}, Right)
+		p.Dead, p.Score = dead, score
+		return p
+	}
+	tests := []struct {
+		name    string
+		players []*Player
+		over    bool
+		winner  int // index into players, or -1 for a draw
+	}{
+		{"solo alive", []*Player{player(false, 0)}, false, 0},
+		{"solo dead", []*Player{player(true, 0)}, true, 0},
+		{"both alive", []*Player{player(false, 0), player(false, 0)}, false, -1},
+		{"survivor wins", []*Player{player(true, 50), player(false, 10)}, true, 1},
+		{"both dead, higher score wins", []*Player{player(true, 30), player(true, 20)}, true, 0},
+		{"both dead, same score", []*Player{player(true, 20), player(true, 20)}, true, -1},
+		{"tie broken by a later survivor", []*Player{player(true, 20), player(true, 20), player(false, 0)}, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &World{Players: tt.players}
+			if got := w.RoundOver(); got != tt.over {
+				t.Errorf("RoundOver() = %v, want %v", got, tt.over)
+			}
+			var want *Player
+			if tt.winner >= 0 {
+				want = tt.players[tt.winner]
+			}
+			if got := w.Winner(); got != want {
+				t.Errorf("Winner() = %p, want %p", got, want)
+			}
+		})
+	}
+}
+
+func TestStepHeadOnIsATie(t *testing.T) {
+	w := newTestWorld([]*Player{
+		scripted([]Point{{4, 5}, {3, 5}}, Right),
+		scripted([]Point{{6, 5}, {7, 5}}, Left),
+	})
+	w.Step(testStart)
+	if !w.RoundOver() {
+		t.Fatal("RoundOver() = false after a head-on crash")
+	}
+	if got := w.Winner(); got != nil {
+		t.Errorf("Winner() = %q, want a draw", got.Name)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build !js
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// isWeb reports whether the game is running in a browser
+const isWeb = false
+
+// userDataDir returns the folder that holds the game's saved files
+// (settings and high scores) on this platform
+func userDataDir() (string, error) {
+	return os.UserConfigDir()
+}
+
+// readDataFile reads a saved file
+// A missing file gives an error matching fs.ErrNotExist.
+func readDataFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// writeDataFile saves a file, creating its folder if needed
+func writeDataFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// removeDataFile deletes a saved file
+func removeDataFile(path string) error {
+	return os.Remove(path)
+}
+
+// exportFile saves a file meant for the player rather than the game, like
+// a recorded clip or a replay, into the clips folder next to the saved files
+// Returns where it was saved.
+func exportFile(name string, data []byte) (string, error) {
+	dir, err := userDataDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, appConfigDirName, clipsDirName, name)
+	return path, writeDataFile(path, data)
+}
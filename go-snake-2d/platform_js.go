@@ -0,0 +1,104 @@
+//go:build js
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall/js"
+)
+
+// The browser build. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o web/snake.wasm .
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" web/
+//
+// and serve the web folder (see web/index.html). There is no file system
+// in the browser, so saved files live in localStorage instead, keyed by
+// their path.
+
+// isWeb reports whether the game is running in a browser
+const isWeb = true
+
+// userDataDir returns the folder that holds the game's saved files
+// In the browser this only prefixes the localStorage keys.
+func userDataDir() (string, error) {
+	return "", nil
+}
+
+// localStorage returns the page's storage, which is missing when the
+// browser blocks it (e.g. in some private modes or sandboxed iframes)
+func localStorage() (js.Value, error) {
+	s := js.Global().Get("localStorage")
+	if !s.Truthy() {
+		return js.Value{}, errors.New("localStorage is not available")
+	}
+	return s, nil
+}
+
+// readDataFile reads a saved file from localStorage
+// A missing key gives an error matching fs.ErrNotExist, like a missing
+// file on desktop.
+func readDataFile(path string) ([]byte, error) {
+	s, err := localStorage()
+	if err != nil {
+		return nil, err
+	}
+	v := s.Call("getItem", path)
+	if v.IsNull() {
+		return nil, &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
+	}
+	return []byte(v.String()), nil
+}
+
+// writeDataFile saves a file to localStorage
+func writeDataFile(path string, data []byte) (err error) {
+	s, err := localStorage()
+	if err != nil {
+		return err
+	}
+	// setItem throws when the storage quota is exceeded, which syscall/js
+	// turns into a panic
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("saving %s: %v", path, r)
+		}
+	}()
+	s.Call("setItem", path, string(data))
+	return nil
+}
+
+// removeDataFile deletes a saved file from localStorage
+func removeDataFile(path string) error {
+	s, err := localStorage()
+	if err != nil {
+		return err
+	}
+	s.Call("removeItem", path)
+	return nil
+}
+
+// exportFile offers a file meant for the player rather than the game, like
+// a recorded clip or a replay, as a download
+// localStorage is far too small for it. Returns the file name.
+func exportFile(name string, data []byte) (string, error) {
+	doc := js.Global().Get("document")
+	if !doc.Truthy() {
+		return "", errors.New("no document to download from")
+	}
+	buf := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(buf, data)
+	blob := js.Global().Get("Blob").New([]any{buf})
+	url := js.Global().Get("URL").Call("createObjectURL", blob)
+
+	a := doc.Call("createElement", "a")
+	a.Set("href", url)
+	a.Set("download", name)
+	a.Call("click")
+
+	// The URL has to outlive the click for the download to start
+	revoke := js.Global().Get("URL").Get("revokeObjectURL").Call("bind", js.Global().Get("URL"), url)
+	js.Global().Call("setTimeout", revoke, 1000)
+	return name, nil
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// PlayingScene runs the game itself: input, movement ticks and power-ups
+type PlayingScene struct {
+	g *Game
+}
+
+// newPlayingScene creates the gameplay scene for the game's current run
+func newPlayingScene(g *Game) *PlayingScene {
+	return &PlayingScene{g: g}
+}
+
+// Update handles input and advances the snake on each tick
+func (s *PlayingScene) Update() error {
+	g := s.g
+
+	// PAUSE HANDLING
+	// P or Escape pauses. We use "just pressed" detection so the key
+	// press isn't seen again by the paused scene.
+	if g.isJustPressed(ActionPause) {
+		g.scenes.Switch(newPausedScene(g, s))
+		return nil
+	}
+
+	// SAVING
+	// F5 saves the run without stopping it
+	if g.isJustPressed(ActionSave) {
+		g.saveGameWithNotice(g.clock.Now())
+	}
+
+	// Every board runs on a clock of its own, since its snakes speed up
+	// as they grow; split screen has one for each player
+	now := g.clock.Now()
+	g.updateSystems()
+	g.eachBoard(func() { g.playBoard(now) })
+	return nil
+}
+
+// playBoard reads the current board's controllers and moves its snakes
+// as often as its tick interval says
+func (g *Game) playBoard(now time.Time) {
+	// POWER-UPS AND ARENA
+	// Spawning, despawning and effect timers run every frame, not just
+	// on movement ticks, so their timing is independent of snake speed.
+	// So does the shrinking arena, which can end the run, as can the
+	// clock running out in timed runs.
+	if g.handleEvents(now, g.world.Update(now)) {
+		return
+	}
+
+	// INPUT HANDLING
+	// Keyboard controllers read their keys BEFORE the time check so
+	// direction changes feel responsive; the snakes turn on the next tick,
+	// when every controller is asked for its direction (see step)
+	// Keys are looked up through g.bindings so they can be remapped, and
+	// swipes and the on-screen d-pad trigger player one's actions
+	g.pollControllers()
+
+	// TIME-BASED UPDATE
+	// Only update game logic at tickInterval, not every frame
+	// This decouples game speed from render speed. The interval shrinks
+	// as the snake grows (the longest one, in versus mode), so it is
+	// recalculated every frame. Active power-up effects then speed it up
+	// or slow it down.
+	g.updateTickInterval(now)
+
+	// CORE GAME LOGIC
+	// Move the snakes in their current directions, once per tickInterval
+	// that has passed. The moves are counted from the last one rather
+	// than from now, so the snakes keep their speed even when updates
+	// come less often than moves (see maxCatchUpTicks).
+	// If this ends the round, step switches to the game over scene
+	for range maxCatchUpTicks {
+		if now.Sub(g.lastUpdate) < g.tickInterval {
+			return // Not enough time has passed, skip this update
+		}
+		g.lastUpdate = g.lastUpdate.Add(g.tickInterval)
+		g.step(now)
+		if g.scenes.switching() {
+			return
+		}
+	}
+	if now.Sub(g.lastUpdate) >= g.tickInterval {
+		g.lastUpdate = now
+	}
+}
+
+// updateTickInterval works out the time between moves at now, from the
+// length of the longest snake and the power-ups in effect
+func (g *Game) updateTickInterval(now time.Time) {
+	base := g.difficulty.TickInterval(g.world.LongestSnake() - snake.InitialLength)
+	g.tickInterval = time.Duration(float64(base) * g.world.Effects.TickScale(now))
+}
+
+// Draw renders the board and the HUD
+func (s *PlayingScene) Draw(screen *ebiten.Image) {
+	now := s.g.clock.Now()
+	s.g.drawBoard(screen, now, true)
+	s.g.drawHUD(screen, now)
+	s.g.drawNotice(screen)
+	s.g.touch.draw(screen)
+}
+
+// countdownDuration is how long the countdown before a run lasts: one
+// second for each of 3, 2, 1
+const countdownDuration = 3 * time.Second
+
+// CountdownScene counts 3-2-1 over the board before a run starts
+// The snakes don't move until it ends, but keyboard players can already
+// pick their first direction, so nobody crashes on the direction left
+// over from the last run. Like pausing, the countdown doesn't eat into
+// the run's timers.
+type CountdownScene struct {
+	g *Game
+
+	// startedAt is when the countdown began
+	startedAt time.Time
+
+	// shown is the number last shown, so each one is announced once
+	shown int
+}
+
+// newCountdownScene starts the countdown for the game's current run
+func newCountdownScene(g *Game) *CountdownScene {
+	return &CountdownScene{g: g, startedAt: g.clock.Now()}
+}
+
+// remaining returns the number the countdown is on (0 once it is over)
+func (s *CountdownScene) remaining(now time.Time) int {
+	left := countdownDuration - now.Sub(s.startedAt)
+	return int((left + time.Second - 1) / time.Second)
+}
+
+// Update waits out the countdown, then starts the run
+func (s *CountdownScene) Update() error {
+	g := s.g
+	now := g.clock.Now()
+
+	// Steering is read as in play; the snakes turn on their first tick
+	g.eachBoard(g.pollControllers)
+
+	n := s.remaining(now)
+	if n <= 0 {
+		g.shiftTimers(now.Sub(s.startedAt))
+		g.scenes.Switch(newPlayingScene(g))
+		return nil
+	}
+	if n != s.shown {
+		s.shown = n
+		g.audio.play(SoundMenuMove)
+	}
+	return nil
+}
+
+// Draw renders the waiting board with the current number on top
+func (s *CountdownScene) Draw(screen *ebiten.Image) {
+	s.g.drawBoard(screen, s.startedAt, false)
+	s.g.drawHUD(screen, s.startedAt)
+	if n := s.remaining(s.g.clock.Now()); n > 0 {
+		drawCenteredText(screen, fmt.Sprint(n), 96, screenHeight/2-60, color.White)
+	}
+	s.g.touch.draw(screen)
+}
+
+// Pause menu entries, in display order
+const (
+	pauseResume = iota
+	pauseRestart
+	pauseOptions
+	pauseMainMenu
+	pauseQuit
+	pauseCount
+)
+
+// pauseItems are the labels of the pause menu entries
+var pauseItems = [pauseCount]string{
+	pauseResume:   "Resume",
+	pauseRestart:  "Restart",
+	pauseOptions:  "Options",
+	pauseMainMenu: "Main Menu",
+	pauseQuit:     "Quit",
+}
+
+// PausedScene freezes a running game until the player resumes
+// The board stays visible underneath a dimmed overlay with the pause
+// menu on top.
+type PausedScene struct {
+	g    *Game
+	menu Menu
+
+	// entries maps each menu row to its pause* entry, since Quit is left
+	// out in the browser
+	entries []int
+
+	// resume is the scene to return to when unpausing
+	resume Scene
+
+	// pausedAt records when the game was paused so the timers can be
+	// shifted forward by the time spent paused
+	pausedAt time.Time
+
+	// tas is set when pausing a TAS run, whose time only moves on with
+	// its ticks: it is paused at the last tick, with nothing to shift
+	tas bool
+}
+
+// newPausedScene pauses the game, remembering which scene to go back to
+func newPausedScene(g *Game, resume Scene) *PausedScene {
+	s := &PausedScene{g: g, menu: Menu{TextSize: 24, ItemHeight: 34}, resume: resume, pausedAt: g.clock.Now()}
+	if _, ok := resume.(*TASScene); ok {
+		s.pausedAt, s.tas = g.lastUpdate, true
+	}
+	for id, item := range pauseItems {
+		if id == pauseQuit && isWeb {
+			continue
+		}
+		s.entries = append(s.entries, id)
+		s.menu.Items = append(s.menu.Items, tr(item))
+	}
+	return s
+}
+
+// Update handles the pause menu; the pause key (or on touch screens, the
+// pause button) resumes straight away
+// On resume every timer is shifted by the paused duration so the snake
+// doesn't jump forward and effects don't expire while paused. The run
+// can also be saved from here, with its timers as they were when paused,
+// and leaving for the title screen or quitting saves it the same way.
+func (s *PausedScene) Update() error {
+	g := s.g
+	if g.isJustPressed(ActionSave) {
+		g.saveGameWithNotice(s.pausedAt)
+	}
+	if g.isJustPressed(ActionPause) {
+		s.unpause()
+		return nil
+	}
+
+	chosen, ok := g.updateMenu(&s.menu)
+	if !ok {
+		return nil
+	}
+	switch s.entries[chosen] {
+	case pauseResume:
+		s.unpause()
+	case pauseRestart:
+		// A TAS run starts over on its own scene (see TASScene.restart)
+		if s.tas {
+			s.resume.(*TASScene).restart()
+			g.scenes.Switch(s.resume)
+			break
+		}
+		g.resetGame()
+		g.scenes.Switch(g.newRunScene())
+	case pauseOptions:
+		g.scenes.Switch(newOptionsScene(g, s))
+	case pauseMainMenu:
+		s.save()
+		g.scenes.Switch(newTitleScene(g))
+	case pauseQuit:
+		// Returning ebiten.Termination ends RunGame cleanly
+		s.save()
+		return ebiten.Termination
+	}
+	return nil
+}
+
+// unpause shifts the timers past the pause and goes back to the run
+func (s *PausedScene) unpause() {
+	if !s.tas {
+		s.g.shiftTimers(s.g.clock.Now().Sub(s.pausedAt))
+	}
+	s.g.scenes.Switch(s.resume)
+}
+
+// save saves the run when leaving it, so it can be continued from the
+// title screen
+func (s *PausedScene) save() {
+	if err := s.g.saveGame(s.pausedAt); err != nil && !errors.Is(err, errNotSaveable) {
+		log.Printf("saving game: %v", err)
+	}
+}
+
+// Draw renders the frozen board with the "Paused" message on top
+func (s *PausedScene) Draw(screen *ebiten.Image) {
+	s.g.drawBoard(screen, s.pausedAt, !s.tas)
+	s.g.drawHUD(screen, s.pausedAt)
+
+	// Semi-transparent black layer so the frozen board stays visible
+	fillRect(screen, 0, 0, float32(screenWidth), screenHeight, color.RGBA{0, 0, 0, 160}, false)
+
+	// The labels follow a language change made in the options
+	for i, id := range s.entries {
+		s.menu.Items[i] = tr(pauseItems[id])
+	}
+	drawCenteredText(screen, tr("Paused"), 48, 90, color.White)
+	s.menu.draw(screen, 180)
+	drawCenteredText(screen, tr("P or ESC to resume, F5 to save"), 16, screenHeight-40, menuTextColor)
+
+	s.g.drawNotice(screen)
+	s.g.touch.draw(screen)
+}
+
+// deathAnimationDuration is how long dead snakes take to crumble away
+// before the game over screen appears
+const deathAnimationDuration = time.Second
+
+// deathFlashInterval is how long the dead snakes stay lit, and then
+// unlit, while they flash
+const deathFlashInterval = 100 * time.Millisecond
+
+// DyingScene plays the death animation between the crash and the game
+// over screen: the dead snakes flash and fall apart segment by segment,
+// head first, while the board stays as it was when the round ended
+type DyingScene struct {
+	g *Game
+
+	// startedAt is when the snake died; the animation runs for
+	// deathAnimationDuration from there
+	startedAt time.Time
+}
+
+// newDyingScene starts the death animation for the run just ended
+func newDyingScene(g *Game) *DyingScene {
+	g.eachBoard(func() { g.vanished = make([]int, len(g.world.Players)) })
+	return &DyingScene{g: g, startedAt: g.clock.Now()}
+}
+
+// Update crumbles the dead snakes a little more, bursting particles from
+// each segment as it goes, and shows the game over screen once they are
+// gone
+func (s *DyingScene) Update() error {
+	g := s.g
+	g.updateSystems()
+
+	// Every dead snake takes the whole animation to crumble, however long
+	// it is
+	elapsed := g.clock.Now().Sub(s.startedAt)
+	t := min(float64(elapsed)/float64(deathAnimationDuration), 1)
+	g.eachBoard(func() {
+		g.flash = t < 1 && elapsed/deathFlashInterval%2 == 0
+		for i, p := range g.world.Players {
+			if !p.Dead {
+				continue
+			}
+			n := int(t * float64(p.Snake.Len()))
+			for ; g.vanished[i] < n; g.vanished[i]++ {
+				g.burstAt(p.Snake.At(g.vanished[i]), segmentBurstCount, segmentBurstSpeed, segmentBurstLife, g.playerColor(i))
+			}
+		}
+	})
+
+	if t == 1 {
+		g.scenes.Switch(g.resultsScene())
+	}
+	return nil
+}
+
+// Draw renders the board as the round ended, with the dead snakes
+// crumbling
+func (s *DyingScene) Draw(screen *ebiten.Image) {
+	s.g.drawBoard(screen, s.startedAt, false)
+	s.g.drawHUD(screen, s.startedAt)
+}
+
+// GameOverScene shows the final score and high scores after a run ends
+type GameOverScene struct {
+	g *Game
+}
+
+// newGameOverScene creates the game over screen for the run just ended
+func newGameOverScene(g *Game) *GameOverScene {
+	return &GameOverScene{g: g}
+}
+
+// Update waits for the player to restart, change level or leave
+func (s *GameOverScene) Update() error {
+	g := s.g
+
+	// Let the death burst play out behind the results
+	g.updateSystems()
+
+	// Cycle through the levels before starting the next run
+	// The daily challenge's level is fixed, as is the demo's.
+	if g.isJustPressed(ActionNextLevel) && g.variant != VariantDaily && g.mode != ModeDemo {
+		g.selectLevel(g.level + 1)
+		return nil
+	}
+
+	// Check if player wants to restart
+	// Only a fresh press counts, so a key still held from the last run
+	// (or held down now) restarts once rather than every frame
+	if g.isJustPressed(ActionRestart) {
+		// Reset the game to initial state
+		g.resetGame()
+		g.scenes.Switch(g.newRunScene())
+		return nil
+	}
+
+	// Escape goes back to the title screen
+	if g.isJustPressed(ActionBack) {
+		g.scenes.Switch(newTitleScene(g))
+	}
+	return nil
+}
+
+// Draw renders the game over message, final score, the high-score table
+// and restart instructions, stacked top to bottom over the final board
+func (s *GameOverScene) Draw(screen *ebiten.Image) {
+	g := s.g
+	// The last move has finished: show the snakes where they ended up
+	// (dead ones have crumbled away; see DyingScene)
+	g.drawBoard(screen, g.lastUpdate, false)
+
+	// Dim the board so the text stays readable over the snake
+	fillRect(screen, 0, 0, float32(screenWidth), screenHeight, color.RGBA{0, 0, 0, 160}, false)
+
+	switch g.mode {
+	case ModeSolo:
+		s.drawSoloResult(screen)
+	case ModeSplit:
+		s.drawSplitResult(screen)
+	case ModeRoyale:
+		s.drawRoyaleResult(screen)
+	case ModeDemo:
+		s.drawDemoResult(screen)
+	default:
+		s.drawVersusResult(screen)
+	}
+
+	// LEVEL SELECTION AND RESTART INSTRUCTIONS
+	// The seed lets the same run be replayed with -seed, and F8 saves
+	// how it ended as a GIF
+	drawCenteredText(screen, trf("Seed: %d  (F8 to save a clip)", g.seed), 16, screenHeight-108, color.RGBA{150, 150, 150, 255})
+	levelText := trf("Level: %s  (L to change)", g.levelName())
+	switch {
+	case g.variant == VariantDaily:
+		levelText = trf("Daily challenge %s", g.daily)
+	case g.mode == ModeDemo:
+		levelText = trf("Level: %s", g.levelName())
+	}
+	drawCenteredText(screen, levelText, 18, screenHeight-84, color.RGBA{200, 200, 200, 255})
+	drawCenteredText(screen, tr("Press ENTER or SPACE to restart, ESC for menu"), 22, screenHeight-56, color.RGBA{200, 200, 200, 255})
+}
+
+// drawSoloResult shows the final score and the high-score table
+func (s *GameOverScene) drawSoloResult(screen *ebiten.Image) {
+	g := s.g
+
+	// GAME OVER TEXT
+	headline := "Game Over!"
+	switch {
+	case g.finished && g.world.Full:
+		headline = "Board filled!"
+	case g.finished && g.world.BossDefeated():
+		headline = "Boss defeated!"
+	case g.finished && g.variant == VariantTimed:
+		headline = "Time's up!"
+	case g.finished:
+		headline = "Finished!"
+	}
+	drawCenteredText(screen, tr(headline), 48, 40, color.White)
+
+	// FINAL SCORE
+	// Time attack runs are about the time, if they made it to the end
+	p := g.world.Players[0]
+	result := trf("Final score: %d   Time: %s", p.Score, formatRunTime(g.runTime))
+	if g.variant == VariantTimed {
+		result = trf("Final score: %d", p.Score)
+	}
+	if g.variant.ranksByTime() {
+		result = trf("Time: %s", formatRunTime(g.runTime))
+		if !g.finished {
+			result = trf("Did not finish (length %d of %d)", p.Snake.Len(), timeAttackLength)
+		}
+	}
+	drawCenteredText(screen, result, 24, 100, color.White)
+	drawCenteredText(screen, g.unlockedLine(), 14, 126, color.RGBA{255, 215, 0, 255})
+
+	// HIGH SCORES
+	// The table of this run's variant; the entry from this run (if it
+	// made the table) is highlighted
+	y := 145.0
+	title := trf("%s High Scores (%s)", label(g.variant), label(g.runDifficulty))
+	switch {
+	case g.variant.ranksByTime():
+		title = trf("%s Best Times (%s)", label(g.variant), label(g.runDifficulty))
+	case g.variant == VariantDaily:
+		title = tr("Daily Bests")
+	}
+	drawCenteredText(screen, title, 20, y, color.RGBA{255, 215, 0, 255})
+	y += 28
+	var entries []HighScore
+	if g.highScores != nil {
+		entries = g.highScores.Table(g.variant, g.runDifficulty)
+	}
+	// The Daily table goes back further than fits; the leaderboard
+	// screen has the rest
+	entries = entries[:min(len(entries), maxHighScores)]
+	if len(entries) == 0 {
+		drawCenteredText(screen, tr("No scores yet"), 16, y, color.RGBA{200, 200, 200, 255})
+	} else {
+		for i, e := range entries {
+			line := scoreLine(g.variant, i, e)
+			clr := color.Color(color.RGBA{200, 200, 200, 255})
+			if i == g.lastRank {
+				clr = color.RGBA{255, 215, 0, 255}
+			}
+			drawCenteredText(screen, line, 16, y, clr)
+			y += 20
+		}
+	}
+}
+
+// drawVersusResult announces the winner of a versus round and both scores
+func (s *GameOverScene) drawVersusResult(screen *ebiten.Image) {
+	g := s.g
+
+	// WINNER
+	headline, clr := tr("Draw!"), color.Color(color.White)
+	if w := g.world.Winner(); w != nil {
+		headline = trf("%s wins!", tr(w.Name))
+		for i, p := range g.world.Players {
+			if p == w {
+				clr = g.playerColor(i)
+			}
+		}
+	}
+	drawCenteredText(screen, headline, 48, 40, clr)
+
+	// SCORES
+	y := 130.0
+	for i, p := range g.world.Players {
+		status := tr("survived")
+		if p.Dead {
+			status = tr("crashed")
+		}
+		line := trf("%s: %d points, length %d, %s", tr(p.Name), p.Score, p.Snake.Len(), status)
+		drawCenteredText(screen, line, 22, y, g.playerColor(i))
+		y += 36
+	}
+}
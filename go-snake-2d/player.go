@@ -0,0 +1,83 @@
+package main
+
+import "github.com/obliviousorion/go-basics/pkg/snake"
+
+// GameMode selects how many snakes are on the board
+type GameMode int
+
+const (
+	// ModeSolo is the classic game: one snake, scores go into the
+	// high-score table
+	ModeSolo GameMode = iota
+
+	// ModeVersus puts two snakes on one keyboard (WASD against the
+	// arrow keys); the last snake alive wins the round
+	ModeVersus
+
+	// ModeVsComputer is versus mode against a bot (see bot.go); the
+	// human player keeps all the usual controls
+	ModeVsComputer
+
+	// ModeBotMatch lets two bots play each other while the player watches
+	ModeBotMatch
+
+	// ModeSplit gives two players on one keyboard a board each, side by
+	// side, racing to splitTargetScore (see split.go)
+	ModeSplit
+
+	// ModeRoyale puts the player among Settings.Rivals bots on a big
+	// board, where dead snakes turn into food and the last one alive wins
+	// (see royale.go)
+	ModeRoyale
+
+	// ModeDemo lets a perfect bot fill an open board on its own while the
+	// player watches (see demo.go)
+	ModeDemo
+)
+
+// twoPlayers reports whether two people share the keyboard in the mode
+func (m GameMode) twoPlayers() bool {
+	return m == ModeVersus || m == ModeSplit
+}
+
+// Steering lists the actions that turn one player's snake, and the one
+// that makes it dash
+type Steering struct {
+	Up, Down, Left, Right Action
+	Dash                  Action
+}
+
+// playerSteering are the controls of player one and player two
+var playerSteering = [...]Steering{
+	{ActionMoveUp, ActionMoveDown, ActionMoveLeft, ActionMoveRight, ActionDash},
+	{ActionP2MoveUp, ActionP2MoveDown, ActionP2MoveLeft, ActionP2MoveRight, ActionP2Dash},
+}
+
+// newPlayer places a player's snake at a starting position
+func newPlayer(name string, start SnakeStart, controller snake.Controller) *snake.Player {
+	return snake.NewPlayer(name, start.body(), start.Direction, controller)
+}
+
+// versusStarts returns where the two snakes start in versus mode
+// Levels can place the second snake themselves; otherwise it mirrors the
+// first through the center of the board, or for levels using the default
+// start, the snakes begin two rows above and below the center heading
+// opposite ways (those rows are kept clear by every built-in layout).
+func (l Level) versusStarts(w, h int) (SnakeStart, SnakeStart) {
+	if l.Start2 != nil {
+		return l.snakeStart(w, h), *l.Start2
+	}
+	if l.Start != nil {
+		return *l.Start, l.Start.mirrored(w, h)
+	}
+	return SnakeStart{Head: snake.Point{X: w / 2, Y: h/2 - 2}, Direction: snake.Right},
+		SnakeStart{Head: snake.Point{X: w/2 - 1, Y: h/2 + 2}, Direction: snake.Left}
+}
+
+// mirrored returns the start reflected through the center of a w×h board
+func (s SnakeStart) mirrored(w, h int) SnakeStart {
+	return SnakeStart{
+		Head:      snake.Point{X: w - 1 - s.Head.X, Y: h - 1 - s.Head.Y},
+		Direction: snake.Point{X: -s.Direction.X, Y: -s.Direction.Y},
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Each profile has its own settings, high scores, stats and achievements
+// and saved game, in its own folder. The default profile keeps its files directly in the config
+// folder, where they were before profiles existed; the others live in
+// profiles/<name>. The list of profiles, and which one was used last, is
+// kept in profiles.json next to the default profile's files.
+
+const (
+	profilesFileName = "profiles.json"
+	profilesDirName  = "profiles"
+
+	// defaultProfileName is the profile that always exists
+	defaultProfileName = "Default"
+
+	// maxProfileNameLength keeps names short enough for the menus
+	maxProfileNameLength = 12
+)
+
+// profileIndex lists the profiles and remembers the current one
+type profileIndex struct {
+	Current string   `json:"current"`
+	Names   []string `json:"names"`
+
+	// path is the file the index is loaded from and saved to
+	path string
+}
+
+// profileDir returns the folder holding a profile's files
+func profileDir(profile string) (string, error) {
+	dir, err := userDataDir()
+	if err != nil {
+		return "", fmt.Errorf("locating config dir: %w", err)
+	}
+	dir = filepath.Join(dir, appConfigDirName)
+	if profile == defaultProfileName {
+		return dir, nil
+	}
+	return filepath.Join(dir, profilesDirName, profile), nil
+}
+
+// profileIndexPath returns the location of the profile list
+func profileIndexPath() (string, error) {
+	dir, err := profileDir(defaultProfileName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, profilesFileName), nil
+}
+
+// loadProfileIndex reads the profile list from path
+// A missing file gives just the default profile. A broken one does too,
+// but then the error is returned so it can be reported.
+func loadProfileIndex(path string) (*profileIndex, error) {
+	ix := &profileIndex{Current: defaultProfileName, path: path}
+	data, err := readDataFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		ix.Names = []string{defaultProfileName}
+		return ix, nil
+	}
+	if err != nil {
+		ix.Names = []string{defaultProfileName}
+		return ix, fmt.Errorf("reading profiles: %w", err)
+	}
+	if err := json.Unmarshal(data, ix); err != nil {
+		ix.Current, ix.Names = defaultProfileName, []string{defaultProfileName}
+		return ix, fmt.Errorf("parsing profiles %s: %w", path, err)
+	}
+
+	// Hand-edited names that couldn't be folder names are dropped, and
+	// the default profile is always there
+	ix.Names = slices.DeleteFunc(ix.Names, func(n string) bool {
+		return n != defaultProfileName && validateProfileName(n) != nil
+	})
+	if !slices.Contains(ix.Names, defaultProfileName) {
+		ix.Names = append([]string{defaultProfileName}, ix.Names...)
+	}
+	if !slices.Contains(ix.Names, ix.Current) {
+		ix.Current = defaultProfileName
+	}
+	return ix, nil
+}
+
+// Save writes the profile list to disk
+func (ix *profileIndex) Save() error {
+	if ix.path == "" {
+		return errors.New("profile list has no file path")
+	}
+	data, err := json.MarshalIndent(ix, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding profiles: %w", err)
+	}
+	if err := writeDataFile(ix.path, data); err != nil {
+		return fmt.Errorf("writing profiles: %w", err)
+	}
+	return nil
+}
+
+// add creates a profile called name
+func (ix *profileIndex) add(name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	for _, n := range ix.Names {
+		if strings.EqualFold(n, name) {
+			return errors.New(trf("there is already a profile called %s", n))
+		}
+	}
+	ix.Names = append(ix.Names, name)
+	return nil
+}
+
+// validateProfileName checks that name can be shown in the menus and
+// used as a folder name: letters and digits only
+// The errors are shown to the player as they are, so they're translated.
+func validateProfileName(name string) error {
+	if name == "" {
+		return errors.New(tr("the name is empty"))
+	}
+	if len([]rune(name)) > maxProfileNameLength {
+		return errors.New(trf("the name is longer than %d characters", maxProfileNameLength))
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return errors.New(tr("the name can only use letters and digits"))
+		}
+	}
+	return nil
+}
+
+// loadProfile makes name the current profile, loading its settings, high
+// scores, stats and saved game location
+// Anything that can't be loaded falls back to a fresh default, as on
+// first run.
+func (g *Game) loadProfile(name string) {
+	g.profile = name
+
+	// SETTINGS
+	// The settings file holds options-menu choices from the last session
+	// plus hand-edited config (window size, colors, keys). Defaults are
+	// used, and written out, on first run.
+	g.settings = defaultSettings()
+	if path, err := settingsPath(name); err != nil {
+		log.Printf("settings won't be saved: %v", err)
+	} else {
+		settings, err := loadSettings(path)
+		if err != nil {
+			log.Printf("loading settings: %v", err)
+		}
+		g.settings = settings
+	}
+	// Sets the difficulty curve and key bindings (WASD + arrow keys by default)
+	g.applySettings()
+	g.watchSettings()
+
+	// HIGH SCORES
+	// Load saved scores; a missing file just means this is the first run
+	g.highScores = nil
+	if path, err := highScoresPath(name); err != nil {
+		log.Printf("high scores disabled: %v", err)
+	} else {
+		table, err := loadHighScores(path)
+		if err != nil {
+			log.Printf("loading high scores: %v", err)
+		}
+		g.highScores = table
+	}
+
+	// STATS AND ACHIEVEMENTS
+	g.stats = nil
+	if path, err := statsPath(name); err != nil {
+		log.Printf("stats disabled: %v", err)
+	} else {
+		stats, err := loadStats(path)
+		if err != nil {
+			log.Printf("loading stats: %v", err)
+		}
+		g.stats = stats
+	}
+
+	// SAVED GAME
+	// The title screen offers "Continue" when a run was saved last time
+	g.savePath = ""
+	if path, err := saveGamePath(name); err != nil {
+		log.Printf("saving games disabled: %v", err)
+	} else {
+		g.savePath = path
+	}
+}
+
+// switchProfile loads another profile and remembers it as the one to
+// start with next time
+func (g *Game) switchProfile(name string) {
+	g.loadProfile(name)
+	g.profiles.Current = name
+	if err := g.profiles.Save(); err != nil {
+		log.Printf("saving profiles: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"net/http"
+	_ "net/http/pprof" // serves the profiles on -pprof's address
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PROFILING
+//
+// -pprof ADDR starts the game with the profiler on: Go's pprof endpoints
+// are served at http://ADDR/debug/pprof/, e.g. for
+//
+//	go tool pprof http://localhost:6060/debug/pprof/profile?seconds=10
+//
+// and every profileLogInterval the time taken by each update and draw,
+// the garbage collector's pauses, and what each system (see systems.go)
+// cost over the interval are logged. Draw times are what the CPU spends
+// issuing the drawing; the GPU does its work later.
+
+// profileLogInterval is how often the timings are logged and reset
+const profileLogInterval = 5 * time.Second
+
+// histogramBuckets are the upper bounds of a timingHistogram's buckets;
+// one more bucket holds everything slower
+var histogramBuckets = [...]time.Duration{
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2 * time.Millisecond,
+	4 * time.Millisecond,
+	8 * time.Millisecond,
+	16 * time.Millisecond,
+	33 * time.Millisecond,
+}
+
+// timingHistogram counts durations into histogramBuckets
+type timingHistogram struct {
+	counts [len(histogramBuckets) + 1]int
+	n      int
+	total  time.Duration
+	max    time.Duration
+}
+
+// add counts one duration
+func (h *timingHistogram) add(d time.Duration) {
+	i := 0
+	for i < len(histogramBuckets) && d > histogramBuckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.n++
+	h.total += d
+	h.max = max(h.max, d)
+}
+
+// String summarizes the histogram on one line, e.g.
+// "n=300 avg=0.41ms max=3.2ms [≤0.25ms:120 ≤0.5ms:150 ≤4ms:30]",
+// leaving out the empty buckets
+func (h *timingHistogram) String() string {
+	if h.n == 0 {
+		return "n=0"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "n=%d avg=%s max=%s [", h.n, ms(h.total/time.Duration(h.n)), ms(h.max))
+	sep := ""
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		if i < len(histogramBuckets) {
+			fmt.Fprintf(&b, "%s≤%s:%d", sep, ms(histogramBuckets[i]), c)
+		} else {
+			fmt.Fprintf(&b, "%s>%s:%d", sep, ms(histogramBuckets[i-1]), c)
+		}
+		sep = " "
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// ms formats d in milliseconds
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%gms", float64(d.Microseconds())/1000)
+}
+
+// Profiler times the game loop for -pprof
+// A nil *Profiler is valid and does nothing, so the game loop can time
+// itself without checking whether profiling is on.
+type Profiler struct {
+	update, draw, gc timingHistogram
+
+	// systems is how long each system took, updating and drawing, over
+	// the interval, and systemNames their names, by their index in
+	// Game.systems
+	systems     []time.Duration
+	systemNames []string
+
+	// numGC is how many collections had run when the pauses were last
+	// read
+	numGC int64
+
+	// nextLog is when the timings are next logged
+	nextLog time.Time
+}
+
+// startProfiler serves pprof on addr and returns a profiler for a game
+// loop running systems
+// The server runs in the background; if it can't listen, that is logged
+// and the timings are still logged.
+func startProfiler(addr string, systems []System) *Profiler {
+	go func() {
+		log.Printf("pprof serving on http://%s/debug/pprof/", addr)
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			log.Printf("pprof: %v", err)
+		}
+	}()
+	var stats debug.GCStats
+	debug.ReadGCStats(&stats)
+	p := &Profiler{
+		numGC:       stats.NumGC,
+		nextLog:     time.Now().Add(profileLogInterval),
+		systems:     make([]time.Duration, len(systems)),
+		systemNames: make([]string, len(systems)),
+	}
+	for i, s := range systems {
+		// e.g. *main.snakeSystem is "snake"
+		name := strings.TrimLeft(fmt.Sprintf("%T", s), "*")
+		p.systemNames[i] = strings.TrimSuffix(strings.TrimPrefix(name, "main."), "System")
+	}
+	return p
+}
+
+// start returns the time a span being timed starts at (zero when not
+// profiling, to skip reading the clock)
+func (p *Profiler) start() time.Time {
+	if p == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// updated records an update that began at start, logging the timings
+// when they are due
+func (p *Profiler) updated(start time.Time) {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	p.update.add(now.Sub(start))
+	if now.After(p.nextLog) {
+		p.log()
+		p.nextLog = now.Add(profileLogInterval)
+	}
+}
+
+// drawn records a draw that began at start
+func (p *Profiler) drawn(start time.Time) {
+	if p == nil {
+		return
+	}
+	p.draw.add(time.Since(start))
+}
+
+// system records system i updating or drawing from start
+func (p *Profiler) system(i int, start time.Time) {
+	if p == nil {
+		return
+	}
+	p.systems[i] += time.Since(start)
+}
+
+// log writes out the timings since the last log and starts over
+func (p *Profiler) log() {
+	// The pauses of the collections since last time, newest first; only
+	// the last 256 are kept, far more than run in one interval
+	var stats debug.GCStats
+	debug.ReadGCStats(&stats)
+	for _, d := range stats.Pause[:min(int(stats.NumGC-p.numGC), len(stats.Pause))] {
+		p.gc.add(d)
+	}
+	p.numGC = stats.NumGC
+
+	log.Printf("profile: update %v", &p.update)
+	log.Printf("profile: draw %v", &p.draw)
+	log.Printf("profile: gc pauses %v", &p.gc)
+	p.update, p.draw, p.gc = timingHistogram{}, timingHistogram{}, timingHistogram{}
+
+	// The systems, slowest first
+	order := make([]int, len(p.systems))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(p.systems[b], p.systems[a]) })
+	parts := make([]string, len(order))
+	for n, i := range order {
+		parts[n] = p.systemNames[i] + " " + ms(p.systems[i])
+	}
+	log.Printf("profile: systems %s", strings.Join(parts, ", "))
+	clear(p.systems)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// A replay is a solo run written down: how the run was set up, and the
+// input on every tick, one letter each. Runs are deterministic (see
+// seed.go), so playing the inputs back on the same setup plays the same
+// run, tick for tick. TAS runs export replays and play them back (see
+// TASScene); -replay opens one.
+
+// replayVersion is the version of the replay format, raised whenever a
+// change to the rules would make old replays play out differently
+const replayVersion = 2
+
+// replayInputs are the letters inputs are written with: the direction the
+// snake moves in on the tick, upper case, or lower case when it dashes
+const replayInputs = "UDLR"
+
+// Replay is a recorded run, as exported to a replay file
+type Replay struct {
+	Version int `json:"version"`
+
+	Variant    Variant    `json:"variant"`
+	Difficulty Difficulty `json:"difficulty"`
+
+	// Curve is the speed curve the run was played with, which the
+	// settings and -tps can change from the difficulty's
+	Curve DifficultyCurve `json:"curve"`
+
+	// Daily is the date of a daily challenge run
+	Daily string `json:"daily,omitempty"`
+
+	// Level is the level's name, like in a saved game
+	Level    string `json:"level"`
+	MazeSeed uint64 `json:"mazeSeed"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Enemies  int    `json:"enemies,omitempty"`
+
+	Seed uint64 `json:"seed"`
+
+	// Inputs holds one letter per tick (see replayInputs)
+	Inputs string `json:"inputs"`
+
+	// Score and Length are where the run stood after the last input, to
+	// check a playback against
+	Score  int `json:"score"`
+	Length int `json:"length"`
+}
+
+// encodeInput returns the letter for moving in direction d, dashing or not
+func encodeInput(d snake.Point, dash bool) byte {
+	c := replayInputs[0]
+	for i, dir := range snake.Directions {
+		if d == dir {
+			c = replayInputs[i]
+		}
+	}
+	if dash {
+		c += 'a' - 'A'
+	}
+	return c
+}
+
+// decodeInput returns the direction and dash a letter stands for
+func decodeInput(c byte) (d snake.Point, dash bool) {
+	if c >= 'a' && c <= 'z' {
+		c, dash = c-('a'-'A'), true
+	}
+	return snake.Directions[strings.IndexByte(replayInputs, c)], dash
+}
+
+// validate checks the replay can be played back
+func (r *Replay) validate() error {
+	if r.Version != replayVersion {
+		return fmt.Errorf("replay version %d, want %d", r.Version, replayVersion)
+	}
+	if err := (GridSize{Width: r.Width, Height: r.Height}).validate(); err != nil {
+		return err
+	}
+	if c := r.Curve; c.StartRate <= 0 || c.MaxRate < c.StartRate || c.RatePerSegment < 0 {
+		return errors.New("replay curve needs startRate > 0, maxRate >= startRate and ratePerSegment >= 0")
+	}
+	if r.Enemies < 0 {
+		return fmt.Errorf("replay has %d enemies", r.Enemies)
+	}
+	if i := strings.IndexFunc(r.Inputs, func(c rune) bool {
+		return !strings.ContainsRune(replayInputs, c) && !strings.ContainsRune(strings.ToLower(replayInputs), c)
+	}); i >= 0 {
+		return fmt.Errorf("replay input %d is %q, want one of %s", i+1, r.Inputs[i], replayInputs)
+	}
+	return nil
+}
+
+// loadReplay reads a replay file
+func loadReplay(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading replay: %w", err)
+	}
+	var r Replay
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parsing replay %s: %w", path, err)
+	}
+	if err := r.validate(); err != nil {
+		return nil, fmt.Errorf("replay %s: %w", path, err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+func TestInputRoundTrip(t *testing.T) {
+	for _, d := range snake.Directions {
+		for _, dash := range []bool{false, true} {
+			c := encodeInput(d, dash)
+			if !strings.ContainsRune(replayInputs+strings.ToLower(replayInputs), rune(c)) {
+				t.Errorf("encodeInput(%v, %v) = %q, not a replay letter", d, dash, c)
+			}
+			if gd, gdash := decodeInput(c); gd != d || gdash != dash {
+				t.Errorf("decodeInput(%q) = %v, %v; want %v, %v", c, gd, gdash, d, dash)
+			}
+		}
+	}
+	if c := encodeInput(snake.Left, true); c != 'l' {
+		t.Errorf("encodeInput(Left, dash) = %q, want 'l'", c)
+	}
+}
+
+func TestReplayValidate(t *testing.T) {
+	valid := func() Replay {
+		return Replay{
+			Version: replayVersion,
+			Curve:   DifficultyCurve{StartRate: 8, MaxRate: 20, RatePerSegment: 0.5, Exponent: 1},
+			Level:   "Classic",
+			Width:   minLevelCells,
+			Height:  minLevelCells,
+			Seed:    42,
+			Inputs:  "RRrDDLLuU",
+		}
+	}
+	tests := []struct {
+		name   string
+		change func(r *Replay)
+		msg    string // "" if the replay stays valid
+	}{
+		{"valid", func(*Replay) {}, ""},
+		{"no inputs", func(r *Replay) { r.Inputs = "" }, ""},
+		{"old version", func(r *Replay) { r.Version = replayVersion - 1 }, "replay version"},
+		{"board too small", func(r *Replay) { r.Width = minLevelCells - 1 }, "board"},
+		{"board too big", func(r *Replay) { r.Height = maxGridHeight + 1 }, "board"},
+		{"no speed", func(r *Replay) { r.Curve.StartRate = 0 }, "curve"},
+		{"max below start", func(r *Replay) { r.Curve.MaxRate = 4 }, "curve"},
+		{"slowing down", func(r *Replay) { r.Curve.RatePerSegment = -1 }, "curve"},
+		{"negative enemies", func(r *Replay) { r.Enemies = -1 }, "enemies"},
+		{"bad input", func(r *Replay) { r.Inputs = "RRxD" }, `input 3 is 'x'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.change(&r)
+			err := r.validate()
+			switch {
+			case tt.msg == "" && err != nil:
+				t.Errorf("validate() = %v, want nil", err)
+			case tt.msg != "" && (err == nil || !strings.Contains(err.Error(), tt.msg)):
+				t.Errorf("validate() = %v, want an error mentioning %q", err, tt.msg)
+			}
+		})
+	}
+}
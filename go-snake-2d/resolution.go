@@ -0,0 +1,88 @@
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// Resolution selects the shape of the logical screen
+// The logical screen is always screenHeight pixels high; the resolution
+// sets how wide it is, so text and menus keep their size and wider
+// screens just have more room at the sides. The board sizes from the
+// settings keep their rows and gain columns to fill the width (see
+// Settings.boardCells), and the HUD and touch buttons keep to the
+// corners of the screen whatever its width.
+type Resolution int
+
+const (
+	Resolution4x3 Resolution = iota
+	Resolution16x9
+	ResolutionUltrawide
+	resolutionCount
+)
+
+var resolutionNames = [resolutionCount]string{"4:3", "16:9", "Ultrawide"}
+
+// resolutionWidths are the logical screen widths of each resolution
+var resolutionWidths = [resolutionCount]int{
+	Resolution4x3:       baseScreenWidth,
+	Resolution16x9:      screenHeight * 16 / 9,
+	ResolutionUltrawide: screenHeight * 21 / 9,
+}
+
+// screenWidth is the logical screen's width, set by setResolution
+var screenWidth = baseScreenWidth
+
+// setResolution makes r the logical screen's shape
+// The window keeps its height and takes the new screen's shape, unless it
+// is fullscreen or in a browser, where the screen is fitted to what there
+// is. Boards already in play keep their size in cells and are laid out
+// again across the new width.
+func (g *Game) setResolution(r Resolution) {
+	w := resolutionWidths[r]
+	if w == screenWidth {
+		return
+	}
+	if !isWeb && !ebiten.IsFullscreen() {
+		if _, h := ebiten.WindowSize(); h > 0 {
+			ebiten.SetWindowSize(h*w/screenHeight, h)
+		}
+	}
+	screenWidth = w
+	fullScreen = viewport{0, 0, float32(w), screenHeight}
+	splitAreas = [...]viewport{
+		{0, 0, float32(w) / 2, screenHeight},
+		{float32(w) / 2, 0, float32(w) / 2, screenHeight},
+	}
+
+	for i, b := range g.boards {
+		if b.world == nil {
+			continue
+		}
+		area := fullScreen
+		if g.mode == ModeSplit {
+			area = splitAreas[i]
+		}
+		b.view = newBoardView(b.world.Width, b.world.Height, area)
+	}
+	g.eachBoard(func() { g.followSnakes(true) })
+}
+
+// adaptColumns returns a board of size cells, designed for the 4:3
+// screen, with as many columns as the current screen's width makes room
+// for, keeping the rows
+func adaptColumns(size [2]int) [2]int {
+	return [2]int{min(size[1]*screenWidth/screenHeight, maxGridWidth), size[1]}
+}
+
+// MarshalText encodes the resolution by name
+func (r Resolution) MarshalText() ([]byte, error) {
+	return marshalName(resolutionNames[:], int(r))
+}
+
+// UnmarshalText decodes the resolution from its name
+func (r *Resolution) UnmarshalText(b []byte) error {
+	return unmarshalName(resolutionNames[:], b, (*int)(r))
+}
+
+// String returns the display name of the resolution
+func (r Resolution) String() string {
+	return resolutionNames[r]
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Battle royale puts the player on a big board among a crowd of bots
+// (Settings.Rivals of them). Running into any other snake is fatal, and a
+// snake that dies turns into food for the rest (see
+// snake.World.DeadLeaveFood), so the survivors grow on the fallen. The
+// last snake alive wins; the run is over for the player as soon as their
+// own snake dies.
+
+// royaleBoard is the size of the battle royale board in cells, for
+// levels that don't fix their own
+var royaleBoard = [2]int{56, 42}
+
+// royaleRing is how far from the center of the board the snakes start,
+// as a fraction of its width and height
+const royaleRing = 0.35
+
+// royaleClearance is how many cells ahead of each snake's start are
+// cleared of walls, so no snake starts facing one
+const royaleClearance = 3
+
+// royaleStarts returns where the n snakes of a battle royale start on a
+// w×h board: spread evenly around a ring, each heading along it, so they
+// all start the same distance from the walls and from each other
+func royaleStarts(n, w, h int) []SnakeStart {
+	starts := make([]SnakeStart, n)
+	for i := range starts {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+		head := snake.Point{
+			X: w/2 + int(math.Round(cos*royaleRing*float64(w))),
+			Y: h/2 + int(math.Round(sin*royaleRing*float64(h))),
+		}
+		// Clockwise along the ring (y grows downward), on whichever axis
+		// it is closer to
+		dir := snake.Right
+		switch {
+		case cos >= math.Abs(sin):
+			dir = snake.Down
+		case -cos >= math.Abs(sin):
+			dir = snake.Up
+		case sin > 0:
+			dir = snake.Left
+		}
+		starts[i] = SnakeStart{Head: head, Direction: dir}
+	}
+	return starts
+}
+
+// clearStarts removes the walls in set under each start's snake and the
+// royaleClearance cells in front of it, which levels only keep clear at
+// their own starts
+func clearStarts(set map[snake.Point]bool, starts []SnakeStart) {
+	for _, s := range starts {
+		for _, p := range s.body() {
+			delete(set, p)
+		}
+		p := s.Head
+		for range royaleClearance {
+			p = p.Add(s.Direction)
+			delete(set, p)
+		}
+	}
+}
+
+// royalePlayers returns the snakes of a battle royale on a w×h board: the
+// player's, steered by you, then g.rivals bots sharing bot
+func (g *Game) royalePlayers(w, h int, you, bot snake.Controller) []*snake.Player {
+	starts := royaleStarts(g.rivals+1, w, h)
+	players := []*snake.Player{newPlayer("You", starts[0], you)}
+	for i, s := range starts[1:] {
+		players = append(players, newPlayer(fmt.Sprintf("Bot %d", i+1), s, bot))
+	}
+	return players
+}
+
+// roundOver reports whether the run has ended: in battle royale, when the
+// player's snake has died or is the last one left
+func (g *Game) roundOver() bool {
+	if g.mode == ModeRoyale && g.world.Players[0].Dead {
+		return true
+	}
+	return g.world.RoundOver()
+}
+
+// trackedPlayers returns the players the HUD shows the status of and the
+// view follows: everyone, except in battle royale, where that is only the
+// player
+func (g *Game) trackedPlayers() []*snake.Player {
+	if g.mode == ModeRoyale {
+		return g.world.Players[:1]
+	}
+	return g.world.Players
+}
+
+// drawRoyaleResult shows whether the player won, or else how far they got,
+// and how every snake did
+func (s *GameOverScene) drawRoyaleResult(screen *ebiten.Image) {
+	g := s.g
+	w := g.world
+	you := w.Players[0]
+
+	// PLACE
+	// The player places behind every snake still alive when they died
+	alive := w.AlivePlayers()
+	headline, clr := tr("You win!"), g.playerColor(0)
+	switch {
+	case you.Dead && alive == 0:
+		headline, clr = tr("Draw!"), color.White
+	case you.Dead:
+		headline, clr = trf("Knocked out! #%d of %d", alive+1, len(w.Players)), color.RGBA{255, 80, 80, 255}
+	}
+	drawCenteredText(screen, headline, 48, 40, clr)
+
+	// SCORES
+	y := 110.0
+	for i, p := range w.Players {
+		status := tr("survived")
+		if p.Dead {
+			status = tr("knocked out")
+		}
+		line := trf("%s: %d points, %s", tr(p.Name), p.Score, status)
+		drawCenteredText(screen, line, 18, y, g.playerColor(i))
+		y += 24
+	}
+}
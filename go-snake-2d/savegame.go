@@ -0,0 +1,448 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+const saveGameFileName = "savegame.json"
+
+// savedGame is the on-disk form of a run in progress
+// It holds everything needed to carry on exactly where the run stopped,
+// including the random source, so a resumed seeded run still plays out
+// the same. Times are stored relative to the moment of saving, since the
+// run is resumed at some unknown later time.
+type savedGame struct {
+	Mode       GameMode   `json:"mode"`
+	Variant    Variant    `json:"variant"`
+	Difficulty Difficulty `json:"difficulty"`
+
+	// Daily is the date of a daily challenge run
+	Daily string `json:"daily,omitempty"`
+
+	// Elapsed is the time played so far
+	Elapsed time.Duration `json:"elapsed"`
+
+	// Level is the level's name rather than its index, which changes when
+	// custom levels are added or removed
+	Level    string `json:"level"`
+	MazeSeed uint64 `json:"mazeSeed"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+
+	Seed uint64 `json:"seed"`
+	RNG  []byte `json:"rng"`
+
+	Players []savedPlayer `json:"players"`
+	Foods   []snake.Food  `json:"foods"`
+	Enemies []snake.Enemy `json:"enemies,omitempty"`
+
+	// FoodsExpireIn is the time left of each food in Foods, or 0 for food
+	// that never expires
+	FoodsExpireIn []time.Duration `json:"foodsExpireIn,omitempty"`
+
+	PowerUp       *savedPowerUp                       `json:"powerUp,omitempty"`
+	NextPowerUpIn time.Duration                       `json:"nextPowerUpIn"`
+	Effects       map[snake.PowerUpKind]time.Duration `json:"effects,omitempty"`
+
+	// ArenaInset and NextShrinkIn are the state of a survival run's
+	// shrinking arena
+	ArenaInset   int           `json:"arenaInset,omitempty"`
+	NextShrinkIn time.Duration `json:"nextShrinkIn,omitempty"`
+
+	// CheckpointsReached are the level's checkpoints reached so far, and
+	// Checkpoint the run as it stood at the last of them, for a death to
+	// go back to (see checkpoint.go)
+	CheckpointsReached []snake.Point `json:"checkpointsReached,omitempty"`
+	Checkpoint         *savedGame    `json:"checkpoint,omitempty"`
+
+	// Boss and Projectiles are the state of a boss level's fight, counted
+	// in ticks like the enemies
+	Boss        *snake.Boss        `json:"boss,omitempty"`
+	Projectiles []snake.Projectile `json:"projectiles,omitempty"`
+}
+
+type savedPlayer struct {
+	Snake     []snake.Point `json:"snake"`
+	Direction snake.Point   `json:"direction"`
+	Score     int           `json:"score"`
+
+	// Combo and ComboIn are the meals in the combo so far and how long is
+	// left to keep it going (see snake.Combo); a combo of one meal can
+	// still grow, so it is kept as it is rather than as its multiplier
+	Combo   int           `json:"combo,omitempty"`
+	ComboIn time.Duration `json:"comboIn,omitempty"`
+
+	Shield bool `json:"shield,omitempty"`
+
+	// DashIn is how long is left until the snake can dash again
+	DashIn time.Duration `json:"dashIn,omitempty"`
+
+	// Dead is set for battle royale's knocked-out bots, whose snakes are
+	// empty once they have turned into food
+	Dead bool `json:"dead,omitempty"`
+}
+
+type savedPowerUp struct {
+	Kind      snake.PowerUpKind `json:"kind"`
+	Pos       snake.Point       `json:"pos"`
+	Duration  time.Duration     `json:"duration"`
+	ExpiresIn time.Duration     `json:"expiresIn"`
+}
+
+// saveGamePath returns the save file location of a profile
+func saveGamePath(profile string) (string, error) {
+	dir, err := profileDir(profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, saveGameFileName), nil
+}
+
+// errNotSaveable is returned by saveGame for runs the save file can't
+// hold: split-screen races, which have a board for each player, and TAS
+// runs, which are kept in savestates instead (see TASScene)
+var errNotSaveable = errors.New("this run can't be saved")
+
+// saveGame writes the current run to the save file
+// at is the moment the run's timers are measured against: now while
+// playing, or the moment the game was paused.
+func (g *Game) saveGame(at time.Time) error {
+	if g.mode == ModeSplit || g.tas != nil {
+		return errNotSaveable
+	}
+	if g.savePath == "" {
+		return errors.New("no save file location")
+	}
+	sg, err := g.snapshot(at)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(sg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding saved game: %w", err)
+	}
+	if err := writeDataFile(g.savePath, data); err != nil {
+		return fmt.Errorf("writing saved game: %w", err)
+	}
+	return nil
+}
+
+// snapshot captures the current run, with its timers measured against at,
+// for restoreGame to carry on from
+func (g *Game) snapshot(at time.Time) (*savedGame, error) {
+	rng, err := g.rngSource.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("saving random state: %w", err)
+	}
+
+	w := g.world
+	sg := &savedGame{
+		Mode:          g.mode,
+		Variant:       g.variant,
+		Difficulty:    g.runDifficulty,
+		Daily:         g.daily,
+		Elapsed:       g.elapsed(at),
+		Level:         g.levels[g.level].Name,
+		MazeSeed:      g.mazeSeed,
+		Width:         w.Width,
+		Height:        w.Height,
+		Seed:          g.seed,
+		RNG:           rng,
+		Foods:         slices.Clone(w.Foods),
+		NextPowerUpIn: w.NextPowerUpAt.Sub(at),
+		Effects:       make(map[snake.PowerUpKind]time.Duration),
+		Checkpoint:    g.checkpoint,
+	}
+	for e := range w.Entities.All() {
+		switch e := e.(type) {
+		case *snake.Enemy:
+			sg.Enemies = append(sg.Enemies, *e)
+		case *snake.Boss:
+			b := *e
+			sg.Boss = &b
+		case *snake.Projectile:
+			sg.Projectiles = append(sg.Projectiles, *e)
+		}
+	}
+	for _, f := range w.Foods {
+		var left time.Duration
+		if !f.ExpiresAt.IsZero() {
+			// Never 0, which would make the food permanent
+			left = max(f.ExpiresAt.Sub(at), time.Millisecond)
+		}
+		sg.FoodsExpireIn = append(sg.FoodsExpireIn, left)
+	}
+	for _, p := range w.Players {
+		sg.Players = append(sg.Players, savedPlayer{
+			Snake:     p.Snake.Points(),
+			Direction: p.Direction,
+			Score:     p.Score,
+			Combo:     p.Combo.Count,
+			ComboIn:   max(p.Combo.ExpiresAt.Sub(at), 0),
+			Shield:    p.Shield,
+			DashIn:    max(p.DashReadyAt.Sub(at), 0),
+			Dead:      p.Dead,
+		})
+	}
+	if pu := w.PowerUp; pu != nil {
+		sg.PowerUp = &savedPowerUp{Kind: pu.Kind, Pos: pu.Pos, Duration: pu.Duration, ExpiresIn: pu.ExpiresAt.Sub(at)}
+	}
+	for kind := range snake.PowerUpKindCount {
+		if left := w.Effects.Remaining(kind, at); left > 0 {
+			sg.Effects[kind] = left
+		}
+	}
+	if a := w.Arena; a != nil {
+		sg.ArenaInset, sg.NextShrinkIn = a.Inset, a.NextShrinkAt.Sub(at)
+	}
+	for _, c := range w.Checkpoints {
+		if c.Reached {
+			sg.CheckpointsReached = append(sg.CheckpointsReached, c.Pos)
+		}
+	}
+	return sg, nil
+}
+
+// loadSavedGame reads the save file
+// Returns nil without an error when there is no saved game.
+func (g *Game) loadSavedGame() (*savedGame, error) {
+	if g.savePath == "" {
+		return nil, nil
+	}
+	data, err := readDataFile(g.savePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading saved game: %w", err)
+	}
+	// Runs saved before difficulties existed were played at Normal
+	sg := savedGame{Difficulty: DifficultyNormal}
+	if err := json.Unmarshal(data, &sg); err != nil {
+		return nil, fmt.Errorf("parsing saved game %s: %w", g.savePath, err)
+	}
+	return &sg, nil
+}
+
+// hasSavedGame reports whether there is a run to continue
+func (g *Game) hasSavedGame() bool {
+	sg, err := g.loadSavedGame()
+	return err == nil && sg != nil
+}
+
+// restoreGame sets up the game to carry on with a saved run
+// The run is rebuilt like a new one (so controllers and walls are set up
+// as usual), then the saved snakes, food, timers and random state
+// replace the fresh ones.
+func (g *Game) restoreGame(sg *savedGame) error {
+	level := -1
+	for i, l := range g.levels {
+		if l.Name == sg.Level {
+			level = i
+			break
+		}
+	}
+	if level < 0 {
+		return fmt.Errorf("saved level %q no longer exists", sg.Level)
+	}
+	if sg.Width < 1 || sg.Height < 1 || sg.Width > maxGridWidth || sg.Height > maxGridHeight {
+		return fmt.Errorf("saved board %dx%d is too big", sg.Width, sg.Height)
+	}
+
+	g.mode = sg.Mode
+	g.variant = sg.Variant
+	g.runDifficulty = sg.Difficulty
+	g.daily = sg.Daily
+	g.level = level
+	g.mazeSeed = sg.MazeSeed
+	if sg.Mode == ModeRoyale {
+		g.rivals = len(sg.Players) - 1
+		if g.rivals < minRivals || g.rivals > maxRivals {
+			return fmt.Errorf("saved battle royale has %d bots, want %d-%d", g.rivals, minRivals, maxRivals)
+		}
+	}
+	g.applySettings()
+	g.resetGame()
+	w := g.world
+	if len(sg.Players) != len(w.Players) {
+		return fmt.Errorf("saved game has %d players, mode needs %d", len(sg.Players), len(w.Players))
+	}
+
+	// BOARD
+	// The board size setting may have changed since, so use the saved size
+	w.Width, w.Height = sg.Width, sg.Height
+	g.view = newBoardView(w.Width, w.Height, fullScreen)
+	bw, bh := g.view.size()
+	g.trail.reset(int(bw), int(bh))
+	w.Obstacles = g.obstacleSet(g.levels[level], w.Width, w.Height)
+
+	// SNAKES AND FOOD
+	for i, sp := range sg.Players {
+		if len(sp.Snake) == 0 && !sp.Dead {
+			return fmt.Errorf("saved player %d has no snake", i+1)
+		}
+		p := w.Players[i]
+		p.Snake, p.Direction, p.Score, p.Shield = snake.NewBody(sp.Snake), sp.Direction, sp.Score, sp.Shield
+		p.Dead = sp.Dead
+	}
+	// The saved game may be restored again (see checkpoint.go), so the
+	// world gets copies to change
+	w.Foods = slices.Clone(sg.Foods)
+	boss := w.Boss()
+	w.Entities.Clear()
+	for _, e := range sg.Enemies {
+		w.Entities.Add(&e)
+	}
+	for i, c := range w.Checkpoints {
+		w.Checkpoints[i].Reached = slices.Contains(sg.CheckpointsReached, c.Pos)
+	}
+	g.checkpoint = sg.Checkpoint
+	// Only a level with a boss gets the saved one back
+	if boss != nil {
+		if sg.Boss != nil {
+			b := *sg.Boss
+			boss = &b
+		}
+		w.Entities.Add(boss)
+	}
+	for _, s := range sg.Projectiles {
+		w.Entities.Add(&s)
+	}
+
+	// RANDOM STATE
+	g.seed = sg.Seed
+	if err := g.rngSource.UnmarshalBinary(sg.RNG); err != nil {
+		return fmt.Errorf("restoring random state: %w", err)
+	}
+
+	// TIMERS
+	now := g.clock.Now()
+	g.lastUpdate = now
+	g.runStart = now.Add(-sg.Elapsed)
+	w.NextPowerUpAt = now.Add(sg.NextPowerUpIn)
+	w.PowerUp = nil
+	if pu := sg.PowerUp; pu != nil {
+		w.PowerUp = &snake.PowerUp{Kind: pu.Kind, Pos: pu.Pos, Duration: pu.Duration, ExpiresAt: now.Add(pu.ExpiresIn)}
+	}
+	w.Effects = snake.Effects{}
+	for kind, left := range sg.Effects {
+		w.Effects[kind] = now.Add(left)
+	}
+	for i, sp := range sg.Players {
+		w.Players[i].Combo = snake.Combo{Count: sp.Combo, ExpiresAt: now.Add(sp.ComboIn)}
+		w.Players[i].DashReadyAt = now.Add(sp.DashIn)
+	}
+	for i, left := range sg.FoodsExpireIn {
+		if i < len(w.Foods) && left > 0 {
+			w.Foods[i].ExpiresAt = now.Add(left)
+		}
+	}
+	if a := w.Arena; a != nil {
+		if sg.ArenaInset < 0 || w.Width-2*sg.ArenaInset < 1 || w.Height-2*sg.ArenaInset < 1 {
+			return fmt.Errorf("saved arena inset %d doesn't fit the board", sg.ArenaInset)
+		}
+		a.Inset, a.NextShrinkAt = sg.ArenaInset, now.Add(sg.NextShrinkIn)
+	}
+	g.findHints()
+	return nil
+}
+
+// continueGame restores the saved run and opens it paused, so the player
+// can get ready before the snake moves
+func (g *Game) continueGame() error {
+	sg, err := g.loadSavedGame()
+	if err != nil {
+		return err
+	}
+	if sg == nil {
+		return errors.New("no saved game")
+	}
+	g.tas = nil
+	if err := g.restoreGame(sg); err != nil {
+		return err
+	}
+	g.scenes.Switch(newPausedScene(g, newPlayingScene(g)))
+	return nil
+}
+
+// deleteSavedGame removes the save file once its run is over, so it can't
+// be continued again
+func (g *Game) deleteSavedGame() {
+	if g.savePath == "" {
+		return
+	}
+	if err := removeDataFile(g.savePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("removing saved game: %v", err)
+	}
+}
+
+// saveGameWithNotice saves the run and tells the player how it went
+func (g *Game) saveGameWithNotice(at time.Time) {
+	err := g.saveGame(at)
+	if errors.Is(err, errNotSaveable) {
+		msg := "Split-screen races can't be saved"
+		if g.tas != nil {
+			msg = "TAS runs use savestates instead"
+		}
+		g.notify(tr(msg))
+		return
+	}
+	if err != nil {
+		log.Printf("saving game: %v", err)
+		g.notify(tr("Could not save the game"))
+		return
+	}
+	g.notify(tr("Game saved"))
+}
+
+// runClock returns the moment a run in progress is at: now while
+// playing, or the moment it was paused (including while in the options
+// or the controls screen from the pause menu). ok is false outside of a
+// run.
+func (g *Game) runClock() (at time.Time, ok bool) {
+	scene := g.scenes.Current()
+	if s, ok := scene.(*ControlsScene); ok {
+		scene = s.back
+	}
+	if s, ok := scene.(*OptionsScene); ok {
+		scene = s.back
+	}
+	switch s := scene.(type) {
+	case *PlayingScene:
+		// The developer console pauses the run while it is open
+		if g.console.open {
+			return g.console.openedAt, true
+		}
+		return g.clock.Now(), true
+	case *PausedScene:
+		return s.pausedAt, true
+	}
+	return time.Time{}, false
+}
+
+// noticeDuration is how long a notice stays on screen
+const noticeDuration = 2 * time.Second
+
+// notify shows a short message over the board
+func (g *Game) notify(msg string) {
+	g.notice = msg
+	g.noticeUntil = g.clock.Now().Add(noticeDuration)
+}
+
+// drawNotice draws the current notice, if it hasn't timed out
+func (g *Game) drawNotice(screen *ebiten.Image) {
+	if g.notice == "" || g.clock.Now().After(g.noticeUntil) {
+		return
+	}
+	drawCenteredText(screen, g.notice, 20, screenHeight-80, menuSelectedColor)
+}
@@ -0,0 +1,56 @@
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// Scene is one screen of the game (title menu, gameplay, pause, ...)
+// Each scene owns its own input handling and drawing; Game.Update and
+// Game.Draw just forward to whichever scene is active.
+type Scene interface {
+	Update() error
+	Draw(screen *ebiten.Image)
+}
+
+// SceneManager holds the active scene and performs transitions
+type SceneManager struct {
+	current Scene
+
+	// next is a transition requested during this frame's Update
+	next Scene
+}
+
+// NewSceneManager creates a manager starting on the given scene
+func NewSceneManager(initial Scene) *SceneManager {
+	return &SceneManager{current: initial}
+}
+
+// Switch requests a transition to s
+// The switch happens once the current scene's Update returns, so a scene
+// never sees its own transition mid-frame and the key press that caused
+// it isn't handled a second time by the new scene.
+func (m *SceneManager) Switch(s Scene) {
+	m.next = s
+}
+
+// switching reports whether a transition has been requested this frame
+func (m *SceneManager) switching() bool {
+	return m.next != nil
+}
+
+// Current returns the active scene
+func (m *SceneManager) Current() Scene {
+	return m.current
+}
+
+// Update runs the active scene, then applies any requested transition
+func (m *SceneManager) Update() error {
+	err := m.current.Update()
+	if m.next != nil {
+		m.current, m.next = m.next, nil
+	}
+	return err
+}
+
+// Draw renders the active scene
+func (m *SceneManager) Draw(screen *ebiten.Image) {
+	m.current.Draw(screen)
+}
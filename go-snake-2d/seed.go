@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math/rand/v2"
+)
+
+// Every run draws its food positions, poison and power-ups from its own
+// random source, seeded at the start of the run. The same seed (and the
+// same moves) therefore give the same game, which is what challenge runs
+// and bug reports need. The seed is shown on the game over screen and can
+// be fixed with -seed or the "seed" setting.
+
+// fixedSeed returns the seed every run should use: the TAS run's own, the
+// date's seed for the daily challenge, the -seed flag, or else the "seed"
+// setting. Without any of them, each run gets a fresh seed.
+func (g *Game) fixedSeed() (uint64, bool) {
+	if g.tas != nil {
+		return g.tas.replay.Seed, true
+	}
+	if g.variant == VariantDaily && g.daily != "" {
+		return dailySeed(g.daily), true
+	}
+	if g.launch.seedSet {
+		return g.launch.seed, true
+	}
+	if g.settings.Seed != nil {
+		return *g.settings.Seed, true
+	}
+	return 0, false
+}
+
+// seedRun picks the seed for a new run and creates its random source
+func (g *Game) seedRun() {
+	seed, ok := g.fixedSeed()
+	if !ok {
+		seed = rand.Uint64()
+	}
+	g.seed = seed
+	g.rngSource = runSource(seed)
+}
+
+// runSource returns a new random source for a run with the given seed
+// The second PCG word just has to differ from the one the maze generator
+// uses, so runs and mazes don't share a random sequence.
+func runSource(seed uint64) *rand.PCG {
+	return rand.NewPCG(seed, seed^0x9E3779B97F4A7C15)
+}
+
+// newMazeSeed returns the seed for a freshly selected generated level:
+// the fixed seed if there is one, or a random one
+func (g *Game) newMazeSeed() uint64 {
+	if seed, ok := g.fixedSeed(); ok {
+		return seed
+	}
+	return rand.Uint64()
+}
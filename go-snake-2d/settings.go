@@ -0,0 +1,487 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const settingsFileName = "settings.json"
+
+// BoardSize selects how many cells the board has
+// The screen size stays the same, so bigger boards use smaller cells.
+// The sizes are for the 4:3 screen; wider resolutions add columns (see
+// adaptColumns).
+type BoardSize int
+
+const (
+	BoardSmall BoardSize = iota
+	BoardNormal
+	BoardLarge
+	boardSizeCount
+)
+
+var boardSizeNames = [boardSizeCount]string{"Small", "Normal", "Large"}
+
+// boardSizeCells gives the width and height in cells of each board size
+var boardSizeCells = [boardSizeCount][2]int{
+	BoardSmall:  {20, 15},
+	BoardNormal: {32, 24},
+	BoardLarge:  {40, 30},
+}
+
+// GridSize is a board size in cells
+type GridSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// maxGridWidth and maxGridHeight are the largest boards allowed; boards
+// bigger than the screen scroll (see boardView)
+const (
+	maxGridWidth  = 120
+	maxGridHeight = 120
+)
+
+// validate checks that the board is big enough to play on and no bigger
+// than the limit
+func (s GridSize) validate() error {
+	if s.Width < minLevelCells || s.Height < minLevelCells || s.Width > maxGridWidth || s.Height > maxGridHeight {
+		return fmt.Errorf("board %dx%d must be between %dx%d and %dx%d",
+			s.Width, s.Height, minLevelCells, minLevelCells, maxGridWidth, maxGridHeight)
+	}
+	return nil
+}
+
+// String formats the size as "WxH"
+func (s GridSize) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// ControlScheme selects which keys steer the snake
+type ControlScheme int
+
+const (
+	ControlsBoth ControlScheme = iota
+	ControlsWASD
+	ControlsArrows
+	controlSchemeCount
+)
+
+var controlSchemeNames = [controlSchemeCount]string{"WASD + Arrows", "WASD", "Arrows"}
+
+// maxEnemies is the most enemies the options allow
+const maxEnemies = 3
+
+// minRivals and maxRivals bound how many bots battle royale has
+const (
+	minRivals = 3
+	maxRivals = 7
+)
+
+// volumeStep is how much one left/right press changes a volume
+const volumeStep = 10
+
+// stepVolume moves a volume percentage by delta steps of volumeStep,
+// wrapping from 100 back to 0 like the other options so it can be
+// changed by selecting it repeatedly
+func stepVolume(v, delta int) int {
+	return cycle(v/volumeStep, delta, 100/volumeStep+1) * volumeStep
+}
+
+// Settings holds the game's configuration: the player's choices from the
+// options menu plus values that can only be set by editing the file
+// (window size, custom speed curve, colors and key bindings).
+// They are applied to the game as soon as they change and saved as JSON
+// in the user config dir. Any field missing from the file keeps its
+// default, so a partial file is fine, and edits to the file are picked up
+// while the game runs (see hotreload.go).
+type Settings struct {
+	Difficulty Difficulty    `json:"difficulty"`
+	Board      BoardSize     `json:"board"`
+	Controls   ControlScheme `json:"controls"`
+
+	// Grid, when set, replaces the Board preset with a custom size in
+	// cells, e.g. "grid": {"width": 50, "height": 30}
+	Grid *GridSize `json:"grid,omitempty"`
+
+	// SFXVolume and MusicVolume are the sound effect and background
+	// music volumes in percent (0-100)
+	SFXVolume   int `json:"sfxVolume"`
+	MusicVolume int `json:"musicVolume"`
+
+	// Muted silences every sound, keeping the volumes for when it is
+	// turned back on (toggled with M anywhere in the game)
+	Muted bool `json:"muted,omitempty"`
+
+	// BotLevel is the skill of the computer opponent
+	BotLevel BotLevel `json:"botLevel"`
+
+	// Enemies is how many enemies hunt the snakes (0-maxEnemies)
+	Enemies int `json:"enemies"`
+
+	// Rivals is how many bots battle royale puts on the board
+	// (minRivals-maxRivals)
+	Rivals int `json:"rivals"`
+
+	// Variant is the goal of solo runs, chosen on the title screen
+	Variant Variant `json:"variant"`
+
+	// Initials are the letters last entered for a high score, offered
+	// again next time
+	Initials string `json:"initials,omitempty"`
+
+	// TouchDPad shows an on-screen d-pad for touch screens; swipes work
+	// either way
+	TouchDPad bool `json:"touchDPad"`
+
+	// Background is drawn under the board: plain, a checkerboard or a grid
+	Background BackgroundStyle `json:"background"`
+
+	// Palette swaps in colorblind-safe colors (see Palette)
+	Palette Palette `json:"palette"`
+
+	// Patterns marks things apart by shape as well as color: the second
+	// snake is dotted and poison carries a cross
+	Patterns bool `json:"patterns"`
+
+	// SnakeStyle colors the snakes' bodies: solid, a gradient or a
+	// rainbow
+	SnakeStyle SnakeStyle `json:"snakeStyle"`
+
+	// Trails draws fading afterimages behind the moving snakes
+	Trails bool `json:"trails"`
+
+	// Starfield, Vignette and Scanlines turn the ambient effects on (see
+	// ambient.go); they cost a little drawing time on slow machines
+	Starfield bool `json:"starfield"`
+	Vignette  bool `json:"vignette"`
+	Scanlines bool `json:"scanlines"`
+
+	// Mirror shows the board flipped left to right, while the keys keep
+	// moving the snakes the way they say in the world: right still goes
+	// right, which now looks like left
+	Mirror bool `json:"mirror,omitempty"`
+
+	// Hints shows the players a safe route to the nearest food (see
+	// hint.go)
+	Hints bool `json:"hints,omitempty"`
+
+	// Language is the code of the language the game is shown in, e.g.
+	// "es" (see i18n.go)
+	Language string `json:"language,omitempty"`
+
+	// Resolution is the logical screen's shape: 4:3, 16:9 or ultrawide
+	Resolution Resolution `json:"resolution"`
+
+	// Window is the initial window size in pixels; it follows the window
+	// when the player resizes it
+	Window WindowSize `json:"window"`
+
+	// UpdateRate is how many times a second the game updates (Ebiten's
+	// TPS), one of updateRates; higher is smoother on fast displays but
+	// never changes how fast the snakes move. VSync ties drawing to the
+	// display's refresh.
+	UpdateRate UpdateRate `json:"updateRate"`
+	VSync      bool       `json:"vsync"`
+
+	// Fullscreen starts the game fullscreen; F11 toggles it and the
+	// choice is remembered here
+	Fullscreen bool `json:"fullscreen"`
+
+	// SpeedCurve, when set, replaces the difficulty's curve with a custom
+	// tick-rate ramp
+	SpeedCurve *DifficultyCurve `json:"speedCurve,omitempty"`
+
+	// Colors is the board and menu color theme
+	Colors Theme `json:"colors"`
+
+	// DayNight blends Colors into NightColors and back as the day goes by
+	// (see DayNightCycle)
+	DayNight    DayNightCycle `json:"dayNight"`
+	NightColors Theme         `json:"nightColors"`
+
+	// Font replaces the built-in font or scales the text (see
+	// FontSettings)
+	Font FontSettings `json:"font"`
+
+	// Seed, when set, fixes the random seed of every run so games can be
+	// replayed; -seed overrides it (see seed.go)
+	Seed *uint64 `json:"seed,omitempty"`
+
+	// Keys, when set, overrides the Controls scheme for the listed
+	// actions, e.g. "keys": {"pause": ["P"], "moveUp": ["I"]}; the
+	// controls screen sets them too (see controls.go)
+	Keys KeyBindings `json:"keys,omitempty"`
+
+	// path is the file the settings are loaded from and saved to
+	path string
+}
+
+// WindowSize is a window size in pixels
+type WindowSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// minWindowWidth and minWindowHeight keep a configured window usable
+const (
+	minWindowWidth  = 320
+	minWindowHeight = 240
+)
+
+// defaultSettings returns the settings used on first run
+func defaultSettings() Settings {
+	return Settings{
+		Difficulty:  DifficultyNormal,
+		Board:       BoardNormal,
+		Controls:    ControlsBoth,
+		Background:  BackgroundChecker,
+		BotLevel:    BotNormal,
+		Rivals:      5,
+		SFXVolume:   70,
+		MusicVolume: 50,
+		UpdateRate:  baseTPS,
+		VSync:       true,
+		Starfield:   true,
+		Vignette:    true,
+		Window:      WindowSize{Width: baseScreenWidth, Height: screenHeight},
+		Colors:      defaultTheme(),
+		NightColors: defaultNightTheme(),
+	}
+}
+
+// settingsPath returns the settings file location of a profile
+func settingsPath(profile string) (string, error) {
+	dir, err := profileDir(profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, settingsFileName), nil
+}
+
+// loadSettings reads settings from path
+// A missing file gives the defaults and writes them out, so there is a
+// file to edit. A broken file also gives the defaults (without
+// overwriting it), but then the error is returned so it can be reported.
+// Out-of-range values are reset to their defaults and reported the same way.
+func loadSettings(path string) (Settings, error) {
+	s := defaultSettings()
+	s.path = path
+
+	data, err := readDataFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, s.Save()
+	}
+	if err != nil {
+		return s, fmt.Errorf("reading settings: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		s = defaultSettings()
+		s.path = path
+		return s, fmt.Errorf("parsing settings %s: %w", path, err)
+	}
+	if err := s.validate(); err != nil {
+		return s, fmt.Errorf("settings %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// validate resets any out-of-range values to their defaults and reports
+// what it changed
+func (s *Settings) validate() error {
+	def := defaultSettings()
+	var errs []error
+
+	if s.SFXVolume < 0 || s.SFXVolume > 100 {
+		errs = append(errs, fmt.Errorf("sfxVolume %d out of range 0-100", s.SFXVolume))
+		s.SFXVolume = def.SFXVolume
+	}
+	if s.MusicVolume < 0 || s.MusicVolume > 100 {
+		errs = append(errs, fmt.Errorf("musicVolume %d out of range 0-100", s.MusicVolume))
+		s.MusicVolume = def.MusicVolume
+	}
+	if s.Enemies < 0 || s.Enemies > maxEnemies {
+		errs = append(errs, fmt.Errorf("enemies %d out of range 0-%d", s.Enemies, maxEnemies))
+		s.Enemies = def.Enemies
+	}
+	if s.Rivals < minRivals || s.Rivals > maxRivals {
+		errs = append(errs, fmt.Errorf("rivals %d out of range %d-%d", s.Rivals, minRivals, maxRivals))
+		s.Rivals = def.Rivals
+	}
+	if s.Grid != nil {
+		if err := s.Grid.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("grid: %w", err))
+			s.Grid = nil
+		}
+	}
+	if s.Window.Width < minWindowWidth || s.Window.Height < minWindowHeight {
+		errs = append(errs, fmt.Errorf("window %dx%d smaller than %dx%d",
+			s.Window.Width, s.Window.Height, minWindowWidth, minWindowHeight))
+		s.Window = def.Window
+	}
+	if c := s.SpeedCurve; c != nil && (c.StartRate <= 0 || c.MaxRate < c.StartRate || c.RatePerSegment < 0) {
+		errs = append(errs, errors.New("speedCurve needs startRate > 0, maxRate >= startRate and ratePerSegment >= 0"))
+		s.SpeedCurve = nil
+	}
+	if err := s.UpdateRate.validate(); err != nil {
+		errs = append(errs, err)
+		s.UpdateRate = def.UpdateRate
+	}
+	if err := s.Font.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("font: %w", err))
+		s.Font.Scale = 0
+	}
+	return errors.Join(errs...)
+}
+
+// Save writes the settings to disk, creating the config folder if needed
+func (s Settings) Save() error {
+	if s.path == "" {
+		return errors.New("settings have no file path")
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding settings: %w", err)
+	}
+	if err := writeDataFile(s.path, data); err != nil {
+		return fmt.Errorf("writing settings: %w", err)
+	}
+	return nil
+}
+
+// boardCells returns the board size chosen in the settings: the custom
+// grid if there is one, or else the board preset
+func (s Settings) boardCells() [2]int {
+	if s.Grid != nil {
+		return [2]int{s.Grid.Width, s.Grid.Height}
+	}
+	return adaptColumns(boardSizeCells[s.Board])
+}
+
+// keyBindings returns the keys for every action in the given mode: the
+// defaults, with player one's steering set by the Controls scheme, then
+// any keys from the file or the controls screen
+func (s *Settings) keyBindings(mode GameMode) KeyBindings {
+	b := defaultKeyBindings()
+	scheme := s.Controls
+	if mode.twoPlayers() {
+		// The arrow keys and right Shift belong to player two
+		scheme = ControlsWASD
+		b.Bind(ActionDash, ebiten.KeyShiftLeft)
+	}
+	switch scheme {
+	case ControlsWASD:
+		b.Bind(ActionMoveUp, ebiten.KeyW)
+		b.Bind(ActionMoveDown, ebiten.KeyS)
+		b.Bind(ActionMoveLeft, ebiten.KeyA)
+		b.Bind(ActionMoveRight, ebiten.KeyD)
+	case ControlsArrows:
+		b.Bind(ActionMoveUp, ebiten.KeyArrowUp)
+		b.Bind(ActionMoveDown, ebiten.KeyArrowDown)
+		b.Bind(ActionMoveLeft, ebiten.KeyArrowLeft)
+		b.Bind(ActionMoveRight, ebiten.KeyArrowRight)
+	}
+
+	// Keys listed explicitly win over the scheme
+	for action, keys := range s.Keys {
+		b.Bind(action, keys...)
+	}
+	return b
+}
+
+// applySettings pushes the current settings into the running game
+// Controls take effect immediately (they also depend on the game mode, so
+// this runs again when it changes); the board size and the number of
+// enemies are used from the next reset, since the current board can't
+// change under the snake. The difficulty is fixed when a run starts (see
+// startGame), so its curve comes from runDifficulty.
+func (g *Game) applySettings() {
+	g.difficulty = difficultyPresets[g.runDifficulty].curve
+	if g.settings.SpeedCurve != nil {
+		g.difficulty = *g.settings.SpeedCurve
+	}
+
+	g.bindings = g.settings.keyBindings(g.mode)
+	g.touch.dpad = g.settings.TouchDPad
+	g.dayTheme = g.settings.Colors.withPalette(g.settings.Palette)
+	g.nightTheme = g.settings.NightColors.withPalette(g.settings.Palette)
+	g.night = -1
+	g.updateDayNight()
+	setFont(g.settings.Font)
+	g.setResolution(g.settings.Resolution)
+	ebiten.SetTPS(int(g.settings.UpdateRate))
+	ebiten.SetVsyncEnabled(g.settings.VSync)
+	setLanguage(g.settings.Language)
+	g.audio.setVolumes(g.settings.SFXVolume, g.settings.MusicVolume)
+	g.audio.setMuted(g.settings.Muted)
+
+	// Command-line flags win over everything, except that a TAS run
+	// keeps the speed it was recorded at
+	g.applySpeedOverride()
+	if g.tas != nil {
+		g.difficulty = g.tas.replay.Curve
+	}
+}
+
+// The option enums are stored by name (e.g. "difficulty": "Hard") so the
+// settings file stays readable and hand-editable
+
+// MarshalText encodes the board size by name
+func (b BoardSize) MarshalText() ([]byte, error) {
+	return marshalName(boardSizeNames[:], int(b))
+}
+
+// UnmarshalText decodes the board size from its name
+func (b *BoardSize) UnmarshalText(t []byte) error {
+	return unmarshalName(boardSizeNames[:], t, (*int)(b))
+}
+
+// String returns the display name of the board size
+func (b BoardSize) String() string {
+	return boardSizeNames[b]
+}
+
+// MarshalText encodes the control scheme by name
+func (c ControlScheme) MarshalText() ([]byte, error) {
+	return marshalName(controlSchemeNames[:], int(c))
+}
+
+// UnmarshalText decodes the control scheme from its name
+func (c *ControlScheme) UnmarshalText(b []byte) error {
+	return unmarshalName(controlSchemeNames[:], b, (*int)(c))
+}
+
+// String returns the display name of the control scheme
+func (c ControlScheme) String() string {
+	return controlSchemeNames[c]
+}
+
+// marshalName returns names[v], rejecting out-of-range values
+func marshalName(names []string, v int) ([]byte, error) {
+	if v < 0 || v >= len(names) {
+		return nil, fmt.Errorf("invalid option %d", v)
+	}
+	return []byte(names[v]), nil
+}
+
+// unmarshalName sets *v to the index of b in names (case-insensitive)
+func unmarshalName(names []string, b []byte, v *int) error {
+	for i, n := range names {
+		if strings.EqualFold(n, string(b)) {
+			*v = i
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown option %q (want one of %s)", b, strings.Join(names, ", "))
+}
+
+// cycle returns v moved by delta, wrapping within [0, n)
+func cycle(v, delta, n int) int {
+	return ((v+delta)%n + n) % n
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"time"
+)
+
+// SnakeStyle selects how snake bodies are colored
+type SnakeStyle int
+
+const (
+	// SnakeSolid fills every segment with the snake's color
+	SnakeSolid SnakeStyle = iota
+	// SnakeGradient fades the snake's color from the head down to the
+	// tail
+	SnakeGradient
+	// SnakeRainbow runs the hues along the body, cycling over time
+	SnakeRainbow
+	snakeStyleCount
+)
+
+var snakeStyleNames = [snakeStyleCount]string{"Solid", "Gradient", "Rainbow"}
+
+const (
+	// gradientTail is how bright the tail of a gradient snake is, as a
+	// fraction of the head's color
+	gradientTail = 0.35
+
+	// rainbowSpan is how many degrees of hue a rainbow snake's body
+	// covers from head to tail, and rainbowCycle how long the colors take
+	// to go all the way round
+	rainbowSpan  = 300.0
+	rainbowCycle = 4 * time.Second
+)
+
+// segmentColor returns the color of segment j of an n-segment snake
+// whose own color is clr, in the given style at now
+// The second snake's rainbow runs half a turn ahead of the first's, so
+// the two stay apart.
+func segmentColor(style SnakeStyle, clr color.Color, player, j, n int, now time.Time) color.Color {
+	// Where the segment is along the body: 0 at the head, 1 at the tail
+	t := 0.0
+	if n > 1 {
+		t = float64(j) / float64(n-1)
+	}
+	switch style {
+	case SnakeGradient:
+		return shade(clr, float32(1-t*(1-gradientTail)))
+	case SnakeRainbow:
+		spin := float64(now.UnixMilli()%rainbowCycle.Milliseconds()) / float64(rainbowCycle.Milliseconds())
+		hue := math.Mod(360*spin+rainbowSpan*t+180*float64(player), 360)
+		return hsv(hue, 0.8, 1)
+	}
+	return clr
+}
+
+// shade returns clr with its brightness scaled by f (0-1), keeping its
+// opacity
+func shade(clr color.Color, f float32) color.RGBA {
+	c := faded(clr, f)
+	_, _, _, a := clr.RGBA()
+	c.A = uint8(a >> 8)
+	return c
+}
+
+// hsv converts a hue in degrees, saturation and value (0-1) to a color
+func hsv(h, s, v float64) color.RGBA {
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g = c, x
+	case h < 120:
+		r, g = x, c
+	case h < 180:
+		g, b = c, x
+	case h < 240:
+		g, b = x, c
+	case h < 300:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := v - c
+	return color.RGBA{uint8((r + m) * 255), uint8((g + m) * 255), uint8((b + m) * 255), 255}
+}
+
+// MarshalText encodes the snake style by name
+func (s SnakeStyle) MarshalText() ([]byte, error) {
+	return marshalName(snakeStyleNames[:], int(s))
+}
+
+// UnmarshalText decodes the snake style from its name
+func (s *SnakeStyle) UnmarshalText(b []byte) error {
+	return unmarshalName(snakeStyleNames[:], b, (*int)(s))
+}
+
+// String returns the display name of the snake style
+func (s SnakeStyle) String() string {
+	return snakeStyleNames[s]
+}
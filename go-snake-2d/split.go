@@ -0,0 +1,101 @@
+package main
+
+import (
+	"image/color"
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Split screen is a race on two boards: each player gets a board, and a
+// world, of their own, shown side by side, and the first to
+// splitTargetScore points wins. Crashing loses the race outright. Both
+// boards are built the same and draw their food from the same random
+// sequence, so neither player is dealt the easier board.
+
+// splitTargetScore is how many points win a split-screen race
+const splitTargetScore = 200
+
+// splitAreas are the viewports of the boards, player one's on the left;
+// setResolution keeps them to the screen's width
+var splitAreas = [...]viewport{
+	{0, 0, baseScreenWidth / 2, screenHeight},
+	{baseScreenWidth / 2, 0, baseScreenWidth / 2, screenHeight},
+}
+
+// resetSplit sets up both boards for a new race on lvl, each w×h cells
+// with one player's snake at the level's start
+func (g *Game) resetSplit(lvl Level, w, h int, keys1, keys2 snake.Controller) {
+	players := [len(splitAreas)]*snake.Player{
+		newPlayer("Player 1", lvl.snakeStart(w, h), keys1),
+		newPlayer("Player 2", lvl.snakeStart(w, h), keys2),
+	}
+	g.setBoards(len(splitAreas))
+	for i, b := range g.boards {
+		g.Board = b
+		b.first = i
+		// Every board gets a source of its own with the run's seed, so
+		// they deal the same food
+		src := g.rngSource
+		if i > 0 {
+			src = runSource(g.seed)
+		}
+		g.resetBoard(lvl, w, h, splitAreas[i], []*snake.Player{players[i]}, rand.New(src))
+	}
+	g.Board = g.boards[0]
+}
+
+// splitWinner returns the index of the board whose player won the race
+// just ended: the one who reached the target, or else the one still
+// going when the other crashed. It returns -1 for a draw.
+func (g *Game) splitWinner() int {
+	winner := -1
+	for i, b := range g.boards {
+		p := b.world.Players[0]
+		if p.Score >= splitTargetScore {
+			return i
+		}
+		if !p.Dead {
+			if winner >= 0 {
+				return -1
+			}
+			winner = i
+		}
+	}
+	return winner
+}
+
+// drawSplitDivider draws the line between the boards
+func (g *Game) drawSplitDivider(screen *ebiten.Image) {
+	for _, area := range splitAreas[1:] {
+		fillRect(screen, area.x-boardEdge/2, area.y, boardEdge, area.h, g.theme.HUD, false)
+	}
+}
+
+// drawSplitResult shows who won the race, and where both players got to
+func (s *GameOverScene) drawSplitResult(screen *ebiten.Image) {
+	g := s.g
+
+	// WINNER
+	headline, clr := tr("Draw!"), color.Color(color.White)
+	if i := g.splitWinner(); i >= 0 {
+		headline = trf("%s wins!", tr(g.boards[i].world.Players[0].Name))
+		clr = g.theme.snakeColor(i)
+	}
+	drawCenteredText(screen, headline, 48, 40, clr)
+	drawCenteredText(screen, trf("First to %d points", splitTargetScore), 18, 100, menuTextColor)
+
+	// SCORES
+	y := 130.0
+	for i, b := range g.boards {
+		p := b.world.Players[0]
+		status := tr("survived")
+		if p.Dead {
+			status = tr("crashed")
+		}
+		line := trf("%s: %d points, length %d, %s", tr(p.Name), p.Score, p.Snake.Len(), status)
+		drawCenteredText(screen, line, 22, y, g.theme.snakeColor(i))
+		y += 36
+	}
+}
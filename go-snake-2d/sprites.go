@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// spriteSize is the size in pixels of one tile in the sprite atlas
+// Tiles are scaled to the board's cell size when drawn.
+const spriteSize = 16
+
+// Sprite identifies a tile in the sprite atlas
+// The snake tiles are drawn in shades of gray and tinted with the
+// player's color, so one set of tiles serves every player and theme.
+type Sprite int
+
+const (
+	SpriteHead   Sprite = iota // facing right
+	SpriteBody                 // straight, running left to right
+	SpriteCorner               // joining the right and bottom edges
+	SpriteTail                 // joined to the rest of the body on the right
+	SpriteFood
+	SpritePoison
+	SpriteDot   // the accessibility pattern dot, drawn by loadAtlas
+	spriteCount // keep last: number of tiles
+)
+
+// atlasTiles is how many tiles the atlas image has; the ones after it are
+// drawn in code
+const atlasTiles = SpriteDot
+
+// atlasFileName is the atlas image asset, holding every tile in one row,
+// in Sprite order
+const atlasFileName = "sprites/atlas.png"
+
+// Atlas is the set of tiles the board is drawn with
+// The tiles are side by side in one image, so a batch of them can be
+// drawn in one go (see SpriteBatch).
+type Atlas struct {
+	img   *ebiten.Image
+	tiles [spriteCount]*ebiten.Image
+}
+
+// loadAtlas cuts an atlas image into its tiles
+func loadAtlas(img *ebiten.Image) (*Atlas, error) {
+	if w, h := img.Bounds().Dx(), img.Bounds().Dy(); w < int(atlasTiles)*spriteSize || h < spriteSize {
+		return nil, fmt.Errorf("sprite atlas is %dx%d, want at least %dx%d", w, h, int(atlasTiles)*spriteSize, spriteSize)
+	}
+
+	// Copy the tiles into an image with room for the ones drawn here
+	full := ebiten.NewImage(int(spriteCount)*spriteSize, spriteSize)
+	full.DrawImage(img.SubImage(image.Rect(0, 0, int(atlasTiles)*spriteSize, spriteSize)).(*ebiten.Image), nil)
+	dotX := float32(SpriteDot*spriteSize) + spriteSize/2
+	vector.FillCircle(full, dotX, spriteSize/2, spriteSize/6, color.White, true)
+
+	a := &Atlas{img: full}
+	for s := range spriteCount {
+		x := int(s) * spriteSize
+		a.tiles[s] = full.SubImage(image.Rect(x, 0, x+spriteSize, spriteSize)).(*ebiten.Image)
+	}
+	return a, nil
+}
+
+// draw renders a tile filling the size×size square at (x, y), turned
+// clockwise by quarterTurns and tinted with clr
+func (a *Atlas) draw(screen *ebiten.Image, s Sprite, x, y, size float32, quarterTurns int, clr color.Color) {
+	op := &ebiten.DrawImageOptions{}
+	// Rotate about the tile's center, then scale and move it into place
+	op.GeoM.Translate(-spriteSize/2, -spriteSize/2)
+	op.GeoM.Rotate(float64(quarterTurns) * math.Pi / 2)
+	op.GeoM.Scale(float64(size)/spriteSize, float64(size)/spriteSize)
+	op.GeoM.Translate(float64(x+size/2), float64(y+size/2))
+	op.GeoM.Scale(displayScale, displayScale)
+	op.ColorScale.ScaleWithColor(clr)
+	screen.DrawImage(a.tiles[s], op)
+}
+
+// SpriteBatch collects tiles from an atlas and draws them all with one
+// DrawTriangles call, so a snake of hundreds of segments costs about as
+// much to draw as a short one
+// Its buffers are kept between frames, so a batch allocates nothing once
+// it has grown to the size of the longest snake.
+type SpriteBatch struct {
+	vertices []ebiten.Vertex
+	indices  []uint32
+}
+
+// add queues a tile to fill the size×size square at (x, y), turned
+// clockwise by quarterTurns and tinted with clr, like Atlas.draw
+func (b *SpriteBatch) add(s Sprite, x, y, size float32, quarterTurns int, clr color.Color) {
+	// The corners of the tile in the atlas, clockwise from the top left
+	sx, sy := float32(int(s)*spriteSize), float32(0)
+	src := [4][2]float32{{sx, sy}, {sx + spriteSize, sy}, {sx + spriteSize, sy + spriteSize}, {sx, sy + spriteSize}}
+	// ...and of the square on the screen. Turning the tile a quarter
+	// clockwise puts each source corner on the next screen corner.
+	d := float32(displayScale)
+	x0, y0, x1, y1 := x*d, y*d, (x+size)*d, (y+size)*d
+	dst := [4][2]float32{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+
+	r, g, bl, a := clr.RGBA()
+	base := uint32(len(b.vertices))
+	for i := range 4 {
+		to := dst[(i+quarterTurns%4+4)%4]
+		b.vertices = append(b.vertices, ebiten.Vertex{
+			DstX: to[0], DstY: to[1],
+			SrcX: src[i][0], SrcY: src[i][1],
+			ColorR: float32(r) / 0xffff, ColorG: float32(g) / 0xffff,
+			ColorB: float32(bl) / 0xffff, ColorA: float32(a) / 0xffff,
+		})
+	}
+	b.indices = append(b.indices, base, base+1, base+2, base, base+2, base+3)
+}
+
+// flush draws the queued tiles from the atlas onto screen, in the order
+// they were added, and empties the batch
+func (b *SpriteBatch) flush(screen *ebiten.Image, a *Atlas) {
+	if len(b.indices) == 0 {
+		return
+	}
+	// Colors come from color.Color, which is premultiplied
+	op := &ebiten.DrawTrianglesOptions{ColorScaleMode: ebiten.ColorScaleModePremultipliedAlpha}
+	screen.DrawTriangles32(b.vertices, b.indices, a.img, op)
+	b.vertices, b.indices = b.vertices[:0], b.indices[:0]
+}
+
+// patternColor is the dark overlay of the accessibility patterns
+var patternColor = color.RGBA{0, 0, 0, 170}
+
+// drawCross marks the size×size cell at (x, y) with an X, the pattern
+// that tells poison apart from food without color
+func drawCross(screen *ebiten.Image, x, y, size float32) {
+	inset, width := size/4, max(size/8, 1)
+	strokeLine(screen, x+inset, y+inset, x+size-inset, y+size-inset, width, patternColor, true)
+	strokeLine(screen, x+size-inset, y+inset, x+inset, y+size-inset, width, patternColor, true)
+}
+
+// segmentSprite picks the tile for segment i of a snake, and how many
+// clockwise quarter turns orient it, from the segment's neighbors: the
+// head faces where the snake is heading, the tail points away from the
+// body, and a body segment is straight or a corner depending on whether
+// its neighbors are in line
+func segmentSprite(body *snake.Body, i int, heading snake.Point) (Sprite, int) {
+	last := body.Len() - 1
+	switch {
+	case i == 0:
+		return SpriteHead, quarterTurns(heading)
+	case i == last:
+		toBody, ok := neighborDir(body.At(i), body.At(i-1))
+		if !ok {
+			toBody = heading
+		}
+		return SpriteTail, quarterTurns(toBody)
+	}
+
+	toHead, okHead := neighborDir(body.At(i), body.At(i-1))
+	toTail, okTail := neighborDir(body.At(i), body.At(i+1))
+	switch {
+	case !okHead && !okTail:
+		return SpriteBody, quarterTurns(heading)
+	case !okHead:
+		return SpriteBody, quarterTurns(toTail)
+	case !okTail || toHead.X == -toTail.X && toHead.Y == -toTail.Y:
+		return SpriteBody, quarterTurns(toHead)
+	}
+
+	// The corner tile joins Right and Down; find the turn that makes it
+	// join the two neighbors instead
+	for turns := range 4 {
+		a, b := rotate(snake.Right, turns), rotate(snake.Down, turns)
+		if a == toHead && b == toTail || a == toTail && b == toHead {
+			return SpriteCorner, turns
+		}
+	}
+	return SpriteBody, quarterTurns(toHead)
+}
+
+// neighborDir returns the direction from a to the neighboring cell b, or
+// false if b isn't next to a (e.g. after a teleport)
+func neighborDir(a, b snake.Point) (snake.Point, bool) {
+	d := snake.Point{X: b.X - a.X, Y: b.Y - a.Y}
+	return d, abs(d.X)+abs(d.Y) == 1
+}
+
+// quarterTurns returns how many clockwise quarter turns take Right to d
+func quarterTurns(d snake.Point) int {
+	for turns := range 4 {
+		if rotate(snake.Right, turns) == d {
+			return turns
+		}
+	}
+	return 0
+}
+
+// rotate turns a direction clockwise (on screen, where y points down) by
+// the given number of quarter turns
+func rotate(d snake.Point, turns int) snake.Point {
+	for range turns % 4 {
+		d = snake.Point{X: -d.Y, Y: d.X}
+	}
+	return d
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Each profile keeps lifetime stats, and the achievements they have
+// unlocked, in stats.json next to its high scores. Like the high scores,
+// only solo runs count, since the other modes' results depend on the
+// opponents, and runs changed by the console or mods don't count at all.
+// A run's numbers are gathered from the event bus as it goes and added to
+// the profile's when it ends, so a run abandoned halfway isn't counted.
+
+const statsFileName = "stats.json"
+
+// Stats are a profile's totals over all its counted runs
+type Stats struct {
+	Runs   int `json:"runs"`
+	Deaths int `json:"deaths"`
+
+	FoodEaten       int `json:"foodEaten"`
+	PoisonEaten     int `json:"poisonEaten"`
+	PowerUps        int `json:"powerUps"`
+	LevelsCompleted int `json:"levelsCompleted"`
+	BossesDefeated  int `json:"bossesDefeated"`
+
+	BestScore    int `json:"bestScore"`
+	LongestSnake int `json:"longestSnake"`
+
+	TimePlayed time.Duration `json:"timePlayed"`
+
+	// Achievements holds when each unlocked achievement was unlocked, by
+	// ID (see achievements)
+	Achievements map[string]time.Time `json:"achievements,omitempty"`
+
+	// path is the JSON file the stats are loaded from and saved to
+	path string
+}
+
+// Achievement is a goal a profile can reach once, shown on the stats
+// screen with the date it was reached
+type Achievement struct {
+	// ID names the achievement in the stats file, so it stays the same
+	// when the name is reworded
+	ID          string
+	Name        string
+	Description string
+
+	// reached reports whether the stats meet the goal
+	reached func(s *Stats) bool
+}
+
+// achievements are every achievement there is, in the order they are
+// listed
+var achievements = []Achievement{
+	{"first-bite", "First Bite", "Eat your first food", func(s *Stats) bool { return s.FoodEaten >= 1 }},
+	{"glutton", "Glutton", "Eat 500 food", func(s *Stats) bool { return s.FoodEaten >= 500 }},
+	{"long-snake", "Long Snake", "Grow to 30 segments", func(s *Stats) bool { return s.LongestSnake >= 30 }},
+	{"high-scorer", "High Scorer", "Score 200 points in a run", func(s *Stats) bool { return s.BestScore >= 200 }},
+	{"powered-up", "Powered Up", "Collect 25 power-ups", func(s *Stats) bool { return s.PowerUps >= 25 }},
+	{"iron-stomach", "Iron Stomach", "Eat 10 poison", func(s *Stats) bool { return s.PoisonEaten >= 10 }},
+	{"clean-sweep", "Clean Sweep", "Complete a level", func(s *Stats) bool { return s.LevelsCompleted >= 1 }},
+	{"boss-slayer", "Boss Slayer", "Defeat a boss", func(s *Stats) bool { return s.BossesDefeated >= 1 }},
+	{"regular", "Regular", "Play 50 runs", func(s *Stats) bool { return s.Runs >= 50 }},
+	{"dedicated", "Dedicated", "Play for an hour", func(s *Stats) bool { return s.TimePlayed >= time.Hour }},
+}
+
+// statsPath returns the stats file location of a profile
+func statsPath(profile string) (string, error) {
+	dir, err := profileDir(profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, statsFileName), nil
+}
+
+// loadStats reads the stats from path
+// A missing file is not an error: the profile hasn't finished a run yet
+func loadStats(path string) (*Stats, error) {
+	s := &Stats{path: path}
+	data, err := readDataFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("reading stats: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		// Keep going from zero rather than refusing to start
+		*s = Stats{path: path}
+		return s, fmt.Errorf("parsing stats %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Save writes the stats to disk
+func (s *Stats) Save() error {
+	if s.path == "" {
+		return errors.New("stats have no file path")
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding stats: %w", err)
+	}
+	if err := writeDataFile(s.path, data); err != nil {
+		return fmt.Errorf("writing stats: %w", err)
+	}
+	return nil
+}
+
+// add counts a finished run's stats into the totals
+func (s *Stats) add(run Stats) {
+	s.Runs += run.Runs
+	s.Deaths += run.Deaths
+	s.FoodEaten += run.FoodEaten
+	s.PoisonEaten += run.PoisonEaten
+	s.PowerUps += run.PowerUps
+	s.LevelsCompleted += run.LevelsCompleted
+	s.BossesDefeated += run.BossesDefeated
+	s.BestScore = max(s.BestScore, run.BestScore)
+	s.LongestSnake = max(s.LongestSnake, run.LongestSnake)
+	s.TimePlayed += run.TimePlayed
+}
+
+// unlock marks the achievements the stats now meet as unlocked at now,
+// and returns the ones that weren't before
+func (s *Stats) unlock(now time.Time) []Achievement {
+	var unlocked []Achievement
+	for _, a := range achievements {
+		if _, ok := s.Achievements[a.ID]; ok || !a.reached(s) {
+			continue
+		}
+		if s.Achievements == nil {
+			s.Achievements = make(map[string]time.Time)
+		}
+		s.Achievements[a.ID] = now
+		unlocked = append(unlocked, a)
+	}
+	return unlocked
+}
+
+// countsStats reports whether the current run counts towards the
+// profile's stats
+func (g *Game) countsStats() bool {
+	return g.stats != nil && g.mode == ModeSolo && g.tas == nil && !g.cheated
+}
+
+// subscribeStats counts what happens to the player's snake into the
+// run's stats
+func (g *Game) subscribeStats() {
+	g.events.subscribe(func(e snake.Event) {
+		if !g.countsStats() || e.Player != 0 {
+			return
+		}
+		switch e.Kind {
+		case snake.EventAte:
+			g.runStats.FoodEaten++
+		case snake.EventPoisoned:
+			g.runStats.PoisonEaten++
+		case snake.EventPowerUp:
+			g.runStats.PowerUps++
+		case snake.EventLevelCompleted:
+			g.runStats.LevelsCompleted++
+		case snake.EventBossDefeated:
+			g.runStats.BossesDefeated++
+		}
+	}, snake.EventAte, snake.EventPoisoned, snake.EventPowerUp, snake.EventLevelCompleted, snake.EventBossDefeated)
+}
+
+// recordStats adds the run that just ended to the profile's stats,
+// unlocking any achievements it earned, and saves them
+func (g *Game) recordStats(now time.Time) {
+	g.unlocked = nil
+	if !g.countsStats() {
+		return
+	}
+	p := g.world.Players[0]
+	run := g.runStats
+	run.Runs = 1
+	if !g.finished {
+		run.Deaths = 1
+	}
+	run.BestScore = p.Score
+	run.LongestSnake = p.Snake.Len()
+	run.TimePlayed = g.runTime
+	g.stats.add(run)
+	g.unlocked = g.stats.unlock(now)
+	if err := g.stats.Save(); err != nil {
+		// Not fatal: the stats still show for this session
+		log.Printf("saving stats: %v", err)
+	}
+}
+
+// unlockedLine lists the achievements the last run unlocked, or returns
+// "" if it unlocked none
+func (g *Game) unlockedLine() string {
+	if len(g.unlocked) == 0 {
+		return ""
+	}
+	names := make([]string, len(g.unlocked))
+	for i, a := range g.unlocked {
+		names[i] = tr(a.Name)
+	}
+	return trf("Achievement unlocked: %s", strings.Join(names, ", "))
+}
+
+// StatsScene shows the profile's stats on one page and its achievements
+// on another; left/right switch between them
+type StatsScene struct {
+	g    *Game
+	page int
+}
+
+// newStatsScene opens the stats screen on the stats page
+func newStatsScene(g *Game) *StatsScene {
+	return &StatsScene{g: g}
+}
+
+// Update switches pages, and goes back to the title screen on Escape or
+// Enter
+func (s *StatsScene) Update() error {
+	g := s.g
+	if g.isJustPressed(ActionBack) || g.isJustPressed(ActionSelect) {
+		g.scenes.Switch(newTitleScene(g))
+		return nil
+	}
+	if g.isRepeated(ActionMoveRight) || g.isRepeated(ActionP2MoveRight) ||
+		g.isRepeated(ActionMoveLeft) || g.isRepeated(ActionP2MoveLeft) {
+		s.page = 1 - s.page
+		g.audio.play(SoundMenuMove)
+	}
+	return nil
+}
+
+// Draw renders the page being shown
+func (s *StatsScene) Draw(screen *ebiten.Image) {
+	drawCenteredText(screen, trf("Profile: %s", s.g.profile), 48, 40, color.White)
+	st := s.g.stats
+	if st == nil {
+		st = &Stats{}
+	}
+	y := 170.0
+	if s.page == 0 {
+		drawCenteredText(screen, fmt.Sprintf("< %s >", tr("Stats")), 26, 110, menuSelectedColor)
+		for _, line := range []string{
+			trf("Runs: %d   Deaths: %d", st.Runs, st.Deaths),
+			trf("Best score: %d   Longest snake: %d", st.BestScore, st.LongestSnake),
+			trf("Food eaten: %d   Poison eaten: %d", st.FoodEaten, st.PoisonEaten),
+			trf("Power-ups collected: %d", st.PowerUps),
+			trf("Levels completed: %d   Bosses defeated: %d", st.LevelsCompleted, st.BossesDefeated),
+			trf("Time played: %s", st.TimePlayed.Round(time.Second)),
+		} {
+			drawCenteredText(screen, line, 18, y, menuTextColor)
+			y += 32
+		}
+	} else {
+		drawCenteredText(screen, fmt.Sprintf("< %s >", tr("Achievements")), 26, 110, menuSelectedColor)
+		y = 150
+		for _, a := range achievements {
+			line := fmt.Sprintf("%s - %s", tr(a.Name), tr(a.Description))
+			clr := color.Color(color.RGBA{110, 110, 110, 255})
+			if at, ok := st.Achievements[a.ID]; ok {
+				line += "   " + at.Format(dateFormat)
+				clr = menuSelectedColor
+			}
+			drawCenteredText(screen, line, 16, y, clr)
+			y += 24
+		}
+	}
+
+	drawCenteredText(screen, tr("Left/Right to change page, ESC to go back"), 16, screenHeight-40, menuTextColor)
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// The board is drawn by a registry of systems, one for each kind of
+// object on it: walls, portals, snakes, food and so on. The objects
+// themselves live in the world (see package snake), which the rules work
+// on, with the ones that act on their own (enemies, the boss and its
+// shots) in its entity registry, updated by the world's own systems; a
+// system here draws its kind of object from there, and updates whatever
+// state only drawing needs, like the particles or the batch the snakes
+// are drawn with. A new kind of object is a snake.Entity with a
+// snake.System for its rules and a System here to draw it, rather than
+// another field on World or Game and another section of Step or drawBoard.
+
+// System draws one kind of object on the board
+type System interface {
+	// update advances the system's animations, once per update while
+	// the board is live (not while paused)
+	update(g *Game)
+
+	// draw renders the system's objects at frame f
+	draw(g *Game, screen *ebiten.Image, f boardFrame)
+}
+
+// boardFrame is the moment the board is drawn at
+type boardFrame struct {
+	// progress is how far the snakes are through their current move (see
+	// tickProgress), and now the time the board's timers are shown at
+	progress float64
+	now      time.Time
+}
+
+// newSystems returns the board's systems in drawing order, bottom first
+func newSystems() []System {
+	return []System{
+		&wallSystem{},
+		&portalSystem{},
+		&checkpointSystem{},
+		&trailSystem{},
+		&snakeSystem{},
+		&foodSystem{},
+		&powerUpSystem{},
+		&enemySystem{},
+		&bossSystem{},
+		&hintSystem{},
+		&particleSystem{},
+		&bulletTimeSystem{},
+		&minimapSystem{},
+	}
+}
+
+// updateSystems advances every system's animations, on every board
+func (g *Game) updateSystems() {
+	g.eachBoard(func() {
+		for i, s := range g.systems {
+			start := g.prof.start()
+			s.update(g)
+			g.prof.system(i, start)
+		}
+	})
+}
+
+// still is embedded by systems with nothing to animate
+type still struct{}
+
+func (still) update(*Game) {}
+
+// wallSystem draws the level's walls and the closed rings of a shrinking
+// arena
+type wallSystem struct{ still }
+
+func (wallSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	// Level walls are blocks (gray by default)
+	v := g.view
+	v.drawEdge(screen, g.theme.Obstacle)
+	for p := range g.world.Obstacles {
+		x, y := v.cellPos(p)
+		fillRect(screen, x, y, v.cell, v.cell, g.theme.Obstacle, false)
+	}
+
+	// The rings a shrinking arena has closed are wall too, and the next
+	// one flashes just before it closes
+	if g.world.Arena != nil {
+		g.drawArena(screen)
+	}
+}
+
+// portalSystem draws the portals as rings, each pair in its own color so
+// it is clear which ends are linked. They go under the snakes, which
+// pass through them.
+type portalSystem struct{ still }
+
+func (portalSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	v := g.view
+	for i, pt := range g.world.Portals {
+		clr := portalColors[i%len(portalColors)]
+		for _, p := range [...]snake.Point{pt.A, pt.B} {
+			cx, cy := v.center(p)
+			fillCircle(screen, cx, cy, v.cell/2, clr, true)
+			fillCircle(screen, cx, cy, v.cell/3, g.theme.Background, true)
+		}
+	}
+}
+
+// checkpointSystem draws the checkpoints as frames around their cells,
+// filled in once reached. Like portals, they go under the snakes.
+type checkpointSystem struct{ still }
+
+func (checkpointSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	v, cell := g.view, g.view.cell
+	for _, c := range g.world.Checkpoints {
+		x, y := v.cellPos(c.Pos)
+		if c.Reached {
+			fillRect(screen, x, y, cell, cell, faded(checkpointColor, 0.35), false)
+		}
+		drawFrame(screen, x, y, cell, cell, max(cell/8, 1), checkpointColor)
+	}
+}
+
+// trailSystem fades and draws the afterimages of the cells the snakes
+// just left, under the snakes (see Trail)
+type trailSystem struct{}
+
+func (trailSystem) update(g *Game) {
+	g.trail.update()
+}
+
+func (trailSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	if g.settings.Trails {
+		g.trail.draw(screen, g.view.x, g.view.y)
+	}
+}
+
+// snakeSystem draws the snakes
+// Each segment is a tile from the sprite atlas (head, body, corner or
+// tail, turned to match its neighbors) tinted in its player's color
+// (white for player one by default), shaded along the body in the
+// gradient and rainbow styles (see SnakeStyle). Segments slide from the
+// cell they were on before the last tick to the one they are on now, so
+// the snake moves smoothly at any frame rate instead of jumping a cell
+// per tick. Ghost snakes are see-through, and a shielded snake has a ring
+// around its head. Each snake's tiles are batched into one draw call, so
+// long snakes stay cheap to draw.
+type snakeSystem struct {
+	still
+	batch SpriteBatch
+}
+
+func (s *snakeSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	v, cell, w := g.view, g.view.cell, g.world
+	ghost := w.Effects.Active(snake.PowerUpGhost, f.now)
+	for i, pl := range w.Players {
+		var prev []snake.Point
+		if i < len(g.prevSnakes) {
+			prev = g.prevSnakes[i]
+		}
+		clr := g.playerColor(i)
+		var headX, headY float32
+		flashing := pl.Dead && g.flash
+		if flashing {
+			clr = color.White
+		}
+		for j, p := range pl.Snake.All() {
+			if i < len(g.vanished) && j < g.vanished[i] {
+				continue
+			}
+			x, y := float32(p.X), float32(p.Y)
+			// Segments added by growing have no previous cell and stay
+			// put, as do any that jumped more than one cell
+			if j < len(prev) {
+				if _, ok := neighborDir(prev[j], p); ok {
+					x = lerp(float32(prev[j].X), x, f.progress)
+					y = lerp(float32(prev[j].Y), y, f.progress)
+				}
+			}
+			sprite, turns := segmentSprite(&pl.Snake, j, pl.Direction)
+			// Convert grid coords to pixels
+			x, y = v.pos(x, y)
+			segClr := clr
+			if !flashing {
+				segClr = segmentColor(g.settings.SnakeStyle, clr, i, j, pl.Snake.Len(), f.now)
+			}
+			if ghost && !pl.Dead {
+				segClr = faded(segClr, ghostOpacity)
+			}
+			s.batch.add(sprite, x, y, cell, turns, segClr)
+			if g.settings.Patterns && i > 0 {
+				s.batch.add(SpriteDot, x, y, cell, 0, patternColor)
+			}
+			if j == 0 {
+				headX, headY = x, y
+			}
+		}
+		s.batch.flush(screen, g.sprites)
+		if pl.Shield && !pl.Dead {
+			strokeArc(screen, headX+cell/2, headY+cell/2, cell*0.75, 0, 2*math.Pi, max(cell/8, 1), powerUpColors[snake.PowerUpShield])
+		}
+	}
+}
+
+// foodSystem draws the food
+// Food sprites are tinted by kind (red normal, green poison, orange
+// fleeing and gold golden food with the default theme). Food that
+// expires gets a ring around it that shrinks as its time runs out.
+type foodSystem struct{ still }
+
+func (foodSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	v, cell := g.view, g.view.cell
+	for _, food := range g.world.Foods {
+		sprite := SpriteFood
+		if food.Kind == snake.FoodPoison {
+			sprite = SpritePoison
+		}
+		x, y := v.cellPos(food.Pos)
+		g.sprites.draw(screen, sprite, x, y, cell, 0, g.theme.foodColor(food.Kind))
+		if g.settings.Patterns && food.Kind == snake.FoodPoison {
+			drawCross(screen, x, y, cell)
+		}
+		if left := food.Remaining(f.now); left > 0 {
+			frac := float32(left) / float32(food.Lifetime)
+			cx, cy := v.center(food.Pos)
+			start := float32(-math.Pi / 2)
+			strokeArc(screen, cx, cy, cell*0.7, start, start+2*math.Pi*min(frac, 1), max(cell/8, 1), g.theme.foodColor(food.Kind))
+		}
+	}
+}
+
+// powerUpSystem draws the power-up as a plain circle, so it stands out
+// from the food
+type powerUpSystem struct{ still }
+
+func (powerUpSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	pu := g.world.PowerUp
+	if pu == nil {
+		return
+	}
+	cx, cy := g.view.center(pu.Pos)
+	fillCircle(screen, cx, cy, g.view.cell/2, powerUpColors[pu.Kind], true)
+}
+
+// enemySystem draws the enemies as squares with a pair of eyes, so they
+// read as creatures rather than walls or items
+type enemySystem struct{ still }
+
+func (enemySystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	v, cell := g.view, g.view.cell
+	for e := range g.world.Enemies() {
+		x, y := v.cellPos(e.Pos)
+		inset := cell / 8
+		fillRect(screen, x+inset, y+inset, cell-2*inset, cell-2*inset, g.theme.Enemy, false)
+		fillCircle(screen, x+cell/3, y+cell*2/5, cell/10, g.theme.Background, true)
+		fillCircle(screen, x+cell*2/3, y+cell*2/5, cell/10, g.theme.Background, true)
+	}
+}
+
+// hintSystem draws the route hints, over the items so the route to them
+// shows (see hint.go)
+type hintSystem struct{ still }
+
+func (hintSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	for i, path := range g.hints {
+		if path.Len() > 0 && !g.world.Players[i].Dead {
+			g.drawHint(screen, i, path)
+		}
+	}
+}
+
+// particleSystem moves and draws the particle bursts, on top of the
+// other objects so they aren't hidden by the new food (see Particles)
+type particleSystem struct{}
+
+func (particleSystem) update(g *Game) {
+	g.particles.update()
+}
+
+func (particleSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	g.particles.draw(screen, g.view.x, g.view.y)
+}
+
+// bulletTimeTint is how strongly the board is tinted while bullet time is
+// at full strength
+const bulletTimeTint = 0.15
+
+// bulletTimeSystem tints the board in bullet time's color while it runs,
+// fading out as the snakes ease back to full speed, so the slowdown reads
+// as an effect rather than lag
+type bulletTimeSystem struct{ still }
+
+func (bulletTimeSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	strength := g.world.Effects.Fading(snake.PowerUpBulletTime, f.now)
+	if strength <= 0 {
+		return
+	}
+	clr := faded(powerUpColors[snake.PowerUpBulletTime], float32(strength)*bulletTimeTint)
+	fillRect(screen, 0, 0, float32(screenWidth), screenHeight, clr, false)
+}
+
+// minimapSystem draws the minimap over the board, only for boards that
+// scroll, where most of the board is out of view
+type minimapSystem struct{ still }
+
+func (minimapSystem) draw(g *Game, screen *ebiten.Image, f boardFrame) {
+	g.drawMinimap(screen)
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// A TAS (tool-assisted) run is a solo run played one tick at a time: it
+// stands still until the frame advance key moves it on by a tick, or runs
+// at normal speed until stopped again. The input of every tick is
+// recorded, a savestate can be saved and loaded to try a stretch again,
+// and the inputs can be exported as a replay (see replay.go).
+//
+// The run keeps time by its ticks alone: each one lasts exactly as long as
+// the snake's speed says, however long it took to press the key, and the
+// timers that run every frame in play run once per tick. So the same
+// inputs always play out the same, which a replay depends on.
+
+// tasStatusColor is the color of the TAS status lines
+var tasStatusColor = color.RGBA{255, 120, 200, 255}
+
+// TASScene plays a TAS run
+type TASScene struct {
+	g *Game
+
+	// keys reads the steering keys; ctl steers the snake with the input
+	// of the tick being played
+	keys *KeyboardController
+	ctl  tasController
+
+	// replay is how the run is set up (its Inputs are left empty), and
+	// inputs the input of every tick so far, one letter each (see
+	// replayInputs)
+	replay Replay
+	inputs []byte
+
+	// tick is how many ticks have been played; while it is behind
+	// len(inputs), as after a restart or when opening a replay, the
+	// recorded inputs are played back instead of the keys
+	tick int
+
+	// running is set while the run plays at normal speed, ticking when
+	// the wall clock reaches nextTick, and over once the run has ended
+	running  bool
+	nextTick time.Time
+	over     bool
+
+	// state is the savestate, serialized, or nil if none was saved
+	state []byte
+}
+
+// tasState is a savestate: the run as it stood after tick ticks
+type tasState struct {
+	Tick int        `json:"tick"`
+	Game *savedGame `json:"game"`
+}
+
+// tasController steers the snake of a TAS run as the scene says
+type tasController struct {
+	dir  snake.Point
+	dash bool
+}
+
+// NextDirection returns the direction of the tick being played
+func (c *tasController) NextDirection(state snake.GameState) snake.Point {
+	return c.dir
+}
+
+// WantsDash reports whether the tick being played dashes
+func (c *tasController) WantsDash() bool {
+	return c.dash
+}
+
+// startTAS starts a TAS run, of r when given, playing its inputs back,
+// or else a new one set up from the settings, like a solo run
+func (g *Game) startTAS(r *Replay) error {
+	g.mode = ModeSolo
+	g.rivals = g.settings.Rivals
+	g.tas = nil
+	s := &TASScene{g: g, keys: newKeyboardController(g, playerSteering[0])}
+
+	if r == nil {
+		// A new run is fixed as it starts, down to its seed, so restarts
+		// and replays play the same run
+		g.variant, g.daily, g.runDifficulty = g.settings.Variant, "", g.settings.Difficulty
+		if g.variant == VariantDaily {
+			g.startDaily()
+		}
+		g.applySettings()
+		seed, ok := g.fixedSeed()
+		if !ok {
+			seed = rand.Uint64()
+		}
+		lvl := g.levels[g.level]
+		w, h := g.boardCells(lvl)
+		r = &Replay{
+			Version:    replayVersion,
+			Variant:    g.variant,
+			Difficulty: g.runDifficulty,
+			Curve:      g.difficulty,
+			Daily:      g.daily,
+			Level:      lvl.Name,
+			MazeSeed:   g.mazeSeed,
+			Width:      w,
+			Height:     h,
+			Enemies:    g.enemyCount(),
+			Seed:       seed,
+		}
+	} else {
+		level := -1
+		for i, l := range g.levels {
+			if l.Name == r.Level {
+				level = i
+				break
+			}
+		}
+		if level < 0 {
+			return fmt.Errorf("replay level %q doesn't exist", r.Level)
+		}
+		g.variant, g.daily, g.runDifficulty = r.Variant, r.Daily, r.Difficulty
+		g.level, g.mazeSeed = level, r.MazeSeed
+		s.inputs = []byte(r.Inputs)
+	}
+
+	s.replay = *r
+	s.replay.Inputs = ""
+	g.tas = s
+	g.applySettings()
+	s.restart()
+	g.scenes.Switch(s)
+	return nil
+}
+
+// restart starts the run over from its first tick, with the inputs
+// recorded so far to be played back
+func (s *TASScene) restart() {
+	s.g.resetGame()
+	s.tick = 0
+	s.ready()
+}
+
+// ready hands the snake over to the scene and stops, once the run has
+// been set up or restored
+func (s *TASScene) ready() {
+	s.g.world.Players[0].Controller = &s.ctl
+	s.running, s.over = false, false
+}
+
+// Update steps the run as the keys say
+func (s *TASScene) Update() error {
+	g := s.g
+	if g.isJustPressed(ActionPause) {
+		g.scenes.Switch(newPausedScene(g, s))
+		return nil
+	}
+
+	// The steering keys are read every frame, as in play, for the next
+	// tick recorded
+	s.keys.poll()
+	g.updateSystems()
+
+	now := g.clock.Now()
+	switch {
+	case g.isJustPressed(ActionSaveState):
+		s.saveState()
+	case g.isJustPressed(ActionLoadState):
+		s.loadState()
+	case g.isJustPressed(ActionExportReplay):
+		s.exportReplay()
+	case g.isJustPressed(ActionTASRun):
+		s.running = !s.running && !s.over
+		s.nextTick = now
+	case g.isRepeated(ActionFrameAdvance):
+		// Stop first; holding the key then advances a tick at a time
+		if s.running {
+			s.running = false
+		} else {
+			s.advance()
+		}
+	}
+
+	// Running, the ticks follow the wall clock at the run's own speed
+	if s.running && !now.Before(s.nextTick) {
+		s.advance()
+		s.nextTick = now.Add(g.tickInterval)
+	}
+	return nil
+}
+
+// advance plays one tick: the next recorded input, if there is one, or
+// else the keys pressed since the last tick, which are recorded
+func (s *TASScene) advance() {
+	g := s.g
+	if s.over {
+		return
+	}
+
+	// INPUT
+	// Keys pressed while the inputs play back are dropped
+	state := g.world.State(0)
+	dir, dash := s.keys.NextDirection(state), s.keys.WantsDash()
+	if s.tick < len(s.inputs) {
+		dir, dash = decodeInput(s.inputs[s.tick])
+	} else {
+		s.inputs = append(s.inputs, encodeInput(dir, dash))
+	}
+	s.ctl.dir, s.ctl.dash = dir, dash
+	s.tick++
+
+	// TICK
+	// The tick ends, and the timers run, a tick interval after the last
+	// one
+	now := g.lastUpdate
+	g.updateTickInterval(now)
+	now = now.Add(g.tickInterval)
+	if g.handleEvents(now, g.world.Update(now)) {
+		return
+	}
+	g.lastUpdate = now
+	g.step(now)
+}
+
+// end stops the run where it ended (see endGame), for a savestate to be
+// loaded or the replay exported
+func (s *TASScene) end() {
+	s.over, s.running = true, false
+	if !s.g.finished {
+		s.g.audio.play(SoundDie)
+	}
+}
+
+// saveState saves the run as it stands into the savestate
+// It is serialized like a saved game, so nothing played afterwards can
+// change it.
+func (s *TASScene) saveState() {
+	g := s.g
+	if s.over {
+		g.notify(tr("The run is over"))
+		return
+	}
+	sg, err := g.snapshot(g.lastUpdate)
+	if err == nil {
+		s.state, err = json.Marshal(tasState{Tick: s.tick, Game: sg})
+	}
+	if err != nil {
+		log.Printf("saving state: %v", err)
+		g.notify(tr("Could not save the state"))
+		return
+	}
+	g.notify(trf("State saved at tick %d", s.tick))
+}
+
+// loadState puts the run back to the savestate
+// The inputs recorded after it are dropped, so the ticks from there on
+// are recorded afresh.
+func (s *TASScene) loadState() {
+	g := s.g
+	if s.state == nil {
+		g.notify(tr("No state saved"))
+		return
+	}
+	var st tasState
+	err := json.Unmarshal(s.state, &st)
+	if err == nil {
+		err = g.restoreGame(st.Game)
+	}
+	if err != nil {
+		log.Printf("loading state: %v", err)
+		g.notify(tr("Could not load the state"))
+		return
+	}
+	s.ready()
+	s.tick = st.Tick
+	s.inputs = s.inputs[:st.Tick]
+	g.notify(trf("State loaded at tick %d", s.tick))
+}
+
+// exportReplay saves the ticks played so far as a replay file
+func (s *TASScene) exportReplay() {
+	g := s.g
+	r := s.replay
+	r.Inputs = string(s.inputs[:s.tick])
+	p := g.world.Players[0]
+	r.Score, r.Length = p.Score, p.Snake.Len()
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		log.Printf("encoding replay: %v", err)
+		g.notify(tr("Could not export the replay"))
+		return
+	}
+	path, err := exportFile("snake-"+time.Now().Format("20060102-150405")+".replay.json", data)
+	if err != nil {
+		log.Printf("exporting replay: %v", err)
+		g.notify(tr("Could not export the replay"))
+		return
+	}
+	log.Printf("exported replay to %s", path)
+	g.notify(tr("Replay exported"))
+}
+
+// Draw renders the board as the last tick left it, with the TAS status
+// along the bottom
+func (s *TASScene) Draw(screen *ebiten.Image) {
+	g := s.g
+	g.drawBoard(screen, g.lastUpdate, false)
+	g.drawHUD(screen, g.lastUpdate)
+
+	// STATUS
+	status := trf("Tick %d, recording", s.tick)
+	if s.tick < len(s.inputs) {
+		status = trf("Tick %d of %d, playing back", s.tick, len(s.inputs))
+	}
+	switch {
+	case s.over:
+		status += " - " + tr("run over")
+	case s.running:
+		status += " - " + tr("running")
+	}
+	fillRect(screen, 0, screenHeight-56, float32(screenWidth), 56, debugBackgroundColor, false)
+	drawCenteredText(screen, status, 18, screenHeight-52, tasStatusColor)
+	drawCenteredText(screen, tr("F: advance  R: run/stop  F6: save state  F7: load state  F9: export replay"), 14, screenHeight-26, menuTextColor)
+
+	g.drawNotice(screen)
+}
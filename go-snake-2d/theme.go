@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Theme is the set of colors used to draw the board and menus
+// It is part of the settings file, so players can recolor the game.
+type Theme struct {
+	Background HexColor `json:"background"`
+	Snake      HexColor `json:"snake"`
+	Snake2     HexColor `json:"snake2"`
+	Food       HexColor `json:"food"`
+	Poison     HexColor `json:"poison"`
+	Fleeing    HexColor `json:"fleeing"`
+	Golden     HexColor `json:"golden"`
+	Enemy      HexColor `json:"enemy"`
+	Obstacle   HexColor `json:"obstacle"`
+	HUD        HexColor `json:"hud"`
+
+	// Text and Highlight are the menus' text and selected entry colors
+	Text      HexColor `json:"text"`
+	Highlight HexColor `json:"highlight"`
+}
+
+// defaultTheme matches the game's original look: white snake and red
+// food on black
+func defaultTheme() Theme {
+	return Theme{
+		Background: HexColor{0, 0, 0, 255},
+		Snake:      HexColor{255, 255, 255, 255},
+		Snake2:     HexColor{0, 190, 255, 255},
+		Food:       HexColor{255, 0, 0, 255},
+		Poison:     HexColor{120, 200, 0, 255},
+		Fleeing:    HexColor{255, 140, 0, 255},
+		Golden:     HexColor{255, 215, 0, 255},
+		Enemy:      HexColor{200, 0, 200, 255},
+		Obstacle:   HexColor{110, 110, 110, 255},
+		HUD:        HexColor{200, 200, 200, 255},
+		Text:       HexColor{200, 200, 200, 255},
+		Highlight:  HexColor{255, 215, 0, 255},
+	}
+}
+
+// Palette adjusts the theme for color vision deficiencies
+// The standard palette uses the theme as it is. The others replace the
+// colors that have to be told apart (the second snake and the foods)
+// with ones from the Okabe-Ito palette that stay distinct for players
+// with that kind of color blindness. The first snake, background and
+// walls keep the theme's colors.
+type Palette int
+
+const (
+	PaletteStandard Palette = iota
+	PaletteDeuteranopia
+	PaletteProtanopia
+	paletteCount
+)
+
+var paletteNames = [paletteCount]string{"Standard", "Deuteranopia", "Protanopia"}
+
+// paletteColors are the replacement colors of each non-standard palette
+var paletteColors = [paletteCount]struct {
+	Snake2, Food, Poison, Fleeing, Golden HexColor
+}{
+	// Red-green deficiency: orange food against purple poison and a
+	// sky blue second snake; fleeing food is bluish green and golden
+	// apples yellow
+	PaletteDeuteranopia: {Snake2: HexColor{86, 180, 233, 255}, Food: HexColor{230, 159, 0, 255}, Poison: HexColor{204, 121, 167, 255}, Fleeing: HexColor{0, 158, 115, 255}, Golden: HexColor{240, 228, 66, 255}},
+	// Reds look dark: yellow food against blue poison, and an orange
+	// second snake; fleeing food is bluish green and golden apples white
+	PaletteProtanopia: {Snake2: HexColor{230, 159, 0, 255}, Food: HexColor{240, 228, 66, 255}, Poison: HexColor{0, 114, 178, 255}, Fleeing: HexColor{0, 158, 115, 255}, Golden: HexColor{255, 255, 255, 255}},
+}
+
+// withPalette returns the theme with the palette's colors applied
+func (t Theme) withPalette(p Palette) Theme {
+	if p == PaletteStandard || p < 0 || p >= paletteCount {
+		return t
+	}
+	c := paletteColors[p]
+	t.Snake2, t.Food, t.Poison, t.Fleeing, t.Golden = c.Snake2, c.Food, c.Poison, c.Fleeing, c.Golden
+	return t
+}
+
+// lerp returns the theme t of the way (0-1) from this one to o, color by
+// color
+func (th Theme) lerp(o Theme, t float64) Theme {
+	return Theme{
+		Background: th.Background.lerp(o.Background, t),
+		Snake:      th.Snake.lerp(o.Snake, t),
+		Snake2:     th.Snake2.lerp(o.Snake2, t),
+		Food:       th.Food.lerp(o.Food, t),
+		Poison:     th.Poison.lerp(o.Poison, t),
+		Fleeing:    th.Fleeing.lerp(o.Fleeing, t),
+		Golden:     th.Golden.lerp(o.Golden, t),
+		Enemy:      th.Enemy.lerp(o.Enemy, t),
+		Obstacle:   th.Obstacle.lerp(o.Obstacle, t),
+		HUD:        th.HUD.lerp(o.HUD, t),
+		Text:       th.Text.lerp(o.Text, t),
+		Highlight:  th.Highlight.lerp(o.Highlight, t),
+	}
+}
+
+// MarshalText encodes the palette by name
+func (p Palette) MarshalText() ([]byte, error) {
+	return marshalName(paletteNames[:], int(p))
+}
+
+// UnmarshalText decodes the palette from its name
+func (p *Palette) UnmarshalText(b []byte) error {
+	return unmarshalName(paletteNames[:], b, (*int)(p))
+}
+
+// String returns the display name of the palette
+func (p Palette) String() string {
+	return paletteNames[p]
+}
+
+// rivalColors are the colors of the snakes after the first two, which
+// only battle royale has
+var rivalColors = [...]color.RGBA{
+	{255, 120, 60, 255},
+	{80, 200, 255, 255},
+	{200, 100, 255, 255},
+	{255, 230, 80, 255},
+	{255, 100, 170, 255},
+	{140, 255, 140, 255},
+}
+
+// snakeColor returns the color of player i's snake
+func (t Theme) snakeColor(i int) color.Color {
+	switch {
+	case i == 1:
+		return t.Snake2
+	case i > 1:
+		return rivalColors[(i-2)%len(rivalColors)]
+	}
+	return t.Snake
+}
+
+// faded returns clr at opacity a (0-1)
+// Colors are premultiplied, so every channel is scaled, not just alpha.
+func faded(clr color.Color, a float32) color.RGBA {
+	r, g, b, al := clr.RGBA()
+	return color.RGBA{
+		R: uint8(float32(r>>8) * a),
+		G: uint8(float32(g>>8) * a),
+		B: uint8(float32(b>>8) * a),
+		A: uint8(float32(al>>8) * a),
+	}
+}
+
+// powerUpColors are the colors of each kind of power-up, on the board and
+// in the HUD's effect timers
+var powerUpColors = [snake.PowerUpKindCount]color.RGBA{
+	snake.PowerUpSpeedBoost: {255, 220, 0, 255},
+	snake.PowerUpSlowMotion: {0, 200, 255, 255},
+	snake.PowerUpShrink:     {200, 0, 255, 255},
+	snake.PowerUpGhost:      {220, 220, 255, 255},
+	snake.PowerUpShield:     {0, 255, 160, 255},
+	snake.PowerUpMagnet:     {255, 80, 80, 255},
+	snake.PowerUpBulletTime: {255, 140, 220, 255},
+}
+
+// ghostOpacity is how opaque snakes are drawn while PowerUpGhost is
+// active, so they look like they can pass through themselves
+const ghostOpacity = 0.45
+
+// checkpointColor is the color of checkpoints
+var checkpointColor = color.RGBA{120, 230, 120, 255}
+
+// portalColors tell pairs of portals apart, in order
+var portalColors = [...]color.RGBA{
+	{0, 160, 255, 255},
+	{255, 120, 0, 255},
+	{180, 80, 255, 255},
+	{0, 200, 140, 255},
+}
+
+// foodColor returns the theme color for a kind of food
+func (t Theme) foodColor(kind snake.FoodKind) color.Color {
+	switch kind {
+	case snake.FoodPoison:
+		return t.Poison
+	case snake.FoodFleeing:
+		return t.Fleeing
+	case snake.FoodGolden:
+		return t.Golden
+	case snake.FoodRemains:
+		// Dimmer than fresh food, so the remains read as a dead snake's
+		return faded(t.Food, 0.6)
+	}
+	return t.Food
+}
+
+// HexColor is a color stored as "#RRGGBB" or "#RRGGBBAA" in JSON
+type HexColor color.RGBA
+
+// RGBA implements color.Color
+func (c HexColor) RGBA() (r, g, b, a uint32) {
+	return color.RGBA(c).RGBA()
+}
+
+// lerp returns the color t of the way (0-1) from c to o
+func (c HexColor) lerp(o HexColor, t float64) HexColor {
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	return HexColor{mix(c.R, o.R), mix(c.G, o.G), mix(c.B, o.B), mix(c.A, o.A)}
+}
+
+// MarshalText encodes the color as "#RRGGBB", adding the alpha byte only
+// when the color isn't fully opaque
+func (c HexColor) MarshalText() ([]byte, error) {
+	if c.A == 255 {
+		return fmt.Appendf(nil, "#%02x%02x%02x", c.R, c.G, c.B), nil
+	}
+	return fmt.Appendf(nil, "#%02x%02x%02x%02x", c.R, c.G, c.B, c.A), nil
+}
+
+// UnmarshalText parses "#RRGGBB" or "#RRGGBBAA" (the # is optional)
+func (c *HexColor) UnmarshalText(b []byte) error {
+	s := strings.TrimPrefix(string(b), "#")
+	if len(s) != 6 && len(s) != 8 {
+		return fmt.Errorf("color %q: want #RRGGBB or #RRGGBBAA", b)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return fmt.Errorf("color %q: %w", b, err)
+	}
+	if len(s) == 6 {
+		v = v<<8 | 0xff
+	}
+	*c = HexColor{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}
+	return nil
+}
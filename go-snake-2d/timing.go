@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// The snakes move on a clock of their own (see PlayingScene.Update), so
+// how often Ebiten calls Update, its TPS, only changes how smooth the
+// game looks and feels. The cosmetic effects that change a little every
+// update (shake, particles, trails, the starfield, the scrolling view)
+// and the key repeat are tuned for baseTPS and scaled to the rate the game
+// really runs at, so they last just as long at any TPS.
+
+// baseTPS is the update rate the per-update tuning constants are for
+// (Ebiten's default)
+const baseTPS = 60
+
+// updateRates are the update rates the options offer
+var updateRates = []int{30, 60, 120, 144, 240}
+
+// maxCatchUpTicks is how many moves the snakes may make in one update to
+// catch up with their clock when updates come slower than moves, e.g. at
+// 30 TPS and top speed; further behind than that (after a hitch), the
+// moves are dropped rather than played all at once
+const maxCatchUpTicks = 4
+
+// UpdateRate is Ebiten's TPS, in updates per second
+// Not to be confused with the -tps flag, which sets how fast the snakes
+// move.
+type UpdateRate int
+
+// validate checks the rate is one of updateRates
+func (r UpdateRate) validate() error {
+	if !slices.Contains(updateRates, int(r)) {
+		return fmt.Errorf("updateRate %d is not one of %v", int(r), updateRates)
+	}
+	return nil
+}
+
+// step steps the rate through updateRates by delta, wrapping around
+func (r UpdateRate) step(delta int) UpdateRate {
+	i := max(slices.Index(updateRates, int(r)), 0)
+	return UpdateRate(updateRates[cycle(i, delta, len(updateRates))])
+}
+
+// updateStep returns how many baseTPS updates one update is worth at the
+// current rate: 0.5 at 120 TPS, 2 at 30
+func updateStep() float64 {
+	return baseTPS / float64(ebiten.TPS())
+}
+
+// perUpdate converts a fraction kept every baseTPS update (a decay, like
+// shakeDecay) to the fraction to keep every update at the current rate
+func perUpdate(keep float64) float64 {
+	return math.Pow(keep, updateStep())
+}
+
+// updates converts a count of baseTPS updates (like repeatDelay) to
+// updates at the current rate, at least one
+func updates(n int) int {
+	return max(int(math.Round(float64(n)/updateStep())), 1)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// swipeThreshold is how far in pixels a finger has to travel before
+	// the movement counts as a swipe
+	swipeThreshold = 30
+
+	// tapMaxDuration and tapMaxDistance separate a tap from a slow or
+	// short drag
+	tapMaxDuration = 300 * time.Millisecond
+	tapMaxDistance = 12
+
+	// D-pad layout: four square buttons arranged around a center point
+	// dpadInset in from the bottom-right corner, within reach of the
+	// right thumb
+	dpadButtonSize = 56
+	dpadInset      = 96
+
+	// The pause button sits pauseButtonInset in from the top-right
+	// corner, away from the HUD
+	pauseButtonSize  = 40
+	pauseButtonInset = 8
+)
+
+var (
+	touchButtonColor        = color.RGBA{255, 255, 255, 40}
+	touchButtonPressedColor = color.RGBA{255, 255, 255, 100}
+	touchIconColor          = color.RGBA{255, 255, 255, 160}
+)
+
+// noButton marks a touch that didn't start on an on-screen button
+const noButton Action = -1
+
+// touchButton is an on-screen button that triggers an action
+type touchButton struct {
+	action     Action
+	x, y, size float32
+}
+
+// dpadButtons returns the four d-pad arrows, offset from the d-pad center
+// The d-pad keeps to the bottom-right corner whatever the screen's width.
+func dpadButtons() [4]touchButton {
+	cx, cy := float32(screenWidth-dpadInset), float32(screenHeight-dpadInset)
+	return [...]touchButton{
+		{ActionMoveUp, cx - dpadButtonSize/2, cy - dpadButtonSize*3/2, dpadButtonSize},
+		{ActionMoveDown, cx - dpadButtonSize/2, cy + dpadButtonSize/2, dpadButtonSize},
+		{ActionMoveLeft, cx - dpadButtonSize*3/2, cy - dpadButtonSize/2, dpadButtonSize},
+		{ActionMoveRight, cx + dpadButtonSize/2, cy - dpadButtonSize/2, dpadButtonSize},
+	}
+}
+
+// pauseButton returns the button that pauses and resumes the game, in the
+// top-right corner
+func pauseButton() touchButton {
+	return touchButton{ActionPause, float32(screenWidth - pauseButtonSize - pauseButtonInset), pauseButtonInset, pauseButtonSize}
+}
+
+// contains reports whether the screen position (x, y) is on the button
+func (b touchButton) contains(x, y int) bool {
+	fx, fy := float32(x), float32(y)
+	return fx >= b.x && fx < b.x+b.size && fy >= b.y && fy < b.y+b.size
+}
+
+// touchTrack follows one finger from the moment it touches the screen
+type touchTrack struct {
+	// originX and originY are where the finger went down, or where the
+	// last swipe fired so one long drag can steer several times
+	originX, originY int
+
+	startedAt time.Time
+
+	// swiped is set once the finger has triggered a swipe; it is then
+	// no longer a tap candidate
+	swiped bool
+
+	// button is the on-screen button the finger went down on
+	button Action
+}
+
+// TouchInput turns touch-screen gestures into actions, so the game is
+// playable on phones and in mobile browsers:
+//   - a swipe steers in the direction of the swipe
+//   - a tap selects menu entries and restarts after game over
+//   - the optional d-pad and the pause button act like held keys
+//
+// It is updated once per frame and queried like KeyBindings.
+type TouchInput struct {
+	touches map[ebiten.TouchID]*touchTrack
+
+	// pressed and justPressed are the actions triggered this frame
+	pressed, justPressed [actionCount]bool
+
+	// tapped is set for the frame a tap ends, at (tapX, tapY)
+	tapped     bool
+	tapX, tapY int
+
+	// used is set once the screen has been touched, which is when the
+	// touch-only buttons start being shown
+	used bool
+
+	// dpad enables the on-screen d-pad (see Settings.TouchDPad)
+	dpad bool
+
+	// ids is reused every frame to avoid allocating
+	ids []ebiten.TouchID
+}
+
+// update reads this frame's touches, now being the time of the frame
+func (t *TouchInput) update(now time.Time) {
+	if t.touches == nil {
+		t.touches = make(map[ebiten.TouchID]*touchTrack)
+	}
+	t.pressed = [actionCount]bool{}
+	t.justPressed = [actionCount]bool{}
+	t.tapped = false
+
+	// NEW TOUCHES
+	// A finger landing on a button presses it straight away
+	t.ids = inpututil.AppendJustPressedTouchIDs(t.ids[:0])
+	for _, id := range t.ids {
+		x, y := logicalPos(ebiten.TouchPosition(id))
+		tr := &touchTrack{originX: x, originY: y, startedAt: now, button: t.buttonAt(x, y)}
+		t.touches[id] = tr
+		t.used = true
+		if tr.button != noButton {
+			t.justPressed[tr.button] = true
+		}
+	}
+
+	for id, tr := range t.touches {
+		// RELEASED TOUCHES
+		// A short, still touch that didn't start on a button is a tap
+		if inpututil.IsTouchJustReleased(id) {
+			x, y := logicalPos(inpututil.TouchPositionInPreviousTick(id))
+			dist := math.Hypot(float64(x-tr.originX), float64(y-tr.originY))
+			if tr.button == noButton && !tr.swiped &&
+				now.Sub(tr.startedAt) <= tapMaxDuration && dist <= tapMaxDistance {
+				t.tapped, t.tapX, t.tapY = true, x, y
+				t.justPressed[ActionSelect] = true
+				t.justPressed[ActionRestart] = true
+			}
+			delete(t.touches, id)
+			continue
+		}
+
+		// HELD BUTTONS
+		if tr.button != noButton {
+			t.pressed[tr.button] = true
+			continue
+		}
+
+		// SWIPES
+		// Fire as soon as the finger has moved far enough rather than on
+		// release, so turns happen without lifting the finger
+		x, y := logicalPos(ebiten.TouchPosition(id))
+		dx, dy := x-tr.originX, y-tr.originY
+		if max(abs(dx), abs(dy)) < swipeThreshold {
+			continue
+		}
+		action := ActionMoveRight
+		switch {
+		case abs(dx) >= abs(dy) && dx < 0:
+			action = ActionMoveLeft
+		case abs(dy) > abs(dx) && dy < 0:
+			action = ActionMoveUp
+		case abs(dy) > abs(dx):
+			action = ActionMoveDown
+		}
+		t.pressed[action] = true
+		t.justPressed[action] = true
+		tr.swiped = true
+		tr.originX, tr.originY = x, y
+	}
+}
+
+// buttonAt returns the action of the visible button at (x, y), or
+// noButton
+func (t *TouchInput) buttonAt(x, y int) Action {
+	if pause := pauseButton(); pause.contains(x, y) {
+		return pause.action
+	}
+	if t.dpad {
+		for _, b := range dpadButtons() {
+			if b.contains(x, y) {
+				return b.action
+			}
+		}
+	}
+	return noButton
+}
+
+// isPressed reports whether a gesture or button is triggering the action
+func (t *TouchInput) isPressed(action Action) bool {
+	return t.pressed[action] || t.justPressed[action]
+}
+
+// isJustPressed reports whether a gesture or button triggered the action
+// this frame
+func (t *TouchInput) isJustPressed(action Action) bool {
+	return t.justPressed[action]
+}
+
+// tap returns where a tap ended this frame, if one did
+func (t *TouchInput) tap() (x, y int, ok bool) {
+	return t.tapX, t.tapY, t.tapped
+}
+
+// draw renders the pause button and the d-pad
+// Nothing is drawn until the screen has been touched, so keyboard
+// players never see them.
+func (t *TouchInput) draw(screen *ebiten.Image) {
+	if !t.used {
+		return
+	}
+	pause := pauseButton()
+	t.drawButton(screen, pause)
+	// Two bars: the usual pause symbol
+	bx, by, s := pause.x, pause.y, pause.size
+	fillRect(screen, bx+s*0.3, by+s*0.25, s*0.14, s*0.5, touchIconColor, false)
+	fillRect(screen, bx+s*0.56, by+s*0.25, s*0.14, s*0.5, touchIconColor, false)
+
+	if !t.dpad {
+		return
+	}
+	for _, b := range dpadButtons() {
+		t.drawButton(screen, b)
+		drawArrow(screen, b)
+	}
+}
+
+// drawButton draws a button's background, brighter while it is held
+func (t *TouchInput) drawButton(screen *ebiten.Image, b touchButton) {
+	clr := touchButtonColor
+	if t.isPressed(b.action) {
+		clr = touchButtonPressedColor
+	}
+	fillRect(screen, b.x, b.y, b.size, b.size, clr, false)
+}
+
+// drawArrow draws a triangle on a d-pad button pointing in its direction
+func drawArrow(screen *ebiten.Image, b touchButton) {
+	// The path is built in screen pixels, since it is drawn directly
+	k := float32(displayScale)
+	cx, cy := (b.x+b.size/2)*k, (b.y+b.size/2)*k
+	r := b.size * 0.25 * k
+
+	// Tip, then the two corners of the base, for an arrow pointing up;
+	// rotated for the other directions
+	pts := [3][2]float32{{0, -r}, {-r, r * 0.6}, {r, r * 0.6}}
+	var p vector.Path
+	for i, pt := range pts {
+		x, y := pt[0], pt[1]
+		switch b.action {
+		case ActionMoveDown:
+			x, y = -x, -y
+		case ActionMoveLeft:
+			x, y = y, -x
+		case ActionMoveRight:
+			x, y = -y, x
+		}
+		if i == 0 {
+			p.MoveTo(cx+x, cy+y)
+		} else {
+			p.LineTo(cx+x, cy+y)
+		}
+	}
+	p.Close()
+
+	op := &vector.DrawPathOptions{AntiAlias: true}
+	op.ColorScale.ScaleWithColor(touchIconColor)
+	vector.FillPath(screen, &p, nil, op)
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Motion trail tuning
+const (
+	// trailOpacity is how opaque a cell's afterimage starts out
+	trailOpacity = 0.35
+
+	// trailFade is the fraction of its opacity the trail loses every
+	// update at baseTPS (see timing.go)
+	trailFade = 0.08
+)
+
+// Trail is the fading afterimage the snakes leave in the cells they have
+// just left
+// Vacated cells are stamped into an offscreen image of the board, which
+// loses a little of its opacity every update, so old stamps fade out by
+// themselves instead of being tracked one by one. The image is in logical
+// pixels and scaled up when drawn: the afterimage is soft anyway, and a
+// big scrolling board stays affordable.
+type Trail struct {
+	img *ebiten.Image
+
+	// fader is a white pixel, stretched over img to fade it
+	fader *ebiten.Image
+}
+
+// fadeBlend scales what is already in the image by one minus the source
+// alpha, leaving everything a little more transparent
+var fadeBlend = ebiten.Blend{
+	BlendFactorSourceRGB:        ebiten.BlendFactorZero,
+	BlendFactorSourceAlpha:      ebiten.BlendFactorZero,
+	BlendFactorDestinationRGB:   ebiten.BlendFactorOneMinusSourceAlpha,
+	BlendFactorDestinationAlpha: ebiten.BlendFactorOneMinusSourceAlpha,
+	BlendOperationRGB:           ebiten.BlendOperationAdd,
+	BlendOperationAlpha:         ebiten.BlendOperationAdd,
+}
+
+// reset clears the trail for a w×h pixel board, e.g. when a run starts
+func (t *Trail) reset(w, h int) {
+	if t.img != nil && (t.img.Bounds().Dx() != w || t.img.Bounds().Dy() != h) {
+		t.img.Deallocate()
+		t.img = nil
+	}
+	if t.img == nil {
+		t.img = ebiten.NewImage(max(w, 1), max(h, 1))
+	}
+	t.img.Clear()
+}
+
+// stamp leaves the afterimage of a cell-sized square at (x, y), in pixels
+// from the board's top-left corner
+func (t *Trail) stamp(x, y, size float32, clr color.Color) {
+	if t.img == nil {
+		return
+	}
+	vector.FillRect(t.img, x, y, size, size, faded(clr, trailOpacity), false)
+}
+
+// update fades the whole trail a little
+func (t *Trail) update() {
+	if t.img == nil {
+		return
+	}
+	if t.fader == nil {
+		t.fader = ebiten.NewImage(1, 1)
+		t.fader.Fill(color.White)
+	}
+	b := t.img.Bounds()
+	op := &ebiten.DrawImageOptions{Blend: fadeBlend}
+	op.GeoM.Scale(float64(b.Dx()), float64(b.Dy()))
+	op.ColorScale.ScaleAlpha(float32(1 - perUpdate(1-trailFade)))
+	t.img.DrawImage(t.fader, op)
+}
+
+// draw renders the trail with the board's top-left corner at (ox, oy)
+func (t *Trail) draw(screen *ebiten.Image, ox, oy float32) {
+	if t.img == nil {
+		return
+	}
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Translate(float64(ox), float64(oy))
+	op.GeoM.Scale(displayScale, displayScale)
+	screen.DrawImage(t.img, op)
+}
+
+// stampTrails stamps the cells each snake left on its last move, going
+// by where the snakes were before it (see prevSnakes)
+func (g *Game) stampTrails() {
+	v := g.view
+	for i, p := range g.world.Players {
+		if i >= len(g.prevSnakes) || p.Dead {
+			continue
+		}
+		for _, c := range g.prevSnakes[i] {
+			if p.Snake.Contains(c) {
+				continue
+			}
+			x, y := v.local(c)
+			g.trail.stamp(x-v.cell/2, y-v.cell/2, v.cell, g.playerColor(i))
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/obliviousorion/go-basics/pkg/snake"
+)
+
+// Variant selects the goal of a solo run
+// Versus rounds are always played as Classic.
+type Variant int
+
+const (
+	// VariantClassic plays until the snake dies, for the highest score
+	VariantClassic Variant = iota
+
+	// VariantTimeAttack races to grow the snake to timeAttackLength;
+	// the fastest times make the table
+	VariantTimeAttack
+
+	// VariantTimed scores as much as possible before timedDuration runs
+	// out
+	VariantTimed
+
+	// VariantSurvival closes the board in ring by ring as time goes on
+	// (see snake.Arena); like Classic it is played for the highest score
+	VariantSurvival
+
+	// VariantDaily is the daily challenge: a Classic run seeded from the
+	// date, the same for everyone (see daily.go)
+	VariantDaily
+
+	variantCount
+)
+
+var variantNames = [variantCount]string{"Classic", "Time Attack", "Timed", "Survival", "Daily"}
+
+// timeAttackLength is the snake length that finishes a time attack run
+const timeAttackLength = 30
+
+// timedDuration is how long a timed run lasts
+const timedDuration = 2 * time.Minute
+
+// timedFoodLifetime is how long food stays put in timed and time attack
+// runs whose difficulty doesn't set a lifetime
+const timedFoodLifetime = 12 * time.Second
+
+// survivalWarning is how long the survival arena's next ring flashes
+// before it closes; how often rings close and how far the arena shrinks
+// depend on the difficulty (see difficultyPresets)
+const survivalWarning = 2 * time.Second
+
+// arena returns the shrinking arena for the run's variant, or nil
+func (g *Game) arena() *snake.Arena {
+	if g.variant != VariantSurvival {
+		return nil
+	}
+	p := difficultyPresets[g.runDifficulty]
+	return &snake.Arena{
+		Interval:  p.shrinkInterval,
+		MinWidth:  p.arenaMinWidth,
+		MinHeight: p.arenaMinHeight,
+	}
+}
+
+// ranksByTime reports whether the variant's table is ordered by fastest
+// time rather than by highest score
+func (v Variant) ranksByTime() bool {
+	return v == VariantTimeAttack
+}
+
+// goalReached reports whether the solo run has met its variant's goal
+// at now, which ends it as a finish rather than a death
+// Filling the whole board wins any run, as does beating a boss.
+func (g *Game) goalReached(now time.Time) bool {
+	if g.world.Full || g.world.BossDefeated() {
+		return true
+	}
+	if g.mode == ModeSplit {
+		return g.world.Players[0].Score >= splitTargetScore
+	}
+	if g.mode != ModeSolo {
+		return false
+	}
+	switch g.variant {
+	case VariantTimeAttack:
+		return g.world.Players[0].Snake.Len() >= timeAttackLength
+	case VariantTimed:
+		return g.elapsed(now) >= timedDuration
+	}
+	return false
+}
+
+// elapsed returns how long the run has been going at now, not counting
+// time spent paused or counting down
+func (g *Game) elapsed(now time.Time) time.Duration {
+	return now.Sub(g.runStart)
+}
+
+// formatRunTime formats a run time with millisecond precision, e.g.
+// "1:05.250"
+func formatRunTime(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	return fmt.Sprintf("%d:%02d.%03d", int(d/time.Minute), int(d%time.Minute/time.Second), int(d%time.Second/time.Millisecond))
+}
+
+// arenaWarningColor is the flashing ring about to close
+var arenaWarningColor = color.RGBA{200, 40, 40, 160}
+
+// drawArena covers the closed rings of the arena with wall, and flashes
+// the next ring when it is about to close
+func (g *Game) drawArena(screen *ebiten.Image) {
+	w, v := g.world, g.view
+	lo, hi := w.Bounds()
+	bw, bh := v.size()
+	drawFrame(screen, v.x, v.y, bw, bh, float32(lo.X)*v.cell, g.theme.Obstacle)
+
+	now := g.clock.Now()
+	if w.ShrinkWarning(now, survivalWarning) && w.Arena.NextShrinkAt.Sub(now)/deathFlashInterval%2 == 0 {
+		x, y := v.cellPos(lo)
+		x2, y2 := v.cellPos(hi)
+		drawFrame(screen, x, y, x2-x, y2-y, v.cell, arenaWarningColor)
+	}
+}
+
+// drawFrame fills a band of the given thickness just inside the edges of
+// the w×h rectangle at (x, y)
+func drawFrame(screen *ebiten.Image, x, y, w, h, thickness float32, clr color.Color) {
+	if thickness <= 0 {
+		return
+	}
+	fillRect(screen, x, y, w, thickness, clr, false)
+	fillRect(screen, x, y+h-thickness, w, thickness, clr, false)
+	fillRect(screen, x, y+thickness, thickness, h-2*thickness, clr, false)
+	fillRect(screen, x+w-thickness, y+thickness, thickness, h-2*thickness, clr, false)
+}
+
+// MarshalText encodes the variant by name
+func (v Variant) MarshalText() ([]byte, error) {
+	return marshalName(variantNames[:], int(v))
+}
+
+// UnmarshalText decodes the variant from its name
+func (v *Variant) UnmarshalText(b []byte) error {
+	return unmarshalName(variantNames[:], b, (*int)(v))
+}
+
+// String returns the display name of the variant
+func (v Variant) String() string {
+	return variantNames[v]
+}